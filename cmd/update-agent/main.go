@@ -6,10 +6,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/coreos/go-systemd/v22/login1"
 	"github.com/coreos/pkg/flagutil"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 
 	"github.com/flatcar/flatcar-linux-update-operator/pkg/agent"
@@ -27,6 +29,55 @@ var (
 
 	reapTimeout = flag.Int("grace-period", defaultGracePeriodSeconds,
 		"Period of time in seconds given to a pod to terminate when rebooting for an update")
+
+	drainJobGrace = flag.Duration("drain-job-grace", 0,
+		"Maximum period of time to wait for pods owned by a Job to complete before evicting them. "+
+			"E.g. '10m'. Job pods are evicted immediately if not set")
+
+	drainGracePeriod = flag.Int("drain-grace-period", 0,
+		"Period of time in seconds given to each pod to terminate when evicted. Pod's own termination grace "+
+			"period is used if not set")
+
+	drainTimeout = flag.Duration("drain-timeout", 0,
+		"Maximum period of time to spend draining the node before rebooting anyway. E.g. '15m'. "+
+			"Defaults to the value of -grace-period")
+
+	drainLastPodSelectors = flag.String("drain-last-pod-selectors", "",
+		"List of semicolon-separated label selectors matching pods, which are evicted only after all other pods "+
+			"are gone. E.g. 'app=foo;tier=control,team=platform'. Defaults to operator pods if not set")
+
+	drainByPriority = flag.Bool("drain-by-priority", false,
+		"Evict pods in tiers of equal priority, starting with lowest priority, so critical workloads are "+
+			"evicted last")
+
+	drainPriorityTierPause = flag.Duration("drain-priority-tier-pause", 0,
+		"Period of time to wait between evicting priority tiers when -drain-by-priority is enabled. "+
+			"E.g. '10s'. Defaults to 5 seconds")
+
+	drainGracePeriodAnnotation = flag.String("drain-grace-period-annotation", "",
+		"Pod annotation holding period of time in seconds the pod needs to terminate. Pods requesting longer "+
+			"grace period than they would get otherwise are evicted with it and waited for before rebooting. "+
+			"E.g. 'example.com/drain-grace-period-seconds'")
+
+	drainMaxGracePeriod = flag.Duration("drain-max-grace-period", 0,
+		"Maximum grace period pods may request using -drain-grace-period-annotation. E.g. '30m'. "+
+			"Defaults to 1 hour")
+
+	drainVolumeDetachAccessModes = flag.String("drain-volume-detach-access-modes", "",
+		"List of comma-separated access modes of persistent volume claims. After draining, volumes of evicted "+
+			"pods using claims with any of given access modes are waited for to detach before rebooting. "+
+			"E.g. 'ReadWriteOnce,ReadWriteOncePod'. Volumes are not waited for if not set")
+
+	drainVolumeDetachTimeout = flag.Duration("drain-volume-detach-timeout", 0,
+		"Maximum period of time to wait for volumes to detach before rebooting anyway. E.g. '10m'. "+
+			"Defaults to 5 minutes")
+
+	drainConcurrency = flag.Int("drain-concurrency", 0,
+		"Maximum number of pods evicted at once. Pods are evicted all at once if not set")
+
+	annotationPrefix = flag.String("annotation-prefix", "",
+		"Prefix of labels and annotations used to coordinate with the update-operator, which must be configured "+
+			"with the same prefix. E.g. 'example.com/'. Defaults to 'flatcar-linux-update.v1.flatcar-linux.net/'")
 )
 
 func main() {
@@ -68,12 +119,37 @@ func main() {
 		klog.Fatalf("Failed establishing connection to logind dbus: %v", err)
 	}
 
+	var drainLastSelectors []string
+	if *drainLastPodSelectors != "" {
+		drainLastSelectors = strings.Split(*drainLastPodSelectors, ";")
+	}
+
+	var volumeDetachAccessModes []corev1.PersistentVolumeAccessMode
+
+	if *drainVolumeDetachAccessModes != "" {
+		for _, accessMode := range strings.Split(*drainVolumeDetachAccessModes, ",") {
+			volumeDetachAccessModes = append(volumeDetachAccessModes, corev1.PersistentVolumeAccessMode(accessMode))
+		}
+	}
+
 	config := &agent.Config{
-		NodeName:               *node,
-		PodDeletionGracePeriod: time.Duration(*reapTimeout) * time.Second,
-		Clientset:              clientset,
-		StatusReceiver:         updateEngineClient,
-		Rebooter:               rebooter,
+		NodeName:                     *node,
+		PodDeletionGracePeriod:       time.Duration(*reapTimeout) * time.Second,
+		Clientset:                    clientset,
+		StatusReceiver:               updateEngineClient,
+		Rebooter:                     rebooter,
+		DrainJobGrace:                *drainJobGrace,
+		DrainLastPodSelectors:        drainLastSelectors,
+		DrainGracePeriodSeconds:      *drainGracePeriod,
+		DrainTimeout:                 *drainTimeout,
+		DrainByPriority:              *drainByPriority,
+		DrainPriorityTierPause:       *drainPriorityTierPause,
+		DrainGracePeriodAnnotation:   *drainGracePeriodAnnotation,
+		DrainMaxGracePeriod:          *drainMaxGracePeriod,
+		DrainVolumeDetachAccessModes: volumeDetachAccessModes,
+		DrainVolumeDetachTimeout:     *drainVolumeDetachTimeout,
+		DrainConcurrency:             *drainConcurrency,
+		AnnotationPrefix:             *annotationPrefix,
 	}
 
 	agent, err := agent.New(config)
@@ -2,46 +2,349 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/coreos/pkg/flagutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/klog/v2"
 
 	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/metrics"
 	"github.com/flatcar/flatcar-linux-update-operator/pkg/operator"
 	"github.com/flatcar/flatcar-linux-update-operator/pkg/version"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/webhook"
+)
+
+const (
+	commandTimeout = time.Minute
+
+	// notificationSecretEnv is environment variable holding notification webhook secret, used when
+	// secret file is not configured.
+	notificationSecretEnv = "UPDATE_OPERATOR_NOTIFICATION_WEBHOOK_SECRET"
 )
 
 type flagsSet struct {
 	beforeRebootAnnotations flagutil.StringSliceFlag
 	afterRebootAnnotations  flagutil.StringSliceFlag
+	reconcilePhases         flagutil.StringSliceFlag
+	rebootWindows           flagutil.StringSliceFlag
+	exclusionWindows        flagutil.StringSliceFlag
+	rebootWindowProfiles    flagutil.StringSliceFlag
+	approvalLabels          flagutil.StringSliceFlag
+	afterRebootWorkloads    flagutil.StringSliceFlag
+	annotationDescriptions  flagutil.StringSliceFlag
+	protectedNamespaces     flagutil.StringSliceFlag
+	drainCompleteAnnotation *string
 	kubeconfig              *string
+	namespace               *string
 	rebootWindowStart       *string
 	rebootWindowLength      *string
+	metricsAddress          *string
+	beforeRebootSelector    *string
+	pauseSelector           *string
+	excludeNodeLabel        *string
+	onlyRebootOutdated      *bool
+	newVersionAnnotation    *string
+	rebootReasonSource      *string
+	lastRebootReason        *string
+	annotationPrefix        *string
+	karpenterDoNotDisrupt   *bool
+	cordonBeforeReboot      *bool
+	reconcileTimeout        *time.Duration
+	updateMaturityDelay     *time.Duration
+	rebootSLO               *time.Duration
+	stabilizationPeriod     *time.Duration
+	interRebootDelay        *time.Duration
+	nodeSelector            *string
+	watchNodes              *bool
+	minReadyFraction        *float64
+	nodeChangeDebounce      *time.Duration
+	errorRetryBackoff       *time.Duration
+	lockType                *string
+	lockName                *string
+	eventSourceComponent    *string
+	leaderElectionComponent *string
+	disableLeaderElection   *bool
+	leaderElectionTimeout   *time.Duration
+	rebootPriority          *string
+	nodeSelectionStrategy   *string
+	notReadyNodePolicy      *string
+	bootTimeAnnotation      *string
+	zoneLabel               *string
+	rebootGroupLabel        *string
+	rolloutOrderLabel       *string
+	pauseConfigMap          *string
+	rebootWindowConfigMap   *string
+	rebootingNodesConfigMap *string
+	healthAddress           *string
+	statusAddress           *string
+	unhealthyAfter          *time.Duration
+	rebootTimeout           *time.Duration
+	skipStuckNodes          *bool
+	blockingPodAnnotation   *string
+	checkPDBs               *bool
+	blockOnProtected        *bool
+	deferRunningJobs        *bool
+	rebootTaint             *string
+	notificationWebhookURL  *string
+	notificationTemplate    *string
+	notificationSecretFile  *string
+	forceRebootLimit        *int
+	beforeRebootTimeout     *time.Duration
+	beforeRebootAction      *string
+	beforeRebootGracePeriod *time.Duration
+	afterRebootTimeout      *time.Duration
+	afterRebootBackoff      *time.Duration
+	afterRebootMaxAttempts  *int
+	smokeTestPodTemplate    *string
+	smokeTestTimeout        *time.Duration
 	printVersion            *bool
+	validate                *bool
 }
 
 func handleFlags() *flagsSet {
 	flags := &flagsSet{
 		kubeconfig: flag.String("kubeconfig", "",
 			"Path to a kubeconfig file. Default to the in-cluster config if not provided."),
+		namespace: flag.String("namespace", "",
+			"Namespace to keep leader election lock in. Default to the value of POD_NAMESPACE environment variable"),
 
 		rebootWindowStart: flag.String("reboot-window-start", "",
 			"Day of week ('Sun', 'Mon', ...; optional) and time of day at which the reboot window starts. "+
 				"E.g. 'Mon 14:00', '11:00'"),
 
 		rebootWindowLength: flag.String("reboot-window-length", "", "Length of the reboot window. E.g. '1h30m'"),
-		printVersion:       flag.Bool("version", false, "Print version and exit"),
+		metricsAddress: flag.String("metrics-address", "",
+			"Address to serve Prometheus metrics on, e.g. ':8080'. Metrics are not served if empty."),
+		beforeRebootSelector: flag.String("before-reboot-label-selector", "",
+			"Label selector which nodes must match in addition to before reboot annotations before a reboot is "+
+				"allowed. E.g. 'fluo-approved=true'"),
+		pauseSelector: flag.String("pause-label-selector", "",
+			"Label selector matching nodes which should not be rebooted. E.g. 'fluo-paused=true'"),
+		onlyRebootOutdated: flag.Bool("only-reboot-outdated", false,
+			"Reboot nodes only when version of the pending update differs from the version they run"),
+		newVersionAnnotation: flag.String("new-version-annotation", "",
+			"Annotation holding version of the pending update, used with -only-reboot-outdated. "+
+				"Defaults to the annotation set by the update-agent"),
+		rebootReasonSource: flag.String("reboot-reason-source-annotation", "",
+			"Annotation from which reason of the reboot is read when the reboot process starts. "+
+				"Defaults to the annotation holding version of the pending update set by the update-agent"),
+		lastRebootReason: flag.String("last-reboot-reason-annotation", "",
+			"Annotation to which reason of the reboot is written when the reboot process finishes. "+
+				"Defaults to 'flatcar-linux-update.v1.flatcar-linux.net/last-reboot-reason'"),
+		annotationPrefix: flag.String("annotation-prefix", "",
+			"Prefix of labels and annotations used to coordinate with the update-agent, which must be configured "+
+				"with the same prefix. E.g. 'example.com/'. Defaults to 'flatcar-linux-update.v1.flatcar-linux.net/'"),
+		excludeNodeLabel: flag.String("exclude-node-label", "",
+			"Label selector matching nodes which are never rebooted, leaving their reboot state untouched. "+
+				"E.g. 'flatcar.io/reboot-exclude=true'"),
+		karpenterDoNotDisrupt: flag.Bool("karpenter-do-not-disrupt", false,
+			"Annotate nodes going through the reboot process with 'karpenter.sh/do-not-disrupt' to prevent "+
+				"Karpenter from disrupting them. The annotation is removed once the reboot process finishes"),
+		cordonBeforeReboot: flag.Bool("cordon-before-reboot", false,
+			"Mark nodes as unschedulable when scheduling their reboot and as schedulable again once after "+
+				"reboot checks pass. Nodes which were already unschedulable are left unschedulable"),
+		reconcileTimeout: flag.Duration("reconcile-timeout", 0,
+			"Maximum duration of a single reconciliation loop. Defaults to 90% of the reconciliation period"),
+		updateMaturityDelay: flag.Duration("update-maturity-delay", 0,
+			"Minimum time since an update became available on a node before the node is rebooted. E.g. '72h'"),
+		rebootSLO: flag.Duration("reboot-slo", 0,
+			"Expected maximum duration of the reboot process of a node. Warning event is emitted for nodes "+
+				"exceeding it. E.g. '20m'"),
+		stabilizationPeriod: flag.Duration("post-reboot-stabilization-period", 0,
+			"Minimum time a rebooted node must be continuously Ready before after reboot checks start. E.g. '5m'"),
+		interRebootDelay: flag.Duration("inter-reboot-delay", 0,
+			"Minimum time to wait after a node finishes rebooting before rebooting the next node. E.g. '10m'"),
+		nodeSelector: flag.String("node-selector", "",
+			"Label selector limiting which nodes are managed by the operator. E.g. 'flatcar.io/managed=true'"),
+		watchNodes: flag.Bool("watch-nodes", false,
+			"Reconcile as soon as reboot related labels or annotations of a node change, in addition to "+
+				"reconciling periodically"),
+		minReadyFraction: flag.Float64("min-ready-fraction", 0,
+			"Minimum fraction of nodes, between 0 and 1, which must remain Ready. Nodes are not rebooted when "+
+				"it would drop the fraction of Ready nodes below it. E.g. '0.9'"),
+		nodeChangeDebounce: flag.Duration("node-change-debounce", 0,
+			"Time to wait after a node change before reconciling when watching nodes. Defaults to '1s'"),
+		errorRetryBackoff: flag.Duration("error-retry-backoff", 0,
+			"Time to wait before retrying failed reconciliation, doubled after each consecutive failure up to "+
+				"the reconciliation period. Defaults to '1s'"),
+		rebootPriority: flag.String("reboot-priority-annotation", "",
+			"Node annotation holding integer reboot priority. Nodes with lower priority are rebooted first, "+
+				"nodes without the annotation have priority 0. E.g. 'flatcar.io/reboot-priority'"),
+		nodeSelectionStrategy: flag.String("node-selection-strategy", "",
+			"Order in which nodes requiring a reboot are rebooted. Either 'priority', which honors "+
+				"--reboot-priority-annotation, or 'uptime', which reboots nodes up the longest first. "+
+				"Defaults to 'priority'"),
+		notReadyNodePolicy: flag.String("not-ready-node-policy", "",
+			"How to handle nodes requiring a reboot which are not Ready. Either 'skip', which never reboots them, "+
+				"'last', which reboots them after Ready nodes, or 'first', which reboots them before Ready nodes. "+
+				"Defaults to rebooting them like any other node"),
+		bootTimeAnnotation: flag.String("boot-time-annotation", "",
+			"Node annotation holding UNIX timestamp of node boot time, used by 'uptime' node selection strategy. "+
+				"Nodes without it are considered booted when they became Ready. "+
+				"Defaults to 'flatcar-linux-update.v1.flatcar-linux.net/boot-time'"),
+		zoneLabel: flag.String("zone-label", "",
+			"Node label holding the zone of the node. If set, at most one node per zone is rebooted at a time. "+
+				"E.g. 'topology.kubernetes.io/zone'"),
+		rebootGroupLabel: flag.String("reboot-group-label", "",
+			"Node label holding the reboot group of the node. If set, maximum number of rebooting nodes applies "+
+				"to each group of nodes with the same label value independently. E.g. 'node.kubernetes.io/pool'"),
+		rolloutOrderLabel: flag.String("rollout-order-label", "",
+			"Node label holding integer rollout order. If set, nodes are rebooted group by group in ascending "+
+				"order, nodes without the label have order 0. E.g. 'flatcar.io/rollout-order'"),
+		pauseConfigMap: flag.String("pause-configmap", "",
+			"Name of the ConfigMap in operator namespace pausing scheduling of new reboots when its 'paused' key "+
+				"is set to 'true'. Reboots in progress are still completed. E.g. 'flatcar-linux-update-operator-pause'"),
+		rebootWindowConfigMap: flag.String("reboot-window-configmap", "",
+			"Name of the ConfigMap in operator namespace holding the reboot window under 'rebootWindowStart' and "+
+				"'rebootWindowLength' keys, which replaces --reboot-window-start and --reboot-window-length and "+
+				"can be changed without restarting the operator. E.g. 'flatcar-linux-update-operator-reboot-window'"),
+		rebootingNodesConfigMap: flag.String("rebooting-nodes-configmap", "",
+			"Name of the ConfigMap in operator namespace in which names of currently rebooting nodes are recorded "+
+				"under 'rebootingNodes' key. E.g. 'flatcar-linux-update-operator-rebooting-nodes'"),
+		healthAddress: flag.String("health-address", "",
+			"Address to serve /healthz and /readyz endpoints on. E.g. ':8081'"),
+		statusAddress: flag.String("status-address", "",
+			"Address to serve /status endpoint with reboot state of nodes as JSON on. E.g. ':8082'"),
+		unhealthyAfter: flag.Duration("unhealthy-after", 0,
+			"Duration without successful reconciliation after which /healthz reports failure. "+
+				"Defaults to 10 reconciliation periods"),
+		rebootTimeout: flag.Duration("reboot-timeout", 0,
+			"Maximum duration since approving the reboot of a node until it finishes rebooting. Warning event is "+
+				"emitted for nodes exceeding it. E.g. '1h'"),
+		skipStuckNodes: flag.Bool("skip-stuck-nodes", false,
+			"Do not count nodes exceeding reboot timeout towards the maximum number of rebooting nodes"),
+		blockingPodAnnotation: flag.String("blocking-pod-annotation", "",
+			"Pod annotation which, when set to 'true', prevents the node running the pod from being rebooted. "+
+				"E.g. 'flatcar.io/do-not-evict'"),
+		checkPDBs: flag.Bool("check-pod-disruption-budgets", false,
+			"Do not schedule reboot of nodes running pods whose PodDisruptionBudget currently allows no disruptions"),
+		blockOnProtected: flag.Bool("block-on-protected-namespaces", false,
+			"Do not schedule reboot of nodes running pods from namespaces given by -protected-namespaces at all, "+
+				"instead of rebooting them after other nodes, e.g. when nodes are not drained before rebooting"),
+		deferRunningJobs: flag.Bool("defer-nodes-running-jobs", false,
+			"Do not schedule reboot of nodes running pods owned by Jobs, including CronJobs, until the pods complete"),
+		rebootTaint: flag.String("reboot-taint", "",
+			"Taint in format '<key>[=<value>]:<effect>' added to nodes scheduled for rebooting and removed once "+
+				"after reboot checks pass. E.g. 'flatcar.io/rebooting=true:NoSchedule'"),
+		notificationWebhookURL: flag.String("notification-webhook-url", "",
+			"URL of the webhook notified when nodes start and finish rebooting, e.g. Slack incoming webhook. "+
+				"Notifications are not sent if empty"),
+		notificationTemplate: flag.String("notification-template", "",
+			fmt.Sprintf("Go template of the notification text, with access to .Node, .Event and .Time fields. "+
+				"Default to %q", operator.DefaultNotificationTemplate)),
+		notificationSecretFile: flag.String("notification-webhook-secret-file", "",
+			"Path to file with secret used for signing notifications in '"+webhook.SignatureHeader+"' header. "+
+				"If empty, secret is read from "+notificationSecretEnv+" environment variable. "+
+				"Notifications are not signed if no secret is configured"),
+		lockType: flag.String("leader-election-resource-lock", resourcelock.LeasesResourceLock,
+			"Type of the resource used for leader election lock. Either 'leases' or 'configmapsleases'. "+
+				"Use 'configmapsleases' when migrating from ConfigMap lock used by older versions"),
+		lockName: flag.String("leader-election-resource-name", "",
+			"Name of the resource used for leader election lock. Instances managing different nodes in the same "+
+				"namespace must use different names. Defaults to 'flatcar-linux-update-operator-lock'"),
+		eventSourceComponent: flag.String("event-source-component", "",
+			"Component reported as the source of events about nodes. Instances managing different nodes may use "+
+				"different components to tell their events apart. Defaults to 'update-operator'"),
+		leaderElectionComponent: flag.String("leader-election-event-source-component", "",
+			"Component reported as the source of events about leader election. "+
+				"Defaults to 'update-operator-leader-election'"),
+		disableLeaderElection: flag.Bool("disable-leader-election", false,
+			"Start reconciling without acquiring leader election lock, e.g. when running locally. "+
+				"Only a single instance of the operator may run then"),
+		leaderElectionTimeout: flag.Duration("leader-election-timeout", 0,
+			"Maximum duration to wait for acquiring leader election lock on startup before exiting with an "+
+				"error, e.g. when lacking permissions to the lock. Waits indefinitely if not set. E.g. '10m'"),
+		forceRebootLimit: flag.Int("force-reboot-limit", 0,
+			"Maximum number of nodes going through the reboot process at once when forcing reboot of a node "+
+				"using 'force-reboot' command. Defaults to 2"),
+		beforeRebootTimeout: flag.Duration("before-reboot-timeout", 0,
+			"Maximum duration a node may wait for before reboot checks. When exceeded, "+
+				"--before-reboot-timeout-action is taken. Waits indefinitely if not set. E.g. '1h'"),
+		beforeRebootAction: flag.String("before-reboot-timeout-action", "",
+			"Action taken when before reboot checks do not pass within --before-reboot-timeout. Either 'abort', "+
+				"which aborts the reboot process and reboots the node after other nodes, or 'proceed', which "+
+				"reboots the node anyway. Defaults to 'abort'"),
+		beforeRebootGracePeriod: flag.Duration("before-reboot-revert-grace-period", 0,
+			"Duration a node running before reboot checks must keep not wanting to reboot before its before "+
+				"reboot checks are reverted. Reverts immediately if not set. E.g. '5m'"),
+		afterRebootTimeout: flag.Duration("after-reboot-check-timeout", 0,
+			"Maximum duration of a single attempt of after reboot checks. When exceeded, checks are retried "+
+				"after a backoff. Waits indefinitely if not set. E.g. '30m'"),
+		afterRebootBackoff: flag.Duration("after-reboot-check-backoff", 0,
+			"Backoff before retrying after reboot checks for the first time, doubled with every failed attempt up to 1h. "+
+				"Defaults to 1m"),
+		afterRebootMaxAttempts: flag.Int("after-reboot-check-max-attempts", 0,
+			"Number of failed attempts of after reboot checks after which the node is labeled as failed to "+
+				"reboot. Defaults to 3"),
+		smokeTestPodTemplate: flag.String("after-reboot-smoke-test-pod-template", "",
+			"Path to YAML or JSON file with Pod template of a Job run on each rebooted node, which must succeed "+
+				"before after reboot checks pass. If the Job fails, the node is labeled as failed to reboot"),
+		smokeTestTimeout: flag.Duration("after-reboot-smoke-test-timeout", 0,
+			"Time within which smoke test Job must succeed. Defaults to '10m'"),
+		drainCompleteAnnotation: flag.String("drain-complete-annotation", "",
+			"Node annotation which must be set to 'true' by custom drain logic once the node is drained, "+
+				"before a reboot is allowed. E.g. 'example.com/drain-complete'"),
+		printVersion: flag.Bool("version", false, "Print version and exit"),
+		validate: flag.Bool("validate", false,
+			"Validate configuration and exit without connecting to the cluster"),
 	}
 
 	flag.Var(&flags.beforeRebootAnnotations, "before-reboot-annotations",
-		"List of comma-separated Kubernetes node annotations that must be set to 'true' before a reboot is allowed")
+		"List of comma-separated Kubernetes node annotations that must be set to 'true' before a reboot is allowed. "+
+			"Use <annotation>=<value> to require a different value")
 
 	flag.Var(&flags.afterRebootAnnotations, "after-reboot-annotations",
 		"List of comma-separated Kubernetes node annotations that must be set to 'true' before a node is marked "+
-			"schedulable and the operator lock is released")
+			"schedulable and the operator lock is released. Use <annotation>=<value> to require a different value")
+
+	flag.Var(&flags.rebootWindows, "reboot-windows",
+		"List of comma-separated additional reboot windows in format '<start>/<length>'. Nodes are rebooted "+
+			"when any of the reboot windows is open. E.g. 'Mon 22:00/2h,Sat 08:00/4h'")
+
+	flag.Var(&flags.exclusionWindows, "exclusion-windows",
+		"List of comma-separated windows in format '<start>/<length>' during which nodes are not rebooted, "+
+			"even if a reboot window is open. E.g. 'Fri 00:00/72h'")
+
+	flag.Var(&flags.approvalLabels, "before-reboot-approval-labels",
+		"List of comma-separated node labels removed once reboot of the node is approved, so approval is not "+
+			"reused for the next reboot. Defaults to labels required to have a value by "+
+			"--before-reboot-label-selector. E.g. 'fluo-approved'")
+
+	flag.Var(&flags.rebootWindowProfiles, "reboot-window-profiles",
+		"List of comma-separated reboot window profiles in format '<name>=[<start>/<length>[;<start>/<length>]]', "+
+			"which nodes may opt into using the reboot-window-profile annotation instead of the default reboot "+
+			"windows. Profiles without windows allow rebooting at any time. E.g. 'anytime=,nightly=01:00/4h'")
+
+	flag.Var(&flags.afterRebootWorkloads, "after-reboot-ready-workloads",
+		"List of comma-separated workloads in format '<kind>/<namespace>/<name>' which must be ready before "+
+			"after reboot checks pass. Kind is either 'daemonset', which must have a ready pod on the node, "+
+			"or 'deployment', which must have all replicas ready")
+
+	flag.Var(&flags.annotationDescriptions, "annotation-descriptions",
+		"List of comma-separated descriptions of before and after reboot annotations in format "+
+			"'<annotation>=<description>', included in logs when waiting for them. E.g. 'anno1=backup taken'")
+
+	flag.Var(&flags.protectedNamespaces, "protected-namespaces",
+		"List of comma-separated namespaces of critical workloads. Nodes running pods from these namespaces, "+
+			"other than DaemonSet pods, are rebooted after other nodes. E.g. 'payments,billing'")
+
+	flag.Var(&flags.reconcilePhases, "reconcile-phases",
+		fmt.Sprintf("List of comma-separated reconciliation phases to run, in order. Default to %q",
+			strings.Join(operator.DefaultReconcilePhases(), ",")))
 
 	klog.InitFlags(nil)
 
@@ -71,47 +374,335 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Create Kubernetes client (clientset).
-	client, err := k8sutil.GetClient(*flags.kubeconfig)
+	rebootWindows, err := parseWindows(flags.rebootWindows)
 	if err != nil {
-		klog.Fatalf("Failed to create Kubernetes client: %v", err)
+		klog.Fatalf("Failed to parse reboot windows: %v", err)
 	}
 
-	namespace := os.Getenv("POD_NAMESPACE")
-	if namespace == "" {
-		klog.Fatalf("Unable to determine operator namespace: please ensure POD_NAMESPACE environment variable is set")
+	exclusionWindows, err := parseWindows(flags.exclusionWindows)
+	if err != nil {
+		klog.Fatalf("Failed to parse exclusion windows: %v", err)
+	}
+
+	rebootWindowProfiles, err := parseWindowProfiles(flags.rebootWindowProfiles)
+	if err != nil {
+		klog.Fatalf("Failed to parse reboot window profiles: %v", err)
+	}
+
+	var rebootTaint *corev1.Taint
+
+	if *flags.rebootTaint != "" {
+		taint, err := parseTaint(*flags.rebootTaint)
+		if err != nil {
+			klog.Fatalf("Failed to parse reboot taint: %v", err)
+		}
+
+		rebootTaint = &taint
+	}
+
+	var smokeTestPodTemplate *corev1.PodTemplateSpec
+
+	if *flags.smokeTestPodTemplate != "" {
+		smokeTestPodTemplate, err = readPodTemplate(*flags.smokeTestPodTemplate)
+		if err != nil {
+			klog.Fatalf("Failed to read smoke test pod template: %v", err)
+		}
+	}
+
+	notificationSecret, err := readNotificationSecret(*flags.notificationSecretFile)
+	if err != nil {
+		klog.Fatalf("Failed to read notification webhook secret: %v", err)
+	}
+
+	config := operator.Config{
+		BeforeRebootAnnotations:            flags.beforeRebootAnnotations,
+		AfterRebootAnnotations:             flags.afterRebootAnnotations,
+		RebootWindowStart:                  *flags.rebootWindowStart,
+		RebootWindowLength:                 *flags.rebootWindowLength,
+		RebootWindows:                      rebootWindows,
+		ExclusionWindows:                   exclusionWindows,
+		RebootWindowProfiles:               rebootWindowProfiles,
+		Namespace:                          *flags.namespace,
+		LockType:                           *flags.lockType,
+		LeaderElectionResourceName:         *flags.lockName,
+		EventSourceComponent:               *flags.eventSourceComponent,
+		LeaderElectionEventSourceComponent: *flags.leaderElectionComponent,
+		DisableLeaderElection:              *flags.disableLeaderElection,
+		LeaderElectionTimeout:              *flags.leaderElectionTimeout,
+		BeforeRebootLabelSelector:          *flags.beforeRebootSelector,
+		BeforeRebootApprovalLabels:         flags.approvalLabels,
+		PauseLabelSelector:                 *flags.pauseSelector,
+		ExcludeNodeLabel:                   *flags.excludeNodeLabel,
+		OnlyRebootOutdated:                 *flags.onlyRebootOutdated,
+		NewVersionAnnotation:               *flags.newVersionAnnotation,
+		RebootReasonSourceAnnotation:       *flags.rebootReasonSource,
+		LastRebootReasonAnnotation:         *flags.lastRebootReason,
+		AnnotationPrefix:                   *flags.annotationPrefix,
+		NodeName:                           os.Getenv("POD_NODE_NAME"),
+		ReconcilePhases:                    flags.reconcilePhases,
+		KarpenterDoNotDisrupt:              *flags.karpenterDoNotDisrupt,
+		CordonBeforeReboot:                 *flags.cordonBeforeReboot,
+		ReconcileTimeout:                   *flags.reconcileTimeout,
+		UpdateMaturityDelay:                *flags.updateMaturityDelay,
+		RebootSLO:                          *flags.rebootSLO,
+		PostRebootStabilizationPeriod:      *flags.stabilizationPeriod,
+		InterRebootDelay:                   *flags.interRebootDelay,
+		NodeSelector:                       *flags.nodeSelector,
+		WatchNodes:                         *flags.watchNodes,
+		MinReadyFraction:                   *flags.minReadyFraction,
+		NodeChangeDebounce:                 *flags.nodeChangeDebounce,
+		ErrorRetryBackoff:                  *flags.errorRetryBackoff,
+		RebootPriorityAnnotation:           *flags.rebootPriority,
+		NodeSelectionStrategy:              operator.NodeSelectionStrategy(*flags.nodeSelectionStrategy),
+		NotReadyNodePolicy:                 operator.NotReadyNodePolicy(*flags.notReadyNodePolicy),
+		BootTimeAnnotation:                 *flags.bootTimeAnnotation,
+		ZoneLabelKey:                       *flags.zoneLabel,
+		RebootGroupLabelKey:                *flags.rebootGroupLabel,
+		RolloutOrderLabelKey:               *flags.rolloutOrderLabel,
+		PauseConfigMap:                     *flags.pauseConfigMap,
+		RebootWindowConfigMap:              *flags.rebootWindowConfigMap,
+		RebootingNodesConfigMap:            *flags.rebootingNodesConfigMap,
+		HealthAddress:                      *flags.healthAddress,
+		StatusAddress:                      *flags.statusAddress,
+		UnhealthyAfter:                     *flags.unhealthyAfter,
+		RebootTimeout:                      *flags.rebootTimeout,
+		SkipStuckNodes:                     *flags.skipStuckNodes,
+		BlockingPodAnnotation:              *flags.blockingPodAnnotation,
+		CheckPodDisruptionBudgets:          *flags.checkPDBs,
+		ProtectedNamespaces:                flags.protectedNamespaces,
+		BlockOnProtectedNamespaces:         *flags.blockOnProtected,
+		DeferNodesRunningJobs:              *flags.deferRunningJobs,
+		RebootTaint:                        rebootTaint,
+		NotificationWebhookURL:             *flags.notificationWebhookURL,
+		NotificationTemplate:               *flags.notificationTemplate,
+		NotificationWebhookSecret:          notificationSecret,
+		ForceRebootLimit:                   *flags.forceRebootLimit,
+		BeforeRebootTimeout:                *flags.beforeRebootTimeout,
+		BeforeRebootTimeoutAction:          operator.BeforeRebootTimeoutAction(*flags.beforeRebootAction),
+		BeforeRebootRevertGracePeriod:      *flags.beforeRebootGracePeriod,
+		AfterRebootCheckTimeout:            *flags.afterRebootTimeout,
+		AfterRebootCheckBackoff:            *flags.afterRebootBackoff,
+		AfterRebootCheckMaxAttempts:        *flags.afterRebootMaxAttempts,
+		AfterRebootReadyWorkloads:          flags.afterRebootWorkloads,
+		AfterRebootSmokeTestPodTemplate:    smokeTestPodTemplate,
+		AfterRebootSmokeTestTimeout:        *flags.smokeTestTimeout,
+		DrainCompleteAnnotation:            *flags.drainCompleteAnnotation,
+		AnnotationDescriptions:             flags.annotationDescriptions,
+	}
+
+	if *flags.validate {
+		if err := config.Validate(); err != nil {
+			klog.Fatalf("Invalid configuration: %v", err)
+		}
+
+		fmt.Println("Configuration is valid")
+		os.Exit(0)
+	}
+
+	// Create Kubernetes client (clientset).
+	config.Client, err = k8sutil.GetClient(*flags.kubeconfig)
+	if err != nil {
+		klog.Fatalf("Failed to create Kubernetes client: %v", err)
 	}
 
 	// TODO: a better id might be necessary.
 	// Currently, KVO uses env.POD_NAME and the upstream controller-manager uses this.
 	// Both end up having the same value in general, but Hostname is
 	// more likely to have a value.
-	hostname, err := os.Hostname()
+	config.LockID, err = os.Hostname()
 	if err != nil {
 		klog.Fatalf("Getting hostname: %v", err)
 	}
 
 	// Construct update-operator.
-	operatorInstance, err := operator.New(operator.Config{
-		Client:                  client,
-		BeforeRebootAnnotations: flags.beforeRebootAnnotations,
-		AfterRebootAnnotations:  flags.afterRebootAnnotations,
-		RebootWindowStart:       *flags.rebootWindowStart,
-		RebootWindowLength:      *flags.rebootWindowLength,
-		Namespace:               namespace,
-		LockID:                  hostname,
-	})
+	operatorInstance, err := operator.New(config)
 	if err != nil {
 		klog.Fatalf("Failed to initialize %s: %v", os.Args[0], err)
 	}
 
+	// Commands perform a single action using operator configuration instead of running the operator.
+	if args := flag.Args(); len(args) > 0 {
+		if err := runCommand(operatorInstance, args); err != nil {
+			klog.Fatalf("Failed running command %q: %v", args[0], err)
+		}
+
+		return
+	}
+
+	if err := metrics.Register(prometheus.DefaultRegisterer); err != nil {
+		klog.Fatalf("Failed to register metrics: %v", err)
+	}
+
+	if *flags.metricsAddress != "" {
+		go serveMetrics(*flags.metricsAddress)
+	}
+
 	klog.Infof("%s running", os.Args[0])
 
-	// Run operator until the stop channel is closed.
-	stop := make(chan struct{})
-	defer close(stop)
+	// Run operator until termination signal is received, so leader election lock gets released
+	// on shutdown and another instance can take over without waiting for the lease to expire.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := operatorInstance.Run(stop); err != nil {
+	if err := operatorInstance.Run(ctx.Done()); err != nil {
 		klog.Fatalf("Error while running %s: %v", os.Args[0], err)
 	}
 }
+
+// runCommand runs command given by arguments. Supported commands are:
+//
+//	force-reboot <node> - schedules reboot of given node immediately, bypassing reboot windows and
+//	                      maximum number of rebooting nodes. See operator.ForceReboot for details.
+//	reconcile-once      - runs a single reconciliation loop without acquiring leader election lock.
+//	                      See operator.ReconcileOnce for details.
+func runCommand(operatorInstance *operator.Kontroller, args []string) error {
+	switch command := args[0]; command {
+	case "force-reboot":
+		//nolint:gomnd // Command and node name.
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s force-reboot <node>", os.Args[0])
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+
+		if err := operatorInstance.ForceReboot(ctx, args[1]); err != nil {
+			return fmt.Errorf("forcing reboot of node %q: %w", args[1], err)
+		}
+
+		klog.Infof("Reboot of node %q scheduled", args[1])
+
+		return nil
+	case "reconcile-once":
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+
+		if err := operatorInstance.ReconcileOnce(ctx); err != nil {
+			return fmt.Errorf("reconciling: %w", err)
+		}
+
+		klog.Info("Reconciliation finished")
+
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// parseWindows parses reboot or exclusion windows in format '<start>/<length>'.
+func parseWindows(values []string) ([]operator.RebootWindow, error) {
+	windows := make([]operator.RebootWindow, 0, len(values))
+
+	for _, value := range values {
+		parts := strings.Split(value, "/")
+
+		//nolint:gomnd // Window consists of start and length.
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("window %q must be in format '<start>/<length>'", value)
+		}
+
+		windows = append(windows, operator.RebootWindow{
+			Start:  strings.TrimSpace(parts[0]),
+			Length: strings.TrimSpace(parts[1]),
+		})
+	}
+
+	return windows, nil
+}
+
+// parseWindowProfiles parses reboot window profiles in format '<name>=[<start>/<length>[;<start>/<length>]]'.
+func parseWindowProfiles(values []string) (map[string][]operator.RebootWindow, error) {
+	profiles := make(map[string][]operator.RebootWindow, len(values))
+
+	for _, value := range values {
+		separator := strings.Index(value, "=")
+		if separator == -1 {
+			return nil, fmt.Errorf("reboot window profile %q must be in format '<name>=[<start>/<length>[;...]]'", value)
+		}
+
+		name, windowValues := strings.TrimSpace(value[:separator]), []string{}
+
+		if windowsValue := strings.TrimSpace(value[separator+1:]); windowsValue != "" {
+			windowValues = strings.Split(windowsValue, ";")
+		}
+
+		windows, err := parseWindows(windowValues)
+		if err != nil {
+			return nil, fmt.Errorf("parsing reboot window profile %q: %w", name, err)
+		}
+
+		profiles[name] = windows
+	}
+
+	return profiles, nil
+}
+
+// parseTaint parses taint in format '<key>[=<value>]:<effect>'.
+func parseTaint(value string) (corev1.Taint, error) {
+	separator := strings.LastIndex(value, ":")
+	if separator == -1 {
+		return corev1.Taint{}, fmt.Errorf("taint %q must be in format '<key>[=<value>]:<effect>'", value)
+	}
+
+	taint := corev1.Taint{
+		Key:    value[:separator],
+		Effect: corev1.TaintEffect(value[separator+1:]),
+	}
+
+	if index := strings.Index(taint.Key, "="); index != -1 {
+		taint.Key, taint.Value = taint.Key[:index], taint.Key[index+1:]
+	}
+
+	return taint, nil
+}
+
+// readPodTemplate reads Pod template from YAML or JSON file with given path.
+func readPodTemplate(path string) (*corev1.PodTemplateSpec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+
+	defer file.Close()
+
+	template := &corev1.PodTemplateSpec{}
+
+	//nolint:gomnd // Just buffer size.
+	if err := yaml.NewYAMLOrJSONDecoder(file, 4096).Decode(template); err != nil {
+		return nil, fmt.Errorf("decoding Pod template from %q: %w", path, err)
+	}
+
+	return template, nil
+}
+
+// readNotificationSecret reads notification webhook secret from file with given path, ignoring
+// surrounding whitespace. If path is empty, secret is read from notificationSecretEnv environment variable.
+func readNotificationSecret(path string) ([]byte, error) {
+	if path == "" {
+		return []byte(os.Getenv(notificationSecretEnv)), nil
+	}
+
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("file %q is empty", path)
+	}
+
+	return secret, nil
+}
+
+func serveMetrics(address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	klog.Infof("Serving metrics on %q", address)
+
+	//nolint:gosec // Metrics server does not need timeouts, as it only serves cheap requests.
+	if err := http.ListenAndServe(address, mux); err != nil {
+		klog.Fatalf("Failed serving metrics: %v", err)
+	}
+}
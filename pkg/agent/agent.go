@@ -7,20 +7,29 @@ package agent
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	watchtools "k8s.io/client-go/tools/watch"
 	"k8s.io/klog/v2"
 	"k8s.io/kubectl/pkg/drain"
@@ -32,14 +41,67 @@ import (
 
 // Config represents configurable options for agent.
 type Config struct {
-	NodeName                string
-	PodDeletionGracePeriod  time.Duration
-	Clientset               kubernetes.Interface
-	StatusReceiver          StatusReceiver
-	Rebooter                Rebooter
-	HostFilesPrefix         string
+	NodeName               string
+	PodDeletionGracePeriod time.Duration
+	Clientset              kubernetes.Interface
+	StatusReceiver         StatusReceiver
+	Rebooter               Rebooter
+	HostFilesPrefix        string
+	// AnnotationPrefix is a prefix of labels and annotations used to coordinate with the update-operator,
+	// e.g. "example.com/". It must match the prefix configured for the update-operator. If empty,
+	// constants.Prefix is used.
+	AnnotationPrefix        string
 	PollInterval            time.Duration
 	MaxOperatorResponseTime time.Duration
+	// DrainJobGrace is a maximum amount of time agent waits for pods owned by a Job
+	// to complete before evicting them. If zero, Job pods are evicted immediately.
+	DrainJobGrace time.Duration
+	// DrainLastPodSelectors is a list of label selectors matching pods, which are evicted only
+	// after all other pods are gone from the node. If nil, DefaultDrainLastPodSelectors are used.
+	// Pods from kube-system namespace are never evicted, so there is no need to include them.
+	DrainLastPodSelectors []string
+	// DrainGracePeriodSeconds is a period of time in seconds given to each pod to terminate when
+	// evicted. If zero, pod's own termination grace period is used.
+	DrainGracePeriodSeconds int
+	// DrainTimeout is a maximum amount of time agent waits for evicted pods to terminate, including pods
+	// matching DrainLastPodSelectors. When exceeded, node is rebooted anyway and a warning event is emitted
+	// for it. If zero, PodDeletionGracePeriod is used.
+	DrainTimeout time.Duration
+	// DrainByPriority enables evicting pods in tiers of equal priority, starting with lowest priority,
+	// so critical workloads are evicted last. Pods matching DrainLastPodSelectors are still evicted only
+	// after all other pods are gone. DrainTimeout applies to all tiers together.
+	DrainByPriority bool
+	// DrainPriorityTierPause is a period of time agent waits after evicting a priority tier before evicting
+	// the next one, when DrainByPriority is enabled. If zero, 5 seconds is used.
+	DrainPriorityTierPause time.Duration
+	// DrainGracePeriodAnnotation is a pod annotation holding period of time in seconds which the pod needs
+	// to terminate, e.g. to flush its state. Pods requesting longer grace period than they would get
+	// otherwise are evicted with requested grace period, capped at DrainMaxGracePeriod, and the node is not
+	// rebooted until they terminate or their grace period passes. If empty, grace period is not extended.
+	DrainGracePeriodAnnotation string
+	// DrainMaxGracePeriod is a maximum grace period pods may request using DrainGracePeriodAnnotation.
+	// If zero, 1 hour is used.
+	DrainMaxGracePeriod time.Duration
+	// DrainVolumeDetachAccessModes is a list of access modes of persistent volume claims. After draining,
+	// agent waits for volumes of evicted pods using claims with any of given access modes to be detached
+	// from the node, as reported by VolumeAttachments, before rebooting, so pods using them can start
+	// on other nodes without multi-attach errors. If empty, agent does not wait for volumes to detach.
+	DrainVolumeDetachAccessModes []corev1.PersistentVolumeAccessMode
+	// DrainVolumeDetachTimeout is a maximum amount of time agent waits for volumes to detach. When exceeded,
+	// node is rebooted anyway and a warning event is emitted for it. If zero, 5 minutes is used.
+	DrainVolumeDetachTimeout time.Duration
+	// DrainConcurrency is a maximum number of pods evicted at once, including pods with different grace
+	// periods. Next pod is evicted once one of evicted pods terminates, while eviction blocked by
+	// a PodDisruptionBudget is retried for each pod separately. Pods with extended grace period are
+	// evicted only after all other pods. DrainTimeout still applies to all pods together. If zero,
+	// all pods are evicted at once.
+	DrainConcurrency int
+}
+
+// DefaultDrainLastPodSelectors returns default selectors of pods which are evicted last,
+// which keeps the operator functional as long as possible when draining its own node.
+func DefaultDrainLastPodSelectors() []string {
+	return []string{"app=flatcar-linux-update-operator"}
 }
 
 // StatusReceiver describe dependency of object providing status updates from update_engine.
@@ -62,21 +124,48 @@ type klocksmith struct {
 	nodeName                string
 	nc                      corev1client.NodeInterface
 	clientset               kubernetes.Interface
+	keys                    constants.Keys
 	ue                      StatusReceiver
 	lc                      Rebooter
 	reapTimeout             time.Duration
 	hostFilesPrefix         string
 	pollInterval            time.Duration
 	maxOperatorResponseTime time.Duration
+	drainJobGrace           time.Duration
+	drainLastPodSelectors   []labels.Selector
+	drainGracePeriodSeconds int
+	drainTimeout            time.Duration
+	drainByPriority         bool
+	drainPriorityTierPause  time.Duration
+	drainGracePeriodAnno    string
+	drainMaxGracePeriod     time.Duration
+	volumeDetachAccessModes []corev1.PersistentVolumeAccessMode
+	volumeDetachTimeout     time.Duration
+	drainSlots              chan struct{}
+	eventRecorder           record.EventRecorder
 }
 
 const (
 	defaultPollInterval            = 10 * time.Second
 	defaultMaxOperatorResponseTime = 24 * time.Hour
+	defaultDrainPriorityTierPause  = 5 * time.Second
+	defaultDrainMaxGracePeriod     = time.Hour
+	defaultVolumeDetachTimeout     = 5 * time.Minute
+
+	// Termination grace period of pods which do not specify one, as defaulted by the API server.
+	defaultTerminationGracePeriodSeconds = 30
 
 	updateConfPath         = "/usr/share/flatcar/update.conf"
 	updateConfOverridePath = "/etc/flatcar/update.conf"
 	osReleasePath          = "/etc/os-release"
+
+	eventSourceComponent = "update-agent"
+
+	// Reason of the event emitted when draining the node takes longer than configured drain timeout.
+	eventReasonDrainTimeoutExceeded = "DrainTimeoutExceeded"
+
+	// Reason of the event emitted when volumes do not detach from the node within configured timeout.
+	eventReasonVolumeDetachTimeoutExceeded = "VolumeDetachTimeoutExceeded"
 )
 
 // New returns initialized klocksmith.
@@ -97,6 +186,11 @@ func New(config *Config) (Klocksmith, error) {
 		return nil, fmt.Errorf("node name can't be empty")
 	}
 
+	keys := constants.NewKeys(config.AnnotationPrefix)
+	if err := keys.Validate(); err != nil {
+		return nil, fmt.Errorf("validating annotation prefix: %w", err)
+	}
+
 	pollInterval := config.PollInterval
 	if pollInterval == 0 {
 		pollInterval = defaultPollInterval
@@ -107,19 +201,124 @@ func New(config *Config) (Klocksmith, error) {
 		maxOperatorResponseTime = defaultMaxOperatorResponseTime
 	}
 
+	drainLastPodSelectors, err := parseDrainLastPodSelectors(config.DrainLastPodSelectors)
+	if err != nil {
+		return nil, fmt.Errorf("parsing drain last pod selectors: %w", err)
+	}
+
+	drainTimeout := config.DrainTimeout
+	if drainTimeout == 0 {
+		drainTimeout = config.PodDeletionGracePeriod
+	}
+
+	drainPriorityTierPause := config.DrainPriorityTierPause
+	if drainPriorityTierPause == 0 {
+		drainPriorityTierPause = defaultDrainPriorityTierPause
+	}
+
+	drainMaxGracePeriod := config.DrainMaxGracePeriod
+	if drainMaxGracePeriod == 0 {
+		drainMaxGracePeriod = defaultDrainMaxGracePeriod
+	}
+
+	if err := checkAccessModes(config.DrainVolumeDetachAccessModes); err != nil {
+		return nil, fmt.Errorf("checking drain volume detach access modes: %w", err)
+	}
+
+	volumeDetachTimeout := config.DrainVolumeDetachTimeout
+	if volumeDetachTimeout == 0 {
+		volumeDetachTimeout = defaultVolumeDetachTimeout
+	}
+
+	if config.DrainConcurrency < 0 {
+		return nil, fmt.Errorf("drain concurrency must not be negative, got %d", config.DrainConcurrency)
+	}
+
+	var drainSlots chan struct{}
+	if config.DrainConcurrency > 0 {
+		drainSlots = make(chan struct{}, config.DrainConcurrency)
+	}
+
+	eventRecorder := newEventRecorder(config.Clientset)
+
+	nodeClient := &k8sutil.AnnotationsSizeGuard{
+		NodeInterface: config.Clientset.CoreV1().Nodes(),
+		Keys:          keys,
+		EventRecorder: eventRecorder,
+	}
+
 	return &klocksmith{
 		nodeName:                config.NodeName,
-		nc:                      config.Clientset.CoreV1().Nodes(),
+		nc:                      nodeClient,
 		clientset:               config.Clientset,
+		keys:                    keys,
 		ue:                      config.StatusReceiver,
 		lc:                      config.Rebooter,
 		reapTimeout:             config.PodDeletionGracePeriod,
 		hostFilesPrefix:         config.HostFilesPrefix,
 		pollInterval:            pollInterval,
 		maxOperatorResponseTime: maxOperatorResponseTime,
+		drainJobGrace:           config.DrainJobGrace,
+		drainLastPodSelectors:   drainLastPodSelectors,
+		drainGracePeriodSeconds: config.DrainGracePeriodSeconds,
+		drainTimeout:            drainTimeout,
+		drainByPriority:         config.DrainByPriority,
+		drainPriorityTierPause:  drainPriorityTierPause,
+		drainGracePeriodAnno:    config.DrainGracePeriodAnnotation,
+		drainMaxGracePeriod:     drainMaxGracePeriod,
+		volumeDetachAccessModes: config.DrainVolumeDetachAccessModes,
+		volumeDetachTimeout:     volumeDetachTimeout,
+		drainSlots:              drainSlots,
+		eventRecorder:           eventRecorder,
 	}, nil
 }
 
+// newEventRecorder creates a recorder for events about the node agent runs on.
+//
+// Events about cluster-scoped objects are always created in the default namespace.
+func newEventRecorder(cs kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&corev1client.EventSinkImpl{
+		Interface: cs.CoreV1().Events(metav1.NamespaceDefault),
+	})
+
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
+		Component: eventSourceComponent,
+	})
+}
+
+// checkAccessModes checks if given persistent volume access modes are supported.
+func checkAccessModes(accessModes []corev1.PersistentVolumeAccessMode) error {
+	for _, accessMode := range accessModes {
+		switch accessMode {
+		case corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany, corev1.ReadWriteOncePod:
+		default:
+			return fmt.Errorf("unsupported access mode %q", accessMode)
+		}
+	}
+
+	return nil
+}
+
+func parseDrainLastPodSelectors(selectors []string) ([]labels.Selector, error) {
+	if selectors == nil {
+		selectors = DefaultDrainLastPodSelectors()
+	}
+
+	parsedSelectors := make([]labels.Selector, 0, len(selectors))
+
+	for _, selector := range selectors {
+		parsedSelector, err := labels.Parse(selector)
+		if err != nil {
+			return nil, fmt.Errorf("parsing selector %q: %w", selector, err)
+		}
+
+		parsedSelectors = append(parsedSelectors, parsedSelector)
+	}
+
+	return parsedSelectors, nil
+}
+
 // Run starts the agent to listen for an update_engine reboot signal and react
 // by draining pods and rebooting. Runs until the stop channel is closed.
 func (k *klocksmith) Run(ctx context.Context) error {
@@ -157,18 +356,18 @@ func (k *klocksmith) process(ctx context.Context) error {
 
 	// Only make a node schedulable if a reboot was in progress. This prevents a node from being made schedulable
 	// if it was made unschedulable by something other than the agent.
-	annotation := constants.AnnotationAgentMadeUnschedulable
+	annotation := k.keys.AnnotationAgentMadeUnschedulable
 	madeUnschedulableAnnotation, madeUnschedulableAnnotationExists := node.Annotations[annotation]
 	makeSchedulable := madeUnschedulableAnnotation == constants.True
 
 	// Set flatcar-linux.net/update1/reboot-in-progress=false and
 	// flatcar-linux.net/update1/reboot-needed=false.
 	anno := map[string]string{
-		constants.AnnotationRebootInProgress: constants.False,
-		constants.AnnotationRebootNeeded:     constants.False,
+		k.keys.AnnotationRebootInProgress: constants.False,
+		k.keys.AnnotationRebootNeeded:     constants.False,
 	}
 	labels := map[string]string{
-		constants.LabelRebootNeeded: constants.False,
+		k.keys.LabelRebootNeeded: constants.False,
 	}
 
 	klog.Infof("Setting annotations %#v", anno)
@@ -192,7 +391,7 @@ func (k *klocksmith) process(ctx context.Context) error {
 		}
 
 		anno = map[string]string{
-			constants.AnnotationAgentMadeUnschedulable: constants.False,
+			k.keys.AnnotationAgentMadeUnschedulable: constants.False,
 		}
 
 		klog.Infof("Setting annotations %#v", anno)
@@ -246,11 +445,11 @@ func (k *klocksmith) process(ctx context.Context) error {
 
 	// Set constants.AnnotationRebootInProgress and drain self.
 	anno = map[string]string{
-		constants.AnnotationRebootInProgress: constants.True,
+		k.keys.AnnotationRebootInProgress: constants.True,
 	}
 
 	if !alreadyUnschedulable {
-		anno[constants.AnnotationAgentMadeUnschedulable] = constants.True
+		anno[k.keys.AnnotationAgentMadeUnschedulable] = constants.True
 	}
 
 	klog.Infof("Setting annotations %#v", anno)
@@ -269,23 +468,40 @@ func (k *klocksmith) process(ctx context.Context) error {
 		klog.Info("Node already marked as unschedulable")
 	}
 
-	drainer := newDrainer(ctx, k.clientset, k.reapTimeout)
-
 	klog.Info("Getting pod list for deletion")
 
-	pods, errs := drainer.GetPodsForDeletion(k.nodeName)
+	pods, errs := newDrainer(ctx, k.clientset, k.drainGracePeriodSeconds).GetPodsForDeletion(k.nodeName)
 	if len(errs) > 0 {
 		return fmt.Errorf("getting pods for deletion: %v", errs)
 	}
 
-	klog.Infof("Deleting/Evicting %d pods", len(pods.Pods()))
+	podsToDelete := pods.Pods()
+
+	if k.drainJobGrace > 0 {
+		podsToDelete = k.waitForJobPods(ctx, podsToDelete)
+	}
+
+	// Drain timeout applies to all pods together, no matter in which order they are evicted.
+	var drainDeadline time.Time
+	if k.drainTimeout > 0 {
+		drainDeadline = time.Now().Add(k.drainTimeout)
+	}
+
+	// Evict pods matching drain last selectors only once all other pods are gone.
+	podsToDeleteFirst, podsToDeleteLast := k.splitDrainLastPods(podsToDelete)
 
-	if err := drainer.DeleteOrEvictPods(pods.Pods()); err != nil {
-		if ctx.Err() != nil {
-			return fmt.Errorf("deleting/evicting pods: %w", ctx.Err())
+	for _, pods := range [][]corev1.Pod{podsToDeleteFirst, podsToDeleteLast} {
+		if len(pods) == 0 {
+			continue
 		}
 
-		klog.Errorf("Ignoring node drain error and proceeding with reboot: %v", err)
+		if err := k.evictPodsInTiers(ctx, node, pods, drainDeadline); err != nil {
+			return fmt.Errorf("deleting/evicting pods: %w", err)
+		}
+	}
+
+	if err := k.waitForVolumesToDetach(ctx, node, podsToDelete); err != nil {
+		return fmt.Errorf("waiting for volumes to detach: %w", err)
 	}
 
 	klog.Info("Node drained, rebooting")
@@ -307,24 +523,38 @@ func (k *klocksmith) updateStatusCallback(ctx context.Context, status updateengi
 
 	// update our status.
 	anno := map[string]string{
-		constants.AnnotationStatus:          status.CurrentOperation,
-		constants.AnnotationLastCheckedTime: fmt.Sprintf("%d", status.LastCheckedTime),
-		constants.AnnotationNewVersion:      status.NewVersion,
+		k.keys.AnnotationStatus:          status.CurrentOperation,
+		k.keys.AnnotationLastCheckedTime: fmt.Sprintf("%d", status.LastCheckedTime),
+		k.keys.AnnotationNewVersion:      status.NewVersion,
 	}
 
 	labels := map[string]string{}
 
+	rebootNeeded := status.CurrentOperation == updateengine.UpdateStatusUpdatedNeedReboot
+
 	// Indicate we need a reboot.
-	if status.CurrentOperation == updateengine.UpdateStatusUpdatedNeedReboot {
+	if rebootNeeded {
 		klog.Info("Indicating a reboot is needed")
 
-		anno[constants.AnnotationRebootNeeded] = constants.True
-		labels[constants.LabelRebootNeeded] = constants.True
+		anno[k.keys.AnnotationRebootNeeded] = constants.True
+		labels[k.keys.LabelRebootNeeded] = constants.True
+	}
+
+	updateF := func(node *corev1.Node) {
+		for key, value := range anno {
+			node.Annotations[key] = value
+		}
+
+		for key, value := range labels {
+			node.Labels[key] = value
+		}
+
+		k.setUpdateAvailableTime(node, rebootNeeded)
 	}
 
 	err := wait.PollImmediateUntil(k.pollInterval, func() (bool, error) {
-		if err := k8sutil.SetNodeAnnotationsLabels(ctx, k.nc, k.nodeName, anno, labels); err != nil {
-			klog.Errorf("Failed to set annotation %q: %v", constants.AnnotationStatus, err)
+		if err := k8sutil.UpdateNodeRetry(ctx, k.nc, k.nodeName, updateF); err != nil {
+			klog.Errorf("Failed to set annotation %q: %v", k.keys.AnnotationStatus, err)
 
 			return false, nil
 		}
@@ -336,6 +566,23 @@ func (k *klocksmith) updateStatusCallback(ctx context.Context, status updateengi
 	}
 }
 
+// setUpdateAvailableTime records when update requiring a reboot has been observed for the first time,
+// so the operator can delay the reboot until the update matures. The timestamp is preserved across
+// agent restarts and removed once the reboot is no longer needed.
+func (k *klocksmith) setUpdateAvailableTime(node *corev1.Node, rebootNeeded bool) {
+	if !rebootNeeded {
+		delete(node.Annotations, k.keys.AnnotationUpdateAvailableTime)
+
+		return
+	}
+
+	if _, ok := node.Annotations[k.keys.AnnotationUpdateAvailableTime]; ok {
+		return
+	}
+
+	node.Annotations[k.keys.AnnotationUpdateAvailableTime] = strconv.FormatInt(time.Now().Unix(), 10)
+}
+
 // setInfoLabels labels our node with helpful info about Flatcar Container Linux.
 func (k *klocksmith) setInfoLabels(ctx context.Context) error {
 	versionInfo, err := getVersionInfo(k.hostFilesPrefix)
@@ -344,9 +591,9 @@ func (k *klocksmith) setInfoLabels(ctx context.Context) error {
 	}
 
 	labels := map[string]string{
-		constants.LabelID:      versionInfo.id,
-		constants.LabelGroup:   versionInfo.group,
-		constants.LabelVersion: versionInfo.version,
+		k.keys.LabelID:      versionInfo.id,
+		k.keys.LabelGroup:   versionInfo.group,
+		k.keys.LabelVersion: versionInfo.version,
 	}
 
 	if err := k8sutil.SetNodeLabels(ctx, k.nc, k.nodeName, labels); err != nil {
@@ -382,8 +629,8 @@ func (k *klocksmith) waitForOkToReboot(ctx context.Context) error {
 	}
 
 	shouldRebootSelector := fields.Set(map[string]string{
-		constants.AnnotationOkToReboot:   constants.True,
-		constants.AnnotationRebootNeeded: constants.True,
+		k.keys.AnnotationOkToReboot:   constants.True,
+		k.keys.AnnotationRebootNeeded: constants.True,
 	}).AsSelector()
 
 	return k.waitForNodeCondition(ctx, node, func(annotations map[string]string) bool {
@@ -397,7 +644,7 @@ func (k *klocksmith) waitForNotOkToReboot(ctx context.Context) error {
 		return fmt.Errorf("getting self node (%q): %w", k.nodeName, err)
 	}
 
-	if node.Annotations[constants.AnnotationOkToReboot] != constants.True {
+	if node.Annotations[k.keys.AnnotationOkToReboot] != constants.True {
 		return nil
 	}
 
@@ -406,7 +653,7 @@ func (k *klocksmith) waitForNotOkToReboot(ctx context.Context) error {
 		// true' vs '== False'; due to the operator matching on '== True', and not
 		// going out of its way to convert '' => 'False', checking the exact inverse
 		// of what the operator checks is the correct thing to do.
-		return annotations[constants.AnnotationOkToReboot] != constants.True
+		return annotations[k.keys.AnnotationOkToReboot] != constants.True
 	})
 }
 
@@ -450,24 +697,516 @@ func (k *klocksmith) waitForNodeCondition(ctx context.Context, node *corev1.Node
 	}
 
 	if _, err := watchtools.UntilWithoutRetry(ctx, watcher, watchF); err != nil {
-		return fmt.Errorf("waiting for annotation %q: %w", constants.AnnotationOkToReboot, err)
+		return fmt.Errorf("waiting for annotation %q: %w", k.keys.AnnotationOkToReboot, err)
 	}
 
 	return nil
 }
 
+// waitForJobPods waits up to configured grace period for pods owned by a Job to complete,
+// to avoid wasting work done by them. It returns given pods without the ones which completed.
+func (k *klocksmith) waitForJobPods(ctx context.Context, pods []corev1.Pod) []corev1.Pod {
+	runningJobPods := map[string]corev1.Pod{}
+	otherPods := []corev1.Pod{}
+
+	for _, pod := range pods {
+		pod := pod
+
+		if k8sutil.IsJobPod(&pod) && !k8sutil.PodCompleted(&pod) {
+			runningJobPods[pod.Namespace+"/"+pod.Name] = pod
+
+			continue
+		}
+
+		otherPods = append(otherPods, pod)
+	}
+
+	if len(runningJobPods) == 0 {
+		return otherPods
+	}
+
+	klog.Infof("Waiting up to %s for %d Job pods to complete", k.drainJobGrace, len(runningJobPods))
+
+	graceCtx, cancel := context.WithTimeout(ctx, k.drainJobGrace)
+	defer cancel()
+
+	err := wait.PollImmediateUntil(k.pollInterval, func() (bool, error) {
+		for key, pod := range runningJobPods {
+			currentPod, err := k.clientset.CoreV1().Pods(pod.Namespace).Get(graceCtx, pod.Name, metav1.GetOptions{})
+
+			switch {
+			case apierrors.IsNotFound(err):
+			case err != nil:
+				klog.Warningf("Failed getting Job pod %q: %v", key, err)
+
+				continue
+			case !k8sutil.PodCompleted(currentPod):
+				continue
+			}
+
+			klog.Infof("Job pod %q completed", key)
+
+			delete(runningJobPods, key)
+		}
+
+		return len(runningJobPods) == 0, nil
+	}, graceCtx.Done())
+	if err != nil {
+		klog.Warningf("Not all Job pods completed within %s: %v", k.drainJobGrace, err)
+	}
+
+	for key, pod := range runningJobPods {
+		klog.Infof("Evicting Job pod %q which did not complete", key)
+
+		otherPods = append(otherPods, pod)
+	}
+
+	return otherPods
+}
+
+// evictPods evicts given pods and waits for them to terminate until given drain deadline, if not zero.
+// Pods requesting extended grace period are evicted at the same time with their grace period and
+// waited for until they terminate or their grace period passes, if it ends after the drain deadline.
+// With drain concurrency configured, pods with extended grace period are evicted only after other pods,
+// as they may hold drain slots until their grace period passes, starving pods with earlier deadline.
+//
+// Eviction errors are ignored, so the node gets rebooted even if not all pods are gone.
+// Error is only returned when given context is done.
+func (k *klocksmith) evictPods(
+	ctx context.Context, node *corev1.Node, pods []corev1.Pod, drainDeadline time.Time,
+) error {
+	groups := k.gracePeriodGroups(pods)
+
+	if k.drainSlots != nil {
+		for _, group := range groups {
+			if err := k.evictPodsWithGracePeriod(
+				ctx, node, group.pods, group.gracePeriodSeconds, drainDeadline,
+			); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	errs := make([]error, len(groups))
+
+	var wg sync.WaitGroup
+
+	for i, group := range groups {
+		i, group := i, group
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = k.evictPodsWithGracePeriod(ctx, node, group.pods, group.gracePeriodSeconds, drainDeadline)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evictPodsWithGracePeriod evicts given pods with given grace period and waits for them to terminate
+// until given drain deadline or until given grace period passes, whichever is later. If given grace
+// period is zero, configured drain grace period is used.
+func (k *klocksmith) evictPodsWithGracePeriod(
+	ctx context.Context, node *corev1.Node, pods []corev1.Pod, gracePeriodSeconds int, drainDeadline time.Time,
+) error {
+	drainCtx := ctx
+	timeout := k.drainTimeout
+
+	if gracePeriodSeconds == 0 {
+		gracePeriodSeconds = k.drainGracePeriodSeconds
+	} else if gracePeriod := time.Duration(gracePeriodSeconds) * time.Second; !drainDeadline.IsZero() {
+		if gracePeriodDeadline := time.Now().Add(gracePeriod); gracePeriodDeadline.After(drainDeadline) {
+			klog.Infof("Waiting up to %v for %d pods with extended grace period to terminate", gracePeriod, len(pods))
+
+			drainDeadline = gracePeriodDeadline
+			timeout = gracePeriod
+		}
+	}
+
+	if !drainDeadline.IsZero() {
+		if time.Now().After(drainDeadline) {
+			klog.Warningf("Draining node already exceeded timeout of %v, not evicting remaining %d pods", timeout, len(pods))
+
+			return nil
+		}
+
+		var cancel context.CancelFunc
+
+		drainCtx, cancel = context.WithDeadline(ctx, drainDeadline)
+		defer cancel()
+	}
+
+	err := k.deleteOrEvictPods(drainCtx, pods, gracePeriodSeconds)
+
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(drainCtx.Err(), context.DeadlineExceeded):
+		klog.Warningf("Draining node exceeded timeout of %v, proceeding with reboot: %v", timeout, err)
+
+		k.eventRecorder.Eventf(node, corev1.EventTypeWarning, eventReasonDrainTimeoutExceeded,
+			"Draining node %q exceeded timeout of %v, rebooting anyway", k.nodeName, timeout)
+	default:
+		klog.Errorf("Ignoring node drain error and proceeding with reboot: %v", err)
+	}
+
+	return nil
+}
+
+// deleteOrEvictPods evicts given pods with given grace period and waits for them to terminate until
+// given context is done. With drain concurrency configured, each pod is evicted only once it acquires one
+// of drain slots shared by all evicted pods, otherwise all pods are evicted at once.
+//
+// Errors of all pods which failed to be evicted are aggregated into the returned error.
+func (k *klocksmith) deleteOrEvictPods(ctx context.Context, pods []corev1.Pod, gracePeriodSeconds int) error {
+	drainer := newDrainer(ctx, k.clientset, gracePeriodSeconds)
+
+	if k.drainSlots == nil {
+		return drainer.DeleteOrEvictPods(pods)
+	}
+
+	klog.Infof("Evicting %d pods, at most %d at once", len(pods), cap(k.drainSlots))
+
+	errs := make([]error, len(pods))
+
+	var wg sync.WaitGroup
+
+	for i := range pods {
+		i := i
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			select {
+			case k.drainSlots <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("waiting for drain slot: %w", ctx.Err())
+
+				return
+			}
+
+			defer func() { <-k.drainSlots }()
+
+			errs[i] = drainer.DeleteOrEvictPods([]corev1.Pod{pods[i]})
+		}()
+	}
+
+	wg.Wait()
+
+	failedPods := []string{}
+	failedErrs := []error{}
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		failedPods = append(failedPods, fmt.Sprintf("%s/%s", pods[i].Namespace, pods[i].Name))
+		failedErrs = append(failedErrs, err)
+	}
+
+	if len(failedErrs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("evicting pods %s: %w", strings.Join(failedPods, ", "), utilerrors.NewAggregate(failedErrs))
+}
+
+// waitForVolumesToDetach waits up to configured timeout for volumes of given evicted pods, which use
+// persistent volume claims with configured access modes, to be detached from the node.
+//
+// Errors reading volume information are logged and the node gets rebooted anyway, as when draining.
+// Error is only returned when given context is done.
+func (k *klocksmith) waitForVolumesToDetach(ctx context.Context, node *corev1.Node, pods []corev1.Pod) error {
+	if len(k.volumeDetachAccessModes) == 0 {
+		return nil
+	}
+
+	volumes := k.volumesToDetach(ctx, pods)
+	if len(volumes) == 0 {
+		return nil
+	}
+
+	klog.Infof("Waiting up to %v for %d volumes to detach", k.volumeDetachTimeout, len(volumes))
+
+	detachCtx, cancel := context.WithTimeout(ctx, k.volumeDetachTimeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntil(k.pollInterval, func() (bool, error) {
+		attachments, err := k.clientset.StorageV1().VolumeAttachments().List(detachCtx, metav1.ListOptions{})
+		if err != nil {
+			klog.Warningf("Failed listing volume attachments: %v", err)
+
+			return false, nil
+		}
+
+		attached := 0
+
+		for _, attachment := range attachments.Items {
+			volume := attachment.Spec.Source.PersistentVolumeName
+			if attachment.Spec.NodeName != k.nodeName || volume == nil {
+				continue
+			}
+
+			if _, ok := volumes[*volume]; ok {
+				attached++
+			}
+		}
+
+		klog.V(4).Infof("%d volumes still attached", attached)
+
+		return attached == 0, nil
+	}, detachCtx.Done())
+
+	switch {
+	case err == nil:
+		klog.Info("All volumes detached")
+
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	default:
+		klog.Warningf("Volumes did not detach within %v, proceeding with reboot", k.volumeDetachTimeout)
+
+		k.eventRecorder.Eventf(node, corev1.EventTypeWarning, eventReasonVolumeDetachTimeoutExceeded,
+			"Volumes did not detach from node %q within %v, rebooting anyway", k.nodeName, k.volumeDetachTimeout)
+	}
+
+	return nil
+}
+
+// volumesToDetach returns names of persistent volumes bound to claims with configured access modes,
+// which are used by given pods.
+func (k *klocksmith) volumesToDetach(ctx context.Context, pods []corev1.Pod) map[string]struct{} {
+	volumes := map[string]struct{}{}
+
+	for _, pod := range pods {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+
+			claimName := volume.PersistentVolumeClaim.ClaimName
+
+			claim, err := k.clientset.CoreV1().PersistentVolumeClaims(pod.Namespace).Get(ctx, claimName, metav1.GetOptions{})
+			if err != nil {
+				klog.Warningf("Failed getting persistent volume claim %s/%s of pod %q: %v",
+					pod.Namespace, claimName, pod.Name, err)
+
+				continue
+			}
+
+			if claim.Spec.VolumeName == "" || !k.volumeDetachAccessMode(claim) {
+				continue
+			}
+
+			volumes[claim.Spec.VolumeName] = struct{}{}
+		}
+	}
+
+	return volumes
+}
+
+// volumeDetachAccessMode returns true if given persistent volume claim has any of configured access modes.
+func (k *klocksmith) volumeDetachAccessMode(claim *corev1.PersistentVolumeClaim) bool {
+	for _, accessMode := range claim.Spec.AccessModes {
+		for _, volumeDetachAccessMode := range k.volumeDetachAccessModes {
+			if accessMode == volumeDetachAccessMode {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// podGroup is a group of pods evicted with the same grace period.
+type podGroup struct {
+	// Grace period in seconds. Zero means configured drain grace period.
+	gracePeriodSeconds int
+	pods               []corev1.Pod
+}
+
+// gracePeriodGroups groups given pods by grace period they should be evicted with. Pods which do not
+// request extended grace period using configured annotation come first, followed by pods with extended
+// grace period, in order of ascending grace period.
+func (k *klocksmith) gracePeriodGroups(pods []corev1.Pod) []podGroup {
+	groups := []podGroup{{}}
+	groupIndexes := map[int]int{0: 0}
+
+	for _, pod := range pods {
+		gracePeriodSeconds := k.extendedGracePeriodSeconds(pod)
+
+		index, ok := groupIndexes[gracePeriodSeconds]
+		if !ok {
+			index = len(groups)
+			groupIndexes[gracePeriodSeconds] = index
+
+			groups = append(groups, podGroup{gracePeriodSeconds: gracePeriodSeconds})
+		}
+
+		groups[index].pods = append(groups[index].pods, pod)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].gracePeriodSeconds < groups[j].gracePeriodSeconds
+	})
+
+	nonEmptyGroups := []podGroup{}
+
+	for _, group := range groups {
+		if len(group.pods) > 0 {
+			nonEmptyGroups = append(nonEmptyGroups, group)
+		}
+	}
+
+	return nonEmptyGroups
+}
+
+// extendedGracePeriodSeconds returns grace period in seconds requested by given pod using configured
+// annotation, capped at configured maximum grace period. Zero is returned when pod does not request
+// grace period longer than it would get otherwise.
+func (k *klocksmith) extendedGracePeriodSeconds(pod corev1.Pod) int {
+	if k.drainGracePeriodAnno == "" {
+		return 0
+	}
+
+	value, ok := pod.Annotations[k.drainGracePeriodAnno]
+	if !ok {
+		return 0
+	}
+
+	requested, err := strconv.Atoi(value)
+	if err != nil {
+		klog.Warningf("Ignoring invalid value %q of annotation %q of pod %s/%s: %v",
+			value, k.drainGracePeriodAnno, pod.Namespace, pod.Name, err)
+
+		return 0
+	}
+
+	if maxGracePeriodSeconds := int(k.drainMaxGracePeriod.Seconds()); requested > maxGracePeriodSeconds {
+		requested = maxGracePeriodSeconds
+	}
+
+	if requested <= k.defaultGracePeriodSeconds(pod) {
+		return 0
+	}
+
+	return requested
+}
+
+// defaultGracePeriodSeconds returns grace period in seconds given pod gets when evicted, unless it
+// requests extended one.
+func (k *klocksmith) defaultGracePeriodSeconds(pod corev1.Pod) int {
+	if k.drainGracePeriodSeconds > 0 {
+		return k.drainGracePeriodSeconds
+	}
+
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		return int(*pod.Spec.TerminationGracePeriodSeconds)
+	}
+
+	return defaultTerminationGracePeriodSeconds
+}
+
+// evictPodsInTiers evicts given pods until given drain deadline. When draining by priority is enabled,
+// pods are evicted in tiers of equal priority, starting with lowest priority, pausing for configured
+// period between tiers. Otherwise, all given pods are evicted at once.
+func (k *klocksmith) evictPodsInTiers(
+	ctx context.Context, node *corev1.Node, pods []corev1.Pod, drainDeadline time.Time,
+) error {
+	tiers := [][]corev1.Pod{pods}
+
+	if k.drainByPriority {
+		tiers = priorityTiers(pods)
+	}
+
+	for i, tier := range tiers {
+		if i > 0 {
+			klog.Infof("Waiting %v before evicting pods with priority %d", k.drainPriorityTierPause, podPriority(tier[0]))
+
+			sleepOrDone(k.drainPriorityTierPause, ctx.Done())
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		klog.Infof("Deleting/Evicting %d pods", len(tier))
+
+		if err := k.evictPods(ctx, node, tier, drainDeadline); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// priorityTiers groups given pods by their priority. Returned groups are sorted by ascending priority.
+func priorityTiers(pods []corev1.Pod) [][]corev1.Pod {
+	sorted := append([]corev1.Pod{}, pods...)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return podPriority(sorted[i]) < podPriority(sorted[j])
+	})
+
+	tiers := [][]corev1.Pod{}
+
+	for i, pod := range sorted {
+		if i == 0 || podPriority(pod) != podPriority(sorted[i-1]) {
+			tiers = append(tiers, []corev1.Pod{})
+		}
+
+		tiers[len(tiers)-1] = append(tiers[len(tiers)-1], pod)
+	}
+
+	return tiers
+}
+
+// podPriority returns effective priority of given pod, resolved from its PriorityClass on admission.
+// Pods without resolved priority are treated as having the default priority of zero.
+func podPriority(pod corev1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+
+	return *pod.Spec.Priority
+}
+
 type drainer interface {
 	GetPodsForDeletion(nodeName string) (*drain.PodDeleteList, []error)
 	DeleteOrEvictPods([]corev1.Pod) error
 }
 
-func newDrainer(ctx context.Context, cs kubernetes.Interface, timeout time.Duration) drainer {
+// newDrainer creates a drainer evicting pods with given grace period, or pod's own termination grace period
+// if zero. Draining is stopped when given context is done.
+func newDrainer(ctx context.Context, cs kubernetes.Interface, gracePeriodSeconds int) drainer {
+	if gracePeriodSeconds == 0 {
+		gracePeriodSeconds = -1
+	}
+
 	return &drain.Helper{
 		Ctx:                ctx,
 		Client:             cs,
 		Force:              false,
-		GracePeriodSeconds: -1,
-		Timeout:            timeout,
+		GracePeriodSeconds: gracePeriodSeconds,
 		// Explicitly don't terminate self? we'll probably just be a
 		// Mirror pod or daemonset anyway..
 		IgnoreAllDaemonSets: true,
@@ -487,6 +1226,35 @@ func newDrainer(ctx context.Context, cs kubernetes.Interface, timeout time.Durat
 	}
 }
 
+// splitDrainLastPods splits given pods into ones which should be evicted first and ones which
+// match drain last selectors and should be evicted only after all other pods are gone.
+func (k *klocksmith) splitDrainLastPods(pods []corev1.Pod) ([]corev1.Pod, []corev1.Pod) {
+	first := []corev1.Pod{}
+	last := []corev1.Pod{}
+
+	for _, pod := range pods {
+		if k.drainLast(pod) {
+			last = append(last, pod)
+
+			continue
+		}
+
+		first = append(first, pod)
+	}
+
+	return first, last
+}
+
+func (k *klocksmith) drainLast(pod corev1.Pod) bool {
+	for _, selector := range k.drainLastPodSelectors {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // sleepOrDone blocks until the done channel receives
 // or until at least the duration d has elapsed, whichever comes first. This
 // is similar to time.Sleep(d), except it can be interrupted.
@@ -7,17 +7,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -128,6 +133,14 @@ func Test_Creating_new_agent(t *testing.T) {
 			"no_status_receiver_is_configured": func(c *agent.Config) { c.StatusReceiver = nil },
 			"no_rebooter_is_configured":        func(c *agent.Config) { c.Rebooter = nil },
 			"empty_node_name_is_given":         func(c *agent.Config) { c.NodeName = "" },
+			"invalid_drain_last_pod_selector_is_given": func(c *agent.Config) {
+				c.DrainLastPodSelectors = []string{"foo in (bar"}
+			},
+			"invalid_annotation_prefix_is_given": func(c *agent.Config) { c.AnnotationPrefix = "Example Com/" },
+			"unsupported_drain_volume_detach_access_mode_is_given": func(c *agent.Config) {
+				c.DrainVolumeDetachAccessModes = []corev1.PersistentVolumeAccessMode{"ReadWriteSometimes"}
+			},
+			"negative_drain_concurrency_is_given": func(c *agent.Config) { c.DrainConcurrency = -1 },
 		}
 
 		for n, mutateConfigF := range cases {
@@ -176,9 +189,8 @@ func Test_Running_agent(t *testing.T) {
 
 		done := runAgent(ctx, t, testConfig)
 
+		// Subtests are not parallel, as agent run time limit is already counting while they wait to be scheduled.
 		t.Run("reading_OS_ID_from_etc_os_release_file", func(t *testing.T) {
-			t.Parallel()
-
 			// This is currently the only way to check that agent has read /etc/os-release file.
 			assertNodeProperty(ctx, t, &assertNodePropertyContext{
 				done:   done,
@@ -188,8 +200,6 @@ func Test_Running_agent(t *testing.T) {
 		})
 
 		t.Run("reading_Flatcar_version_from_etc_os_release_file", func(t *testing.T) {
-			t.Parallel()
-
 			// This is currently the only way to check that agent has read /etc/os-release file.
 			assertNodeProperty(ctx, t, &assertNodePropertyContext{
 				done:   done,
@@ -199,8 +209,6 @@ func Test_Running_agent(t *testing.T) {
 		})
 
 		t.Run("reading_Flatcar_group_from_update_configuration_file_in_usr_directory", func(t *testing.T) {
-			t.Parallel()
-
 			// This is currently the only way to check that agent
 			// read /etc/flatcar/update.conf or /usr/share/flatcar/update.conf.
 			assertNodeProperty(ctx, t, &assertNodePropertyContext{
@@ -344,6 +352,26 @@ func Test_Running_agent(t *testing.T) {
 				})
 			})
 		})
+
+		t.Run("resets_reboot_state_indicators_using_configured_annotation_prefix", func(t *testing.T) {
+			t.Parallel()
+
+			keys := constants.NewKeys("example.com/")
+
+			testConfig, _, _ := validTestConfig(t, testNode())
+			testConfig.StatusReceiver = &mockStatusReceiver{}
+			testConfig.AnnotationPrefix = "example.com/"
+
+			ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+			done := runAgent(ctx, t, testConfig)
+
+			assertNodeProperty(ctx, t, &assertNodePropertyContext{
+				done:   done,
+				config: testConfig,
+				testF:  assertNodeAnnotationValue(keys.AnnotationRebootNeeded, constants.False),
+			})
+		})
 	})
 
 	t.Run("waits_for_not_ok_to_reboot_annotation_from_operator_after_updating_node_information", func(t *testing.T) {
@@ -497,6 +525,29 @@ func Test_Running_agent(t *testing.T) {
 			})
 		})
 
+		t.Run("records_time_when_update_requiring_reboot_became_available", func(t *testing.T) {
+			t.Parallel()
+
+			assertNodeProperty(ctx, t, &assertNodePropertyContext{
+				done:   done,
+				config: testConfig,
+				testF: func(t *testing.T, node *corev1.Node) bool {
+					t.Helper()
+
+					value, ok := node.Annotations[constants.AnnotationUpdateAvailableTime]
+					if !ok {
+						return false
+					}
+
+					if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+						t.Fatalf("Expected UNIX timestamp in annotation %q, got %q", constants.AnnotationUpdateAvailableTime, value)
+					}
+
+					return true
+				},
+			})
+		})
+
 		t.Run("waits_for_ok_to_reboot_annotation_from_operator", func(t *testing.T) {
 			t.Parallel()
 
@@ -855,61 +906,878 @@ func Test_Running_agent(t *testing.T) {
 				case <-allExpectedPodsScheduledForRemoval:
 				}
 
-				select {
-				case <-ctx.Done():
-					t.Fatalf("Timed out waiting pod GET request")
-				case <-podGetRequest:
+				select {
+				case <-ctx.Done():
+					t.Fatalf("Timed out waiting pod GET request")
+				case <-podGetRequest:
+				}
+
+				client := testConfig.Clientset.CoreV1().Pods(podsToCreate[0].Namespace)
+
+				if err := client.Delete(ctx, podsToCreate[0].Name, metav1.DeleteOptions{}); err != nil {
+					t.Fatalf("Failed removing test pod: %v", err)
+				}
+
+				select {
+				case <-ctx.Done():
+					t.Fatalf("Timed out waiting for reboot to be triggered")
+				case expected := <-rebootTriggerred:
+					if !expected {
+						t.Fatalf("Got unexpected reboot call")
+					}
+				}
+			})
+		})
+
+		t.Run("ignores_pods_which_has_not_terminated_when_pod_deletion_grace_period_is_reached", func(t *testing.T) {
+			t.Parallel()
+
+			rebootTriggerred := make(chan bool)
+
+			podsToCreate := []*corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "foo",
+						Namespace:       "default",
+						OwnerReferences: testPodControllerReference(),
+					},
+					Spec: corev1.PodSpec{
+						NodeName: testNode().Name,
+					},
+				},
+			}
+
+			fakeClient := fake.NewSimpleClientset(podsToCreate[0], testNode())
+			addEvictionSupport(t, fakeClient)
+
+			testConfig, node, _ := validTestConfig(t, testNode())
+			testConfig.Clientset = fakeClient
+			testConfig.Rebooter = &mockRebooter{
+				rebootF: func(auth bool) {
+					rebootTriggerred <- auth
+				},
+			}
+
+			// We only handle the creating of the eviction and not the delete which will block and cause the timeout
+			// condition we're looking for.
+			fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, nil
+			})
+
+			ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+			assertNodeProperty(ctx, t, &assertNodePropertyContext{
+				done:   runAgent(ctx, t, testConfig),
+				config: testConfig,
+				testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+			})
+
+			okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+			select {
+			case <-ctx.Done():
+				t.Fatal("Timed out waiting for reboot to be triggered")
+			case <-rebootTriggerred:
+			}
+		})
+	})
+
+	t.Run("waits_configured_grace_period_for_Job_pods_to_complete_before_evicting_them", func(t *testing.T) {
+		t.Parallel()
+
+		podsToCreate := []*corev1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "job-completing-within-grace",
+					Namespace:       "default",
+					OwnerReferences: testJobPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "job-exceeding-grace",
+					Namespace:       "default",
+					OwnerReferences: testJobPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+		}
+
+		fakeClient := fake.NewSimpleClientset(podsToCreate[0], podsToCreate[1], testNode())
+		addEvictionSupport(t, fakeClient)
+
+		testConfig, node, _ := validTestConfig(t, testNode())
+		testConfig.Clientset = fakeClient
+		testConfig.DrainJobGrace = time.Second
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(podsToCreate))
+
+		// Report first pod as completed when agent checks it.
+		fakeClient.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			getAction, ok := action.(k8stesting.GetActionImpl)
+			if !ok || getAction.Name != podsToCreate[0].Name {
+				return false, nil, nil
+			}
+
+			completedPod := podsToCreate[0].DeepCopy()
+			completedPod.Status.Phase = corev1.PodSucceeded
+
+			return true, completedPod, nil
+		})
+
+		evictedPodsMutex := &sync.Mutex{}
+		evictedPods := map[string]struct{}{}
+
+		fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			createAction, ok := action.(k8stesting.CreateActionImpl)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected action, expected %T, got %T", k8stesting.CreateActionImpl{}, action)
+			}
+
+			eviction, ok := createAction.Object.(*policyv1.Eviction)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected eviction type, got %T", createAction.Object)
+			}
+
+			evictedPodsMutex.Lock()
+			evictedPods[eviction.Name] = struct{}{}
+			evictedPodsMutex.Unlock()
+
+			return true, nil, nil
+		})
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+
+		evictedPodsMutex.Lock()
+		defer evictedPodsMutex.Unlock()
+
+		if _, ok := evictedPods[podsToCreate[0].Name]; ok {
+			t.Errorf("Unexpected eviction of pod %q which completed within grace period", podsToCreate[0].Name)
+		}
+
+		if _, ok := evictedPods[podsToCreate[1].Name]; !ok {
+			t.Errorf("Expected pod %q exceeding grace period to be evicted", podsToCreate[1].Name)
+		}
+	})
+
+	t.Run("evicts_pods_matching_drain_last_selectors_after_other_pods", func(t *testing.T) {
+		t.Parallel()
+
+		podsToCreate := []*corev1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "operator",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+					Labels: map[string]string{
+						"app": "flatcar-linux-update-operator",
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "regular",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+		}
+
+		fakeClient := fake.NewSimpleClientset(podsToCreate[0], podsToCreate[1], testNode())
+		addEvictionSupport(t, fakeClient)
+
+		testConfig, node, _ := validTestConfig(t, testNode())
+		testConfig.Clientset = fakeClient
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(podsToCreate))
+
+		evictedPodsMutex := &sync.Mutex{}
+		evictedPods := []string{}
+
+		fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			createAction, ok := action.(k8stesting.CreateActionImpl)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected action, expected %T, got %T", k8stesting.CreateActionImpl{}, action)
+			}
+
+			eviction, ok := createAction.Object.(*policyv1.Eviction)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected eviction type, got %T", createAction.Object)
+			}
+
+			evictedPodsMutex.Lock()
+			evictedPods = append(evictedPods, eviction.Name)
+			evictedPodsMutex.Unlock()
+
+			// Terminate evicted pods right away, so next pods are evicted without waiting for drain timeout.
+			podsGVR := corev1.SchemeGroupVersion.WithResource("pods")
+
+			return true, nil, fakeClient.Tracker().Delete(podsGVR, eviction.Namespace, eviction.Name)
+		})
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+
+		evictedPodsMutex.Lock()
+		defer evictedPodsMutex.Unlock()
+
+		expectedEvictionOrder := []string{podsToCreate[1].Name, podsToCreate[0].Name}
+
+		if diff := cmp.Diff(expectedEvictionOrder, evictedPods); diff != "" {
+			t.Fatalf("Unexpected eviction order: %s", diff)
+		}
+	})
+
+	t.Run("evicts_pods_in_ascending_priority_order_when_draining_by_priority", func(t *testing.T) {
+		t.Parallel()
+
+		highPriority := int32(1000)
+		lowPriority := int32(100)
+
+		podsToCreate := []*corev1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "high-priority",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+					Priority: &highPriority,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "default-priority",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "low-priority",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+					Priority: &lowPriority,
+				},
+			},
+		}
+
+		fakeClient := fake.NewSimpleClientset(podsToCreate[0], podsToCreate[1], podsToCreate[2], testNode())
+		addEvictionSupport(t, fakeClient)
+
+		testConfig, node, _ := validTestConfig(t, testNode())
+		testConfig.Clientset = fakeClient
+		testConfig.DrainByPriority = true
+		testConfig.DrainPriorityTierPause = time.Millisecond
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(podsToCreate))
+
+		evictedPodsMutex := &sync.Mutex{}
+		evictedPods := []string{}
+
+		fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			createAction, ok := action.(k8stesting.CreateActionImpl)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected action, expected %T, got %T", k8stesting.CreateActionImpl{}, action)
+			}
+
+			eviction, ok := createAction.Object.(*policyv1.Eviction)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected eviction type, got %T", createAction.Object)
+			}
+
+			evictedPodsMutex.Lock()
+			evictedPods = append(evictedPods, eviction.Name)
+			evictedPodsMutex.Unlock()
+
+			// Terminate evicted pods right away, so next pods are evicted without waiting for drain timeout.
+			podsGVR := corev1.SchemeGroupVersion.WithResource("pods")
+
+			return true, nil, fakeClient.Tracker().Delete(podsGVR, eviction.Namespace, eviction.Name)
+		})
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+
+		evictedPodsMutex.Lock()
+		defer evictedPodsMutex.Unlock()
+
+		expectedEvictionOrder := []string{podsToCreate[1].Name, podsToCreate[2].Name, podsToCreate[0].Name}
+
+		if diff := cmp.Diff(expectedEvictionOrder, evictedPods); diff != "" {
+			t.Fatalf("Unexpected eviction order: %s", diff)
+		}
+	})
+
+	t.Run("applies_drain_timeout_to_all_priority_tiers_and_drain_last_pods_together", func(t *testing.T) {
+		t.Parallel()
+
+		highPriority := int32(1000)
+
+		podsToCreate := []*corev1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "default-priority",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "high-priority",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+					Priority: &highPriority,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "operator",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+					Labels: map[string]string{
+						"app": "flatcar-linux-update-operator",
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+		}
+
+		fakeClient := fake.NewSimpleClientset(podsToCreate[0], podsToCreate[1], podsToCreate[2], testNode())
+		addEvictionSupport(t, fakeClient)
+
+		testConfig, node, _ := validTestConfig(t, testNode())
+		testConfig.Clientset = fakeClient
+		testConfig.DrainTimeout = 2 * time.Second
+		testConfig.DrainByPriority = true
+		testConfig.DrainPriorityTierPause = time.Millisecond
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		// Pods are never removed after eviction, simulating pods which do not terminate.
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(podsToCreate))
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		drainStarted := time.Now()
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+
+		// Each of 2 priority tiers and drain last pods waiting for full drain timeout would take 3 times longer.
+		if drainDuration := time.Since(drainStarted); drainDuration > 2*testConfig.DrainTimeout {
+			t.Fatalf("Expected node to be rebooted within drain timeout of %v, took %v", testConfig.DrainTimeout, drainDuration)
+		}
+	})
+
+	t.Run("waits_for_pods_with_extended_grace_period_to_terminate_before_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		gracePeriodAnnotation := "example.com/drain-grace-period-seconds"
+
+		podsToCreate := []*corev1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "regular",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "flushing-state",
+					Namespace:       "default",
+					OwnerReferences: testPodControllerReference(),
+					Annotations: map[string]string{
+						gracePeriodAnnotation: "600",
+					},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: testNode().Name,
+				},
+			},
+		}
+
+		fakeClient := fake.NewSimpleClientset(podsToCreate[0], podsToCreate[1], testNode())
+		addEvictionSupport(t, fakeClient)
+
+		testConfig, node, _ := validTestConfig(t, testNode())
+		testConfig.Clientset = fakeClient
+		testConfig.DrainTimeout = 100 * time.Millisecond
+		testConfig.DrainGracePeriodSeconds = 1
+		testConfig.DrainGracePeriodAnnotation = gracePeriodAnnotation
+		testConfig.DrainMaxGracePeriod = 10 * time.Second
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(podsToCreate))
+
+		evictionsMutex := &sync.Mutex{}
+		evictions := map[string]*int64{}
+		podTerminated := make(chan struct{})
+
+		// Only pod with extended grace period terminates, shortly after being evicted.
+		fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			createAction, ok := action.(k8stesting.CreateActionImpl)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected action, expected %T, got %T", k8stesting.CreateActionImpl{}, action)
+			}
+
+			eviction, ok := createAction.Object.(*policyv1.Eviction)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected eviction type, got %T", createAction.Object)
+			}
+
+			evictionsMutex.Lock()
+			defer evictionsMutex.Unlock()
+
+			evictions[eviction.Name] = eviction.DeleteOptions.GracePeriodSeconds
+
+			if eviction.Name == podsToCreate[1].Name {
+				go func() {
+					time.Sleep(500 * time.Millisecond)
+
+					if err := fakeClient.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"),
+						eviction.Namespace, eviction.Name); err != nil {
+						t.Errorf("Deleting pod: %v", err)
+					}
+
+					close(podTerminated)
+				}()
+			}
+
+			return true, nil, nil
+		})
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+
+		select {
+		case <-podTerminated:
+		default:
+			t.Fatalf("Expected reboot to be triggered only after pod with extended grace period terminated")
+		}
+
+		evictionsMutex.Lock()
+		defer evictionsMutex.Unlock()
+
+		expectedGracePeriods := map[string]int64{
+			podsToCreate[0].Name: int64(testConfig.DrainGracePeriodSeconds),
+			podsToCreate[1].Name: int64(testConfig.DrainMaxGracePeriod.Seconds()),
+		}
+
+		for name, expectedGracePeriod := range expectedGracePeriods {
+			gracePeriod, ok := evictions[name]
+			if !ok {
+				t.Fatalf("Expected pod %q to be evicted", name)
+			}
+
+			if gracePeriod == nil || *gracePeriod != expectedGracePeriod {
+				t.Fatalf("Expected pod %q to be evicted with grace period of %d seconds, got %v",
+					name, expectedGracePeriod, gracePeriod)
+			}
+		}
+	})
+
+	t.Run("retries_evicting_pods_protected_by_pod_disruption_budget", func(t *testing.T) {
+		t.Parallel()
+
+		podToCreate := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "protected",
+				Namespace:       "default",
+				OwnerReferences: testPodControllerReference(),
+			},
+			Spec: corev1.PodSpec{
+				NodeName: testNode().Name,
+			},
+		}
+
+		fakeClient := fake.NewSimpleClientset(podToCreate, testNode())
+		addEvictionSupport(t, fakeClient)
+
+		testConfig, node, _ := validTestConfig(t, testNode())
+		testConfig.Clientset = fakeClient
+		// Evictions blocked by PDB are retried every 5 seconds.
+		testConfig.PodDeletionGracePeriod = 10 * time.Second
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector([]*corev1.Pod{podToCreate}))
+
+		evictionAttemptsMutex := &sync.Mutex{}
+		evictionAttempts := 0
+
+		fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			evictionAttemptsMutex.Lock()
+			defer evictionAttemptsMutex.Unlock()
+
+			evictionAttempts++
+
+			// API server responds with 429 when eviction would violate PodDisruptionBudget.
+			if evictionAttempts == 1 {
+				return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate PDB.", 0)
+			}
+
+			podsGVR := corev1.SchemeGroupVersion.WithResource("pods")
+
+			return true, nil, fakeClient.Tracker().Delete(podsGVR, podToCreate.Namespace, podToCreate.Name)
+		})
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+
+		evictionAttemptsMutex.Lock()
+		defer evictionAttemptsMutex.Unlock()
+
+		if evictionAttempts != 2 {
+			t.Fatalf("Expected eviction to be retried once after being blocked by PDB, got %d attempts", evictionAttempts)
+		}
+	})
+
+	t.Run("evicts_at_most_configured_number_of_pods_at_once_including_pods_with_different_grace_periods",
+		func(t *testing.T) {
+			t.Parallel()
+
+			gracePeriodAnnotation := "example.com/drain-grace-period-seconds"
+
+			podsToCreate := []*corev1.Pod{}
+			objects := []runtime.Object{testNode()}
+
+			for i := 0; i < 4; i++ {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            fmt.Sprintf("pod-%d", i),
+						Namespace:       "default",
+						OwnerReferences: testPodControllerReference(),
+					},
+					Spec: corev1.PodSpec{
+						NodeName: testNode().Name,
+					},
+				}
+
+				// Pods requesting extended grace period share drain slots with other pods.
+				if i%2 == 1 {
+					pod.Annotations = map[string]string{gracePeriodAnnotation: "600"}
+				}
+
+				podsToCreate = append(podsToCreate, pod)
+				objects = append(objects, pod)
+			}
+
+			fakeClient := fake.NewSimpleClientset(objects...)
+			addEvictionSupport(t, fakeClient)
+
+			testConfig, node, _ := validTestConfig(t, testNode())
+			testConfig.Clientset = fakeClient
+			testConfig.DrainConcurrency = 2
+			testConfig.DrainGracePeriodAnnotation = gracePeriodAnnotation
+
+			rebootTriggerred := make(chan struct{}, 1)
+
+			testConfig.Rebooter = &mockRebooter{
+				rebootF: func(auth bool) {
+					rebootTriggerred <- struct{}{}
+				},
+			}
+
+			fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(podsToCreate))
+
+			evictionsMutex := &sync.Mutex{}
+			terminating := 0
+			maxTerminating := 0
+
+			fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				createAction, ok := action.(k8stesting.CreateActionImpl)
+				if !ok {
+					return true, nil, fmt.Errorf("unexpected action, expected %T, got %T", k8stesting.CreateActionImpl{}, action)
+				}
+
+				eviction, ok := createAction.Object.(*policyv1.Eviction)
+				if !ok {
+					return true, nil, fmt.Errorf("unexpected eviction type, got %T", createAction.Object)
 				}
 
-				client := testConfig.Clientset.CoreV1().Pods(podsToCreate[0].Namespace)
+				evictionsMutex.Lock()
+				defer evictionsMutex.Unlock()
 
-				if err := client.Delete(ctx, podsToCreate[0].Name, metav1.DeleteOptions{}); err != nil {
-					t.Fatalf("Failed removing test pod: %v", err)
+				terminating++
+				if terminating > maxTerminating {
+					maxTerminating = terminating
 				}
 
-				select {
-				case <-ctx.Done():
-					t.Fatalf("Timed out waiting for reboot to be triggered")
-				case expected := <-rebootTriggerred:
-					if !expected {
-						t.Fatalf("Got unexpected reboot call")
+				// Pods take a while to terminate, so evicting them all at once would be observed.
+				go func() {
+					time.Sleep(100 * time.Millisecond)
+
+					evictionsMutex.Lock()
+					terminating--
+					evictionsMutex.Unlock()
+
+					podsGVR := corev1.SchemeGroupVersion.WithResource("pods")
+
+					if err := fakeClient.Tracker().Delete(podsGVR, eviction.Namespace, eviction.Name); err != nil {
+						t.Logf("Failed removing pod %s/%s: %v", eviction.Namespace, eviction.Name, err)
 					}
-				}
+				}()
+
+				return true, nil, nil
+			})
+
+			ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+			assertNodeProperty(ctx, t, &assertNodePropertyContext{
+				done:   runAgent(ctx, t, testConfig),
+				config: testConfig,
+				testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
 			})
+
+			okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+			select {
+			case <-ctx.Done():
+				t.Fatal("Timed out waiting for reboot to be triggered")
+			case <-rebootTriggerred:
+			}
+
+			evictionsMutex.Lock()
+			defer evictionsMutex.Unlock()
+
+			if maxTerminating != testConfig.DrainConcurrency {
+				t.Fatalf("Expected at most %d pods to be evicted at once, got %d", testConfig.DrainConcurrency, maxTerminating)
+			}
 		})
 
-		t.Run("ignores_pods_which_has_not_terminated_when_pod_deletion_grace_period_is_reached", func(t *testing.T) {
+	t.Run("evicts_pods_with_extended_grace_period_after_other_pods_when_drain_concurrency_is_configured",
+		func(t *testing.T) {
 			t.Parallel()
 
-			rebootTriggerred := make(chan bool)
+			gracePeriodAnnotation := "example.com/drain-grace-period-seconds"
 
-			podsToCreate := []*corev1.Pod{
-				{
+			podsToCreate := []*corev1.Pod{}
+			objects := []runtime.Object{testNode()}
+
+			for i := 0; i < 3; i++ {
+				pod := &corev1.Pod{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:            "foo",
+						Name:            fmt.Sprintf("pod-%d", i),
 						Namespace:       "default",
 						OwnerReferences: testPodControllerReference(),
 					},
 					Spec: corev1.PodSpec{
 						NodeName: testNode().Name,
 					},
-				},
+				}
+
+				// Pod with extended grace period would hold the only drain slot beyond drain timeout.
+				if i == 0 {
+					pod.Annotations = map[string]string{gracePeriodAnnotation: "600"}
+				}
+
+				podsToCreate = append(podsToCreate, pod)
+				objects = append(objects, pod)
 			}
 
-			fakeClient := fake.NewSimpleClientset(podsToCreate[0], testNode())
+			fakeClient := fake.NewSimpleClientset(objects...)
 			addEvictionSupport(t, fakeClient)
 
 			testConfig, node, _ := validTestConfig(t, testNode())
 			testConfig.Clientset = fakeClient
+			testConfig.DrainConcurrency = 1
+			testConfig.DrainTimeout = 3 * time.Second
+			testConfig.DrainGracePeriodAnnotation = gracePeriodAnnotation
+
+			rebootTriggerred := make(chan struct{}, 1)
+
 			testConfig.Rebooter = &mockRebooter{
 				rebootF: func(auth bool) {
-					rebootTriggerred <- auth
+					rebootTriggerred <- struct{}{}
 				},
 			}
 
-			// We only handle the creating of the eviction and not the delete which will block and cause the timeout
-			// condition we're looking for.
+			fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(podsToCreate))
+
+			evictionsMutex := &sync.Mutex{}
+			evictedPods := map[string]struct{}{}
+
 			fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				createAction, ok := action.(k8stesting.CreateActionImpl)
+				if !ok {
+					return true, nil, fmt.Errorf("unexpected action, expected %T, got %T", k8stesting.CreateActionImpl{}, action)
+				}
+
+				eviction, ok := createAction.Object.(*policyv1.Eviction)
+				if !ok {
+					return true, nil, fmt.Errorf("unexpected eviction type, got %T", createAction.Object)
+				}
+
+				evictionsMutex.Lock()
+				evictedPods[eviction.Name] = struct{}{}
+				evictionsMutex.Unlock()
+
+				terminationTime := 100 * time.Millisecond
+				if eviction.Name == podsToCreate[0].Name {
+					terminationTime = 5 * time.Second
+				}
+
+				go func() {
+					time.Sleep(terminationTime)
+
+					podsGVR := corev1.SchemeGroupVersion.WithResource("pods")
+
+					if err := fakeClient.Tracker().Delete(podsGVR, eviction.Namespace, eviction.Name); err != nil {
+						t.Logf("Failed removing pod %s/%s: %v", eviction.Namespace, eviction.Name, err)
+					}
+				}()
+
 				return true, nil, nil
 			})
 
@@ -928,6 +1796,271 @@ func Test_Running_agent(t *testing.T) {
 				t.Fatal("Timed out waiting for reboot to be triggered")
 			case <-rebootTriggerred:
 			}
+
+			evictionsMutex.Lock()
+			defer evictionsMutex.Unlock()
+
+			for _, pod := range podsToCreate {
+				if _, ok := evictedPods[pod.Name]; !ok {
+					t.Fatalf("Expected pod %q to be evicted before reboot", pod.Name)
+				}
+			}
+		})
+
+	t.Run("evicts_pods_with_configured_grace_period_and_reboots_when_drain_timeout_is_exceeded", func(t *testing.T) {
+		t.Parallel()
+
+		daemonSet := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "logs",
+				Namespace: "default",
+			},
+		}
+
+		deploymentPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "web-6d4cf56db6-x2x7k",
+				Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: "apps/v1",
+						Kind:       "ReplicaSet",
+						Name:       "web-6d4cf56db6",
+						Controller: pointer.BoolPtr(true),
+					},
+				},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: testNode().Name,
+			},
+		}
+
+		daemonSetPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "logs-8xk2p",
+				Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: "apps/v1",
+						Kind:       "DaemonSet",
+						Name:       daemonSet.Name,
+						Controller: pointer.BoolPtr(true),
+					},
+				},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: testNode().Name,
+			},
+		}
+
+		fakeClient := fake.NewSimpleClientset(daemonSet, deploymentPod, daemonSetPod, testNode())
+		addEvictionSupport(t, fakeClient)
+
+		testConfig, node, _ := validTestConfig(t, testNode())
+		testConfig.Clientset = fakeClient
+		// Drain timeout takes precedence over pod deletion grace period.
+		testConfig.PodDeletionGracePeriod = time.Hour
+		testConfig.DrainTimeout = 100 * time.Millisecond
+		testConfig.DrainGracePeriodSeconds = 30
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector([]*corev1.Pod{deploymentPod, daemonSetPod}))
+
+		evictionsMutex := &sync.Mutex{}
+		evictions := map[string]*int64{}
+
+		// Pods are never removed after eviction, simulating pods which do not terminate.
+		fakeClient.PrependReactor("create", "pods/eviction", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			createAction, ok := action.(k8stesting.CreateActionImpl)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected action, expected %T, got %T", k8stesting.CreateActionImpl{}, action)
+			}
+
+			eviction, ok := createAction.Object.(*policyv1.Eviction)
+			if !ok {
+				return true, nil, fmt.Errorf("unexpected eviction type, expected %T, got %T", &policyv1.Eviction{}, eviction)
+			}
+
+			evictionsMutex.Lock()
+			defer evictionsMutex.Unlock()
+
+			evictions[eviction.Name] = eviction.DeleteOptions.GracePeriodSeconds
+
+			return true, nil, nil
+		})
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+
+		t.Run("evicting_only_pods_not_owned_by_DaemonSet", func(t *testing.T) {
+			evictionsMutex.Lock()
+			defer evictionsMutex.Unlock()
+
+			if _, ok := evictions[daemonSetPod.Name]; ok {
+				t.Fatalf("Unexpected eviction of DaemonSet pod %q", daemonSetPod.Name)
+			}
+
+			gracePeriod, ok := evictions[deploymentPod.Name]
+			if !ok {
+				t.Fatalf("Expected pod %q to be evicted", deploymentPod.Name)
+			}
+
+			if gracePeriod == nil || *gracePeriod != int64(testConfig.DrainGracePeriodSeconds) {
+				t.Fatalf("Expected pod to be evicted with grace period of %d seconds, got %v",
+					testConfig.DrainGracePeriodSeconds, gracePeriod)
+			}
+		})
+
+		t.Run("emitting_event_about_exceeded_drain_timeout", func(t *testing.T) {
+			// Events are sent asynchronously, so wait for them to arrive.
+			err := wait.PollImmediateUntil(100*time.Millisecond, func() (bool, error) {
+				events, err := fakeClient.CoreV1().Events(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return false, fmt.Errorf("listing events: %w", err)
+				}
+
+				for _, event := range events.Items {
+					if event.Reason == "DrainTimeoutExceeded" && event.InvolvedObject.Name == node.Name {
+						return true, nil
+					}
+				}
+
+				return false, nil
+			}, ctx.Done())
+			if err != nil {
+				t.Fatalf("Waiting for drain timeout event: %v", err)
+			}
+		})
+	})
+
+	t.Run("waits_for_volumes_of_evicted_pods_to_detach_before_rebooting_when_configured", func(t *testing.T) {
+		t.Parallel()
+
+		fakeClient, testConfig, node, volumeAttachment := volumeAttachedTestConfig(t)
+		testConfig.DrainVolumeDetachTimeout = time.Hour
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		volumeAttachmentsListed := make(chan struct{}, 1)
+
+		fakeClient.PrependReactor("list", "volumeattachments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			select {
+			case volumeAttachmentsListed <- struct{}{}:
+			default:
+			}
+
+			return false, nil, nil
+		})
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for volume attachments to be checked")
+		case <-volumeAttachmentsListed:
+		}
+
+		select {
+		case <-rebootTriggerred:
+			t.Fatal("Unexpected reboot while volume is still attached")
+		case <-time.After(2 * testConfig.PollInterval):
+		}
+
+		err := fakeClient.StorageV1().VolumeAttachments().Delete(ctx, volumeAttachment.Name, metav1.DeleteOptions{})
+		if err != nil {
+			t.Fatalf("Deleting volume attachment: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+	})
+
+	t.Run("reboots_when_volumes_do_not_detach_within_configured_timeout_by", func(t *testing.T) {
+		t.Parallel()
+
+		fakeClient, testConfig, node, _ := volumeAttachedTestConfig(t)
+		testConfig.DrainVolumeDetachTimeout = 100 * time.Millisecond
+
+		rebootTriggerred := make(chan struct{}, 1)
+
+		testConfig.Rebooter = &mockRebooter{
+			rebootF: func(auth bool) {
+				rebootTriggerred <- struct{}{}
+			},
+		}
+
+		ctx := contextWithTimeout(t, agentRunTimeLimit)
+
+		assertNodeProperty(ctx, t, &assertNodePropertyContext{
+			done:   runAgent(ctx, t, testConfig),
+			config: testConfig,
+			testF:  assertNodeAnnotationValue(constants.AnnotationRebootNeeded, constants.True),
+		})
+
+		okToReboot(ctx, t, testConfig.Clientset.CoreV1().Nodes(), node.Name)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timed out waiting for reboot to be triggered")
+		case <-rebootTriggerred:
+		}
+
+		t.Run("emitting_event_about_exceeded_volume_detach_timeout", func(t *testing.T) {
+			// Events are sent asynchronously, so wait for them to arrive.
+			err := wait.PollImmediateUntil(100*time.Millisecond, func() (bool, error) {
+				events, err := fakeClient.CoreV1().Events(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return false, fmt.Errorf("listing events: %w", err)
+				}
+
+				for _, event := range events.Items {
+					if event.Reason == "VolumeDetachTimeoutExceeded" && event.InvolvedObject.Name == node.Name {
+						return true, nil
+					}
+				}
+
+				return false, nil
+			}, ctx.Done())
+			if err != nil {
+				t.Fatalf("Waiting for volume detach timeout event: %v", err)
+			}
 		})
 	})
 
@@ -2037,6 +3170,69 @@ func addEvictionSupport(t *testing.T, clientset *fake.Clientset) {
 	})
 }
 
+// volumeAttachedTestConfig returns agent configuration waiting for volumes with ReadWriteOnce access mode
+// to detach, with a pod on the test node using such volume, which is attached to the test node.
+func volumeAttachedTestConfig(
+	t *testing.T,
+) (*fake.Clientset, *agent.Config, *corev1.Node, *storagev1.VolumeAttachment) {
+	t.Helper()
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data",
+			Namespace: "default",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			VolumeName:  "pv-data",
+		},
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "database",
+			Namespace:       claim.Namespace,
+			OwnerReferences: testPodControllerReference(),
+		},
+		Spec: corev1.PodSpec{
+			NodeName: testNode().Name,
+			Volumes: []corev1.Volume{
+				{
+					Name: "data",
+					VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+							ClaimName: claim.Name,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	volumeAttachment := &storagev1.VolumeAttachment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "csi-data",
+		},
+		Spec: storagev1.VolumeAttachmentSpec{
+			NodeName: testNode().Name,
+			Source: storagev1.VolumeAttachmentSource{
+				PersistentVolumeName: &claim.Spec.VolumeName,
+			},
+		},
+	}
+
+	fakeClient := fake.NewSimpleClientset(claim, pod, volumeAttachment, testNode())
+	addEvictionSupport(t, fakeClient)
+
+	fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector([]*corev1.Pod{pod}))
+
+	testConfig, node, _ := validTestConfig(t, testNode())
+	testConfig.Clientset = fakeClient
+	testConfig.DrainVolumeDetachAccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+
+	return fakeClient, testConfig, node, volumeAttachment
+}
+
 func testPodControllerReference() []metav1.OwnerReference {
 	return []metav1.OwnerReference{
 		{
@@ -2045,3 +3241,13 @@ func testPodControllerReference() []metav1.OwnerReference {
 		},
 	}
 }
+
+func testJobPodControllerReference() []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		{
+			Kind:       "Job",
+			Name:       "fake-job",
+			Controller: pointer.BoolPtr(true),
+		},
+	}
+}
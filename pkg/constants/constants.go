@@ -26,11 +26,92 @@ const (
 	// with a node-drain and reboot.
 	AnnotationOkToReboot = Prefix + "reboot-ok"
 
+	// AnnotationRebootApproved is a key set to "true" by the update-operator together with
+	// AnnotationOkToReboot, so reboots orchestrated by the update-operator can be told apart from
+	// reboots which happened on their own, e.g. due to a crash or manual intervention.
+	AnnotationRebootApproved = Prefix + "reboot-approved"
+
+	// AnnotationRebootCycleStartTime is a key set by the update-operator to a UNIX timestamp of when
+	// the node entered the reboot process. It is removed once the reboot process finishes.
+	AnnotationRebootCycleStartTime = Prefix + "reboot-cycle-start-time"
+
+	// AnnotationRebootApprovedTime is a key set by the update-operator to a UNIX timestamp of when
+	// the node was approved for rebooting. It is removed once the reboot process finishes.
+	AnnotationRebootApprovedTime = Prefix + "reboot-approved-time"
+
+	// AnnotationRebootStartedTime is a key set by the update-operator to a UNIX timestamp of when
+	// the node was last allowed to reboot. It is kept after the reboot process finishes and gets
+	// overwritten by the next reboot process.
+	AnnotationRebootStartedTime = Prefix + "reboot-started-time"
+
+	// AnnotationRebootCompletedTime is a key set by the update-operator to a UNIX timestamp of when
+	// the last reboot process of the node finished. It is kept after the reboot process finishes and
+	// gets removed once the next reboot process starts.
+	AnnotationRebootCompletedTime = Prefix + "reboot-completed-time"
+
+	// AnnotationRebootReason is a key set by the update-operator to the reason of the reboot process
+	// when it starts. It is removed once the reboot process finishes or gets cancelled.
+	AnnotationRebootReason = Prefix + "reboot-reason"
+
+	// AnnotationLastRebootReason is a key set by the update-operator to the reason of the last finished
+	// reboot process, providing an audit trail of reboots.
+	AnnotationLastRebootReason = Prefix + "last-reboot-reason"
+
+	// AnnotationReadySinceTime is a key set by the update-operator to a UNIX timestamp of when
+	// the rebooted node was first observed Ready. It is removed once the node is labeled for
+	// after reboot checks or when the node is observed not Ready.
+	AnnotationReadySinceTime = Prefix + "ready-since-time"
+
+	// AnnotationAfterRebootAttempts is a key set by the update-operator to the number of times after
+	// reboot checks of the node did not pass within configured timeout. It is removed once after reboot
+	// checks pass or the node is labeled with LabelRebootFailed.
+	AnnotationAfterRebootAttempts = Prefix + "after-reboot-attempts"
+
+	// AnnotationAfterRebootAttemptTime is a key set by the update-operator to a UNIX timestamp of when
+	// the current attempt of after reboot checks started or when the last attempt failed. It is removed
+	// once after reboot checks pass or the node is labeled with LabelRebootFailed.
+	AnnotationAfterRebootAttemptTime = Prefix + "after-reboot-attempt-time"
+
+	// AnnotationSmokeTestNode is a key set by the update-operator on smoke test Jobs to the name of the
+	// node they test.
+	AnnotationSmokeTestNode = Prefix + "smoke-test-node"
+
+	// AnnotationBeforeRebootTimedOutTime is a key set by the update-operator to a UNIX timestamp of when
+	// before reboot checks of the node did not pass in time and its reboot process was aborted. Nodes with
+	// this annotation are scheduled for rebooting after other nodes. It is removed once before reboot
+	// checks pass.
+	AnnotationBeforeRebootTimedOutTime = Prefix + "before-reboot-timed-out-time"
+
+	// AnnotationRebootUnwantedTime is a key set by the update-operator to a UNIX timestamp of when the node
+	// running before reboot checks was first seen no longer wanting to reboot, when the operator is configured
+	// with a grace period for reverting before reboot checks. It is removed once the node wants to reboot
+	// again or its before reboot checks are reverted.
+	AnnotationRebootUnwantedTime = Prefix + "reboot-unwanted-time"
+
+	// AnnotationRebootWindowProfile is a key which may be set by an admin to a name of the reboot window
+	// profile configured in the update-operator, so the node is rebooted during reboot windows of the
+	// profile instead of the default reboot windows.
+	AnnotationRebootWindowProfile = Prefix + "reboot-window-profile"
+
+	// AnnotationRequestReboot is a key which may be set by an admin to "true" to request rebooting
+	// the node, even if no update is pending. The node then goes through the regular reboot process.
+	// It is removed by the update-operator once the reboot process finishes.
+	AnnotationRequestReboot = Prefix + "request-reboot"
+
 	// AnnotationRebootPaused is a key that may be set by the administrator to "true" to prevent
 	// update-operator from considering a node for rebooting.  Never set by
 	// the update-agent or update-operator.
 	AnnotationRebootPaused = Prefix + "reboot-paused"
 
+	// AnnotationRebootPausedUntil is a key that may be set by the administrator to an RFC3339 timestamp
+	// to prevent update-operator from considering a node for rebooting until that time. Never set by
+	// the update-agent or update-operator.
+	AnnotationRebootPausedUntil = Prefix + "reboot-paused-until"
+
+	// AnnotationBootTime is a key that may be set to a UNIX timestamp of when the node booted. It is used
+	// by update-operator to reboot nodes which are up the longest first, when configured to do so.
+	AnnotationBootTime = Prefix + "boot-time"
+
 	// AnnotationStatus is a key set by the update-agent to the current operator status of update_agent.
 	//
 	// Possible values are:
@@ -52,6 +133,10 @@ const (
 	// It is zero if an update has never been checked for, or a UNIX timestamp.
 	AnnotationLastCheckedTime = Prefix + "last-checked-time"
 
+	// AnnotationUpdateAvailableTime is a key set by the update-agent to a UNIX timestamp of when it first
+	// observed an update requiring a reboot. It is removed once no reboot is needed anymore.
+	AnnotationUpdateAvailableTime = Prefix + "update-available-time"
+
 	// AnnotationNewVersion is a key set by the update-agent to NEW_VERSION reported by update_engine.
 	//
 	// It is an opaque string, but might be semver.
@@ -61,6 +146,10 @@ const (
 	// it was responsible for making node unschedulable.
 	AnnotationAgentMadeUnschedulable = Prefix + "agent-made-unschedulable"
 
+	// AnnotationOperatorMadeUnschedulable is a key set by update-operator to indicate
+	// it was responsible for making node unschedulable before the reboot.
+	AnnotationOperatorMadeUnschedulable = Prefix + "operator-made-unschedulable"
+
 	// LabelBeforeReboot is a key set to true when the operator is waiting for configured annotation
 	// before and after the reboot respectively.
 	LabelBeforeReboot = Prefix + "before-reboot"
@@ -69,6 +158,11 @@ const (
 	// before and after the reboot respectively.
 	LabelAfterReboot = Prefix + "after-reboot"
 
+	// LabelRebootFailed is a key set to true by the update-operator when after reboot checks of the node
+	// did not pass within configured number of attempts. It must be removed by the administrator to retry
+	// after reboot checks.
+	LabelRebootFailed = Prefix + "reboot-failed"
+
 	// LabelID is a key set by the update-agent to the value of "ID" in /etc/os-release.
 	LabelID = Prefix + "id"
 
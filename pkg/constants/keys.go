@@ -0,0 +1,161 @@
+package constants
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Keys holds label and annotation keys used by the update-agent and the update-operator, which
+// share a common prefix. Fields have the same names and meaning as constants of this package.
+//
+// Both the update-agent and the update-operator must use the same keys to cooperate.
+type Keys struct {
+	AnnotationRebootNeeded              string
+	LabelRebootNeeded                   string
+	AnnotationRebootInProgress          string
+	AnnotationOkToReboot                string
+	AnnotationRebootApproved            string
+	AnnotationRebootCycleStartTime      string
+	AnnotationRebootApprovedTime        string
+	AnnotationRebootStartedTime         string
+	AnnotationRebootCompletedTime       string
+	AnnotationRebootReason              string
+	AnnotationLastRebootReason          string
+	AnnotationReadySinceTime            string
+	AnnotationAfterRebootAttempts       string
+	AnnotationAfterRebootAttemptTime    string
+	AnnotationSmokeTestNode             string
+	AnnotationBeforeRebootTimedOutTime  string
+	AnnotationRebootUnwantedTime        string
+	AnnotationRebootWindowProfile       string
+	AnnotationRequestReboot             string
+	AnnotationRebootPaused              string
+	AnnotationRebootPausedUntil         string
+	AnnotationBootTime                  string
+	AnnotationStatus                    string
+	AnnotationLastCheckedTime           string
+	AnnotationUpdateAvailableTime       string
+	AnnotationNewVersion                string
+	AnnotationAgentMadeUnschedulable    string
+	AnnotationOperatorMadeUnschedulable string
+	LabelBeforeReboot                   string
+	LabelAfterReboot                    string
+	LabelRebootFailed                   string
+	LabelID                             string
+	LabelGroup                          string
+	LabelVersion                        string
+}
+
+// NewKeys returns label and annotation keys using given prefix instead of Prefix, e.g. "example.com/",
+// so organizations can namespace them according to their own conventions. If prefix is empty, keys
+// are equal to constants of this package.
+func NewKeys(prefix string) Keys {
+	if prefix == "" {
+		prefix = Prefix
+	}
+
+	withPrefix := func(key string) string {
+		return prefix + strings.TrimPrefix(key, Prefix)
+	}
+
+	return Keys{
+		AnnotationRebootNeeded:              withPrefix(AnnotationRebootNeeded),
+		LabelRebootNeeded:                   withPrefix(LabelRebootNeeded),
+		AnnotationRebootInProgress:          withPrefix(AnnotationRebootInProgress),
+		AnnotationOkToReboot:                withPrefix(AnnotationOkToReboot),
+		AnnotationRebootApproved:            withPrefix(AnnotationRebootApproved),
+		AnnotationRebootCycleStartTime:      withPrefix(AnnotationRebootCycleStartTime),
+		AnnotationRebootApprovedTime:        withPrefix(AnnotationRebootApprovedTime),
+		AnnotationRebootStartedTime:         withPrefix(AnnotationRebootStartedTime),
+		AnnotationRebootCompletedTime:       withPrefix(AnnotationRebootCompletedTime),
+		AnnotationRebootReason:              withPrefix(AnnotationRebootReason),
+		AnnotationLastRebootReason:          withPrefix(AnnotationLastRebootReason),
+		AnnotationReadySinceTime:            withPrefix(AnnotationReadySinceTime),
+		AnnotationAfterRebootAttempts:       withPrefix(AnnotationAfterRebootAttempts),
+		AnnotationAfterRebootAttemptTime:    withPrefix(AnnotationAfterRebootAttemptTime),
+		AnnotationSmokeTestNode:             withPrefix(AnnotationSmokeTestNode),
+		AnnotationBeforeRebootTimedOutTime:  withPrefix(AnnotationBeforeRebootTimedOutTime),
+		AnnotationRebootUnwantedTime:        withPrefix(AnnotationRebootUnwantedTime),
+		AnnotationRebootWindowProfile:       withPrefix(AnnotationRebootWindowProfile),
+		AnnotationRequestReboot:             withPrefix(AnnotationRequestReboot),
+		AnnotationRebootPaused:              withPrefix(AnnotationRebootPaused),
+		AnnotationRebootPausedUntil:         withPrefix(AnnotationRebootPausedUntil),
+		AnnotationBootTime:                  withPrefix(AnnotationBootTime),
+		AnnotationStatus:                    withPrefix(AnnotationStatus),
+		AnnotationLastCheckedTime:           withPrefix(AnnotationLastCheckedTime),
+		AnnotationUpdateAvailableTime:       withPrefix(AnnotationUpdateAvailableTime),
+		AnnotationNewVersion:                withPrefix(AnnotationNewVersion),
+		AnnotationAgentMadeUnschedulable:    withPrefix(AnnotationAgentMadeUnschedulable),
+		AnnotationOperatorMadeUnschedulable: withPrefix(AnnotationOperatorMadeUnschedulable),
+		LabelBeforeReboot:                   withPrefix(LabelBeforeReboot),
+		LabelAfterReboot:                    withPrefix(LabelAfterReboot),
+		LabelRebootFailed:                   withPrefix(LabelRebootFailed),
+		LabelID:                             withPrefix(LabelID),
+		LabelGroup:                          withPrefix(LabelGroup),
+		LabelVersion:                        withPrefix(LabelVersion),
+	}
+}
+
+// OptionalAnnotations returns keys of informational annotations set by FLUO, which are not required
+// for the reboot process to function. They may be removed from the node when its annotations grow
+// too large, in the returned order, starting with history of past reboots.
+func (k Keys) OptionalAnnotations() []string {
+	return []string{
+		k.AnnotationLastRebootReason,
+		k.AnnotationRebootCompletedTime,
+		k.AnnotationLastCheckedTime,
+		k.AnnotationStatus,
+	}
+}
+
+// Validate checks if all keys are valid label and annotation keys.
+func (k Keys) Validate() error {
+	for _, key := range k.all() {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid key %q: %s", key, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (k Keys) all() []string {
+	return []string{
+		k.AnnotationRebootNeeded,
+		k.LabelRebootNeeded,
+		k.AnnotationRebootInProgress,
+		k.AnnotationOkToReboot,
+		k.AnnotationRebootApproved,
+		k.AnnotationRebootCycleStartTime,
+		k.AnnotationRebootApprovedTime,
+		k.AnnotationRebootStartedTime,
+		k.AnnotationRebootCompletedTime,
+		k.AnnotationRebootReason,
+		k.AnnotationLastRebootReason,
+		k.AnnotationReadySinceTime,
+		k.AnnotationAfterRebootAttempts,
+		k.AnnotationAfterRebootAttemptTime,
+		k.AnnotationSmokeTestNode,
+		k.AnnotationBeforeRebootTimedOutTime,
+		k.AnnotationRebootUnwantedTime,
+		k.AnnotationRebootWindowProfile,
+		k.AnnotationRequestReboot,
+		k.AnnotationRebootPaused,
+		k.AnnotationRebootPausedUntil,
+		k.AnnotationBootTime,
+		k.AnnotationStatus,
+		k.AnnotationLastCheckedTime,
+		k.AnnotationUpdateAvailableTime,
+		k.AnnotationNewVersion,
+		k.AnnotationAgentMadeUnschedulable,
+		k.AnnotationOperatorMadeUnschedulable,
+		k.LabelBeforeReboot,
+		k.LabelAfterReboot,
+		k.LabelRebootFailed,
+		k.LabelID,
+		k.LabelGroup,
+		k.LabelVersion,
+	}
+}
@@ -2,23 +2,54 @@ package k8sutil
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	watchtools "k8s.io/client-go/tools/watch"
 	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/metrics"
+)
+
+const (
+	// MaxAnnotationsSize is the maximum total size of object annotations accepted by the API server.
+	MaxAnnotationsSize = 256 * (1 << 10)
+
+	// AnnotationsSizeThreshold is the total size of node annotations above which optional annotations
+	// are removed before updating the node, leaving room for annotations set by other components.
+	AnnotationsSizeThreshold = MaxAnnotationsSize * 9 / 10
 )
 
+var annotationsTrimmed = metrics.NewNodeCounterVec("node_annotations_trimmed_total",
+	"Number of optional annotations removed from nodes to keep annotations size below the threshold.")
+
 // NodeGetter is a subset of corev1client.NodeInterface used by this package for getting node objects.
 type NodeGetter interface {
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Node, error)
 }
 
 // GetNodeRetry gets a node object, retrying up to DefaultBackoff number of times if it fails.
+// Retrying stops when given context is done.
 func GetNodeRetry(ctx context.Context, nc NodeGetter, node string) (*corev1.Node, error) {
 	var apiNode *corev1.Node
 
-	err := retry.OnError(retry.DefaultBackoff, func(error) bool { return true }, func() error {
+	retriable := func(error) bool { return ctx.Err() == nil }
+
+	err := retry.OnError(retry.DefaultBackoff, retriable, func() error {
 		n, getErr := nc.Get(ctx, node, metav1.GetOptions{})
 		if getErr != nil {
 			return fmt.Errorf("getting node %q: %w", node, getErr)
@@ -35,6 +66,63 @@ func GetNodeRetry(ctx context.Context, nc NodeGetter, node string) (*corev1.Node
 	return apiNode, nil
 }
 
+// NodeWatcher is a subset of corev1client.NodeInterface used by this package for watching nodes.
+type NodeWatcher interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*corev1.NodeList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// ErrNodeDeleted is returned by NodeAnnotationCondition when the watched node gets deleted.
+var ErrNodeDeleted = errors.New("node was deleted")
+
+// NodeAnnotationCondition returns a condition function which is satisfied when annotations of the watched
+// node match given selector. Bookmark events are ignored and deletion of the node is a terminal error.
+func NodeAnnotationCondition(selector fields.Selector) watchtools.ConditionFunc {
+	return func(event watch.Event) (bool, error) {
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			node, ok := event.Object.(*corev1.Node)
+			if !ok {
+				return false, fmt.Errorf("unexpected object type %T, expected %T", event.Object, &corev1.Node{})
+			}
+
+			return selector.Matches(fields.Set(node.Annotations)), nil
+		case watch.Deleted:
+			return false, ErrNodeDeleted
+		case watch.Bookmark:
+			return false, nil
+		default:
+			return false, fmt.Errorf("unexpected event type %q", event.Type)
+		}
+	}
+}
+
+// WaitForNodeAnnotation blocks until annotations of given node match given selector or given context is done.
+// Current state of the node is checked first, so it returns immediately if annotations already match.
+func WaitForNodeAnnotation(ctx context.Context, nc NodeWatcher, node string, selector fields.Selector) error {
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", node).String()
+
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = fieldSelector
+
+			return nc.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = fieldSelector
+
+			return nc.Watch(ctx, options)
+		},
+	}
+
+	_, err := watchtools.UntilWithSync(ctx, listWatch, &corev1.Node{}, nil, NodeAnnotationCondition(selector))
+	if err != nil {
+		return fmt.Errorf("waiting for annotations of node %q to match %q: %w", node, selector.String(), err)
+	}
+
+	return nil
+}
+
 // UpdateNode is a function updating properties of received node object.
 type UpdateNode func(*corev1.Node)
 
@@ -45,13 +133,63 @@ type NodeUpdater interface {
 	Update(ctx context.Context, node *corev1.Node, opts metav1.UpdateOptions) (*corev1.Node, error)
 }
 
-// UpdateNodeRetry calls f to update a node object in Kubernetes.
-// It will attempt to update the node by applying f to it up to DefaultBackoff
+// ErrConflictRetriesExhausted is returned wrapped by UpdateNodeRetry when node could not be updated because of
+// conflicts even after all retries, as opposed to a failure caused by any other API error.
+var ErrConflictRetriesExhausted = errors.New("retries exhausted due to conflicts")
+
+// DefaultUpdateNodeBackoff returns backoff used by UpdateNodeRetry. Delays grow exponentially and are jittered,
+// so retries of nodes updated concurrently are spread over time rather than hitting the API server at once.
+func DefaultUpdateNodeBackoff() wait.Backoff {
+	//nolint:gomnd // Just backoff parameters.
+	return wait.Backoff{
+		Steps:    5,
+		Duration: 10 * time.Millisecond,
+		Factor:   2.0,
+		Jitter:   1.0,
+	}
+}
+
+// UpdateNodeRetry calls f to update a node object in Kubernetes, retrying on conflicts
+// using DefaultUpdateNodeBackoff. See UpdateNodeRetryWithBackoff for details.
+func UpdateNodeRetry(ctx context.Context, nodeUpdater NodeUpdater, nodeName string, updateF UpdateNode) error {
+	return UpdateNodeRetryWithBackoff(ctx, nodeUpdater, nodeName, DefaultUpdateNodeBackoff(), updateF)
+}
+
+// UpdateNodeRetryWithBackoff calls f to update a node object in Kubernetes.
+// It will attempt to update the node by applying f to it up to given backoff
 // number of times.
 // Given update function will be called each time since the node object will likely have changed if
-// a retry is necessary.
-func UpdateNodeRetry(ctx context.Context, nodeUpdater NodeUpdater, nodeName string, updateF UpdateNode) error {
-	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+// a retry is necessary. Node is not updated and retrying stops when given context is done.
+// If all attempts fail due to conflicts, returned error wraps ErrConflictRetriesExhausted.
+func UpdateNodeRetryWithBackoff(
+	ctx context.Context, nodeUpdater NodeUpdater, nodeName string, backoff wait.Backoff, updateF UpdateNode,
+) error {
+	_, err := mutateNode(ctx, nodeUpdater, nodeName, backoff, updateF)
+
+	return err
+}
+
+// MutateNode calls f to update a node object in Kubernetes the same way as UpdateNodeRetry does and
+// returns the node object as updated by the API server, so callers can inspect the result.
+func MutateNode(
+	ctx context.Context, nodeUpdater NodeUpdater, nodeName string, updateF UpdateNode,
+) (*corev1.Node, error) {
+	return mutateNode(ctx, nodeUpdater, nodeName, DefaultUpdateNodeBackoff(), updateF)
+}
+
+func mutateNode(
+	ctx context.Context, nodeUpdater NodeUpdater, nodeName string, backoff wait.Backoff, updateF UpdateNode,
+) (*corev1.Node, error) {
+	retriable := func(err error) bool { return apierrors.IsConflict(err) && ctx.Err() == nil }
+
+	var updatedNode *corev1.Node
+
+	err := retry.OnError(backoff, retriable, func() error {
+		// Not all clients respect the context, so make sure node is not updated once it is done.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
 		node, getErr := nodeUpdater.Get(ctx, nodeName, metav1.GetOptions{})
 		if getErr != nil {
 			return fmt.Errorf("getting node %q: %w", nodeName, getErr)
@@ -59,16 +197,99 @@ func UpdateNodeRetry(ctx context.Context, nodeUpdater NodeUpdater, nodeName stri
 
 		updateF(node)
 
-		_, err := nodeUpdater.Update(ctx, node, metav1.UpdateOptions{})
+		var err error
+
+		updatedNode, err = nodeUpdater.Update(ctx, node, metav1.UpdateOptions{})
 
 		return err
 	})
-	if err != nil {
-		// May be conflict if max retries were hit.
-		return fmt.Errorf("updating node %q: %w", nodeName, err)
+
+	switch {
+	case err == nil:
+		return updatedNode, nil
+	case retriable(err):
+		return nil, fmt.Errorf("updating node %q: %w: %v", nodeName, ErrConflictRetriesExhausted, err)
+	default:
+		return nil, fmt.Errorf("updating node %q: %w", nodeName, err)
 	}
+}
 
-	return nil
+// AnnotationsSize returns total size of given annotations, calculated the same way
+// as the API server does when validating objects.
+func AnnotationsSize(annotations map[string]string) int {
+	size := 0
+
+	for k, v := range annotations {
+		size += len(k) + len(v)
+	}
+
+	return size
+}
+
+// ErrAnnotationsTooLarge is returned wrapped by AnnotationsSizeGuard when total size of node annotations
+// exceeds MaxAnnotationsSize even after removing optional annotations, so the node is not updated.
+var ErrAnnotationsTooLarge = errors.New("node annotations too large")
+
+// EventReasonAnnotationsTrimmed is a reason of the Warning event emitted by AnnotationsSizeGuard
+// when it removes optional annotations from a node.
+const EventReasonAnnotationsTrimmed = "AnnotationsTrimmed"
+
+// AnnotationsSizeGuard is a node client keeping total size of annotations of updated nodes below
+// the limit accepted by the API server. When annotations exceed AnnotationsSizeThreshold, optional
+// annotations are removed before updating the node. If annotations still exceed MaxAnnotationsSize,
+// the node is not updated and an error wrapping ErrAnnotationsTooLarge is returned.
+type AnnotationsSizeGuard struct {
+	corev1client.NodeInterface
+
+	// Keys of the node. Optional annotations are removed in the order returned by Keys.OptionalAnnotations.
+	Keys constants.Keys
+	// Recorder used for emitting Warning event when annotations are removed. Optional.
+	EventRecorder record.EventRecorder
+}
+
+// Update removes optional annotations from given node if necessary and updates it.
+func (g *AnnotationsSizeGuard) Update(
+	ctx context.Context, node *corev1.Node, opts metav1.UpdateOptions,
+) (*corev1.Node, error) {
+	if trimmed := trimAnnotations(node, g.Keys); len(trimmed) > 0 && g.EventRecorder != nil {
+		g.EventRecorder.Eventf(node, corev1.EventTypeWarning, EventReasonAnnotationsTrimmed,
+			"Removed optional annotations %s, as node annotations exceed size threshold of %d bytes",
+			strings.Join(trimmed, ", "), AnnotationsSizeThreshold)
+	}
+
+	if size := AnnotationsSize(node.Annotations); size > MaxAnnotationsSize {
+		return nil, fmt.Errorf("%w: total size of %d bytes exceeds limit of %d bytes",
+			ErrAnnotationsTooLarge, size, MaxAnnotationsSize)
+	}
+
+	return g.NodeInterface.Update(ctx, node, opts)
+}
+
+// trimAnnotations removes optional annotations from given node, using given keys, until total size
+// of its annotations drops below AnnotationsSizeThreshold. Keys of removed annotations are returned.
+func trimAnnotations(node *corev1.Node, keys constants.Keys) []string {
+	trimmed := []string{}
+
+	for _, key := range keys.OptionalAnnotations() {
+		if AnnotationsSize(node.Annotations) <= AnnotationsSizeThreshold {
+			break
+		}
+
+		if _, ok := node.Annotations[key]; !ok {
+			continue
+		}
+
+		klog.InfoS("Node annotations exceed size threshold, removing optional annotation",
+			"node", node.Name, "annotation", key, "threshold", AnnotationsSizeThreshold)
+
+		delete(node.Annotations, key)
+
+		annotationsTrimmed.With(metrics.NodeLabels(node, keys, "", "")).Inc()
+
+		trimmed = append(trimmed, key)
+	}
+
+	return trimmed
 }
 
 // SetNodeLabels sets all keys in m to their respective values in
@@ -95,15 +316,41 @@ func SetNodeAnnotations(ctx context.Context, nc NodeUpdater, node string, m map[
 // node's annotations and labels, respectively.
 func SetNodeAnnotationsLabels(
 	ctx context.Context, nc NodeUpdater, nodeName string, annotations, labels map[string]string,
+) error {
+	return SetAndDeleteNodeMetadata(ctx, nc, nodeName, labels, annotations, nil, nil)
+}
+
+// SetAndDeleteNodeMetadata sets given labels and annotations of the node and deletes labels and annotations
+// with given keys from it, all in a single update. Keys are deleted after setting values, so deleting takes
+// precedence when a key is given in both.
+func SetAndDeleteNodeMetadata(
+	ctx context.Context, nc NodeUpdater, nodeName string,
+	setLabels, setAnnotations map[string]string, deleteLabels, deleteAnnotations []string,
 ) error {
 	return UpdateNodeRetry(ctx, nc, nodeName, func(node *corev1.Node) {
-		for k, v := range annotations {
-			node.Annotations[k] = v
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
 		}
 
-		for k, v := range labels {
+		for k, v := range setLabels {
 			node.Labels[k] = v
 		}
+
+		for k, v := range setAnnotations {
+			node.Annotations[k] = v
+		}
+
+		for _, k := range deleteLabels {
+			delete(node.Labels, k)
+		}
+
+		for _, k := range deleteAnnotations {
+			delete(node.Annotations, k)
+		}
 	})
 }
 
@@ -113,3 +360,37 @@ func Unschedulable(ctx context.Context, nc NodeUpdater, node string, sched bool)
 		n.Spec.Unschedulable = sched
 	})
 }
+
+// AddNodeTaint adds given taint to the node. If the node already has a taint with the same key and effect,
+// its value is updated, so adding the same taint again is a no-op.
+func AddNodeTaint(ctx context.Context, nc NodeUpdater, node string, taint corev1.Taint) error {
+	return UpdateNodeRetry(ctx, nc, node, func(n *corev1.Node) {
+		for i := range n.Spec.Taints {
+			if n.Spec.Taints[i].MatchTaint(&taint) {
+				n.Spec.Taints[i].Value = taint.Value
+
+				return
+			}
+		}
+
+		n.Spec.Taints = append(n.Spec.Taints, taint)
+	})
+}
+
+// RemoveNodeTaint removes taints with the same key and effect as given taint from the node.
+// Removing a taint which is not present is a no-op.
+func RemoveNodeTaint(ctx context.Context, nc NodeUpdater, node string, taint corev1.Taint) error {
+	return UpdateNodeRetry(ctx, nc, node, func(n *corev1.Node) {
+		taints := []corev1.Taint{}
+
+		for _, t := range n.Spec.Taints {
+			t := t
+
+			if !t.MatchTaint(&taint) {
+				taints = append(taints, t)
+			}
+		}
+
+		n.Spec.Taints = taints
+	})
+}
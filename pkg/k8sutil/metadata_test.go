@@ -2,18 +2,28 @@ package k8sutil_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
 	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
 )
 
@@ -50,7 +60,7 @@ func Test_Updating_node(t *testing.T) {
 
 			node.Annotations[annotationKey] = "21"
 
-			return true, node, errors.NewConflict(schema.GroupResource{}, node.Name, fmt.Errorf("test error"))
+			return true, node, apierrors.NewConflict(schema.GroupResource{}, node.Name, fmt.Errorf("test error"))
 		})
 
 		ctx := context.TODO()
@@ -101,13 +111,681 @@ func Test_Updating_node(t *testing.T) {
 			ctx := context.TODO()
 			nc := fakeClient.CoreV1().Nodes()
 
-			if err := k8sutil.UpdateNodeRetry(ctx, nc, node.Name, func(*corev1.Node) {}); err == nil {
+			err := k8sutil.UpdateNodeRetry(ctx, nc, node.Name, func(*corev1.Node) {})
+			if err == nil {
 				t.Fatalf("Expected error updating node")
 			}
+
+			if errors.Is(err, k8sutil.ErrConflictRetriesExhausted) {
+				t.Fatalf("Expected error other than %q, got %v", k8sutil.ErrConflictRetriesExhausted, err)
+			}
+		})
+
+		t.Run("context_is_already_done_without_updating_node", func(t *testing.T) {
+			t.Parallel()
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "testNodeName",
+				},
+			}
+
+			fakeClient := fake.NewSimpleClientset(node)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			nc := fakeClient.CoreV1().Nodes()
+
+			err := k8sutil.UpdateNodeRetry(ctx, nc, node.Name, func(node *corev1.Node) {
+				node.Labels = map[string]string{"foo": "bar"}
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected error %q, got %v", context.Canceled, err)
+			}
+
+			if actions := fakeClient.Actions(); len(actions) != 0 {
+				t.Fatalf("Expected no requests to be made, got %v", actions)
+			}
+		})
+
+		t.Run("conflicts_persist_after_all_retries_with_given_backoff", func(t *testing.T) {
+			t.Parallel()
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "testNodeName",
+				},
+			}
+
+			fakeClient := fake.NewSimpleClientset(node)
+
+			updates := 0
+
+			fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				updates++
+
+				return true, nil, apierrors.NewConflict(schema.GroupResource{}, node.Name, fmt.Errorf("test error"))
+			})
+
+			ctx := context.TODO()
+			nc := fakeClient.CoreV1().Nodes()
+
+			backoff := wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 2, Jitter: 1}
+
+			err := k8sutil.UpdateNodeRetryWithBackoff(ctx, nc, node.Name, backoff, func(*corev1.Node) {})
+			if !errors.Is(err, k8sutil.ErrConflictRetriesExhausted) {
+				t.Fatalf("Expected error %q, got %v", k8sutil.ErrConflictRetriesExhausted, err)
+			}
+
+			if updates != backoff.Steps {
+				t.Fatalf("Expected %d update attempts, got %d", backoff.Steps, updates)
+			}
+		})
+	})
+}
+
+func Test_Retrying_node_operations_stops_when_context_is_cancelled(t *testing.T) {
+	t.Parallel()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "testNodeName",
+		},
+	}
+
+	for name, f := range map[string]func(context.Context, *fake.Clientset) error{
+		"getting_node": func(ctx context.Context, fakeClient *fake.Clientset) error {
+			_, err := k8sutil.GetNodeRetry(ctx, fakeClient.CoreV1().Nodes(), node.Name)
+
+			return err
+		},
+		"updating_node": func(ctx context.Context, fakeClient *fake.Clientset) error {
+			return k8sutil.UpdateNodeRetry(ctx, fakeClient.CoreV1().Nodes(), node.Name, func(*corev1.Node) {})
+		},
+	} {
+		f := f
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeClient := fake.NewSimpleClientset(node)
+
+			ctx, cancel := context.WithCancel(context.Background())
+
+			calls := 0
+
+			// Fake client does not respect the context, so simulate request being interrupted by cancellation.
+			fakeClient.PrependReactor("*", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				calls++
+
+				cancel()
+
+				return true, nil, apierrors.NewConflict(schema.GroupResource{}, node.Name, context.Canceled)
+			})
+
+			if err := f(ctx, fakeClient); err == nil {
+				t.Fatalf("Expected error")
+			}
+
+			if calls != 1 {
+				t.Fatalf("Expected exactly one request, got %d", calls)
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Updating_node_with_annotations_size_near_threshold(t *testing.T) {
+	t.Parallel()
+
+	optionalAnnotations := map[string]string{
+		constants.AnnotationLastRebootReason: "Update to 1.2.3",
+		constants.AnnotationLastCheckedTime:  "1",
+		constants.AnnotationStatus:           "UPDATE_STATUS_IDLE",
+	}
+
+	requiredAnnotations := map[string]string{
+		constants.AnnotationNewVersion: "1.2.3",
+	}
+
+	fillerKey := "filler"
+
+	// nodeWithAnnotationsSize returns node with optional, required and filler annotations,
+	// making total size of annotations equal to given size.
+	nodeWithAnnotationsSize := func(size int) *corev1.Node {
+		annotations := map[string]string{}
+
+		for _, m := range []map[string]string{optionalAnnotations, requiredAnnotations} {
+			for k, v := range m {
+				annotations[k] = v
+			}
+		}
+
+		annotations[fillerKey] = strings.Repeat("a", size-k8sutil.AnnotationsSize(annotations)-len(fillerKey))
+
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "testNodeName",
+				Annotations: annotations,
+			},
+		}
+	}
+
+	// updateGuardedNode updates given node using AnnotationsSizeGuard and returns the node as stored
+	// by the API server, recorded events and the update error.
+	updateGuardedNode := func(t *testing.T, node *corev1.Node) (*corev1.Node, []string, error) {
+		t.Helper()
+
+		fakeClient := fake.NewSimpleClientset(node)
+		recorder := record.NewFakeRecorder(10)
+
+		nc := &k8sutil.AnnotationsSizeGuard{
+			NodeInterface: fakeClient.CoreV1().Nodes(),
+			Keys:          constants.NewKeys(""),
+			EventRecorder: recorder,
+		}
+
+		ctx := context.TODO()
+
+		updateErr := k8sutil.UpdateNodeRetry(ctx, nc, node.Name, func(*corev1.Node) {})
+
+		updatedNode, err := nc.Get(ctx, node.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Getting updated node: %v", err)
+		}
+
+		close(recorder.Events)
+
+		events := []string{}
+
+		for event := range recorder.Events {
+			events = append(events, event)
+		}
+
+		return updatedNode, events, updateErr
+	}
+
+	t.Run("keeps_optional_annotations_when_size_does_not_exceed_threshold", func(t *testing.T) {
+		t.Parallel()
+
+		node := nodeWithAnnotationsSize(k8sutil.AnnotationsSizeThreshold)
+
+		updatedNode, events, err := updateGuardedNode(t, node)
+		if err != nil {
+			t.Fatalf("Unexpected error updating node: %v", err)
+		}
+
+		for k := range optionalAnnotations {
+			if _, ok := updatedNode.Annotations[k]; !ok {
+				t.Fatalf("Expected annotation %q to be kept", k)
+			}
+		}
+
+		if len(events) != 0 {
+			t.Fatalf("Expected no events, got %v", events)
+		}
+	})
+
+	t.Run("removes_optional_annotations_starting_with_history_until_size_drops_below_threshold", func(t *testing.T) {
+		t.Parallel()
+
+		node := nodeWithAnnotationsSize(k8sutil.AnnotationsSizeThreshold + 1)
+
+		updatedNode, _, err := updateGuardedNode(t, node)
+		if err != nil {
+			t.Fatalf("Unexpected error updating node: %v", err)
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationLastRebootReason]; ok {
+			t.Fatalf("Expected annotation %q to be removed", constants.AnnotationLastRebootReason)
+		}
+
+		for _, k := range []string{constants.AnnotationLastCheckedTime, constants.AnnotationStatus, fillerKey} {
+			if _, ok := updatedNode.Annotations[k]; !ok {
+				t.Fatalf("Expected annotation %q to be kept", k)
+			}
+		}
+	})
+
+	t.Run("emits_warning_event_when_optional_annotations_are_removed", func(t *testing.T) {
+		t.Parallel()
+
+		node := nodeWithAnnotationsSize(k8sutil.AnnotationsSizeThreshold + 1)
+
+		_, events, err := updateGuardedNode(t, node)
+		if err != nil {
+			t.Fatalf("Unexpected error updating node: %v", err)
+		}
+
+		expectedPrefix := corev1.EventTypeWarning + " " + k8sutil.EventReasonAnnotationsTrimmed
+
+		if len(events) != 1 || !strings.HasPrefix(events[0], expectedPrefix) {
+			t.Fatalf("Expected single event starting with %q, got %v", expectedPrefix, events)
+		}
+
+		if !strings.Contains(events[0], constants.AnnotationLastRebootReason) {
+			t.Fatalf("Expected event to mention removed annotation %q, got %q",
+				constants.AnnotationLastRebootReason, events[0])
+		}
+	})
+
+	t.Run("never_removes_annotations_required_by_reboot_process", func(t *testing.T) {
+		t.Parallel()
+
+		node := nodeWithAnnotationsSize(k8sutil.MaxAnnotationsSize)
+
+		updatedNode, _, err := updateGuardedNode(t, node)
+		if err != nil {
+			t.Fatalf("Unexpected error updating node: %v", err)
+		}
+
+		for k := range requiredAnnotations {
+			if _, ok := updatedNode.Annotations[k]; !ok {
+				t.Fatalf("Expected annotation %q to be kept", k)
+			}
+		}
+	})
+
+	t.Run("returns_error_without_updating_node_when_size_exceeds_limit_after_removing_optional_annotations",
+		func(t *testing.T) {
+			t.Parallel()
+
+			node := nodeWithAnnotationsSize(k8sutil.MaxAnnotationsSize + k8sutil.AnnotationsSize(optionalAnnotations) + 1)
+
+			updatedNode, _, err := updateGuardedNode(t, node)
+			if !errors.Is(err, k8sutil.ErrAnnotationsTooLarge) {
+				t.Fatalf("Expected error %q, got %v", k8sutil.ErrAnnotationsTooLarge, err)
+			}
+
+			if diff := cmp.Diff(node.Annotations, updatedNode.Annotations); diff != "" {
+				t.Fatalf("Expected node not to be updated (-expected +got):\n%s", diff)
+			}
 		})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Mutating_node_returns_node_as_updated_by_API_server(t *testing.T) {
+	t.Parallel()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "testNodeName",
+		},
+	}
+
+	fakeClient := fake.NewSimpleClientset(node)
+
+	fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updatedNode, ok := action.(k8stesting.UpdateAction).GetObject().(*corev1.Node)
+		if !ok {
+			t.Fatalf("Unexpected object type %T", action.(k8stesting.UpdateAction).GetObject())
+		}
+
+		// Simulate API server modifying the object, e.g. by a mutating webhook.
+		updatedNode = updatedNode.DeepCopy()
+		updatedNode.ResourceVersion = "2"
+
+		return true, updatedNode, nil
+	})
+
+	updatedNode, err := k8sutil.MutateNode(context.TODO(), fakeClient.CoreV1().Nodes(), node.Name, func(n *corev1.Node) {
+		n.Annotations = map[string]string{"foo": "bar"}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error mutating node: %v", err)
+	}
+
+	if v := updatedNode.Annotations["foo"]; v != "bar" {
+		t.Fatalf("Expected mutated annotation value %q, got %q", "bar", v)
+	}
+
+	if updatedNode.ResourceVersion != "2" {
+		t.Fatalf("Expected node returned by API server, got resource version %q", updatedNode.ResourceVersion)
+	}
+}
+
+func Test_Setting_and_deleting_node_metadata_updates_labels_and_annotations_at_once(t *testing.T) {
+	t.Parallel()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "testNodeName",
+			Labels:      map[string]string{"keep": "label", "delete": "label", "both": "label"},
+			Annotations: map[string]string{"keep": "annotation", "delete": "annotation"},
+		},
+	}
+
+	fakeClient := fake.NewSimpleClientset(node)
+
+	updates := 0
+
+	fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updates++
+
+		return false, nil, nil
+	})
+
+	ctx := context.TODO()
+	nc := fakeClient.CoreV1().Nodes()
+
+	err := k8sutil.SetAndDeleteNodeMetadata(ctx, nc, node.Name,
+		map[string]string{"set": "label", "both": "new"}, map[string]string{"set": "annotation"},
+		[]string{"delete", "both"}, []string{"delete"})
+	if err != nil {
+		t.Fatalf("Unexpected error updating node: %v", err)
+	}
+
+	if updates != 1 {
+		t.Fatalf("Expected exactly one update, got %d", updates)
+	}
+
+	updatedNode, err := nc.Get(ctx, node.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Getting node: %v", err)
+	}
+
+	expectedLabels := map[string]string{"keep": "label", "set": "label"}
+	if diff := cmp.Diff(expectedLabels, updatedNode.Labels); diff != "" {
+		t.Fatalf("Unexpected labels (-want +got):\n%s", diff)
+	}
+
+	expectedAnnotations := map[string]string{"keep": "annotation", "set": "annotation"}
+	if diff := cmp.Diff(expectedAnnotations, updatedNode.Annotations); diff != "" {
+		t.Fatalf("Unexpected annotations (-want +got):\n%s", diff)
+	}
+}
+
+func Test_Tainting_node(t *testing.T) {
+	t.Parallel()
+
+	taint := corev1.Taint{
+		Key:    "flatcar.io/rebooting",
+		Value:  constants.True,
+		Effect: corev1.TaintEffectNoSchedule,
+	}
+
+	otherTaint := corev1.Taint{
+		Key:    "other",
+		Effect: corev1.TaintEffectNoExecute,
+	}
+
+	// taintsAfter creates node with given taints, calls given function on it and returns updated taints.
+	taintsAfter := func(
+		t *testing.T, taints []corev1.Taint, f func(context.Context, k8sutil.NodeUpdater, string) error,
+	) []corev1.Taint {
+		t.Helper()
+
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "testNodeName",
+			},
+			Spec: corev1.NodeSpec{
+				Taints: taints,
+			},
+		}
+
+		ctx := context.TODO()
+		nc := fake.NewSimpleClientset(node).CoreV1().Nodes()
+
+		if err := f(ctx, nc, node.Name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		updatedNode, err := nc.Get(ctx, node.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Getting updated node: %v", err)
+		}
+
+		return updatedNode.Spec.Taints
+	}
+
+	addTaint := func(ctx context.Context, nc k8sutil.NodeUpdater, node string) error {
+		return k8sutil.AddNodeTaint(ctx, nc, node, taint)
+	}
+
+	removeTaint := func(ctx context.Context, nc k8sutil.NodeUpdater, node string) error {
+		return k8sutil.RemoveNodeTaint(ctx, nc, node, taint)
+	}
+
+	t.Run("adds_taint_keeping_existing_taints", func(t *testing.T) {
+		t.Parallel()
+
+		got := taintsAfter(t, []corev1.Taint{otherTaint}, addTaint)
+
+		if diff := cmp.Diff([]corev1.Taint{otherTaint, taint}, got); diff != "" {
+			t.Fatalf("Unexpected taints (-expected +got):\n%s", diff)
+		}
+	})
+
+	t.Run("does_nothing_when_adding_already_present_taint", func(t *testing.T) {
+		t.Parallel()
+
+		got := taintsAfter(t, []corev1.Taint{taint, otherTaint}, addTaint)
+
+		if diff := cmp.Diff([]corev1.Taint{taint, otherTaint}, got); diff != "" {
+			t.Fatalf("Unexpected taints (-expected +got):\n%s", diff)
+		}
+	})
+
+	t.Run("updates_value_of_present_taint_with_the_same_key_and_effect", func(t *testing.T) {
+		t.Parallel()
+
+		oldTaint := taint
+		oldTaint.Value = constants.False
+
+		got := taintsAfter(t, []corev1.Taint{oldTaint}, addTaint)
+
+		if diff := cmp.Diff([]corev1.Taint{taint}, got); diff != "" {
+			t.Fatalf("Unexpected taints (-expected +got):\n%s", diff)
+		}
+	})
+
+	t.Run("removes_taint_keeping_other_taints", func(t *testing.T) {
+		t.Parallel()
+
+		got := taintsAfter(t, []corev1.Taint{taint, otherTaint}, removeTaint)
+
+		if diff := cmp.Diff([]corev1.Taint{otherTaint}, got); diff != "" {
+			t.Fatalf("Unexpected taints (-expected +got):\n%s", diff)
+		}
+	})
+
+	t.Run("does_nothing_when_removing_taint_which_is_not_present", func(t *testing.T) {
+		t.Parallel()
+
+		got := taintsAfter(t, []corev1.Taint{otherTaint}, removeTaint)
+
+		if diff := cmp.Diff([]corev1.Taint{otherTaint}, got); diff != "" {
+			t.Fatalf("Unexpected taints (-expected +got):\n%s", diff)
+		}
+	})
+}
+
+//nolint:funlen // Just many test cases.
+func Test_Node_annotation_condition(t *testing.T) {
+	t.Parallel()
+
+	annotationKey := "test-annotation"
+
+	nodeWithAnnotation := func(value string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "testNodeName",
+				Annotations: map[string]string{annotationKey: value},
+			},
+		}
+	}
+
+	matchingNode := nodeWithAnnotation(constants.True)
+	notMatchingNode := nodeWithAnnotation(constants.False)
+
+	cases := map[string]struct {
+		events      []watch.Event
+		expectDone  bool
+		expectedErr error
+	}{
+		"is_satisfied_by_initial_added_event_of_matching_node": {
+			events:     []watch.Event{{Type: watch.Added, Object: matchingNode}},
+			expectDone: true,
+		},
+		"is_satisfied_by_modified_event_of_matching_node": {
+			events: []watch.Event{
+				{Type: watch.Added, Object: notMatchingNode},
+				{Type: watch.Modified, Object: notMatchingNode},
+				{Type: watch.Modified, Object: matchingNode},
+			},
+			expectDone: true,
+		},
+		"is_not_satisfied_by_events_of_not_matching_node": {
+			events: []watch.Event{
+				{Type: watch.Added, Object: notMatchingNode},
+				{Type: watch.Modified, Object: notMatchingNode},
+			},
+		},
+		"ignores_bookmark_events": {
+			events: []watch.Event{
+				{Type: watch.Added, Object: notMatchingNode},
+				{Type: watch.Bookmark, Object: &corev1.Node{}},
+			},
+		},
+		"fails_when_node_is_deleted": {
+			events: []watch.Event{
+				{Type: watch.Added, Object: notMatchingNode},
+				{Type: watch.Deleted, Object: notMatchingNode},
+			},
+			expectedErr: k8sutil.ErrNodeDeleted,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			condition := k8sutil.NodeAnnotationCondition(fields.OneTermEqualSelector(annotationKey, constants.True))
+
+			var done bool
+
+			var err error
+
+			for _, event := range testCase.events {
+				done, err = condition(event)
+				if done || err != nil {
+					break
+				}
+			}
+
+			if !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", testCase.expectedErr, err)
+			}
+
+			if done != testCase.expectDone {
+				t.Fatalf("Expected condition to be satisfied: %t, got %t", testCase.expectDone, done)
+			}
+		})
+	}
+
+	t.Run("fails_on_error_event", func(t *testing.T) {
+		t.Parallel()
+
+		condition := k8sutil.NodeAnnotationCondition(fields.OneTermEqualSelector(annotationKey, constants.True))
+
+		if _, err := condition(watch.Event{Type: watch.Error, Object: &metav1.Status{}}); err == nil {
+			t.Fatalf("Expected error")
+		}
+	})
+}
+
+//nolint:funlen // Just subtests.
+func Test_Waiting_for_node_annotation(t *testing.T) {
+	t.Parallel()
+
+	annotationKey := "test-annotation"
+	selector := fields.OneTermEqualSelector(annotationKey, constants.True)
+
+	testNode := func(value string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "testNodeName",
+				Annotations: map[string]string{annotationKey: value},
+			},
+		}
+	}
+
+	t.Run("returns_immediately_when_node_annotations_already_match_selector", func(t *testing.T) {
+		t.Parallel()
+
+		node := testNode(constants.True)
+		fakeClient := fake.NewSimpleClientset(node)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		t.Cleanup(cancel)
+
+		if err := k8sutil.WaitForNodeAnnotation(ctx, fakeClient.CoreV1().Nodes(), node.Name, selector); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("returns_once_node_annotations_match_selector", func(t *testing.T) {
+		t.Parallel()
+
+		node := testNode(constants.False)
+		fakeClient := fake.NewSimpleClientset(node)
+
+		watchStarted := make(chan struct{})
+		signalWatchStarted := sync.Once{}
+
+		// Signal when watch is established, so node is updated only after the initial list.
+		fakeClient.PrependWatchReactor("nodes", func(action k8stesting.Action) (bool, watch.Interface, error) {
+			watcher, err := fakeClient.Tracker().Watch(action.GetResource(), action.GetNamespace())
+
+			signalWatchStarted.Do(func() { close(watchStarted) })
+
+			return true, watcher, err
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		t.Cleanup(cancel)
+
+		nc := fakeClient.CoreV1().Nodes()
+
+		errCh := make(chan error, 1)
+
+		go func() {
+			errCh <- k8sutil.WaitForNodeAnnotation(ctx, nc, node.Name, selector)
+		}()
+
+		<-watchStarted
+
+		annotations := map[string]string{annotationKey: constants.True}
+
+		if err := k8sutil.SetNodeAnnotations(ctx, nc, node.Name, annotations); err != nil {
+			t.Fatalf("Updating node annotations: %v", err)
+		}
+
+		if err := <-errCh; err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("returns_error_when_context_is_done_before_node_annotations_match_selector", func(t *testing.T) {
+		t.Parallel()
+
+		node := testNode(constants.False)
+		fakeClient := fake.NewSimpleClientset(node)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		t.Cleanup(cancel)
+
+		if err := k8sutil.WaitForNodeAnnotation(ctx, fakeClient.CoreV1().Nodes(), node.Name, selector); err == nil {
+			t.Fatalf("Expected error")
+		}
 	})
 }
 
+// updateNode creates given node and updates it with no changes, returning updated node.
 func atomicCounterIncrement(t *testing.T, annotationKey string) func(n *corev1.Node) {
 	t.Helper()
 
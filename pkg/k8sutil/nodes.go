@@ -0,0 +1,36 @@
+package k8sutil
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeReady returns true if given node reports Ready condition with status True.
+// Nodes not reporting Ready condition at all are considered not ready.
+func NodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// NodeReadySince returns time since when given node reports Ready condition with status True, as recorded
+// in the last transition time of the condition, which is zero if not reported. If the node is not ready,
+// as defined by NodeReady, false is returned.
+func NodeReadySince(node *corev1.Node) (time.Time, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			if condition.Status != corev1.ConditionTrue {
+				return time.Time{}, false
+			}
+
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+
+	return time.Time{}, false
+}
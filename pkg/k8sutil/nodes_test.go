@@ -0,0 +1,110 @@
+package k8sutil_test
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+func Test_Node_is_ready_only_when_it_reports_ready_condition_with_status_true(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		conditions []corev1.NodeCondition
+		ready      bool
+	}{
+		"ready": {
+			conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			},
+			ready: true,
+		},
+		"not_ready": {
+			conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}},
+		},
+		"unknown_readiness": {
+			conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}},
+		},
+		"no_ready_condition": {},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			node := &corev1.Node{Status: corev1.NodeStatus{Conditions: testCase.conditions}}
+
+			if ready := k8sutil.NodeReady(node); ready != testCase.ready {
+				t.Fatalf("Expected node ready: %t, got %t", testCase.ready, ready)
+			}
+		})
+	}
+}
+
+func Test_Node_is_ready_since_last_transition_time_of_ready_condition_with_status_true(t *testing.T) {
+	t.Parallel()
+
+	lastTransitionTime := time.Unix(1700000000, 0)
+
+	cases := map[string]struct {
+		conditions    []corev1.NodeCondition
+		ready         bool
+		expectedSince time.Time
+	}{
+		"ready": {
+			conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+				{
+					Type:               corev1.NodeReady,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(lastTransitionTime),
+				},
+			},
+			ready:         true,
+			expectedSince: lastTransitionTime,
+		},
+		"ready_without_last_transition_time": {
+			conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			ready:      true,
+		},
+		"not_ready": {
+			conditions: []corev1.NodeCondition{
+				{
+					Type:               corev1.NodeReady,
+					Status:             corev1.ConditionFalse,
+					LastTransitionTime: metav1.NewTime(lastTransitionTime),
+				},
+			},
+		},
+		"unknown_readiness": {
+			conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}},
+		},
+		"no_ready_condition": {},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			node := &corev1.Node{Status: corev1.NodeStatus{Conditions: testCase.conditions}}
+
+			since, ready := k8sutil.NodeReadySince(node)
+			if ready != testCase.ready {
+				t.Fatalf("Expected node ready: %t, got %t", testCase.ready, ready)
+			}
+
+			if !since.Equal(testCase.expectedSince) {
+				t.Fatalf("Expected node ready since %v, got %v", testCase.expectedSince, since)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package k8sutil
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+)
+
+// NodePhase is a well-known phase of the reboot process, identified by node labels and annotations
+// set by the update-agent and the update-operator.
+type NodePhase string
+
+const (
+	// NodePhaseRebootable matches nodes which want to reboot and may be scheduled for rebooting.
+	NodePhaseRebootable NodePhase = "rebootable"
+	// NodePhaseBeforeReboot matches nodes waiting for before reboot checks to complete.
+	NodePhaseBeforeReboot NodePhase = "before-reboot"
+	// NodePhaseRebooting matches nodes approved for rebooting, which did not finish rebooting yet.
+	NodePhaseRebooting NodePhase = "rebooting"
+	// NodePhaseJustRebooted matches nodes approved for rebooting, which finished rebooting.
+	NodePhaseJustRebooted NodePhase = "just-rebooted"
+	// NodePhaseAfterReboot matches nodes waiting for after reboot checks to complete.
+	NodePhaseAfterReboot NodePhase = "after-reboot"
+)
+
+// PhaseSelectors holds selectors and requirements identifying phases of the reboot process,
+// built for given label and annotation keys.
+type PhaseSelectors struct {
+	// JustRebooted is a selector for combination of annotations
+	// expected to be on a node after it has completed a reboot.
+	//
+	// The update-operator sets AnnotationOkToReboot to true to
+	// trigger a reboot, and the update-agent sets
+	// AnnotationRebootNeeded and AnnotationRebootInProgress
+	// to false when it has finished.
+	JustRebooted fields.Selector
+
+	// Rebootable is a selector for the annotation expected to be on a node when it can be rebooted.
+	//
+	// The update-agent sets AnnotationRebootNeeded to true when
+	// it would like to reboot, and false when it starts up.
+	//
+	// If AnnotationRebootPaused is set to "true", the update-agent will not consider it for rebooting.
+	Rebootable fields.Selector
+
+	// StillRebooting is a selector for the annotation set expected to be
+	// on a node when it's in the process of rebooting.
+	StillRebooting fields.Selector
+
+	// BeforeReboot requires a node to be waiting for before reboot checks to complete.
+	BeforeReboot *labels.Requirement
+
+	// AfterReboot requires a node to be waiting for after reboot checks to complete.
+	AfterReboot *labels.Requirement
+}
+
+// NewPhaseSelectors returns selectors identifying phases of the reboot process using given keys.
+func NewPhaseSelectors(keys constants.Keys) PhaseSelectors {
+	return PhaseSelectors{
+		JustRebooted: fields.Set(map[string]string{
+			keys.AnnotationOkToReboot:       constants.True,
+			keys.AnnotationRebootNeeded:     constants.False,
+			keys.AnnotationRebootInProgress: constants.False,
+		}).AsSelector(),
+		Rebootable: fields.AndSelectors(
+			fields.OneTermEqualSelector(keys.AnnotationRebootNeeded, constants.True),
+			fields.OneTermNotEqualSelector(keys.AnnotationRebootPaused, constants.True),
+			fields.OneTermNotEqualSelector(keys.AnnotationOkToReboot, constants.True),
+			fields.OneTermNotEqualSelector(keys.AnnotationRebootInProgress, constants.True),
+		),
+		StillRebooting: fields.Set(map[string]string{
+			keys.AnnotationOkToReboot:   constants.True,
+			keys.AnnotationRebootNeeded: constants.True,
+		}).AsSelector(),
+		BeforeReboot: NewRequirementOrDie(keys.LabelBeforeReboot, selection.In, []string{constants.True}),
+		AfterReboot:  NewRequirementOrDie(keys.LabelAfterReboot, selection.In, []string{constants.True}),
+	}
+}
+
+// Selectors identifying phases of the reboot process using default keys.
+var defaultPhaseSelectors = NewPhaseSelectors(constants.NewKeys(""))
+
+//nolint:godot // TODO: Complaining about not capitalized comments for variables. We should get rid of those completely.
+var (
+	// JustRebootedSelector is PhaseSelectors.JustRebooted using default keys.
+	JustRebootedSelector = defaultPhaseSelectors.JustRebooted
+
+	// RebootableSelector is PhaseSelectors.Rebootable using default keys.
+	RebootableSelector = defaultPhaseSelectors.Rebootable
+
+	// StillRebootingSelector is PhaseSelectors.StillRebooting using default keys.
+	StillRebootingSelector = defaultPhaseSelectors.StillRebooting
+
+	// BeforeRebootReq is PhaseSelectors.BeforeReboot using default keys.
+	BeforeRebootReq = defaultPhaseSelectors.BeforeReboot
+
+	// AfterRebootReq is PhaseSelectors.AfterReboot using default keys.
+	AfterRebootReq = defaultPhaseSelectors.AfterReboot
+)
+
+// NodeLister is a subset of corev1client.NodeInterface used by this package for listing node objects.
+type NodeLister interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*corev1.NodeList, error)
+}
+
+// NodeInPhase returns true when given node is in given phase of the reboot process, using default keys.
+//
+// Phases are not exclusive, e.g. rebootable node may be waiting for before reboot checks
+// at the same time.
+func NodeInPhase(node *corev1.Node, phase NodePhase) bool {
+	return defaultPhaseSelectors.NodeInPhase(node, phase)
+}
+
+// NodeInPhase returns true when given node is in given phase of the reboot process.
+func (s PhaseSelectors) NodeInPhase(node *corev1.Node, phase NodePhase) bool {
+	switch phase {
+	case NodePhaseRebootable:
+		return s.Rebootable.Matches(fields.Set(node.Annotations))
+	case NodePhaseBeforeReboot:
+		return s.BeforeReboot.Matches(labels.Set(node.Labels))
+	case NodePhaseRebooting:
+		return s.StillRebooting.Matches(fields.Set(node.Annotations))
+	case NodePhaseJustRebooted:
+		return s.JustRebooted.Matches(fields.Set(node.Annotations))
+	case NodePhaseAfterReboot:
+		return s.AfterReboot.Matches(labels.Set(node.Labels))
+	default:
+		return false
+	}
+}
+
+// FilterNodesByPhase filters a list of nodes and returns nodes in given phase of the reboot process,
+// using default keys.
+func FilterNodesByPhase(nodes []corev1.Node, phase NodePhase) []corev1.Node {
+	return defaultPhaseSelectors.FilterNodesByPhase(nodes, phase)
+}
+
+// FilterNodesByPhase filters a list of nodes and returns nodes in given phase of the reboot process.
+func (s PhaseSelectors) FilterNodesByPhase(nodes []corev1.Node, phase NodePhase) []corev1.Node {
+	var matches []corev1.Node
+
+	for i := range nodes {
+		if s.NodeInPhase(&nodes[i], phase) {
+			matches = append(matches, nodes[i])
+		}
+	}
+
+	return matches
+}
+
+// GetNodesByPhase lists nodes in given phase of the reboot process, using default keys.
+func GetNodesByPhase(ctx context.Context, nc NodeLister, phase NodePhase) ([]corev1.Node, error) {
+	return defaultPhaseSelectors.GetNodesByPhase(ctx, nc, phase)
+}
+
+// GetNodesByPhase lists nodes in given phase of the reboot process. Phases identified by labels
+// are filtered by the API server, while phases identified by annotations are filtered locally,
+// as annotations cannot be used in field selectors.
+func (s PhaseSelectors) GetNodesByPhase(ctx context.Context, nc NodeLister, phase NodePhase) ([]corev1.Node, error) {
+	opts := metav1.ListOptions{}
+
+	switch phase {
+	case NodePhaseBeforeReboot:
+		opts.LabelSelector = labels.NewSelector().Add(*s.BeforeReboot).String()
+	case NodePhaseAfterReboot:
+		opts.LabelSelector = labels.NewSelector().Add(*s.AfterReboot).String()
+	case NodePhaseRebootable, NodePhaseRebooting, NodePhaseJustRebooted:
+	default:
+		return nil, fmt.Errorf("unknown phase %q", phase)
+	}
+
+	nodelist, err := nc.List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	return s.FilterNodesByPhase(nodelist.Items, phase), nil
+}
@@ -0,0 +1,124 @@
+package k8sutil_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+//nolint:funlen // Just many test cases.
+func Test_Getting_nodes_by_phase_returns_only_nodes_in_given_phase(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "rebootable",
+				Annotations: map[string]string{
+					constants.AnnotationRebootNeeded: constants.True,
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "before-reboot",
+				Labels: map[string]string{
+					constants.LabelBeforeReboot: constants.True,
+				},
+				Annotations: map[string]string{
+					constants.AnnotationRebootNeeded: constants.True,
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "rebooting",
+				Annotations: map[string]string{
+					constants.AnnotationOkToReboot:   constants.True,
+					constants.AnnotationRebootNeeded: constants.True,
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "just-rebooted",
+				Annotations: map[string]string{
+					constants.AnnotationOkToReboot:       constants.True,
+					constants.AnnotationRebootNeeded:     constants.False,
+					constants.AnnotationRebootInProgress: constants.False,
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "after-reboot",
+				Labels: map[string]string{
+					constants.LabelAfterReboot: constants.True,
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "paused",
+				Annotations: map[string]string{
+					constants.AnnotationRebootNeeded: constants.True,
+					constants.AnnotationRebootPaused: constants.True,
+				},
+			},
+		},
+	}
+
+	objects := []runtime.Object{}
+	for _, node := range nodes {
+		objects = append(objects, node)
+	}
+
+	nc := fake.NewSimpleClientset(objects...).CoreV1().Nodes()
+
+	for phase, expectedNodes := range map[k8sutil.NodePhase][]string{
+		k8sutil.NodePhaseRebootable:   {"before-reboot", "rebootable"},
+		k8sutil.NodePhaseBeforeReboot: {"before-reboot"},
+		k8sutil.NodePhaseRebooting:    {"rebooting"},
+		k8sutil.NodePhaseJustRebooted: {"just-rebooted"},
+		k8sutil.NodePhaseAfterReboot:  {"after-reboot"},
+	} {
+		phase := phase
+		expectedNodes := expectedNodes
+
+		t.Run(string(phase), func(t *testing.T) {
+			t.Parallel()
+
+			matches, err := k8sutil.GetNodesByPhase(context.TODO(), nc, phase)
+			if err != nil {
+				t.Fatalf("Unexpected error getting nodes: %v", err)
+			}
+
+			names := []string{}
+			for _, node := range matches {
+				names = append(names, node.Name)
+			}
+
+			if strings.Join(names, ",") != strings.Join(expectedNodes, ",") {
+				t.Fatalf("Expected nodes %v, got %v", expectedNodes, names)
+			}
+		})
+	}
+}
+
+func Test_Getting_nodes_by_unknown_phase_returns_error(t *testing.T) {
+	t.Parallel()
+
+	nc := fake.NewSimpleClientset().CoreV1().Nodes()
+
+	if _, err := k8sutil.GetNodesByPhase(context.TODO(), nc, "unknown"); err == nil {
+		t.Fatalf("Expected error getting nodes by unknown phase")
+	}
+}
@@ -1,7 +1,17 @@
 package k8sutil
 
 import (
+	"context"
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
 )
 
 // FilterPods filters given list of pods using given function.
@@ -18,3 +28,126 @@ func FilterPods(pods []corev1.Pod, filter func(*corev1.Pod) bool) []corev1.Pod {
 
 	return result
 }
+
+// IsJobPod returns true if given pod is owned by a Job.
+func IsJobPod(pod *corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "Job" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PodCompleted returns true if given pod has terminated, either successfully or not.
+func PodCompleted(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// NodePods returns pods scheduled to given node.
+func NodePods(ctx context.Context, pc corev1client.PodsGetter, node string) ([]corev1.Pod, error) {
+	pods, err := pc.Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods on node %q: %w", node, err)
+	}
+
+	return pods.Items, nil
+}
+
+// BlockingPod returns the first of given pods which has given annotation set to "true", meaning the node
+// running it must not be drained, or nil if there is no such pod. Completed pods never block the node.
+func BlockingPod(pods []corev1.Pod, annotationKey string) *corev1.Pod {
+	for i := range pods {
+		pod := &pods[i]
+
+		if pod.Annotations[annotationKey] == constants.True && !PodCompleted(pod) {
+			return pod
+		}
+	}
+
+	return nil
+}
+
+// PodInNamespaces returns the first of given pods running in any of given namespaces, or nil if there
+// is no such pod. Completed pods and pods owned by DaemonSets are ignored, as they are not evicted when draining
+// the node.
+func PodInNamespaces(pods []corev1.Pod, namespaces []string) *corev1.Pod {
+	for i := range pods {
+		pod := &pods[i]
+
+		if PodCompleted(pod) || IsDaemonSetPod(pod) {
+			continue
+		}
+
+		for _, namespace := range namespaces {
+			if pod.Namespace == namespace {
+				return pod
+			}
+		}
+	}
+
+	return nil
+}
+
+// RunningJobPod returns the first of given pods which is owned by a Job and did not complete yet, or nil
+// if there is no such pod. Pods created by CronJobs are owned by Jobs, so they are returned as well.
+func RunningJobPod(pods []corev1.Pod) *corev1.Pod {
+	for i := range pods {
+		pod := &pods[i]
+
+		if IsJobPod(pod) && !PodCompleted(pod) {
+			return pod
+		}
+	}
+
+	return nil
+}
+
+// IsDaemonSetPod returns true if given pod is owned by a DaemonSet.
+func IsDaemonSetPod(pod *corev1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PodBlockedByDisruptionBudget checks if any of given pods is selected by any of given PodDisruptionBudgets
+// which currently allows no disruptions, meaning evicting the pod would be rejected. If so, the first such pod
+// and its PodDisruptionBudget are returned, otherwise both are nil. Completed pods and pods owned by DaemonSets
+// are ignored, as they are not evicted when draining the node.
+func PodBlockedByDisruptionBudget(
+	pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget,
+) (*corev1.Pod, *policyv1.PodDisruptionBudget, error) {
+	for i := range pdbs {
+		pdb := &pdbs[i]
+
+		if pdb.Status.DisruptionsAllowed > 0 {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing selector of PodDisruptionBudget %s/%s: %w", pdb.Namespace, pdb.Name, err)
+		}
+
+		for j := range pods {
+			pod := &pods[j]
+
+			if pod.Namespace != pdb.Namespace || PodCompleted(pod) || IsDaemonSetPod(pod) {
+				continue
+			}
+
+			if selector.Matches(labels.Set(pod.Labels)) {
+				return pod, pdb, nil
+			}
+		}
+	}
+
+	return nil, nil, nil
+}
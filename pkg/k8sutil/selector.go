@@ -37,10 +37,16 @@ func FilterNodesByAnnotation(list []corev1.Node, sel fields.Selector) []corev1.N
 // FilterNodesByRequirement filters a list of nodes and returns nodes matching the
 // given label requirement.
 func FilterNodesByRequirement(nodes []corev1.Node, req *labels.Requirement) []corev1.Node {
+	return FilterNodesByRequirements(nodes, req)
+}
+
+// FilterNodesByRequirements filters a list of nodes and returns nodes matching all
+// given label requirements.
+func FilterNodesByRequirements(nodes []corev1.Node, reqs ...*labels.Requirement) []corev1.Node {
 	var matches []corev1.Node
 
 	for _, node := range nodes {
-		if req.Matches(labels.Set(node.Labels)) {
+		if matchesAllRequirements(labels.Set(node.Labels), reqs) {
 			matches = append(matches, node)
 		}
 	}
@@ -48,6 +54,17 @@ func FilterNodesByRequirement(nodes []corev1.Node, req *labels.Requirement) []co
 	return matches
 }
 
+// matchesAllRequirements returns true if given labels match all given requirements.
+func matchesAllRequirements(nodeLabels labels.Set, reqs []*labels.Requirement) bool {
+	for _, req := range reqs {
+		if !req.Matches(nodeLabels) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // FilterContainerLinuxNodes filters a list of nodes and returns nodes with a
 // Flatcar Container Linux OSImage, as reported by the node's /etc/os-release.
 func FilterContainerLinuxNodes(nodes []corev1.Node) []corev1.Node {
@@ -0,0 +1,39 @@
+package k8sutil_test
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+func Test_Filtering_nodes_by_requirements_returns_only_nodes_matching_all_requirements(t *testing.T) {
+	t.Parallel()
+
+	nodeWithLabels := func(name string, labels map[string]string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	nodes := []corev1.Node{
+		nodeWithLabels("both", map[string]string{"foo": "true", "bar": "true"}),
+		nodeWithLabels("only-foo", map[string]string{"foo": "true"}),
+		nodeWithLabels("only-bar", map[string]string{"bar": "true"}),
+		nodeWithLabels("none", nil),
+	}
+
+	fooReq := k8sutil.NewRequirementOrDie("foo", selection.In, []string{"true"})
+	barReq := k8sutil.NewRequirementOrDie("bar", selection.In, []string{"true"})
+
+	matches := k8sutil.FilterNodesByRequirements(nodes, fooReq, barReq)
+
+	if len(matches) != 1 || matches[0].Name != "both" {
+		t.Fatalf("Expected only node %q to match, got %v", "both", matches)
+	}
+
+	if matches := k8sutil.FilterNodesByRequirements(nodes); len(matches) != len(nodes) {
+		t.Fatalf("Expected all nodes to match no requirements, got %v", matches)
+	}
+}
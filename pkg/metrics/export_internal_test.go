@@ -0,0 +1,16 @@
+package metrics
+
+// LabelNamesByMetric returns names of labels of all metrics created using this package,
+// by fully-qualified metric name.
+func LabelNamesByMetric() map[string][]string {
+	collectorsLock.Lock()
+	defer collectorsLock.Unlock()
+
+	result := make(map[string][]string, len(collectorLabelNames))
+
+	for name, labelNames := range collectorLabelNames {
+		result[name] = labelNames
+	}
+
+	return result
+}
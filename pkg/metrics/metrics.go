@@ -0,0 +1,150 @@
+// Package metrics provides helpers for defining and registering Prometheus metrics
+// exported by FLUO, enforcing a consistent label scheme across all of them, so they
+// can be joined in dashboards. Metrics are either node-scoped, describing a single node, or
+// cluster-scoped, and all metrics of each kind carry the same labels.
+package metrics
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+)
+
+// Namespace is a prefix used by all FLUO metric names.
+const Namespace = "fluo"
+
+// Label is a name of a label attached to FLUO metrics.
+type Label string
+
+const (
+	// LabelNode holds the name of the node the metric refers to. Only attached to node-scoped metrics.
+	LabelNode Label = "node"
+
+	// LabelPool holds the update group (pool) of the node the metric refers to. It is empty for
+	// cluster-scoped metrics referring to all pools.
+	LabelPool Label = "pool"
+
+	// LabelReason holds a short, machine-friendly reason of the observation.
+	LabelReason Label = "reason"
+
+	// LabelPhase holds the phase the metric refers to, which is a phase of the reboot process for
+	// node-scoped metrics or a phase of the reconciliation loop for cluster-scoped metrics.
+	LabelPhase Label = "phase"
+)
+
+var (
+	collectorsLock sync.Mutex
+	collectors     []prometheus.Collector
+	// Names of labels of created metrics, by fully-qualified metric name.
+	collectorLabelNames = map[string][]string{}
+)
+
+// NodeLabelNames returns names of labels attached to all node-scoped metrics.
+func NodeLabelNames() []string {
+	return []string{string(LabelNode), string(LabelPool), string(LabelReason), string(LabelPhase)}
+}
+
+// ClusterLabelNames returns names of labels attached to all cluster-scoped metrics.
+func ClusterLabelNames() []string {
+	return []string{string(LabelPool), string(LabelReason), string(LabelPhase)}
+}
+
+// NewNodeCounterVec creates new counter describing a single node with given name, with labels returned
+// by NodeLabelNames, and adds it to the list of collectors registered by Register.
+func NewNodeCounterVec(name, help string) *prometheus.CounterVec {
+	return newCounterVec(name, help, NodeLabelNames())
+}
+
+// NewCounterVec creates new cluster-scoped counter with given name, with labels returned by
+// ClusterLabelNames, and adds it to the list of collectors registered by Register.
+func NewCounterVec(name, help string) *prometheus.CounterVec {
+	return newCounterVec(name, help, ClusterLabelNames())
+}
+
+// NewGaugeVec creates new cluster-scoped gauge with given name, with labels returned by
+// ClusterLabelNames, and adds it to the list of collectors registered by Register.
+func NewGaugeVec(name, help string) *prometheus.GaugeVec {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      name,
+		Help:      help,
+	}, ClusterLabelNames())
+
+	add(name, gauge, ClusterLabelNames())
+
+	return gauge
+}
+
+// NewHistogramVec creates new cluster-scoped histogram with given name and buckets, with labels returned by
+// ClusterLabelNames, and adds it to the list of collectors registered by Register. If buckets are nil,
+// default buckets are used.
+func NewHistogramVec(name, help string, buckets []float64) *prometheus.HistogramVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}, ClusterLabelNames())
+
+	add(name, histogram, ClusterLabelNames())
+
+	return histogram
+}
+
+// Register registers all metrics created using this package with given registerer.
+func Register(registerer prometheus.Registerer) error {
+	collectorsLock.Lock()
+	defer collectorsLock.Unlock()
+
+	for _, c := range collectors {
+		if err := registerer.Register(c); err != nil {
+			return fmt.Errorf("registering collector: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// NodeLabels returns label values of node-scoped metrics for given node, phase and reason. The pool
+// is read from the update group label of the node, using given keys.
+func NodeLabels(node *corev1.Node, keys constants.Keys, phase, reason string) prometheus.Labels {
+	return prometheus.Labels{
+		string(LabelNode):   node.Name,
+		string(LabelPool):   node.Labels[keys.LabelGroup],
+		string(LabelPhase):  phase,
+		string(LabelReason): reason,
+	}
+}
+
+// ClusterLabels returns label values of cluster-scoped metrics for given pool, phase and reason.
+func ClusterLabels(pool, phase, reason string) prometheus.Labels {
+	return prometheus.Labels{
+		string(LabelPool):   pool,
+		string(LabelPhase):  phase,
+		string(LabelReason): reason,
+	}
+}
+
+func newCounterVec(name, help string, labelNames []string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      name,
+		Help:      help,
+	}, labelNames)
+
+	add(name, counter, labelNames)
+
+	return counter
+}
+
+func add(name string, c prometheus.Collector, labelNames []string) {
+	collectorsLock.Lock()
+	defer collectorsLock.Unlock()
+
+	collectors = append(collectors, c)
+	collectorLabelNames[prometheus.BuildFQName(Namespace, "", name)] = labelNames
+}
@@ -0,0 +1,128 @@
+package metrics_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/metrics"
+	// Imported for metrics created by them.
+	_ "github.com/flatcar/flatcar-linux-update-operator/pkg/operator"
+)
+
+//nolint:funlen // Just many subtests.
+func Test_Registering_metrics(t *testing.T) {
+	t.Parallel()
+
+	counter := metrics.NewCounterVec("test_counter_total", "Test counter.")
+	nodeCounter := metrics.NewNodeCounterVec("test_node_counter_total", "Test node counter.")
+	gauge := metrics.NewGaugeVec("test_gauge", "Test gauge.")
+	histogram := metrics.NewHistogramVec("test_histogram_seconds", "Test histogram.", nil)
+
+	keys := constants.NewKeys("example.com")
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "foo",
+			Labels: map[string]string{
+				keys.LabelGroup: "stable",
+			},
+		},
+	}
+
+	counter.With(metrics.ClusterLabels("", "", "test")).Inc()
+	nodeCounter.With(metrics.NodeLabels(node, keys, "before-reboot", "test")).Inc()
+	gauge.With(metrics.ClusterLabels("stable", "rebooting", "")).Set(1)
+	histogram.With(metrics.ClusterLabels("", "", "test")).Observe(1)
+
+	registry := prometheus.NewRegistry()
+
+	if err := metrics.Register(registry); err != nil {
+		t.Fatalf("Unexpected error registering metrics: %v", err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Unexpected error gathering metrics: %v", err)
+	}
+
+	labels := map[string][]string{}
+
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			names := []string{}
+
+			for _, label := range metric.GetLabel() {
+				names = append(names, label.GetName())
+			}
+
+			sort.Strings(names)
+
+			labels[family.GetName()] = names
+		}
+	}
+
+	for name, expectedLabels := range map[string][]string{
+		"fluo_test_counter_total":      {"phase", "pool", "reason"},
+		"fluo_test_node_counter_total": {"node", "phase", "pool", "reason"},
+		"fluo_test_gauge":              {"phase", "pool", "reason"},
+		"fluo_test_histogram_seconds":  {"phase", "pool", "reason"},
+	} {
+		got, ok := labels[name]
+		if !ok {
+			t.Fatalf("Expected metric %q to be registered, got %v", name, labels)
+		}
+
+		if diff := cmp.Diff(expectedLabels, got); diff != "" {
+			t.Fatalf("Unexpected labels for metric %q: %s", name, diff)
+		}
+	}
+
+	t.Run("assigns_node_pool_from_update_group_label_with_given_keys", func(t *testing.T) {
+		t.Parallel()
+
+		if pool := metrics.NodeLabels(node, keys, "", "")[string(metrics.LabelPool)]; pool != "stable" {
+			t.Fatalf("Expected pool %q, got %q", "stable", pool)
+		}
+	})
+
+	t.Run("fails_when_metrics_are_already_registered", func(t *testing.T) {
+		t.Parallel()
+
+		if err := metrics.Register(registry); err == nil {
+			t.Fatalf("Expected error registering metrics twice")
+		}
+	})
+}
+
+func Test_All_FLUO_metrics_use_node_or_cluster_label_scheme(t *testing.T) {
+	t.Parallel()
+
+	labelNamesByMetric := metrics.LabelNamesByMetric()
+
+	// Sample metrics of importing packages, to ensure they are created using this package.
+	for _, name := range []string{
+		"fluo_node_phase_transitions_total",
+		"fluo_reboot_slo_breaches_total",
+		"fluo_node_annotations_trimmed_total",
+		"fluo_reconcile_duration_seconds",
+		"fluo_reboot_cycle_duration_seconds",
+		"fluo_stuck_reboots",
+	} {
+		if _, ok := labelNamesByMetric[name]; !ok {
+			t.Fatalf("Expected metric %q to be created, got %v", name, labelNamesByMetric)
+		}
+	}
+
+	for name, labelNames := range labelNamesByMetric {
+		if !cmp.Equal(labelNames, metrics.NodeLabelNames()) && !cmp.Equal(labelNames, metrics.ClusterLabelNames()) {
+			t.Errorf("Metric %q has labels %v, expected either node labels %v or cluster labels %v",
+				name, labelNames, metrics.NodeLabelNames(), metrics.ClusterLabelNames())
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+const (
+	// Backoff before retrying after reboot checks for the first time, if not configured otherwise.
+	defaultAfterRebootCheckBackoff = time.Minute
+
+	// Backoff before retrying after reboot checks never exceeds this, unless configured backoff is longer.
+	maxAfterRebootCheckBackoff = time.Hour
+
+	// Number of failed attempts of after reboot checks after which the node is labeled as failed
+	// to reboot, if not configured otherwise.
+	defaultAfterRebootCheckMaxAttempts = 3
+)
+
+// failTimedOutAfterRebootChecks finds nodes which did not pass after reboot checks within configured
+// timeout and records a failed attempt for them.
+//
+// Nodes with remaining attempts get their after-reboot label removed, so after reboot checks are
+// scheduled again by markAfterReboot once the backoff elapses. As nodes without after-reboot label
+// do not count as rebooting, other nodes may proceed with rebooting in the meantime.
+//
+// Nodes without remaining attempts are labeled with reboot-failed label and a warning event is emitted
+// for them. They are not retried until the label is removed.
+func (k *Kontroller) failTimedOutAfterRebootChecks(ctx context.Context) error {
+	if k.afterRebootCheckTimeout == 0 {
+		return nil
+	}
+
+	nodelist, err := k.listNodes(labels.NewSelector().Add(*k.selectors.AfterReboot))
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	now := time.Now()
+
+	for _, node := range nodelist.Items {
+		node := node
+
+		if err := k.failTimedOutAfterRebootCheck(ctx, &node, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// failTimedOutAfterRebootCheck records failed attempt of after reboot checks of given node, as described
+// in failTimedOutAfterRebootChecks, if the node runs after reboot checks which timed out at given time.
+func (k *Kontroller) failTimedOutAfterRebootCheck(ctx context.Context, node *corev1.Node, now time.Time) error {
+	if k.afterRebootCheckTimeout == 0 || !k.selectors.AfterReboot.Matches(labels.Set(node.Labels)) {
+		return nil
+	}
+
+	startedAt, ok := k.afterRebootAttemptTime(node)
+	if !ok {
+		// Node started after reboot checks before timeout got configured, so start counting from now.
+		if err := k.startAfterRebootAttempt(ctx, node.Name, now); err != nil {
+			return fmt.Errorf("recording start of after reboot checks of node %q: %w", node.Name, err)
+		}
+
+		return nil
+	}
+
+	if now.Before(startedAt.Add(k.afterRebootCheckTimeout)) {
+		return nil
+	}
+
+	if err := k.failAfterRebootChecks(ctx, node, k.afterRebootAttempts(node)+1, now); err != nil {
+		return fmt.Errorf("recording failed after reboot checks of node %q: %w", node.Name, err)
+	}
+
+	return nil
+}
+
+// startAfterRebootAttempt records that an attempt of after reboot checks of given node started at given time.
+func (k *Kontroller) startAfterRebootAttempt(ctx context.Context, nodeName string, now time.Time) error {
+	err := k8sutil.UpdateNodeRetry(ctx, k.nc, nodeName, func(node *corev1.Node) {
+		node.Annotations[k.keys.AnnotationAfterRebootAttemptTime] = strconv.FormatInt(now.Unix(), 10)
+	})
+	if err != nil {
+		return fmt.Errorf("updating node: %w", err)
+	}
+
+	return nil
+}
+
+// failAfterRebootChecks records given number of failed attempts of after reboot checks of given node.
+func (k *Kontroller) failAfterRebootChecks(ctx context.Context, node *corev1.Node, attempts int, now time.Time) error {
+	failed := attempts >= k.afterRebootMaxAttempts
+
+	err := k8sutil.UpdateNodeRetry(ctx, k.nc, node.Name, func(node *corev1.Node) {
+		delete(node.Labels, k.keys.LabelAfterReboot)
+
+		for _, annotation := range k.afterRebootAnnotations {
+			delete(node.Annotations, annotation)
+		}
+
+		if failed {
+			node.Labels[k.keys.LabelRebootFailed] = constants.True
+
+			delete(node.Annotations, k.keys.AnnotationAfterRebootAttempts)
+			delete(node.Annotations, k.keys.AnnotationAfterRebootAttemptTime)
+
+			return
+		}
+
+		node.Annotations[k.keys.AnnotationAfterRebootAttempts] = strconv.Itoa(attempts)
+		node.Annotations[k.keys.AnnotationAfterRebootAttemptTime] = strconv.FormatInt(now.Unix(), 10)
+	})
+	if err != nil {
+		return fmt.Errorf("updating node: %w", err)
+	}
+
+	k.resetRampUp(node.Name)
+
+	if !failed {
+		klog.InfoS("After reboot checks did not pass in time, retrying after backoff",
+			"node", node.Name, "phase", phaseAfterReboot, "attempts", attempts, "backoff", k.afterRebootBackoffAfter(attempts))
+
+		return nil
+	}
+
+	klog.InfoS("After reboot checks did not pass in time, giving up",
+		"node", node.Name, "phase", phaseAfterReboot, "attempts", attempts, "label", k.keys.LabelRebootFailed)
+
+	k.eventRecorder.Eventf(node, corev1.EventTypeWarning, eventReasonAfterRebootChecksFailed,
+		"After reboot checks of node %q did not pass in %d attempts", node.Name, attempts)
+
+	return nil
+}
+
+// afterRebootChecksRetryable returns true when after reboot checks may be scheduled for given
+// just rebooted node at given time. Nodes labeled as failed to reboot are never retried and
+// nodes with failed attempts are retried once the backoff elapses.
+func (k *Kontroller) afterRebootChecksRetryable(node *corev1.Node, now time.Time) bool {
+	if node.Labels[k.keys.LabelRebootFailed] == constants.True {
+		klog.V(4).InfoS("Node failed to reboot, skipping", "node", node.Name, "label", k.keys.LabelRebootFailed)
+
+		return false
+	}
+
+	attempts := k.afterRebootAttempts(node)
+	if attempts == 0 {
+		return true
+	}
+
+	failedAt, ok := k.afterRebootAttemptTime(node)
+	if !ok {
+		return true
+	}
+
+	retryAt := failedAt.Add(k.afterRebootBackoffAfter(attempts))
+	if now.Before(retryAt) {
+		klog.V(4).InfoS("Backing off after reboot checks", "node", node.Name, "attempts", attempts, "retryAt", retryAt)
+
+		return false
+	}
+
+	return true
+}
+
+// afterRebootBackoffAfter returns backoff before retrying after reboot checks after given number of
+// failed attempts. It doubles with every failed attempt, up to maxAfterRebootCheckBackoff.
+func (k *Kontroller) afterRebootBackoffAfter(attempts int) time.Duration {
+	if k.afterRebootBackoff >= maxAfterRebootCheckBackoff {
+		return k.afterRebootBackoff
+	}
+
+	backoff := k.afterRebootBackoff
+
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+
+		if backoff >= maxAfterRebootCheckBackoff {
+			return maxAfterRebootCheckBackoff
+		}
+	}
+
+	return backoff
+}
+
+// afterRebootAttemptTime returns time recorded in after reboot attempt time annotation of given node.
+func (k *Kontroller) afterRebootAttemptTime(node *corev1.Node) (time.Time, bool) {
+	value, ok := node.Annotations[k.keys.AnnotationAfterRebootAttemptTime]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationAfterRebootAttemptTime, value)
+
+		return time.Time{}, false
+	}
+
+	return time.Unix(timestamp, 0), true
+}
+
+// afterRebootAttempts returns number of failed attempts of after reboot checks of given node, capped at
+// configured maximum number of attempts. Negative number of attempts is considered invalid.
+func (k *Kontroller) afterRebootAttempts(node *corev1.Node) int {
+	value, ok := node.Annotations[k.keys.AnnotationAfterRebootAttempts]
+	if !ok {
+		return 0
+	}
+
+	attempts, err := strconv.Atoi(value)
+	if err == nil && attempts < 0 {
+		err = fmt.Errorf("negative number of attempts")
+	}
+
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationAfterRebootAttempts, value)
+
+		return 0
+	}
+
+	if attempts > k.afterRebootMaxAttempts {
+		return k.afterRebootMaxAttempts
+	}
+
+	return attempts
+}
@@ -0,0 +1,91 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+)
+
+func Test_After_reboot_checks_backoff(t *testing.T) {
+	t.Parallel()
+
+	keys := constants.NewKeys("")
+
+	k := &Kontroller{
+		keys:                   keys,
+		afterRebootBackoff:     time.Minute,
+		afterRebootMaxAttempts: 100,
+	}
+
+	nodeWithAttempts := func(attempts string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Annotations: map[string]string{keys.AnnotationAfterRebootAttempts: attempts},
+			},
+		}
+	}
+
+	for name, testCase := range map[string]struct {
+		attempts string
+		expected time.Duration
+	}{
+		"is_configured_backoff_after_first_attempt": {
+			attempts: "1",
+			expected: time.Minute,
+		},
+		"doubles_with_every_failed_attempt": {
+			attempts: "3",
+			expected: 4 * time.Minute,
+		},
+		"is_capped_after_many_attempts": {
+			attempts: "64",
+			expected: maxAfterRebootCheckBackoff,
+		},
+		"is_capped_when_attempts_exceed_configured_maximum": {
+			attempts: "1000",
+			expected: maxAfterRebootCheckBackoff,
+		},
+	} {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			backoff := k.afterRebootBackoffAfter(k.afterRebootAttempts(nodeWithAttempts(testCase.attempts)))
+			if backoff != testCase.expected {
+				t.Fatalf("Expected backoff %v, got %v", testCase.expected, backoff)
+			}
+		})
+	}
+
+	t.Run("counts_attempts_up_to_configured_maximum", func(t *testing.T) {
+		t.Parallel()
+
+		if attempts := k.afterRebootAttempts(nodeWithAttempts("1000")); attempts != k.afterRebootMaxAttempts {
+			t.Fatalf("Expected %d attempts, got %d", k.afterRebootMaxAttempts, attempts)
+		}
+	})
+
+	t.Run("considers_negative_attempts_invalid", func(t *testing.T) {
+		t.Parallel()
+
+		if attempts := k.afterRebootAttempts(nodeWithAttempts("-5")); attempts != 0 {
+			t.Fatalf("Expected no attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("is_configured_backoff_when_it_exceeds_maximum_backoff", func(t *testing.T) {
+		t.Parallel()
+
+		k := &Kontroller{afterRebootBackoff: 2 * maxAfterRebootCheckBackoff}
+
+		if backoff := k.afterRebootBackoffAfter(3); backoff != k.afterRebootBackoff {
+			t.Fatalf("Expected backoff %v, got %v", k.afterRebootBackoff, backoff)
+		}
+	})
+}
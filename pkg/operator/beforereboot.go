@@ -0,0 +1,172 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+// BeforeRebootTimeoutAction determines what happens with a node which did not pass before reboot checks
+// within configured before reboot timeout.
+type BeforeRebootTimeoutAction string
+
+const (
+	// BeforeRebootTimeoutActionAbort aborts the reboot process of the node, which frees its rebooting slot
+	// for other nodes. The node is scheduled for rebooting again only after other nodes requiring a reboot.
+	// This is the default action.
+	BeforeRebootTimeoutActionAbort BeforeRebootTimeoutAction = "abort"
+
+	// BeforeRebootTimeoutActionProceed approves the reboot of the node as if before reboot checks passed.
+	BeforeRebootTimeoutActionProceed BeforeRebootTimeoutAction = "proceed"
+)
+
+// checkBeforeRebootTimeoutAction checks if given before reboot timeout action, if configured, is supported.
+func checkBeforeRebootTimeoutAction(action BeforeRebootTimeoutAction) error {
+	switch action {
+	case "", BeforeRebootTimeoutActionAbort, BeforeRebootTimeoutActionProceed:
+		return nil
+	default:
+		return fmt.Errorf("unsupported action %q, expected %q or %q",
+			action, BeforeRebootTimeoutActionAbort, BeforeRebootTimeoutActionProceed)
+	}
+}
+
+// handleTimedOutBeforeRebootChecks finds nodes which did not pass before reboot checks within configured
+// timeout and takes configured action for them.
+func (k *Kontroller) handleTimedOutBeforeRebootChecks(ctx context.Context) error {
+	if k.beforeRebootTimeout == 0 {
+		return nil
+	}
+
+	nodelist, err := k.listNodes(labels.NewSelector().Add(*k.selectors.BeforeReboot))
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	now := time.Now()
+
+	for _, node := range nodelist.Items {
+		node := node
+
+		if err := k.handleTimedOutBeforeRebootCheck(ctx, &node, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleTimedOutBeforeRebootCheck takes configured action for given node, as described in
+// handleTimedOutBeforeRebootChecks, if the node runs before reboot checks which timed out at given time.
+//
+// Before reboot checks are considered started when the node entered the reboot process, as recorded
+// when labeling it for before reboot checks.
+func (k *Kontroller) handleTimedOutBeforeRebootCheck(ctx context.Context, node *corev1.Node, now time.Time) error {
+	if k.beforeRebootTimeout == 0 || !k.selectors.BeforeReboot.Matches(labels.Set(node.Labels)) {
+		return nil
+	}
+
+	startedAt, ok := k.rebootCycleStartTime(node)
+	if !ok || now.Before(startedAt.Add(k.beforeRebootTimeout)) {
+		return nil
+	}
+
+	missing := k.describeAnnotations(missingAnnotations(node, k.beforeRebootAnnotations, k.beforeRebootValues))
+
+	klog.InfoS("Before reboot checks did not pass in time", "node", node.Name, "phase", phaseBeforeReboot,
+		"timeout", k.beforeRebootTimeout, "action", k.beforeRebootTimeoutAction, "missingAnnotations", missing)
+
+	k.eventRecorder.Eventf(node, corev1.EventTypeWarning, eventReasonBeforeRebootChecksTimedOut,
+		"Before reboot checks of node %q did not pass in %v, taking action %q",
+		node.Name, k.beforeRebootTimeout, k.beforeRebootTimeoutAction)
+
+	if k.beforeRebootTimeoutAction == BeforeRebootTimeoutActionProceed {
+		if err := k.proceedWithReboot(ctx, node); err != nil {
+			return fmt.Errorf("approving reboot of node %q: %w", node.Name, err)
+		}
+
+		return nil
+	}
+
+	if err := k.abortReboot(ctx, node.Name, now); err != nil {
+		return fmt.Errorf("aborting reboot process of node %q: %w", node.Name, err)
+	}
+
+	return nil
+}
+
+// proceedWithReboot approves the reboot of given node, which did not pass before reboot checks.
+func (k *Kontroller) proceedWithReboot(ctx context.Context, node *corev1.Node) error {
+	opt := k.beforeRebootOptions()
+
+	if err := k8sutil.UpdateNodeRetry(ctx, k.nc, node.Name, func(node *corev1.Node) {
+		k.completeCheck(node, opt)
+	}); err != nil {
+		return fmt.Errorf("updating node: %w", err)
+	}
+
+	k.recordPhaseTransition(node, opt.phase, "checks-timed-out")
+
+	return nil
+}
+
+// abortReboot removes given node from the reboot process, which frees its rebooting slot, and records
+// when it happened at given time, so the node is scheduled for rebooting after other nodes.
+func (k *Kontroller) abortReboot(ctx context.Context, nodeName string, now time.Time) error {
+	// Taint is removed first, so if aborting fails, it is retried in the next loop.
+	if err := k.removeRebootTaint(ctx, nodeName); err != nil {
+		return fmt.Errorf("removing reboot taint: %w", err)
+	}
+
+	uncordoned := false
+
+	err := k8sutil.UpdateNodeRetry(ctx, k.nc, nodeName, func(node *corev1.Node) {
+		delete(node.Labels, k.keys.LabelBeforeReboot)
+		delete(node.Annotations, k.keys.AnnotationRebootCycleStartTime)
+		delete(node.Annotations, k.keys.AnnotationRebootReason)
+
+		for _, annotation := range k.beforeRebootAnnotations {
+			delete(node.Annotations, annotation)
+		}
+
+		node.Annotations[k.keys.AnnotationBeforeRebootTimedOutTime] = strconv.FormatInt(now.Unix(), 10)
+
+		k.allowKarpenterDisruption(node)
+		uncordoned = k.uncordon(node)
+	})
+	if err != nil {
+		return fmt.Errorf("updating node: %w", err)
+	}
+
+	if !uncordoned {
+		return nil
+	}
+
+	return k.ensureUncordoned(ctx, nodeName)
+}
+
+// deferTimedOutNodes moves nodes whose reboot process has been aborted due to timed out before reboot checks
+// to the end of given list of nodes, keeping their order, so other nodes get rebooted first.
+func (k *Kontroller) deferTimedOutNodes(nodes []corev1.Node) []corev1.Node {
+	result := make([]corev1.Node, 0, len(nodes))
+	deferred := []corev1.Node{}
+
+	for _, node := range nodes {
+		if _, ok := node.Annotations[k.keys.AnnotationBeforeRebootTimedOutTime]; ok {
+			deferred = append(deferred, node)
+
+			continue
+		}
+
+		result = append(result, node)
+	}
+
+	return append(result, deferred...)
+}
@@ -0,0 +1,77 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	// ErrOutsideRebootWindow is reported when rebootable nodes are not scheduled for rebooting,
+	// because none of configured reboot windows is open.
+	ErrOutsideRebootWindow = errors.New("outside of reboot window")
+
+	// ErrInsideExclusionWindow is reported when rebootable nodes are not scheduled for rebooting,
+	// because one of configured exclusion windows is open.
+	ErrInsideExclusionWindow = errors.New("inside of exclusion window")
+
+	// ErrInterRebootDelay is reported when rebootable nodes are not scheduled for rebooting,
+	// because configured delay since the last finished reboot did not pass yet.
+	ErrInterRebootDelay = errors.New("waiting for inter reboot delay")
+)
+
+// Reasons used as a value of the reason label of the metric reporting blocked scheduling, by error.
+var schedulingBlockedReasons = map[error]string{
+	ErrOutsideRebootWindow:   "outside-reboot-window",
+	ErrInsideExclusionWindow: "inside-exclusion-window",
+	ErrInterRebootDelay:      "inter-reboot-delay",
+}
+
+// schedulingBlocked returns an error wrapping one of ErrOutsideRebootWindow, ErrInsideExclusionWindow
+// or ErrInterRebootDelay when scheduling given rebootable nodes for rebooting is blocked at given time.
+// Scheduling is blocked by reboot windows only when reboot windows of none of given nodes are open.
+func (k *Kontroller) schedulingBlocked(nodes []corev1.Node, now time.Time) error {
+	if k.insideExclusionWindow(now) {
+		return ErrInsideExclusionWindow
+	}
+
+	if !k.insideAnyNodeRebootWindow(nodes, now) {
+		return ErrOutsideRebootWindow
+	}
+
+	if remaining := k.remainingInterRebootDelay(now); remaining > 0 {
+		return fmt.Errorf("%w: %v remaining", ErrInterRebootDelay, remaining.Round(time.Second))
+	}
+
+	return nil
+}
+
+// recordSchedulingBlocked records given reason why scheduling rebootable nodes for rebooting is blocked,
+// or that it is not blocked if nil, for RebootSchedulingBlocked, metrics and the status endpoint.
+func (k *Kontroller) recordSchedulingBlocked(err error) {
+	for reasonErr, reason := range schedulingBlockedReasons {
+		value := 0.0
+		if errors.Is(err, reasonErr) {
+			value = 1
+		}
+
+		rebootSchedulingBlocked.With(clusterLabels("", reason)).Set(value)
+	}
+
+	k.status.mu.Lock()
+	defer k.status.mu.Unlock()
+
+	k.status.schedulingBlockedBy = err
+}
+
+// RebootSchedulingBlocked returns the reason why rebootable nodes were not scheduled for rebooting in the
+// last reconciliation loop, which can be checked using errors.Is against ErrOutsideRebootWindow,
+// ErrInsideExclusionWindow and ErrInterRebootDelay. It returns nil when scheduling was not blocked.
+func (k *Kontroller) RebootSchedulingBlocked() error {
+	k.status.mu.RLock()
+	defer k.status.mu.RUnlock()
+
+	return k.status.schedulingBlockedBy
+}
@@ -0,0 +1,187 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Kinds of workloads which readiness can be awaited in after reboot checks.
+const (
+	workloadKindDaemonSet  = "daemonset"
+	workloadKindDeployment = "deployment"
+)
+
+// AfterRebootCheck is a check which must pass, in addition to after reboot annotations being set,
+// before after reboot checks of given node are considered passed. It returns true when the check passes.
+//
+// Checks are called in every reconciliation loop until they pass, so they should not block.
+type AfterRebootCheck func(ctx context.Context, node *corev1.Node) (bool, error)
+
+// afterRebootChecks returns custom after reboot checks from given configuration, followed by a check
+// for readiness of configured workloads, if any.
+func afterRebootChecks(config Config) ([]AfterRebootCheck, error) {
+	workloads, err := parseWorkloads(config.AfterRebootReadyWorkloads)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ready workloads: %w", err)
+	}
+
+	checks := append([]AfterRebootCheck{}, config.AfterRebootChecks...)
+
+	if len(workloads) > 0 {
+		checks = append(checks, workloadsReadyCheck(config.Client, workloads))
+	}
+
+	return checks, nil
+}
+
+// workload identifies a DaemonSet or Deployment.
+type workload struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+func (w workload) String() string {
+	return fmt.Sprintf("%s/%s/%s", w.kind, w.namespace, w.name)
+}
+
+// parseWorkloads parses given workloads in format "<kind>/<namespace>/<name>".
+func parseWorkloads(values []string) ([]workload, error) {
+	workloads := make([]workload, 0, len(values))
+
+	for _, value := range values {
+		parts := strings.Split(value, "/")
+
+		//nolint:gomnd // Just kind, namespace and name.
+		if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("workload %q must be in format <kind>/<namespace>/<name>", value)
+		}
+
+		kind := strings.ToLower(parts[0])
+		if kind != workloadKindDaemonSet && kind != workloadKindDeployment {
+			return nil, fmt.Errorf("workload %q has unsupported kind %q, expected %q or %q",
+				value, parts[0], workloadKindDaemonSet, workloadKindDeployment)
+		}
+
+		workloads = append(workloads, workload{kind: kind, namespace: parts[1], name: parts[2]})
+	}
+
+	return workloads, nil
+}
+
+// workloadsReadyCheck returns check which passes when all given workloads are ready. DaemonSets must
+// have a ready pod on the checked node, while Deployments must have all their replicas ready, wherever
+// they are scheduled.
+func workloadsReadyCheck(client kubernetes.Interface, workloads []workload) AfterRebootCheck {
+	return func(ctx context.Context, node *corev1.Node) (bool, error) {
+		for _, workload := range workloads {
+			ready, err := workloadReady(ctx, client, workload, node.Name)
+			if err != nil {
+				return false, fmt.Errorf("checking readiness of %s: %w", workload, err)
+			}
+
+			if !ready {
+				klog.V(4).InfoS("Workload is not ready yet", "node", node.Name, "workload", workload.String())
+
+				return false, nil
+			}
+		}
+
+		return true, nil
+	}
+}
+
+// workloadReady returns true when given workload is ready on given node.
+func workloadReady(ctx context.Context, client kubernetes.Interface, workload workload, nodeName string) (bool, error) {
+	if workload.kind == workloadKindDaemonSet {
+		return daemonSetReadyOnNode(ctx, client, workload, nodeName)
+	}
+
+	return deploymentReady(ctx, client, workload)
+}
+
+// daemonSetReadyOnNode returns true when given DaemonSet has a ready pod on given node.
+func daemonSetReadyOnNode(
+	ctx context.Context, client kubernetes.Interface, workload workload, nodeName string,
+) (bool, error) {
+	daemonSet, err := client.AppsV1().DaemonSets(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("getting DaemonSet: %w", err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(daemonSet.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("parsing DaemonSet selector: %w", err)
+	}
+
+	pods, err := client.CoreV1().Pods(workload.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("listing DaemonSet pods: %w", err)
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		if pod.Spec.NodeName == nodeName && metav1.IsControlledBy(pod, daemonSet) && podReady(pod) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// deploymentReady returns true when all replicas of given Deployment are ready.
+func deploymentReady(ctx context.Context, client kubernetes.Interface, workload workload) (bool, error) {
+	deployment, err := client.AppsV1().Deployments(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("getting Deployment: %w", err)
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.ReadyReplicas >= replicas, nil
+}
+
+// podReady returns true when given pod reports Ready condition.
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// afterRebootChecksPassed returns true when all configured after reboot checks pass for given node.
+// Failing checks are logged and treated as not passed, so they are retried in the next loop.
+func (k *Kontroller) afterRebootChecksPassed(ctx context.Context, node *corev1.Node) bool {
+	for _, check := range k.afterRebootChecks {
+		passed, err := check(ctx, node)
+		if err != nil {
+			klog.ErrorS(err, "Failed running after reboot check", "node", node.Name, "phase", phaseAfterReboot)
+
+			return false
+		}
+
+		if !passed {
+			return false
+		}
+	}
+
+	return true
+}
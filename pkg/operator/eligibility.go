@@ -0,0 +1,61 @@
+package operator
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NodeEligibilityPredicate decides whether given node requiring a reboot is eligible to be scheduled for
+// rebooting. When it is not, returned reason, e.g. "maintenance-freeze", is logged, recorded in metrics
+// and reported in node status. Reasons are used as metric label values, so they should have few distinct
+// values.
+//
+// Predicates are called in every reconciliation loop and when serving status, so they should not block.
+type NodeEligibilityPredicate func(node *corev1.Node) (eligible bool, reason string)
+
+// Reasons reported by built-in node eligibility predicates.
+const (
+	// ineligibleReasonBeingDeleted is used for nodes being deleted, e.g. by cluster autoscaler,
+	// as they will go away anyway.
+	ineligibleReasonBeingDeleted = "being-deleted"
+	// ineligibleReasonPausedByLabel is used for nodes matching configured pause label selector.
+	ineligibleReasonPausedByLabel = "paused-by-label"
+	// ineligibleReasonExcluded is used for nodes matching configured exclude label selector.
+	ineligibleReasonExcluded = "excluded"
+	// ineligibleReasonPausedUntil is used for nodes which have reboot paused until a future time.
+	ineligibleReasonPausedUntil = "paused-until"
+)
+
+// eligibilityPredicates returns built-in node eligibility predicates, followed by given custom ones.
+func (k *Kontroller) eligibilityPredicates(custom []NodeEligibilityPredicate) []NodeEligibilityPredicate {
+	predicates := []NodeEligibilityPredicate{
+		func(node *corev1.Node) (bool, string) {
+			return node.DeletionTimestamp == nil, ineligibleReasonBeingDeleted
+		},
+		func(node *corev1.Node) (bool, string) {
+			return !k.pausedByLabel(node), ineligibleReasonPausedByLabel
+		},
+		func(node *corev1.Node) (bool, string) {
+			return !k.excludedByLabel(node), ineligibleReasonExcluded
+		},
+		// Field selectors cannot compare times, so time-bounded pauses are handled here.
+		func(node *corev1.Node) (bool, string) {
+			return !k.pausedUntil(node, time.Now()), ineligibleReasonPausedUntil
+		},
+	}
+
+	return append(predicates, custom...)
+}
+
+// ineligibleReason returns reason of the first node eligibility predicate which given node does not
+// satisfy or an empty string, if the node is eligible to be scheduled for rebooting.
+func (k *Kontroller) ineligibleReason(node *corev1.Node) string {
+	for _, predicate := range k.nodeEligibilityPredicates {
+		if eligible, reason := predicate(node); !eligible {
+			return reason
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,7 @@
+package operator
+
+// SetReconciledHook sets a function called by given controller after each loop cycle.
+// Must be called before the controller is started.
+func SetReconciledHook(k *Kontroller, hook func()) {
+	k.reconciled = hook
+}
@@ -0,0 +1,94 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+)
+
+// Number of MaxRebootingNodes which may go through the reboot process at once, including forcefully
+// rebooted nodes, if ForceRebootLimit is not configured.
+const defaultForceRebootLimitFactor = 2
+
+// ForceReboot schedules reboot of node with given name immediately, by labeling it for before reboot checks
+// the same way as reconciliation does. Remaining steps of the reboot process are performed as usual by the
+// running operator.
+//
+// ForceReboot intentionally bypasses reboot and exclusion windows, pausing using ConfigMap and MaxRebootingNodes,
+// as it is meant for pushing a single node through the reboot process ahead of the normal schedule. As a safety
+// measure, it fails if the number of nodes going through the reboot process would exceed ForceRebootLimit.
+// Nodes which do not need a reboot, have reboot paused or are already rebooting cannot be forcefully rebooted.
+//
+// Nodes are read directly from the API server, so ForceReboot can be used without running the operator.
+func (k *Kontroller) ForceReboot(ctx context.Context, nodeName string) error {
+	node, err := k.nc.Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("getting node %q: %w", nodeName, err)
+	}
+
+	if err := k.checkForceRebootable(node); err != nil {
+		return fmt.Errorf("node %q cannot be rebooted: %w", nodeName, err)
+	}
+
+	listOptions := metav1.ListOptions{}
+	if k.nodeSelector != nil {
+		listOptions.LabelSelector = k.nodeSelector.String()
+	}
+
+	nodelist, err := k.nc.List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	if rebooting := len(k.rebootingNodes(nodelist)); rebooting >= k.forceRebootLimit {
+		return fmt.Errorf("found %d rebooting nodes, rebooting node %q would exceed limit of %d",
+			rebooting, nodeName, k.forceRebootLimit)
+	}
+
+	klog.InfoS("Forcefully scheduling reboot of node", "node", nodeName)
+
+	if err := k.addRebootTaint(ctx, nodeName); err != nil {
+		return fmt.Errorf("tainting node for reboot: %w", err)
+	}
+
+	reason := map[string]string{
+		k.keys.AnnotationRebootReason: k.pendingRebootReason(node) + " (forced)",
+	}
+
+	err = k.mark(ctx, nodeName, k.keys.LabelBeforeReboot, phaseBeforeReboot, k.beforeRebootAnnotations, reason)
+	if err != nil {
+		return fmt.Errorf("labeling node for before reboot checks: %w", err)
+	}
+
+	k.recordPhaseTransition(node, phaseBeforeReboot, "forced")
+	k.notify(nodeName, NotificationEventRebootStarted)
+
+	return nil
+}
+
+// checkForceRebootable returns an error if given node must not be forcefully rebooted.
+func (k *Kontroller) checkForceRebootable(node *corev1.Node) error {
+	if k.nodeSelector != nil && !k.nodeSelector.Matches(labels.Set(node.Labels)) {
+		return fmt.Errorf("node is not managed by the operator, as it does not match node selector %q", k.nodeSelector)
+	}
+
+	if !k.selectors.Rebootable.Matches(fields.Set(node.Annotations)) {
+		return fmt.Errorf("node does not need a reboot, has reboot paused or is already rebooting")
+	}
+
+	if k.pausedUntil(node, time.Now()) {
+		return fmt.Errorf("node has reboot paused until %s", node.Annotations[k.keys.AnnotationRebootPausedUntil])
+	}
+
+	if !k.notBeforeRebootReq.Matches(labels.Set(node.Labels)) {
+		return fmt.Errorf("node is already scheduled for rebooting")
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package operator
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Number of reconciliation periods without successful reconciliation after which
+	// the operator is reported as unhealthy, if not configured otherwise.
+	defaultUnhealthyAfterPeriods = 10
+
+	healthServerReadHeaderTimeout = 10 * time.Second
+)
+
+// healthStatus tracks the state of the operator reported by health endpoints.
+type healthStatus struct {
+	mu sync.Mutex
+
+	// Whether the operator holds the leader election lock.
+	leading bool
+
+	// Whether the first reconciliation loop has completed since acquiring leadership.
+	reconciled bool
+
+	// Time of the last successful reconciliation loop, or time of acquiring leadership
+	// if there was none yet.
+	lastSuccess time.Time
+
+	// Duration without successful reconciliation after which the operator is unhealthy.
+	unhealthyAfter time.Duration
+}
+
+// leadershipAcquired records that the operator has acquired leadership at given time.
+func (h *healthStatus) leadershipAcquired(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.leading = true
+	h.lastSuccess = now
+}
+
+// reconcileCompleted records the result of a reconciliation loop finished at given time.
+func (h *healthStatus) reconcileCompleted(succeeded bool, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.reconciled = true
+
+	if succeeded {
+		h.lastSuccess = now
+	}
+}
+
+// ready returns an error if the operator is not leading or has not completed any reconciliation loop yet.
+func (h *healthStatus) ready() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.leading {
+		return errors.New("waiting for leadership")
+	}
+
+	if !h.reconciled {
+		return errors.New("waiting for first reconciliation to complete")
+	}
+
+	return nil
+}
+
+// healthy returns an error if the operator is leading, but reconciliation has been failing
+// for longer than configured threshold. Operators waiting for leadership are always healthy.
+func (h *healthStatus) healthy(now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.leading {
+		return nil
+	}
+
+	if failingFor := now.Sub(h.lastSuccess); failingFor > h.unhealthyAfter {
+		return fmt.Errorf("no successful reconciliation for %v", failingFor.Round(time.Second))
+	}
+
+	return nil
+}
+
+// healthHandler returns a handler serving health endpoints.
+func (k *Kontroller) healthHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", healthCheckHandler(func() error {
+		return k.health.healthy(time.Now())
+	}))
+	mux.HandleFunc("/readyz", healthCheckHandler(k.health.ready))
+
+	return mux
+}
+
+// healthCheckHandler returns a handler responding with an error when given check fails.
+func healthCheckHandler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+
+			return
+		}
+
+		// Nothing can be done if writing the response fails.
+		_, _ = w.Write([]byte("ok"))
+	}
+}
+
+// serveHealth starts serving health endpoints on configured address. Returned function
+// stops the server.
+func (k *Kontroller) serveHealth() (func(), error) {
+	return serveHTTP(k.healthAddress, k.healthHandler(), "health")
+}
+
+// serveHTTP starts serving given handler on given address. Given name of served endpoints is used
+// in logs. Returned function stops the server.
+func serveHTTP(address string, handler http.Handler, name string) (func(), error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("listening on %q: %w", address, err)
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: healthServerReadHeaderTimeout,
+	}
+
+	klog.Infof("Serving %s endpoints on %q", name, listener.Addr())
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.Errorf("Failed serving %s endpoints: %v", name, err)
+		}
+	}()
+
+	return func() {
+		if err := server.Close(); err != nil {
+			klog.Warningf("Failed stopping %s server: %v", name, err)
+		}
+	}, nil
+}
@@ -0,0 +1,111 @@
+package operator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/metrics"
+)
+
+// Phases of the reboot process used as a value of the phase label in metrics.
+const (
+	phaseBeforeReboot = "before-reboot"
+	phaseRebooting    = "rebooting"
+	phaseAfterReboot  = "after-reboot"
+	phaseCompleted    = "completed"
+)
+
+// Reasons for nodes entering after-reboot phase.
+const (
+	// reasonVoluntaryReboot is used when node rebooted after being approved by the operator.
+	reasonVoluntaryReboot = "voluntary-reboot"
+	// reasonInvoluntaryReboot is used when node rebooted without approval from the operator,
+	// e.g. due to a crash or manual reboot.
+	reasonInvoluntaryReboot = "involuntary-reboot"
+)
+
+// Reasons for rebootable nodes not being scheduled for rebooting due to pods running on them. Reasons
+// reported by node eligibility predicates are recorded as well.
+const (
+	// skipReasonBlockingPod is used when node runs a pod with configured blocking pod annotation.
+	skipReasonBlockingPod = "blocking-pod"
+	// skipReasonPodDisruptionBudget is used when node runs a pod whose PodDisruptionBudget allows no disruptions.
+	skipReasonPodDisruptionBudget = "pod-disruption-budget"
+	// skipReasonProtectedNamespace is used when node runs a pod from one of configured protected namespaces.
+	skipReasonProtectedNamespace = "protected-namespace"
+	// skipReasonRunningJob is used when node runs a pod owned by a Job which did not complete yet.
+	skipReasonRunningJob = "running-job"
+)
+
+// Buckets for reboot cycle duration, ranging from 1 minute to around 17 hours.
+//
+//nolint:gomnd // Just bucket parameters.
+var rebootCycleDurationBuckets = prometheus.ExponentialBuckets(60, 2, 11)
+
+var (
+	nodePhaseTransitions = metrics.NewNodeCounterVec("node_phase_transitions_total",
+		"Number of times nodes entered given phase of the reboot process.")
+
+	rebootSLOBreaches = metrics.NewNodeCounterVec("reboot_slo_breaches_total",
+		"Number of reboot processes which took longer than configured reboot SLO.")
+
+	reconcilePanics = metrics.NewCounterVec("reconcile_panics_total",
+		"Number of panics recovered during reconciliation.")
+
+	rebootCycleDuration = metrics.NewHistogramVec("reboot_cycle_duration_seconds",
+		"Duration of the reboot process of nodes, from scheduling the reboot until finishing after reboot checks.",
+		rebootCycleDurationBuckets)
+
+	rebootDuration = metrics.NewHistogramVec("reboot_duration_seconds",
+		"Duration of the reboot of nodes, from allowing node to reboot until finishing after reboot checks.",
+		rebootCycleDurationBuckets)
+
+	stuckReboots = metrics.NewGaugeVec("stuck_reboots",
+		"Number of nodes which did not finish rebooting within configured reboot timeout.")
+
+	reconcileTimeouts = metrics.NewCounterVec("reconcile_timeouts_total",
+		"Number of reconciliation loops cancelled due to exceeding the reconcile timeout, by the phase cancelled.")
+
+	reconcileDuration = metrics.NewHistogramVec("reconcile_duration_seconds",
+		"Duration of reconciliation loops.", nil)
+
+	reconcilePhaseDuration = metrics.NewHistogramVec("reconcile_phase_duration_seconds",
+		"Duration of phases of reconciliation loops.", nil)
+
+	rebootSchedulingBlocked = metrics.NewGaugeVec("reboot_scheduling_blocked",
+		"Whether rebootable nodes were not scheduled for rebooting in the last reconciliation loop, by reason.")
+
+	rebootableNodesSkipped = metrics.NewCounterVec("rebootable_nodes_skipped_total",
+		"Number of times rebootable nodes were not scheduled for rebooting, e.g. due to pods running on them, by reason.")
+
+	rebootSlotsAvailable = metrics.NewGaugeVec("reboot_slots_available",
+		"Number of nodes which may still be scheduled for rebooting without exceeding the maximum number of "+
+			"rebooting nodes, as of the last reconciliation loop.")
+
+	nodesRebootable = metrics.NewGaugeVec("nodes_rebootable",
+		"Number of nodes which want to reboot and are not going through the reboot process yet, "+
+			"as of the last reconciliation loop.")
+
+	reconcileErrors = metrics.NewCounterVec("reconcile_errors_total",
+		"Number of reconciliation loops which failed, by the phase which failed.")
+)
+
+// recordPhaseTransition records that given node entered given phase of the reboot process.
+func (k *Kontroller) recordPhaseTransition(node *corev1.Node, phase, reason string) {
+	nodePhaseTransitions.With(metrics.NodeLabels(node, k.keys, phase, reason)).Inc()
+}
+
+// nodeLabels returns label values of node-scoped metrics referring to given node, without phase and reason.
+func (k *Kontroller) nodeLabels(node *corev1.Node) prometheus.Labels {
+	return metrics.NodeLabels(node, k.keys, "", "")
+}
+
+// poolLabels returns label values of cluster-scoped metrics referring to given pool.
+func poolLabels(pool string) prometheus.Labels {
+	return metrics.ClusterLabels(pool, "", "")
+}
+
+// clusterLabels returns label values of cluster-scoped metrics referring to all pools.
+func clusterLabels(phase, reason string) prometheus.Labels {
+	return metrics.ClusterLabels("", phase, reason)
+}
@@ -0,0 +1,210 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newNodeInformer creates an informer for nodes matching given selector. If selector is nil,
+// all nodes are watched.
+func newNodeInformer(client kubernetes.Interface, selector labels.Selector) cache.SharedIndexInformer {
+	// Reconciliation runs periodically anyway, so there is no need for resyncing.
+	noResync := time.Duration(0)
+
+	return corev1informers.NewFilteredNodeInformer(client, noResync, cache.Indexers{}, func(opts *metav1.ListOptions) {
+		if selector != nil {
+			opts.LabelSelector = selector.String()
+		}
+	})
+}
+
+// listNodes returns copies of cached nodes managed by the operator which match given selector,
+// sorted by name like when listing them from the API server. Nodes updated by the operator are
+// returned as updated, even if the informer did not catch up with them yet.
+func (k *Kontroller) listNodes(selector labels.Selector) (*corev1.NodeList, error) {
+	// Selector is applied after the overlay, as updated node may match it even if cached one does not.
+	nodes, err := k.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("listing cached nodes: %w", err)
+	}
+
+	nodelist := &corev1.NodeList{
+		Items: make([]corev1.Node, 0, len(nodes)),
+	}
+
+	for _, node := range nodes {
+		node = k.nodeOverlay.latest(node)
+
+		if selector.Matches(labels.Set(node.Labels)) {
+			nodelist.Items = append(nodelist.Items, *node.DeepCopy())
+		}
+	}
+
+	sort.Slice(nodelist.Items, func(i, j int) bool {
+		return nodelist.Items[i].Name < nodelist.Items[j].Name
+	})
+
+	return nodelist, nil
+}
+
+// cachedNode returns a copy of cached node with given name, if it is managed by the operator.
+// Node updated by the operator is returned as updated, even if the informer did not catch up with it yet.
+func (k *Kontroller) cachedNode(name string) (*corev1.Node, error) {
+	node, err := k.nodeLister.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting cached node %q: %w", name, err)
+	}
+
+	return k.nodeOverlay.latest(node).DeepCopy(), nil
+}
+
+// nodeOverlay holds nodes updated by the operator until the informer catches up with them, so
+// subsequent reconciliation phases see changes made by the operator without waiting for them to be
+// delivered by the watch. Unlike writing to the informer store directly, stale watch events cannot
+// overwrite nodes updated by the operator.
+type nodeOverlay struct {
+	mu    sync.Mutex
+	nodes map[string]*corev1.Node
+}
+
+func newNodeOverlay() *nodeOverlay {
+	return &nodeOverlay{
+		nodes: map[string]*corev1.Node{},
+	}
+}
+
+// set stores given updated node.
+func (o *nodeOverlay) set(node *corev1.Node) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.nodes[node.Name] = node
+}
+
+// forget removes node with given name.
+func (o *nodeOverlay) forget(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.nodes, name)
+}
+
+// latest returns stored version of given cached node if it is newer, otherwise given node is returned.
+// Stored version is removed once given node is at least as new.
+func (o *nodeOverlay) latest(cached *corev1.Node) *corev1.Node {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	updated, ok := o.nodes[cached.Name]
+	if !ok {
+		return cached
+	}
+
+	if notOlder, comparable := notOlderThan(cached, updated); comparable && notOlder {
+		delete(o.nodes, cached.Name)
+
+		return cached
+	}
+
+	return updated
+}
+
+// eventHandler returns handler removing stored nodes once the informer delivers them. When resource
+// versions of nodes cannot be compared, stored node is removed on any event of the node.
+func (o *nodeOverlay) eventHandler() cache.ResourceEventHandler {
+	caughtUp := func(obj interface{}) {
+		node, ok := obj.(*corev1.Node)
+		if !ok {
+			return
+		}
+
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		updated, ok := o.nodes[node.Name]
+		if !ok {
+			return
+		}
+
+		if notOlder, comparable := notOlderThan(node, updated); notOlder || !comparable {
+			delete(o.nodes, node.Name)
+		}
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: caughtUp,
+		UpdateFunc: func(_, newObj interface{}) {
+			caughtUp(newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			if node, ok := obj.(*corev1.Node); ok {
+				o.forget(node.Name)
+			}
+		},
+	}
+}
+
+// notOlderThan returns true when given node is at least as new as given other version of it, based on
+// their resource versions. False is returned as second value when resource versions cannot be compared.
+func notOlderThan(node, other *corev1.Node) (bool, bool) {
+	version, err := strconv.ParseUint(node.ResourceVersion, 10, 64)
+	if err != nil {
+		return false, false
+	}
+
+	otherVersion, err := strconv.ParseUint(other.ResourceVersion, 10, 64)
+	if err != nil {
+		return false, false
+	}
+
+	return version >= otherVersion, true
+}
+
+// cachingNodeClient is a node client which stores updated nodes in the given overlay, so
+// subsequent reconciliation phases see changes made by the operator without waiting for
+// them to be delivered by the watch.
+type cachingNodeClient struct {
+	corev1client.NodeInterface
+
+	overlay *nodeOverlay
+	// Selector of nodes managed by the operator. If nil, all nodes are managed.
+	selector labels.Selector
+}
+
+// Update updates given node and stores the result in the overlay, unless the node is no
+// longer managed by the operator.
+func (c *cachingNodeClient) Update(
+	ctx context.Context, node *corev1.Node, opts metav1.UpdateOptions,
+) (*corev1.Node, error) {
+	updatedNode, err := c.NodeInterface.Update(ctx, node, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.selector != nil && !c.selector.Matches(labels.Set(updatedNode.Labels)) {
+		// Node will be removed from the cache by the watch.
+		c.overlay.forget(updatedNode.Name)
+
+		return updatedNode, nil
+	}
+
+	c.overlay.set(updatedNode.DeepCopy())
+
+	return updatedNode, nil
+}
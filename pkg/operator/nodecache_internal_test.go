@@ -0,0 +1,116 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+//nolint:funlen // Just many subtests.
+func Test_Node_overlay(t *testing.T) {
+	t.Parallel()
+
+	nodeWithVersion := func(resourceVersion string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "test",
+				ResourceVersion: resourceVersion,
+			},
+		}
+	}
+
+	t.Run("returns_updated_node_when_cached_node_is_older", func(t *testing.T) {
+		t.Parallel()
+
+		overlay := newNodeOverlay()
+		overlay.set(nodeWithVersion("2"))
+
+		if node := overlay.latest(nodeWithVersion("1")); node.ResourceVersion != "2" {
+			t.Fatalf("Expected updated node to be returned, got version %q", node.ResourceVersion)
+		}
+	})
+
+	t.Run("keeps_updated_node_when_informer_delivers_stale_node", func(t *testing.T) {
+		t.Parallel()
+
+		overlay := newNodeOverlay()
+		overlay.set(nodeWithVersion("2"))
+
+		overlay.eventHandler().OnUpdate(nodeWithVersion("0"), nodeWithVersion("1"))
+
+		if node := overlay.latest(nodeWithVersion("1")); node.ResourceVersion != "2" {
+			t.Fatalf("Expected updated node to be returned, got version %q", node.ResourceVersion)
+		}
+	})
+
+	t.Run("forgets_updated_node_once_cached_node_is_at_least_as_new", func(t *testing.T) {
+		t.Parallel()
+
+		overlay := newNodeOverlay()
+		overlay.set(nodeWithVersion("2"))
+
+		if node := overlay.latest(nodeWithVersion("3")); node.ResourceVersion != "3" {
+			t.Fatalf("Expected cached node to be returned, got version %q", node.ResourceVersion)
+		}
+
+		if len(overlay.nodes) != 0 {
+			t.Fatalf("Expected updated node to be forgotten, got %v", overlay.nodes)
+		}
+	})
+
+	t.Run("forgets_updated_node_once_informer_delivers_it", func(t *testing.T) {
+		t.Parallel()
+
+		overlay := newNodeOverlay()
+		overlay.set(nodeWithVersion("2"))
+
+		overlay.eventHandler().OnUpdate(nodeWithVersion("1"), nodeWithVersion("2"))
+
+		if len(overlay.nodes) != 0 {
+			t.Fatalf("Expected updated node to be forgotten, got %v", overlay.nodes)
+		}
+	})
+
+	t.Run("forgets_deleted_node", func(t *testing.T) {
+		t.Parallel()
+
+		overlay := newNodeOverlay()
+		overlay.set(nodeWithVersion("2"))
+
+		overlay.eventHandler().OnDelete(nodeWithVersion("1"))
+
+		if len(overlay.nodes) != 0 {
+			t.Fatalf("Expected deleted node to be forgotten, got %v", overlay.nodes)
+		}
+	})
+
+	t.Run("is_not_updated_with_nodes_not_matching_node_selector", func(t *testing.T) {
+		t.Parallel()
+
+		selector, err := labels.Parse("managed=true")
+		if err != nil {
+			t.Fatalf("Parsing selector: %v", err)
+		}
+
+		node := nodeWithVersion("")
+
+		overlay := newNodeOverlay()
+		nc := &cachingNodeClient{
+			NodeInterface: fake.NewSimpleClientset(node).CoreV1().Nodes(),
+			overlay:       overlay,
+			selector:      selector,
+		}
+
+		if _, err := nc.Update(context.Background(), node, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("Unexpected error updating node: %v", err)
+		}
+
+		if len(overlay.nodes) != 0 {
+			t.Fatalf("Expected node not matching selector not to be stored, got %v", overlay.nodes)
+		}
+	})
+}
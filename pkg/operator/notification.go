@@ -0,0 +1,133 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/webhook"
+)
+
+// Events in the reboot process of a node which notifications are sent for.
+const (
+	// NotificationEventRebootStarted is used when node is scheduled for rebooting.
+	NotificationEventRebootStarted = "reboot-started"
+	// NotificationEventRebootFinished is used when node finishes after reboot checks.
+	NotificationEventRebootFinished = "reboot-finished"
+)
+
+const (
+	// DefaultNotificationTemplate is a template of the notification text used when none is configured.
+	DefaultNotificationTemplate = `Node {{ .Node }} ` +
+		`{{ if eq .Event "reboot-started" }}started{{ else }}finished{{ end }} rebooting ` +
+		`at {{ .Time.UTC.Format "2006-01-02 15:04:05 MST" }}`
+
+	// Maximum time a single notification may take, so notifications do not pile up when webhook is unresponsive.
+	notificationTimeout = 30 * time.Second
+)
+
+// Notification describes an event in the reboot process of a node. It is available in
+// notification template.
+type Notification struct {
+	Node  string
+	Event string
+	Time  time.Time
+}
+
+// notificationPayload is a JSON payload POSTed to the notification webhook. Text field makes it
+// compatible with Slack incoming webhooks.
+type notificationPayload struct {
+	Text      string `json:"text"`
+	Node      string `json:"node"`
+	Event     string `json:"event"`
+	Timestamp string `json:"timestamp"`
+}
+
+// notifier sends notifications about the reboot process of nodes.
+type notifier interface {
+	notify(ctx context.Context, notification Notification) error
+}
+
+// webhookNotifier sends notifications to a webhook.
+type webhookNotifier struct {
+	sender   *webhook.Sender
+	template *template.Template
+}
+
+// newWebhookNotifier creates notifier sending notifications to given webhook URL, with text
+// rendered using given template. If template is empty, DefaultNotificationTemplate is used.
+// If secret is not empty, notifications are signed with it.
+func newWebhookNotifier(url, text string, secret []byte) (*webhookNotifier, error) {
+	tmpl, err := parseNotificationTemplate(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing notification template: %w", err)
+	}
+
+	return &webhookNotifier{
+		sender:   &webhook.Sender{URL: url, Secret: secret},
+		template: tmpl,
+	}, nil
+}
+
+// parseNotificationTemplate parses given notification template. If template is empty,
+// DefaultNotificationTemplate is used.
+func parseNotificationTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultNotificationTemplate
+	}
+
+	tmpl, err := template.New("notification").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func (w *webhookNotifier) notify(ctx context.Context, notification Notification) error {
+	text := &bytes.Buffer{}
+
+	if err := w.template.Execute(text, notification); err != nil {
+		return fmt.Errorf("rendering notification template: %w", err)
+	}
+
+	payload := notificationPayload{
+		Text:      text.String(),
+		Node:      notification.Node,
+		Event:     notification.Event,
+		Timestamp: notification.Time.UTC().Format(time.RFC3339),
+	}
+
+	if err := w.sender.Send(ctx, payload); err != nil {
+		return fmt.Errorf("sending notification: %w", err)
+	}
+
+	return nil
+}
+
+// notify sends notification about given event of the reboot process of given node, if configured.
+// Notification is sent in the background, so reconciliation is never blocked by it. Failures are only logged.
+func (k *Kontroller) notify(nodeName, event string) {
+	if k.notifier == nil {
+		return
+	}
+
+	notification := Notification{
+		Node:  nodeName,
+		Event: event,
+		Time:  time.Now(),
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		defer cancel()
+
+		if err := k.notifier.notify(ctx, notification); err != nil {
+			klog.ErrorS(err, "Failed sending notification", "node", nodeName, "event", event)
+		}
+	}()
+}
@@ -3,21 +3,35 @@ package operator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
-	"k8s.io/apimachinery/pkg/util/wait"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
 	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
@@ -25,78 +39,346 @@ import (
 )
 
 const (
-	leaderElectionEventSourceComponent = "update-operator-leader-election"
-	defaultMaxRebootingNodes           = 1
-	defaultLockType                    = resourcelock.ConfigMapsLeasesResourceLock
+	defaultLeaderElectionEventSourceComponent = "update-operator-leader-election"
+	defaultEventSourceComponent               = "update-operator"
+	defaultMaxRebootingNodes                  = 1
+	uncordonRetries                           = 3
+	defaultRampUpStep                         = 1
+	defaultLockType                           = resourcelock.LeasesResourceLock
 
-	leaderElectionResourceName = "flatcar-linux-update-operator-lock"
+	// Name of the leader election lock, if not configured otherwise. Same for all lock types,
+	// so migrating between them using configmapsleases lock type is possible.
+	defaultLeaderElectionResourceName = "flatcar-linux-update-operator-lock"
+
+	// Reason of the reboot process recorded when the configured reboot reason source annotation
+	// is not set on the node or when reboot process started without a reason being recorded.
+	rebootReasonUnknown = "unknown"
+
+	// Environment variable holding operator namespace, used when namespace is not configured.
+	namespaceEnv = "POD_NAMESPACE"
+
+	// Key of the pause ConfigMap which pauses the operator when set to "true".
+	pauseConfigMapKey = "paused"
+
+	// Reason of the event emitted when reboot process of the node takes longer than configured SLO.
+	eventReasonRebootSLOBreached = "RebootSLOBreached"
+
+	// Reason of the event emitted when node does not finish rebooting within configured reboot timeout.
+	eventReasonRebootTimeoutExceeded = "RebootTimeoutExceeded"
+
+	// Reason of the event emitted when after reboot checks of the node do not pass within configured attempts.
+	eventReasonAfterRebootChecksFailed = "AfterRebootChecksFailed"
+
+	// Reason of the event emitted when before reboot checks of the node do not pass within configured timeout.
+	eventReasonBeforeRebootChecksTimedOut = "BeforeRebootChecksTimedOut"
+
+	// Event reason for nodes which remain unschedulable after the operator marked them as schedulable.
+	eventReasonNodeRemainsUnschedulable = "NodeRemainsUnschedulable"
+
+	// Annotation preventing Karpenter from voluntarily disrupting the node, e.g. by consolidation.
+	karpenterDoNotDisruptAnnotation = "karpenter.sh/do-not-disrupt"
 
 	// Arbitrarily copied from KVO.
 	defaultLeaderElectionLease = 90 * time.Second
+	// How often waiting for acquiring leader election lock is logged.
+	leaderElectionWaitLogInterval = 30 * time.Second
 	// ReconciliationPeriod.
 	defaultReconciliationPeriod = 30 * time.Second
+	// Percentage of reconciliation period used as a default reconcile timeout, so slow loops
+	// get cancelled before the next one is due.
+	defaultReconcileTimeoutPercentage = 90
 )
 
-//nolint:godot // TODO: Complaining about not capitalized comments for variables. We should get rid of those completely.
-var (
-	// justRebootedSelector is a selector for combination of annotations
-	// expected to be on a node after it has completed a reboot.
-	//
-	// The update-operator sets constants.AnnotationOkToReboot to true to
-	// trigger a reboot, and the update-agent sets
-	// constants.AnnotationRebootNeeded and
-	// constants.AnnotationRebootInProgress to false when it has finished.
-	justRebootedSelector = fields.Set(map[string]string{
-		constants.AnnotationOkToReboot:       constants.True,
-		constants.AnnotationRebootNeeded:     constants.False,
-		constants.AnnotationRebootInProgress: constants.False,
-	}).AsSelector()
-
-	// rebootableSelector is a selector for the annotation expected to be on a node when it can be rebooted.
-	//
-	// The update-agent sets constants.AnnotationRebootNeeded to true when
-	// it would like to reboot, and false when it starts up.
-	//
-	// If constants.AnnotationRebootPaused is set to "true", the update-agent will not consider it for rebooting.
-	rebootableSelector = fields.ParseSelectorOrDie(constants.AnnotationRebootNeeded + "==" + constants.True +
-		"," + constants.AnnotationRebootPaused + "!=" + constants.True +
-		"," + constants.AnnotationOkToReboot + "!=" + constants.True +
-		"," + constants.AnnotationRebootInProgress + "!=" + constants.True)
-
-	// stillRebootingSelector is a selector for the annotation set expected to be
-	// on a node when it's in the process of rebooting.
-	stillRebootingSelector = fields.Set(map[string]string{
-		constants.AnnotationOkToReboot:   constants.True,
-		constants.AnnotationRebootNeeded: constants.True,
-	}).AsSelector()
-
-	// beforeRebootReq requires a node to be waiting for before reboot checks to complete.
-	beforeRebootReq = k8sutil.NewRequirementOrDie(constants.LabelBeforeReboot, selection.In, []string{constants.True})
-
-	// afterRebootReq requires a node to be waiting for after reboot checks to complete.
-	afterRebootReq = k8sutil.NewRequirementOrDie(constants.LabelAfterReboot, selection.In, []string{constants.True})
-
-	// notBeforeRebootReq is the inverse of the above checks.
-	notBeforeRebootReq = k8sutil.NewRequirementOrDie(
-		constants.LabelBeforeReboot, selection.NotIn, []string{constants.True})
-)
+// ErrLeadershipLost is returned by Run when the operator loses leader election lock while running,
+// e.g. when it fails to renew it in time, so the caller can decide whether to restart the operator.
+var ErrLeadershipLost = errors.New("leader election lock lost")
+
+// ErrLeaderElectionTimeout is returned by Run when the operator does not acquire leader election lock
+// within configured LeaderElectionTimeout, e.g. because it lacks permissions to create or update the lock.
+var ErrLeaderElectionTimeout = errors.New("timed out acquiring leader election lock")
 
 // Config configures a Kontroller.
 type Config struct {
 	// Kubernetes client.
 	Client kubernetes.Interface
-	// Annotations to look for before and after reboots.
+	// Annotations to look for before and after reboots, in format "<key>[=<value>]". Annotations
+	// without value are expected to be set to "true".
 	BeforeRebootAnnotations []string
 	AfterRebootAnnotations  []string
+	// Annotation set to "true", e.g. by custom drain logic running on the node, once the node is drained.
+	// If set, it is required before a reboot is allowed, in addition to BeforeRebootAnnotations, and
+	// waiting for it is logged as waiting for the drain to complete.
+	DrainCompleteAnnotation string
+	// Human-readable descriptions of before and after reboot annotations, in format
+	// "<annotation>=<description>", included in logs when waiting for the annotations.
+	AnnotationDescriptions []string
 	// Reboot window.
-	RebootWindowStart    string
-	RebootWindowLength   string
-	Namespace            string
-	LockID               string
-	LockType             string
-	ReconciliationPeriod time.Duration
-	LeaderElectionLease  time.Duration
-	MaxRebootingNodes    int
+	RebootWindowStart  string
+	RebootWindowLength string
+	// Additional reboot windows. Nodes are scheduled for rebooting when any of configured
+	// reboot windows is open.
+	RebootWindows []RebootWindow
+	// Periods of time during which nodes must not be scheduled for rebooting, even if
+	// a reboot window is open, e.g. during a change freeze.
+	ExclusionWindows []RebootWindow
+	// Reboot windows by profile name. Nodes with the reboot window profile annotation set to a name of
+	// a profile are scheduled for rebooting when any of the profile windows is open, instead of the default
+	// reboot windows. Profiles without windows allow rebooting nodes at any time. Exclusion windows apply
+	// to all nodes.
+	RebootWindowProfiles map[string][]RebootWindow
+	// Namespace where the operator keeps its leader election lock and emits events. If empty,
+	// value of POD_NAMESPACE environment variable is used.
+	Namespace string
+	// Identity of the operator instance in leader election. Not required when leader election is disabled.
+	LockID string
+	// If true, the operator does not acquire leader election lock and starts reconciling immediately,
+	// e.g. when running locally or in tests. Only a single instance of the operator may run then.
+	DisableLeaderElection bool
+	// Type of the resource used for leader election lock, either "leases" or "configmapsleases".
+	// If empty, "leases" is used.
+	LockType string
+	// Name of the resource used for leader election lock. Instances managing disjoint sets of nodes
+	// in the same namespace must use different names. If empty, "flatcar-linux-update-operator-lock" is used.
+	LeaderElectionResourceName string
+	// Component reported as the source of events about nodes. Instances managing different nodes may use
+	// different components to tell their events apart. If empty, "update-operator" is used.
+	EventSourceComponent string
+	// Component reported as the source of events about leader election. If empty,
+	// "update-operator-leader-election" is used.
+	LeaderElectionEventSourceComponent string
+	ReconciliationPeriod               time.Duration
+	LeaderElectionLease                time.Duration
+	// Maximum duration Run waits for acquiring leader election lock before returning
+	// ErrLeaderElectionTimeout. Acquiring the lock is retried in the meantime. If zero,
+	// Run waits indefinitely, e.g. while other instance holds the lock.
+	LeaderElectionTimeout time.Duration
+	MaxRebootingNodes     int
+	// Number of consecutive successful reboots after which the number of nodes which may go through the reboot
+	// process at once is raised by RampUpStep, starting from 1 up to MaxRebootingNodes. A reboot is successful
+	// once its after reboot checks pass and failing an attempt of after reboot checks resets the limit to 1.
+	// Number of successful reboots is kept in memory, so ramp-up starts over after operator restarts or
+	// leadership changes. If zero, MaxRebootingNodes applies from the start.
+	RampUpSuccessfulReboots int
+	// Number of nodes by which the number of nodes which may go through the reboot process at once is raised
+	// once RampUpSuccessfulReboots consecutive reboots succeed. If zero, 1 is used.
+	RampUpStep int
+	// If true, reconciliation is also triggered when reboot related labels or annotations of a node change,
+	// rather than only every ReconciliationPeriod, which still applies as a safety net.
+	WatchNodes bool
+	// Minimum fraction of managed nodes, between 0 and 1, which must remain Ready. Nodes which are not Ready
+	// or are going through the reboot process count as not Ready. Ready nodes are not scheduled for rebooting
+	// when it would drop the fraction of Ready nodes below this value. If zero, no minimum is enforced.
+	MinReadyFraction float64
+	// Time to wait after observing a node change before reconciling when WatchNodes is enabled, so changes
+	// made to multiple nodes at once are handled together. If zero, 1 second is used.
+	NodeChangeDebounce time.Duration
+	// Time to wait before retrying reconciliation after it fails, rather than waiting for the full
+	// ReconciliationPeriod, so transient errors like update conflicts do not slow down the reboot process.
+	// It is doubled after each consecutive failure, up to ReconciliationPeriod. If zero, 1 second is used.
+	ErrorRetryBackoff time.Duration
+	// Label selector which nodes must match in addition to having all before reboot
+	// annotations set before a reboot is allowed, e.g. "fluo-approved=true".
+	BeforeRebootLabelSelector string
+	// Labels removed from the node once its reboot is approved and when its next reboot process starts,
+	// so approval is not reused for the next reboot, e.g. "fluo-approved". If nil, keys of equality
+	// requirements of BeforeRebootLabelSelector, like "fluo-approved=true", are used. Labels which the
+	// selector only checks for existence are never removed.
+	BeforeRebootApprovalLabels []string
+	// Label selector matching nodes which should not be considered for rebooting,
+	// same as nodes with reboot-paused annotation set to true.
+	PauseLabelSelector string
+	// Label selector matching nodes which are never scheduled for rebooting, e.g. "flatcar.io/reboot-exclude=true".
+	// Unlike with PauseLabelSelector, state of matching nodes is left untouched, so reboots already approved
+	// are not cancelled.
+	ExcludeNodeLabel string
+	// If true, nodes are scheduled for rebooting only when version of the pending update differs from the
+	// version they run, as reported by the update-agent, so nodes with stale reboot-needed annotation are not
+	// rebooted needlessly. Nodes without reported versions are rebooted as usual.
+	OnlyRebootOutdated bool
+	// Annotation holding version of the pending update, used when OnlyRebootOutdated is enabled.
+	// If empty, annotation set by the update-agent is used.
+	NewVersionAnnotation string
+	// Annotation from which reason of the reboot process is read when it starts, e.g. version of the pending
+	// update. If empty, annotation holding version of the pending update set by the update-agent is used.
+	RebootReasonSourceAnnotation string
+	// Annotation to which reason of the reboot process is written when it finishes. If empty,
+	// "flatcar-linux-update.v1.flatcar-linux.net/last-reboot-reason" is used.
+	LastRebootReasonAnnotation string
+	// Prefix of labels and annotations used to coordinate with the update-agent, e.g. "example.com/", to avoid
+	// collisions with other tools. It must match the prefix configured for the update-agent. If empty,
+	// constants.Prefix is used.
+	AnnotationPrefix string
+	// Name of the node the operator runs on. If set, this node will be rebooted
+	// only after all other nodes requiring a reboot, to avoid leadership changes mid-rollout.
+	NodeName string
+	// Reconciliation phases to run, in order. If empty, DefaultReconcilePhases are used.
+	ReconcilePhases []string
+	// If true, nodes going through the reboot process are annotated to prevent Karpenter
+	// from disrupting them. The annotation is removed once the reboot process finishes.
+	KarpenterDoNotDisrupt bool
+	// If true, nodes are marked as unschedulable when they are scheduled for rebooting and marked
+	// as schedulable again once after reboot checks pass. Nodes which were already unschedulable
+	// are left unschedulable.
+	CordonBeforeReboot bool
+	// Maximum duration of a single reconciliation loop. If exceeded, outstanding operations are cancelled
+	// and the next loop starts from scratch. Must not exceed ReconciliationPeriod. If zero,
+	// 90% of ReconciliationPeriod is used.
+	ReconcileTimeout time.Duration
+	// Minimum time which must pass since an update became available on the node before the node
+	// is considered for rebooting. Nodes without known update availability time are not delayed.
+	UpdateMaturityDelay time.Duration
+	// Expected maximum duration of the reboot process of a single node. When exceeded, a warning event
+	// is emitted for the node once the reboot process finishes. If zero, no SLO is enforced.
+	RebootSLO time.Duration
+	// Minimum time which rebooted node must be continuously Ready before it is labeled for after reboot checks,
+	// so nodes briefly flapping Ready right after boot are not considered rebooted yet. If zero, nodes are
+	// labeled as soon as they finish rebooting.
+	PostRebootStabilizationPeriod time.Duration
+	// Minimum time which must pass since a node finished the reboot process before the next node is scheduled
+	// for rebooting, e.g. to let monitoring settle. Completion time is kept in memory, so it is not preserved
+	// across operator restarts or leadership changes. If zero, nodes are scheduled for rebooting immediately.
+	InterRebootDelay time.Duration
+	// Label selector limiting which nodes are managed by the operator, e.g. "flatcar.io/managed=true".
+	// If empty, all nodes are managed.
+	NodeSelector string
+	// Annotation holding integer reboot priority of the node, e.g. "flatcar.io/reboot-priority". When multiple
+	// nodes require a reboot, nodes with lower priority are rebooted first. Nodes without the annotation have
+	// priority 0. Nodes with equal priority are rebooted in order of their names. If empty, nodes are rebooted
+	// in order of their names.
+	RebootPriorityAnnotation string
+	// Strategy determining in which order nodes requiring a reboot are rebooted. If empty,
+	// NodeSelectionStrategyPriority is used.
+	NodeSelectionStrategy NodeSelectionStrategy
+	// Policy for nodes requiring a reboot which are not Ready. If empty, such nodes are scheduled for rebooting
+	// like any other node.
+	NotReadyNodePolicy NotReadyNodePolicy
+	// Annotation holding UNIX timestamp of when the node booted, used by NodeSelectionStrategyUptime. Nodes
+	// without the annotation are considered booted when they last became Ready. If empty,
+	// constants.AnnotationBootTime is used.
+	BootTimeAnnotation string
+	// Label holding the failure-domain zone of the node, e.g. "topology.kubernetes.io/zone". If set, at most
+	// one node from each zone is rebooting at a time. Nodes without the label are not limited. If empty,
+	// nodes are rebooted regardless of their zones.
+	ZoneLabelKey string
+	// Label holding the reboot group of the node, e.g. "node.kubernetes.io/pool". If set, MaxRebootingNodes
+	// applies to each group of nodes with the same label value independently. Nodes without the label form
+	// a group of their own. If empty, MaxRebootingNodes applies to the whole cluster.
+	RebootGroupLabelKey string
+	// Label holding integer rollout order of the node, e.g. "flatcar.io/rollout-order". If set, nodes are
+	// rebooted group by group in ascending order of label values. Nodes of the next group are not scheduled
+	// for rebooting until no node of the current group requires a reboot or goes through the reboot process.
+	// Nodes without the label have order 0. If empty, nodes are rebooted regardless of their order.
+	RolloutOrderLabelKey string
+	// Name of the ConfigMap in operator namespace used as a cluster-wide pause switch. When the ConfigMap
+	// has "paused" key set to "true", no new nodes are scheduled for rebooting, while reboots already in
+	// progress are allowed to finish. If empty, the operator cannot be paused this way.
+	PauseConfigMap string
+	// Name of the ConfigMap in operator namespace holding the reboot window under "rebootWindowStart" and
+	// "rebootWindowLength" keys, which is read every reconciliation loop, so the reboot window can be adjusted
+	// without restarting the operator. Reboot window from the ConfigMap replaces the window configured using
+	// RebootWindowStart and RebootWindowLength. Invalid reboot windows are logged and the last valid one is
+	// kept. If the ConfigMap does not exist or holds no reboot window, configured reboot windows are used.
+	// If empty, reboot windows cannot be adjusted this way.
+	RebootWindowConfigMap string
+	// Name of the ConfigMap in operator namespace in which names of nodes going through the reboot process
+	// are recorded at the end of every reconciliation loop, as a comma-separated list under "rebootingNodes"
+	// key, empty when no node is rebooting. The ConfigMap is created if it does not exist. If empty, rebooting
+	// nodes are not recorded.
+	RebootingNodesConfigMap string
+	// Address to serve /healthz and /readyz endpoints on, e.g. ":8081". If empty, health endpoints are not served.
+	HealthAddress string
+	// Address to serve /status endpoint on, e.g. ":8082", which returns the state of the reboot process of managed
+	// nodes as JSON, as of the last reconciliation loop. If empty, status endpoint is not served.
+	StatusAddress string
+	// Duration without successful reconciliation after which the operator is reported as unhealthy.
+	// If zero, 10 reconciliation periods are used.
+	UnhealthyAfter time.Duration
+	// Maximum duration since approving the reboot of the node until the node finishes rebooting. When exceeded,
+	// the node is considered stuck and a warning event is emitted for it. If zero, nodes are never considered stuck.
+	RebootTimeout time.Duration
+	// If true, nodes stuck rebooting do not count towards MaxRebootingNodes, so other nodes can be rebooted.
+	SkipStuckNodes bool
+	// Pod annotation marking pods which must not be evicted, e.g. "flatcar.io/do-not-evict". Nodes running
+	// pods with this annotation set to "true" are not scheduled for rebooting. If empty, no pods block rebooting.
+	BlockingPodAnnotation string
+	// If true, nodes running pods selected by a PodDisruptionBudget which currently allows no disruptions are not
+	// scheduled for rebooting, as draining them would stall on rejected evictions. Requires permission to list
+	// PodDisruptionBudgets.
+	CheckPodDisruptionBudgets bool
+	// Namespaces of critical workloads, e.g. "payments". Nodes running pods from these namespaces, other than
+	// completed pods and pods owned by DaemonSets, are scheduled for rebooting only after other nodes requiring
+	// a reboot, so those pods are moved by draining as few times as possible. If empty, no namespace is protected.
+	ProtectedNamespaces []string
+	// If true, nodes running pods from ProtectedNamespaces are not scheduled for rebooting at all until the pods
+	// are gone, e.g. when nodes are not drained before rebooting.
+	BlockOnProtectedNamespaces bool
+	// If true, nodes running pods owned by Jobs, including ones created by CronJobs, are not scheduled for
+	// rebooting until the pods complete, as evicting them may waste hours of computation. If false, such
+	// nodes are rebooted like other nodes.
+	DeferNodesRunningJobs bool
+	// Taint added to nodes when they are scheduled for rebooting, alongside the before-reboot label, and removed
+	// once after reboot checks pass, e.g. to route traffic away from rebooting nodes. If nil, nodes are not tainted.
+	RebootTaint *corev1.Taint
+	// URL of the webhook, e.g. Slack incoming webhook, which is notified when nodes start and finish rebooting.
+	// Notifications are sent in the background and failures are only logged. If empty, no notifications are sent.
+	NotificationWebhookURL string
+	// Go template of the notification text, which has access to fields of Notification. If empty,
+	// DefaultNotificationTemplate is used.
+	NotificationTemplate string
+	// Secret used for signing notifications, so webhook can verify they are sent by the operator.
+	// Signature is sent in webhook.SignatureHeader. If empty, notifications are not signed.
+	NotificationWebhookSecret []byte
+	// Maximum number of nodes going through the reboot process at once when forcefully rebooting a node
+	// using ForceReboot. If zero, twice MaxRebootingNodes is used.
+	ForceRebootLimit int
+	// Maximum duration a node may wait for before reboot checks. When exceeded, BeforeRebootTimeoutAction is
+	// taken and a warning event is emitted for the node. If zero, the operator waits for before reboot checks
+	// indefinitely.
+	BeforeRebootTimeout time.Duration
+	// Action taken when before reboot checks of the node do not pass within BeforeRebootTimeout. If empty,
+	// BeforeRebootTimeoutActionAbort is used.
+	BeforeRebootTimeoutAction BeforeRebootTimeoutAction
+	// Duration a node running before reboot checks must keep not wanting to reboot, e.g. because a before
+	// reboot hook temporarily removed the reboot-needed annotation, before its before reboot checks are
+	// reverted. If zero, before reboot checks are reverted as soon as the node no longer wants to reboot.
+	BeforeRebootRevertGracePeriod time.Duration
+	// Maximum duration of a single attempt of after reboot checks. When exceeded, after reboot checks of
+	// the node are retried after a backoff and other nodes may proceed with rebooting in the meantime.
+	// If zero, the operator waits for after reboot checks indefinitely.
+	AfterRebootCheckTimeout time.Duration
+	// Backoff before retrying after reboot checks for the first time. It doubles with every failed attempt,
+	// up to 1 hour or the configured backoff, if longer. If zero, 1 minute is used.
+	AfterRebootCheckBackoff time.Duration
+	// Number of failed attempts of after reboot checks after which the node is labeled as failed to reboot
+	// and no longer retried. If zero, 3 attempts are made.
+	AfterRebootCheckMaxAttempts int
+	// Workloads which must be ready before after reboot checks of the node pass, in format
+	// "<kind>/<namespace>/<name>", where kind is either "daemonset" or "deployment". DaemonSets must have
+	// a ready pod on the node, while Deployments must have all their replicas ready.
+	AfterRebootReadyWorkloads []string
+	// Custom checks which must pass before after reboot checks of the node pass, in addition to
+	// after reboot annotations and AfterRebootReadyWorkloads. Errors are logged and checks are retried
+	// in the next reconciliation loop.
+	AfterRebootChecks []AfterRebootCheck
+	// Template of pods of a Job created in operator namespace for each rebooted node, pinned to the node,
+	// which must complete successfully before after reboot checks of the node pass, e.g. to catch boot-time
+	// regressions before other nodes get rebooted. If the Job fails, the node is labeled as failed to reboot.
+	// If nil, no Job is created. Requires permission to create, get and delete Jobs.
+	AfterRebootSmokeTestPodTemplate *corev1.PodTemplateSpec
+	// Time within which smoke test Job must succeed, otherwise it fails. If zero, 10 minutes are used.
+	AfterRebootSmokeTestTimeout time.Duration
+	// Custom predicates which nodes requiring a reboot must satisfy, in addition to built-in ones, e.g. nodes
+	// not being paused, before they are considered for rebooting. Reason of the first unsatisfied predicate
+	// is logged, recorded in metrics and reported in node status.
+	NodeEligibilityPredicates []NodeEligibilityPredicate
+}
+
+// RebootWindow is a repeating period of time during which nodes may be scheduled for rebooting.
+type RebootWindow struct {
+	// Day of week (optional) and time of day at which the window starts, e.g. "Mon 14:00".
+	Start string
+	// Length of the window, e.g. "1h30m".
+	Length string
 }
 
 // Kontroller implement operator part of FLUO.
@@ -104,52 +386,307 @@ type Kontroller struct {
 	kc kubernetes.Interface
 	nc corev1client.NodeInterface
 
+	// Keys of labels and annotations used to coordinate with the update-agent.
+	keys constants.Keys
+
+	// Selectors identifying phases of the reboot process, built for keys.
+	selectors k8sutil.PhaseSelectors
+
+	notBeforeRebootReq *labels.Requirement
+	notAfterRebootReq  *labels.Requirement
+
 	// Annotations to look for before and after reboots.
 	beforeRebootAnnotations []string
 	afterRebootAnnotations  []string
 
+	// Values which before and after reboot annotations are expected to have, by annotation.
+	beforeRebootValues map[string]string
+	afterRebootValues  map[string]string
+
+	// Annotation signaling that the node is drained. Empty when not configured.
+	drainCompleteAnnotation string
+
+	// Descriptions of before and after reboot annotations, by annotation.
+	annotationDescriptions map[string]string
+
 	// Namespace is the kubernetes namespace any resources (e.g. locks,
 	// configmaps, agents) should be created and read under.
 	// It will be set to the namespace the operator is running in automatically.
 	namespace string
 
-	// Reboot window.
-	rebootWindow *Periodic
+	// Reboot windows. Empty when rebooting is allowed at any time.
+	rebootWindows []*Periodic
+
+	// Reboot windows configured using RebootWindows, used together with the reboot window read from
+	// the reboot window ConfigMap.
+	additionalRebootWindows []*Periodic
+
+	// Name of the ConfigMap holding the reboot window. Empty when not configured.
+	rebootWindowConfigMap string
+
+	// Reboot window last read from the reboot window ConfigMap. Nil when configured reboot windows are used.
+	liveRebootWindow *liveRebootWindow
+
+	// Exclusion windows, taking precedence over reboot windows.
+	exclusionWindows []*Periodic
+
+	// Reboot windows by profile name. Empty when no profiles are configured.
+	rebootWindowProfiles map[string][]*Periodic
+
+	// Informer caching nodes managed by the operator, so reconciliation does not
+	// need to list all nodes from the API server in every loop.
+	nodeInformer        cache.SharedIndexInformer
+	nodeLister          corev1listers.NodeLister
+	nodeInformerStarted sync.Once
+	// Nodes updated by the operator, which the informer did not catch up with yet.
+	nodeOverlay *nodeOverlay
+
+	// Label gates used in addition to annotations. Nil when not configured.
+	beforeRebootLabelSelector labels.Selector
+	pauseLabelSelector        labels.Selector
+
+	// Approval labels removed from nodes, so approval is not reused for the next reboot.
+	beforeRebootApprovalLabels []string
+	excludeLabelSelector       labels.Selector
+
+	onlyRebootOutdated bool
+
+	// Annotation holding version of the pending update.
+	newVersionAnnotation string
+
+	// Annotations from which reason of the reboot process is read and to which it is written
+	// once the reboot process finishes.
+	rebootReasonSourceAnnotation string
+	lastRebootReasonAnnotation   string
 
 	maxRebootingNodes int
 
+	rampUpSuccessfulReboots int
+
+	rampUpStep int
+
+	// Number of consecutive successful reboots since the operator started or the last failed reboot.
+	consecutiveSuccessfulReboots int
+
+	minReadyFraction float64
+
+	// Name of the node the operator runs on.
+	nodeName string
+
+	karpenterDoNotDisrupt bool
+
+	cordonBeforeReboot bool
+
+	updateMaturityDelay time.Duration
+
+	rebootSLO time.Duration
+
+	stabilizationPeriod time.Duration
+
+	interRebootDelay time.Duration
+
+	// Time when the last node finished the reboot process. Zero when no node finished it yet.
+	lastRebootFinished time.Time
+
+	// Annotation holding reboot priority of the node. Empty when not configured.
+	rebootPriorityAnnotation string
+
+	// Strategy determining order in which nodes are rebooted.
+	nodeSelectionStrategy NodeSelectionStrategy
+
+	// Policy for nodes requiring a reboot which are not Ready. Empty when not configured.
+	notReadyNodePolicy NotReadyNodePolicy
+
+	// Annotation holding boot time of the node.
+	bootTimeAnnotation string
+
+	// Label holding the zone of the node. Empty when rebooting nodes is not serialized per zone.
+	zoneLabelKey string
+
+	// Label holding the reboot group of the node. Empty when MaxRebootingNodes applies cluster-wide.
+	rebootGroupLabelKey string
+
+	// Label holding the rollout order of the node. Empty when nodes are not rebooted group by group.
+	rolloutOrderLabelKey string
+
+	// Name of the ConfigMap pausing the operator. Empty when not configured.
+	pauseConfigMap string
+
+	// Name of the ConfigMap recording rebooting nodes. Empty when not configured.
+	rebootingNodesConfigMap string
+
+	// Address to serve health endpoints on. Empty when not configured.
+	healthAddress string
+
+	health *healthStatus
+
+	// Address to serve status endpoint on. Empty when not configured.
+	statusAddress string
+
+	status *statusCache
+
+	rebootTimeout time.Duration
+
+	skipStuckNodes bool
+
+	// Annotation marking pods which block rebooting of their nodes. Empty when not configured.
+	blockingPodAnnotation string
+
+	checkPodDisruptionBudgets bool
+
+	// Namespaces whose pods defer or block rebooting of the node running them. Empty when not configured.
+	protectedNamespaces        []string
+	blockOnProtectedNamespaces bool
+
+	deferNodesRunningJobs bool
+
+	// Taint added to nodes going through the reboot process. Nil when not configured.
+	rebootTaint *corev1.Taint
+
+	// Notifier for events of the reboot process. Nil when not configured.
+	notifier notifier
+
+	// Label selector limiting which nodes are managed. Nil when all nodes are managed.
+	nodeSelector labels.Selector
+
+	forceRebootLimit int
+
+	beforeRebootTimeout time.Duration
+
+	beforeRebootTimeoutAction BeforeRebootTimeoutAction
+
+	revertGracePeriod time.Duration
+
+	afterRebootCheckTimeout time.Duration
+
+	afterRebootBackoff time.Duration
+
+	afterRebootMaxAttempts int
+
+	// Checks run for nodes with all after reboot annotations set. Empty when not configured.
+	afterRebootChecks []AfterRebootCheck
+
+	// Template of pods of smoke test Jobs. Nil when not configured.
+	smokeTestPodTemplate *corev1.PodTemplateSpec
+	smokeTestTimeout     time.Duration
+
+	// Built-in and configured predicates deciding which nodes requiring a reboot may be scheduled for rebooting.
+	nodeEligibilityPredicates []NodeEligibilityPredicate
+
+	// Nodes reported as stuck rebooting, with values of their reboot approval time annotation,
+	// so each stuck reboot is reported only once.
+	reportedStuckNodes map[string]string
+
+	// Recorder for events about nodes.
+	eventRecorder record.EventRecorder
+
 	reconciliationPeriod time.Duration
 
+	reconcileTimeout time.Duration
+
+	// Channel triggering reconciliation on node changes. Nil when watching nodes is disabled.
+	reconcileTrigger chan struct{}
+
+	nodeChangeDebounce time.Duration
+
+	// Rate limiter deciding when to retry failed reconciliation.
+	errorRetryLimiter workqueue.RateLimiter
+
 	leaderElectionLease time.Duration
 
+	leaderElectionTimeout time.Duration
+
 	resourceLock resourcelock.Interface
+
+	// Reconciliation phases run in each loop cycle.
+	phases []reconcilePhase
+
+	// Function called after each loop cycle, if set. Used by tests to synchronize with reconciliation.
+	reconciled func()
 }
 
 // New initializes a new Kontroller.
 func New(config Config) (*Kontroller, error) {
+	if config.Namespace == "" {
+		config.Namespace = os.Getenv(namespaceEnv)
+	}
+
 	if err := checkConfig(config); err != nil {
 		return nil, fmt.Errorf("check configuration: %w", err)
 	}
 
-	resourceLock, err := newResourceLock(config)
+	var resourceLock resourcelock.Interface
+
+	if !config.DisableLeaderElection {
+		lock, err := newResourceLock(config)
+		if err != nil {
+			return nil, fmt.Errorf("creating new resource lock: %w", err)
+		}
+
+		resourceLock = lock
+	}
+
+	rebootWindows, err := parseRebootWindows(config)
+	if err != nil {
+		return nil, fmt.Errorf("parsing reboot windows: %w", err)
+	}
+
+	additionalRebootWindows, err := parseWindows(config.RebootWindows)
 	if err != nil {
-		return nil, fmt.Errorf("creating new resource lock: %w", err)
+		return nil, fmt.Errorf("parsing additional reboot windows: %w", err)
 	}
 
-	var rebootWindow *Periodic
+	rebootWindowProfiles, err := parseWindowProfiles(config.RebootWindowProfiles)
+	if err != nil {
+		return nil, fmt.Errorf("parsing reboot window profiles: %w", err)
+	}
 
-	if config.RebootWindowStart != "" && config.RebootWindowLength != "" {
-		rw, err := ParsePeriodic(config.RebootWindowStart, config.RebootWindowLength)
-		if err != nil {
-			return nil, fmt.Errorf("parsing reboot window: %w", err)
-		}
+	exclusionWindows, err := parseWindows(config.ExclusionWindows)
+	if err != nil {
+		return nil, fmt.Errorf("parsing exclusion windows: %w", err)
+	}
+
+	beforeRebootAnnotations, beforeRebootValues, err := parseAnnotationChecks(beforeRebootAnnotationChecks(config))
+	if err != nil {
+		return nil, fmt.Errorf("parsing before reboot annotations: %w", err)
+	}
+
+	annotationDescriptions, err := parseAnnotationDescriptions(config.AnnotationDescriptions)
+	if err != nil {
+		return nil, fmt.Errorf("parsing annotation descriptions: %w", err)
+	}
+
+	afterRebootAnnotations, afterRebootValues, err := parseAnnotationChecks(config.AfterRebootAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("parsing after reboot annotations: %w", err)
+	}
+
+	nodeSelector, err := parseOptionalLabelSelector(config.NodeSelector)
+	if err != nil {
+		return nil, fmt.Errorf("parsing node selector: %w", err)
+	}
+
+	beforeRebootLabelSelector, err := parseOptionalLabelSelector(config.BeforeRebootLabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("parsing before reboot label selector: %w", err)
+	}
+
+	pauseLabelSelector, err := parseOptionalLabelSelector(config.PauseLabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("parsing pause label selector: %w", err)
+	}
 
-		rebootWindow = rw
+	excludeLabelSelector, err := parseOptionalLabelSelector(config.ExcludeNodeLabel)
+	if err != nil {
+		return nil, fmt.Errorf("parsing exclude node label: %w", err)
 	}
 
-	reconciliationPeriod := config.ReconciliationPeriod
-	if reconciliationPeriod == 0 {
-		reconciliationPeriod = defaultReconciliationPeriod
+	reconciliationPeriod := config.reconciliationPeriod()
+	reconcileTimeout := config.reconcileTimeout()
+
+	unhealthyAfter := config.UnhealthyAfter
+	if unhealthyAfter == 0 {
+		unhealthyAfter = reconciliationPeriod * defaultUnhealthyAfterPeriods
 	}
 
 	leaderElectionLeaseDuration := config.LeaderElectionLease
@@ -162,18 +699,289 @@ func New(config Config) (*Kontroller, error) {
 		maxRebootingNodes = defaultMaxRebootingNodes
 	}
 
-	return &Kontroller{
-		kc:                      config.Client,
-		nc:                      config.Client.CoreV1().Nodes(),
-		beforeRebootAnnotations: config.BeforeRebootAnnotations,
-		afterRebootAnnotations:  config.AfterRebootAnnotations,
-		namespace:               config.Namespace,
-		rebootWindow:            rebootWindow,
-		maxRebootingNodes:       maxRebootingNodes,
-		reconciliationPeriod:    reconciliationPeriod,
-		leaderElectionLease:     leaderElectionLeaseDuration,
-		resourceLock:            resourceLock,
-	}, nil
+	rampUpStep := config.RampUpStep
+	if rampUpStep == 0 {
+		rampUpStep = defaultRampUpStep
+	}
+
+	forceRebootLimit := config.ForceRebootLimit
+	if forceRebootLimit == 0 {
+		forceRebootLimit = maxRebootingNodes * defaultForceRebootLimitFactor
+	}
+
+	beforeRebootTimeoutAction := config.BeforeRebootTimeoutAction
+	if beforeRebootTimeoutAction == "" {
+		beforeRebootTimeoutAction = BeforeRebootTimeoutActionAbort
+	}
+
+	afterRebootBackoff := config.AfterRebootCheckBackoff
+	if afterRebootBackoff == 0 {
+		afterRebootBackoff = defaultAfterRebootCheckBackoff
+	}
+
+	afterRebootMaxAttempts := config.AfterRebootCheckMaxAttempts
+	if afterRebootMaxAttempts == 0 {
+		afterRebootMaxAttempts = defaultAfterRebootCheckMaxAttempts
+	}
+
+	afterRebootChecks, err := afterRebootChecks(config)
+	if err != nil {
+		return nil, fmt.Errorf("creating after reboot checks: %w", err)
+	}
+
+	nodeChangeDebounce := config.NodeChangeDebounce
+	if nodeChangeDebounce == 0 {
+		nodeChangeDebounce = defaultNodeChangeDebounce
+	}
+
+	smokeTestTimeout := config.AfterRebootSmokeTestTimeout
+	if smokeTestTimeout == 0 {
+		smokeTestTimeout = defaultAfterRebootSmokeTestTimeout
+	}
+
+	errorRetryBackoff := config.ErrorRetryBackoff
+	if errorRetryBackoff == 0 {
+		errorRetryBackoff = defaultErrorRetryBackoff
+	}
+
+	keys := constants.NewKeys(config.AnnotationPrefix)
+
+	newVersionAnnotation := config.NewVersionAnnotation
+	if newVersionAnnotation == "" {
+		newVersionAnnotation = keys.AnnotationNewVersion
+	}
+
+	bootTimeAnnotation := config.BootTimeAnnotation
+	if bootTimeAnnotation == "" {
+		bootTimeAnnotation = keys.AnnotationBootTime
+	}
+
+	rebootReasonSourceAnnotation := config.RebootReasonSourceAnnotation
+	if rebootReasonSourceAnnotation == "" {
+		rebootReasonSourceAnnotation = keys.AnnotationNewVersion
+	}
+
+	lastRebootReasonAnnotation := config.LastRebootReasonAnnotation
+	if lastRebootReasonAnnotation == "" {
+		lastRebootReasonAnnotation = keys.AnnotationLastRebootReason
+	}
+
+	reconcilePhases := config.ReconcilePhases
+	if len(reconcilePhases) == 0 {
+		reconcilePhases = DefaultReconcilePhases()
+	}
+
+	nodeInformer := newNodeInformer(config.Client, nodeSelector)
+
+	eventRecorder := newEventRecorder(config)
+
+	nodeOverlay := newNodeOverlay()
+	nodeInformer.AddEventHandler(nodeOverlay.eventHandler())
+
+	nodeClient := &cachingNodeClient{
+		NodeInterface: &k8sutil.AnnotationsSizeGuard{
+			NodeInterface: config.Client.CoreV1().Nodes(),
+			Keys:          keys,
+			EventRecorder: eventRecorder,
+		},
+		overlay:  nodeOverlay,
+		selector: nodeSelector,
+	}
+
+	kontroller := &Kontroller{
+		kc:                           config.Client,
+		nc:                           nodeClient,
+		keys:                         keys,
+		selectors:                    k8sutil.NewPhaseSelectors(keys),
+		notBeforeRebootReq:           notTrueRequirement(keys.LabelBeforeReboot),
+		notAfterRebootReq:            notTrueRequirement(keys.LabelAfterReboot),
+		beforeRebootAnnotations:      beforeRebootAnnotations,
+		drainCompleteAnnotation:      config.DrainCompleteAnnotation,
+		annotationDescriptions:       annotationDescriptions,
+		afterRebootAnnotations:       afterRebootAnnotations,
+		beforeRebootValues:           beforeRebootValues,
+		afterRebootValues:            afterRebootValues,
+		namespace:                    config.Namespace,
+		rebootWindows:                rebootWindows,
+		additionalRebootWindows:      additionalRebootWindows,
+		rebootWindowConfigMap:        config.RebootWindowConfigMap,
+		exclusionWindows:             exclusionWindows,
+		rebootWindowProfiles:         rebootWindowProfiles,
+		nodeInformer:                 nodeInformer,
+		nodeLister:                   corev1listers.NewNodeLister(nodeInformer.GetIndexer()),
+		nodeOverlay:                  nodeOverlay,
+		beforeRebootLabelSelector:    beforeRebootLabelSelector,
+		beforeRebootApprovalLabels:   beforeRebootApprovalLabels(config, beforeRebootLabelSelector),
+		pauseLabelSelector:           pauseLabelSelector,
+		excludeLabelSelector:         excludeLabelSelector,
+		onlyRebootOutdated:           config.OnlyRebootOutdated,
+		newVersionAnnotation:         newVersionAnnotation,
+		rebootReasonSourceAnnotation: rebootReasonSourceAnnotation,
+		lastRebootReasonAnnotation:   lastRebootReasonAnnotation,
+		maxRebootingNodes:            maxRebootingNodes,
+		rampUpSuccessfulReboots:      config.RampUpSuccessfulReboots,
+		rampUpStep:                   rampUpStep,
+		minReadyFraction:             config.MinReadyFraction,
+		nodeName:                     config.NodeName,
+		karpenterDoNotDisrupt:        config.KarpenterDoNotDisrupt,
+		cordonBeforeReboot:           config.CordonBeforeReboot,
+		updateMaturityDelay:          config.UpdateMaturityDelay,
+		rebootSLO:                    config.RebootSLO,
+		stabilizationPeriod:          config.PostRebootStabilizationPeriod,
+		interRebootDelay:             config.InterRebootDelay,
+		rebootPriorityAnnotation:     config.RebootPriorityAnnotation,
+		nodeSelectionStrategy:        config.NodeSelectionStrategy,
+		notReadyNodePolicy:           config.NotReadyNodePolicy,
+		bootTimeAnnotation:           bootTimeAnnotation,
+		zoneLabelKey:                 config.ZoneLabelKey,
+		rebootGroupLabelKey:          config.RebootGroupLabelKey,
+		rolloutOrderLabelKey:         config.RolloutOrderLabelKey,
+		pauseConfigMap:               config.PauseConfigMap,
+		rebootingNodesConfigMap:      config.RebootingNodesConfigMap,
+		healthAddress:                config.HealthAddress,
+		health:                       &healthStatus{unhealthyAfter: unhealthyAfter},
+		statusAddress:                config.StatusAddress,
+		status:                       &statusCache{},
+		rebootTimeout:                config.RebootTimeout,
+		skipStuckNodes:               config.SkipStuckNodes,
+		blockingPodAnnotation:        config.BlockingPodAnnotation,
+		checkPodDisruptionBudgets:    config.CheckPodDisruptionBudgets,
+		protectedNamespaces:          config.ProtectedNamespaces,
+		blockOnProtectedNamespaces:   config.BlockOnProtectedNamespaces,
+		deferNodesRunningJobs:        config.DeferNodesRunningJobs,
+		rebootTaint:                  config.RebootTaint,
+		nodeSelector:                 nodeSelector,
+		forceRebootLimit:             forceRebootLimit,
+		beforeRebootTimeout:          config.BeforeRebootTimeout,
+		beforeRebootTimeoutAction:    beforeRebootTimeoutAction,
+		revertGracePeriod:            config.BeforeRebootRevertGracePeriod,
+		afterRebootCheckTimeout:      config.AfterRebootCheckTimeout,
+		afterRebootBackoff:           afterRebootBackoff,
+		afterRebootMaxAttempts:       afterRebootMaxAttempts,
+		afterRebootChecks:            afterRebootChecks,
+		smokeTestPodTemplate:         config.AfterRebootSmokeTestPodTemplate,
+		smokeTestTimeout:             smokeTestTimeout,
+		reportedStuckNodes:           map[string]string{},
+		eventRecorder:                eventRecorder,
+		reconciliationPeriod:         reconciliationPeriod,
+		reconcileTimeout:             reconcileTimeout,
+		nodeChangeDebounce:           nodeChangeDebounce,
+		errorRetryLimiter:            workqueue.NewItemExponentialFailureRateLimiter(errorRetryBackoff, reconciliationPeriod),
+		leaderElectionLease:          leaderElectionLeaseDuration,
+		leaderElectionTimeout:        config.LeaderElectionTimeout,
+		resourceLock:                 resourceLock,
+	}
+
+	kontroller.phases = kontroller.reconcilePhases(reconcilePhases)
+	kontroller.nodeEligibilityPredicates = kontroller.eligibilityPredicates(config.NodeEligibilityPredicates)
+
+	// Smoke test runs last, so it only starts once the node passes all other checks.
+	if config.AfterRebootSmokeTestPodTemplate != nil {
+		kontroller.afterRebootChecks = append(kontroller.afterRebootChecks, kontroller.smokeTestCheck)
+	}
+
+	if config.WatchNodes {
+		kontroller.reconcileTrigger = make(chan struct{}, 1)
+		nodeInformer.AddEventHandler(kontroller.nodeChangeHandler())
+	}
+
+	if config.NotificationWebhookURL != "" {
+		notifier, err := newWebhookNotifier(config.NotificationWebhookURL, config.NotificationTemplate,
+			config.NotificationWebhookSecret)
+		if err != nil {
+			return nil, fmt.Errorf("creating notifier: %w", err)
+		}
+
+		kontroller.notifier = notifier
+	}
+
+	return kontroller, nil
+}
+
+// notTrueRequirement returns requirement matching nodes without given label set to true.
+func notTrueRequirement(label string) *labels.Requirement {
+	return k8sutil.NewRequirementOrDie(label, selection.NotIn, []string{constants.True})
+}
+
+// parseRebootWindows parses reboot windows from given configuration, including the single reboot
+// window configured using RebootWindowStart and RebootWindowLength.
+func parseRebootWindows(config Config) ([]*Periodic, error) {
+	windows := config.RebootWindows
+
+	if config.RebootWindowStart != "" && config.RebootWindowLength != "" {
+		windows = append([]RebootWindow{{
+			Start:  config.RebootWindowStart,
+			Length: config.RebootWindowLength,
+		}}, windows...)
+	}
+
+	return parseWindows(windows)
+}
+
+// parseWindows parses given windows into periodics.
+func parseWindows(windows []RebootWindow) ([]*Periodic, error) {
+	periodics := make([]*Periodic, 0, len(windows))
+
+	for _, window := range windows {
+		periodic, err := ParsePeriodic(window.Start, window.Length)
+		if err != nil {
+			return nil, fmt.Errorf("parsing window starting at %q: %w", window.Start, err)
+		}
+
+		periodics = append(periodics, periodic)
+	}
+
+	return periodics, nil
+}
+
+// parseWindowProfiles parses windows of given reboot window profiles into periodics.
+func parseWindowProfiles(profiles map[string][]RebootWindow) (map[string][]*Periodic, error) {
+	periodics := make(map[string][]*Periodic, len(profiles))
+
+	for name, windows := range profiles {
+		profilePeriodics, err := parseWindows(windows)
+		if err != nil {
+			return nil, fmt.Errorf("parsing profile %q: %w", name, err)
+		}
+
+		periodics[name] = profilePeriodics
+	}
+
+	return periodics, nil
+}
+
+// parseOptionalLabelSelector parses given label selector. If selector is empty, nil is returned.
+func parseOptionalLabelSelector(selector string) (labels.Selector, error) {
+	if selector == "" {
+		return nil, nil //nolint:nilnil // Nil selector means no selector is configured.
+	}
+
+	return labels.Parse(selector)
+}
+
+// beforeRebootApprovalLabels returns approval labels from given configuration or, if not configured,
+// keys of equality requirements of given before reboot label selector. Labels the selector only
+// filters on, e.g. by requiring their existence, are not approval labels, so they are never removed.
+func beforeRebootApprovalLabels(config Config, selector labels.Selector) []string {
+	if config.BeforeRebootApprovalLabels != nil {
+		return config.BeforeRebootApprovalLabels
+	}
+
+	if selector == nil {
+		return nil
+	}
+
+	requirements, _ := selector.Requirements()
+
+	keys := make([]string, 0, len(requirements))
+
+	for _, requirement := range requirements {
+		if operator := requirement.Operator(); operator == selection.Equals || operator == selection.DoubleEquals {
+			keys = append(keys, requirement.Key())
+		}
+	}
+
+	return keys
 }
 
 // checkConfig checks a Kontroller configuration.
@@ -184,66 +992,423 @@ func checkConfig(config Config) error {
 	}
 
 	if config.Namespace == "" {
-		return fmt.Errorf("namespace must not be empty")
+		return fmt.Errorf("namespace must not be empty, either configure it or set %s environment variable", namespaceEnv)
 	}
 
-	if config.LockID == "" {
+	if config.LockID == "" && !config.DisableLeaderElection {
 		return fmt.Errorf("lockID must not be empty")
 	}
 
-	return nil
+	if err := newNodeSelectors(constants.NewKeys(config.AnnotationPrefix)).check(); err != nil {
+		return fmt.Errorf("node selectors are inconsistent: %w", err)
+	}
+
+	return config.Validate()
 }
 
-// newResourceLock creates a resource for locking on arbitrary resources
-// used in leader election.
-func newResourceLock(config Config) (resourcelock.Interface, error) {
-	lockType := config.LockType
-	if lockType == "" {
-		lockType = defaultLockType
+// Validate checks all configuration fields which can be checked without connecting to the cluster,
+// e.g. reboot windows, selectors and durations. Fields required for connecting to the cluster, like
+// Client, Namespace and LockID are not checked, so configuration can be validated before deployment.
+//
+//nolint:funlen,cyclop // Just many fields to check.
+func (c Config) Validate() error {
+	if err := checkLockType(c.LockType); err != nil {
+		return fmt.Errorf("checking lock type: %w", err)
 	}
 
-	leaderElectionBroadcaster := record.NewBroadcaster()
-	leaderElectionBroadcaster.StartRecordingToSink(&corev1client.EventSinkImpl{
-		Interface: config.Client.CoreV1().Events(config.Namespace),
-	})
+	if err := checkLeaderElectionResourceName(c.LeaderElectionResourceName); err != nil {
+		return fmt.Errorf("checking leader election resource name: %w", err)
+	}
 
-	return resourcelock.New(
-		lockType,
-		config.Namespace,
-		leaderElectionResourceName,
-		config.Client.CoreV1(),
-		config.Client.CoordinationV1(),
-		resourcelock.ResourceLockConfig{
-			Identity: config.LockID,
-			EventRecorder: leaderElectionBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
-				Component: leaderElectionEventSourceComponent,
-			}),
-		},
-	)
-}
+	if err := checkEventSourceComponent(c.EventSourceComponent); err != nil {
+		return fmt.Errorf("checking event source component: %w", err)
+	}
 
-// Run starts the operator reconcilitation process and runs until the stop
-// channel is closed.
-func (k *Kontroller) Run(stop <-chan struct{}) error {
-	errCh := make(chan error, 1)
+	if err := checkEventSourceComponent(c.LeaderElectionEventSourceComponent); err != nil {
+		return fmt.Errorf("checking leader election event source component: %w", err)
+	}
 
-	// Leader election is responsible for shutting down the controller, so when leader election
-	// is lost, controller is immediately stopped, as shared context will be cancelled.
-	ctx := k.withLeaderElection(stop, errCh)
+	if err := constants.NewKeys(c.AnnotationPrefix).Validate(); err != nil {
+		return fmt.Errorf("checking annotation prefix: %w", err)
+	}
 
-	klog.V(5).Info("Starting controller")
+	if err := checkNodeSelectionStrategy(c.NodeSelectionStrategy); err != nil {
+		return fmt.Errorf("checking node selection strategy: %w", err)
+	}
 
-	// Call the process loop each period, until stop is closed.
-	wait.Until(func() { k.process(ctx) }, k.reconciliationPeriod, ctx.Done())
+	if err := checkNotReadyNodePolicy(c.NotReadyNodePolicy); err != nil {
+		return fmt.Errorf("checking not ready node policy: %w", err)
+	}
 
-	klog.V(5).Info("Stopping controller")
+	if err := checkBeforeRebootTimeoutAction(c.BeforeRebootTimeoutAction); err != nil {
+		return fmt.Errorf("checking before reboot timeout action: %w", err)
+	}
 
-	return <-errCh
-}
+	if err := checkReconcilePhases(c.ReconcilePhases); err != nil {
+		return fmt.Errorf("checking reconcile phases: %w", err)
+	}
+
+	if err := checkNamespaces(c.ProtectedNamespaces); err != nil {
+		return fmt.Errorf("checking protected namespaces: %w", err)
+	}
+
+	if err := checkRebootTaint(c.RebootTaint); err != nil {
+		return fmt.Errorf("checking reboot taint: %w", err)
+	}
+
+	if err := checkSmokeTestPodTemplate(c.AfterRebootSmokeTestPodTemplate); err != nil {
+		return fmt.Errorf("checking after reboot smoke test pod template: %w", err)
+	}
+
+	if _, err := parseRebootWindows(c); err != nil {
+		return fmt.Errorf("parsing reboot windows: %w", err)
+	}
+
+	if _, err := parseWindows(c.ExclusionWindows); err != nil {
+		return fmt.Errorf("parsing exclusion windows: %w", err)
+	}
+
+	if _, err := parseWindowProfiles(c.RebootWindowProfiles); err != nil {
+		return fmt.Errorf("parsing reboot window profiles: %w", err)
+	}
+
+	if _, _, err := parseAnnotationChecks(beforeRebootAnnotationChecks(c)); err != nil {
+		return fmt.Errorf("parsing before reboot annotations: %w", err)
+	}
+
+	if _, err := parseAnnotationDescriptions(c.AnnotationDescriptions); err != nil {
+		return fmt.Errorf("parsing annotation descriptions: %w", err)
+	}
+
+	if _, _, err := parseAnnotationChecks(c.AfterRebootAnnotations); err != nil {
+		return fmt.Errorf("parsing after reboot annotations: %w", err)
+	}
+
+	if _, err := parseWorkloads(c.AfterRebootReadyWorkloads); err != nil {
+		return fmt.Errorf("parsing after reboot ready workloads: %w", err)
+	}
+
+	if _, err := parseOptionalLabelSelector(c.NodeSelector); err != nil {
+		return fmt.Errorf("parsing node selector: %w", err)
+	}
+
+	if _, err := parseOptionalLabelSelector(c.BeforeRebootLabelSelector); err != nil {
+		return fmt.Errorf("parsing before reboot label selector: %w", err)
+	}
+
+	if _, err := parseOptionalLabelSelector(c.PauseLabelSelector); err != nil {
+		return fmt.Errorf("parsing pause label selector: %w", err)
+	}
+
+	if _, err := parseOptionalLabelSelector(c.ExcludeNodeLabel); err != nil {
+		return fmt.Errorf("parsing exclude node label: %w", err)
+	}
+
+	if _, err := parseNotificationTemplate(c.NotificationTemplate); err != nil {
+		return fmt.Errorf("parsing notification template: %w", err)
+	}
+
+	if err := c.checkDurations(); err != nil {
+		return fmt.Errorf("checking durations: %w", err)
+	}
+
+	if c.MaxRebootingNodes < 0 {
+		return fmt.Errorf("maximum number of rebooting nodes %d must not be negative", c.MaxRebootingNodes)
+	}
+
+	if c.MinReadyFraction < 0 || c.MinReadyFraction > 1 {
+		return fmt.Errorf("minimum ready fraction %v must be between 0 and 1", c.MinReadyFraction)
+	}
+
+	if c.RampUpSuccessfulReboots < 0 {
+		return fmt.Errorf("ramp up successful reboots %d must not be negative", c.RampUpSuccessfulReboots)
+	}
+
+	if c.RampUpStep < 0 {
+		return fmt.Errorf("ramp up step %d must not be negative", c.RampUpStep)
+	}
+
+	if c.ForceRebootLimit < 0 {
+		return fmt.Errorf("force reboot limit %d must not be negative", c.ForceRebootLimit)
+	}
+
+	if c.AfterRebootCheckMaxAttempts < 0 {
+		return fmt.Errorf("after reboot check max attempts %d must not be negative", c.AfterRebootCheckMaxAttempts)
+	}
+
+	return nil
+}
+
+// checkDurations checks if configured durations are not negative and if reconcile timeout fits
+// in reconciliation period.
+func (c Config) checkDurations() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"reconciliation period", c.ReconciliationPeriod},
+		{"leader election lease", c.LeaderElectionLease},
+		{"leader election timeout", c.LeaderElectionTimeout},
+		{"reconcile timeout", c.ReconcileTimeout},
+		{"node change debounce", c.NodeChangeDebounce},
+		{"error retry backoff", c.ErrorRetryBackoff},
+		{"update maturity delay", c.UpdateMaturityDelay},
+		{"reboot SLO", c.RebootSLO},
+		{"post reboot stabilization period", c.PostRebootStabilizationPeriod},
+		{"inter reboot delay", c.InterRebootDelay},
+		{"unhealthy after", c.UnhealthyAfter},
+		{"reboot timeout", c.RebootTimeout},
+		{"before reboot timeout", c.BeforeRebootTimeout},
+		{"before reboot revert grace period", c.BeforeRebootRevertGracePeriod},
+		{"after reboot check timeout", c.AfterRebootCheckTimeout},
+		{"after reboot check backoff", c.AfterRebootCheckBackoff},
+		{"after reboot smoke test timeout", c.AfterRebootSmokeTestTimeout},
+	}
+
+	for _, duration := range durations {
+		if duration.value < 0 {
+			return fmt.Errorf("%s %v must not be negative", duration.name, duration.value)
+		}
+	}
+
+	reconcileTimeout, reconciliationPeriod := c.reconcileTimeout(), c.reconciliationPeriod()
+	if reconcileTimeout > reconciliationPeriod {
+		return fmt.Errorf("reconcile timeout %v must not be greater than reconciliation period %v",
+			reconcileTimeout, reconciliationPeriod)
+	}
+
+	return nil
+}
+
+// reconciliationPeriod returns configured reconciliation period or the default one.
+func (c Config) reconciliationPeriod() time.Duration {
+	if c.ReconciliationPeriod == 0 {
+		return defaultReconciliationPeriod
+	}
+
+	return c.ReconciliationPeriod
+}
+
+// reconcileTimeout returns configured reconcile timeout or the default one, based on reconciliation period.
+func (c Config) reconcileTimeout() time.Duration {
+	if c.ReconcileTimeout == 0 {
+		return c.reconciliationPeriod() * defaultReconcileTimeoutPercentage / 100
+	}
+
+	return c.ReconcileTimeout
+}
+
+// checkLockType checks if given leader election lock type, if configured, is supported.
+func checkLockType(lockType string) error {
+	switch lockType {
+	case "", resourcelock.LeasesResourceLock, resourcelock.ConfigMapsLeasesResourceLock,
+		resourcelock.EndpointsLeasesResourceLock:
+		return nil
+	default:
+		return fmt.Errorf("unsupported lock type %q", lockType)
+	}
+}
+
+// checkLeaderElectionResourceName checks if given leader election resource name, if configured,
+// is a valid object name.
+func checkLeaderElectionResourceName(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q: %s", name, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// checkNamespaces checks if given namespace names are valid.
+func checkNamespaces(namespaces []string) error {
+	for _, namespace := range namespaces {
+		if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
+
+// checkEventSourceComponent checks if given event source component, if configured, is not blank.
+func checkEventSourceComponent(component string) error {
+	if component != "" && strings.TrimSpace(component) == "" {
+		return fmt.Errorf("component must not be blank")
+	}
+
+	return nil
+}
+
+// checkRebootTaint checks if given reboot taint, if configured, is valid.
+func checkRebootTaint(taint *corev1.Taint) error {
+	if taint == nil {
+		return nil
+	}
+
+	if taint.Key == "" {
+		return fmt.Errorf("key must not be empty")
+	}
+
+	switch taint.Effect {
+	case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		return nil
+	default:
+		return fmt.Errorf("unsupported effect %q", taint.Effect)
+	}
+}
+
+// checkSmokeTestPodTemplate checks if given smoke test pod template, if configured, can be used for a Job.
+func checkSmokeTestPodTemplate(template *corev1.PodTemplateSpec) error {
+	if template == nil {
+		return nil
+	}
+
+	if len(template.Spec.Containers) == 0 {
+		return fmt.Errorf("at least one container is required")
+	}
+
+	if template.Spec.RestartPolicy == corev1.RestartPolicyAlways {
+		return fmt.Errorf("restart policy %q is not supported by Jobs", template.Spec.RestartPolicy)
+	}
+
+	return nil
+}
+
+// newResourceLock creates a resource for locking on arbitrary resources
+// used in leader election.
+func newResourceLock(config Config) (resourcelock.Interface, error) {
+	lockType := config.LockType
+	if lockType == "" {
+		lockType = defaultLockType
+	}
+
+	name := config.LeaderElectionResourceName
+	if name == "" {
+		name = defaultLeaderElectionResourceName
+	}
+
+	component := config.LeaderElectionEventSourceComponent
+	if component == "" {
+		component = defaultLeaderElectionEventSourceComponent
+	}
+
+	leaderElectionBroadcaster := record.NewBroadcaster()
+	leaderElectionBroadcaster.StartRecordingToSink(&corev1client.EventSinkImpl{
+		Interface: config.Client.CoreV1().Events(config.Namespace),
+	})
+
+	return resourcelock.New(
+		lockType,
+		config.Namespace,
+		name,
+		config.Client.CoreV1(),
+		config.Client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{
+			Identity: config.LockID,
+			EventRecorder: leaderElectionBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
+				Component: component,
+			}),
+		},
+	)
+}
+
+// newEventRecorder creates a recorder for events about cluster-scoped objects like nodes.
+//
+// Events about cluster-scoped objects are always created in the default namespace.
+func newEventRecorder(config Config) record.EventRecorder {
+	component := config.EventSourceComponent
+	if component == "" {
+		component = defaultEventSourceComponent
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&corev1client.EventSinkImpl{
+		Interface: config.Client.CoreV1().Events(metav1.NamespaceDefault),
+	})
+
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
+		Component: component,
+	})
+}
+
+// Run starts the operator reconcilitation process and runs until the stop
+// channel is closed.
+func (k *Kontroller) Run(stop <-chan struct{}) error {
+	errCh := make(chan error, 1)
+
+	if k.healthAddress != "" {
+		stopHealthServer, err := k.serveHealth()
+		if err != nil {
+			return fmt.Errorf("serving health endpoints: %w", err)
+		}
+
+		defer stopHealthServer()
+	}
+
+	if k.statusAddress != "" {
+		stopStatusServer, err := serveHTTP(k.statusAddress, k.statusHandler(), "status")
+		if err != nil {
+			return fmt.Errorf("serving status endpoint: %w", err)
+		}
+
+		defer stopStatusServer()
+	}
+
+	// Leader election is responsible for shutting down the controller, so when leader election
+	// is lost, controller is immediately stopped, as shared context will be cancelled.
+	ctx, releaseLeadership, err := k.withLeaderElection(stop, errCh)
+	if err != nil {
+		return err
+	}
+
+	// Release the lock once reconciliation stops, so other instance can take over without
+	// waiting for the lease to expire.
+	defer releaseLeadership()
+
+	k.health.leadershipAcquired(time.Now())
+
+	if err := k.syncNodeCache(ctx); err != nil {
+		klog.V(5).Info("Stopping controller before node cache got synced")
+
+		return <-errCh
+	}
+
+	klog.V(5).Info("Starting controller")
+
+	// Call the process loop each period or when triggered by node changes, until stop is closed.
+	k.reconcileUntil(ctx, func() bool {
+		succeeded := k.processRecoveringPanics(ctx)
+
+		k.health.reconcileCompleted(succeeded, time.Now())
+
+		if k.reconciled != nil {
+			k.reconciled()
+		}
+
+		return succeeded
+	})
+
+	klog.V(5).Info("Stopping controller")
+
+	return <-errCh
+}
 
 // withLeaderElection creates a new context which is cancelled when this
 // operator does not hold a lock to operate on the cluster.
-func (k *Kontroller) withLeaderElection(stop <-chan struct{}, errCh chan<- error) context.Context {
+//
+// Returned function releases the lock and waits for the leader election process to finish.
+// It must be called once all operations requiring the lock are done.
+//
+// If leader election is disabled, returned context is only cancelled when user requests to stop the controller.
+//
+// If the lock is not acquired within configured leader election timeout, an error is returned.
+func (k *Kontroller) withLeaderElection(
+	stop <-chan struct{}, errCh chan<- error,
+) (context.Context, func(), error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	go func() {
@@ -254,17 +1419,35 @@ func (k *Kontroller) withLeaderElection(stop <-chan struct{}, errCh chan<- error
 		cancel()
 	}()
 
-	waitLeading := make(chan struct{})
+	if k.resourceLock == nil {
+		klog.Warning("Leader election is disabled, make sure only a single instance of the operator is running")
+
+		return ctx, func() {}, nil
+	}
+
+	// Leader election uses a separate context, so the lock is not released while reconciliation
+	// is still running.
+	leaderElectionCtx, cancelLeaderElection := context.WithCancel(context.Background())
+	leaderElectionDone := make(chan struct{})
+
+	// Buffered, so leading can start after waiting for it timed out.
+	waitLeading := make(chan struct{}, 1)
+
+	// Closed when waiting for leading timed out, so stopping leader election is not reported as losing it.
+	gaveUp := make(chan struct{})
 
 	go func() {
+		defer close(leaderElectionDone)
+
 		// Lease values inspired by a combination of
 		// https://github.com/kubernetes/kubernetes/blob/f7c07a121d2afadde7aa15b12a9d02858b30a0a9/pkg/apis/componentconfig/v1alpha1/defaults.go#L163-L174
 		// and the KVO values
 		// See also
 		// https://github.com/kubernetes/kubernetes/blob/fc31dae165f406026142f0dd9a98cada8474682a/pkg/client/leaderelection/leaderelection.go#L17
-		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
-			Lock:          k.resourceLock,
-			LeaseDuration: k.leaderElectionLease,
+		leaderelection.RunOrDie(leaderElectionCtx, leaderelection.LeaderElectionConfig{
+			Lock:            k.resourceLock,
+			ReleaseOnCancel: true,
+			LeaseDuration:   k.leaderElectionLease,
 			//nolint:gomnd // Set renew deadline to 2/3rd of the lease duration to give
 			//             // controller enough time to renew the lease.
 			RenewDeadline: k.leaderElectionLease * 2 / 3,
@@ -277,340 +1460,1847 @@ func (k *Kontroller) withLeaderElection(stop <-chan struct{}, errCh chan<- error
 					waitLeading <- struct{}{}
 				},
 				OnStoppedLeading: func() {
-					errCh <- fmt.Errorf("leaderelection lost")
+					// Cancel first, so in-flight reconciliation stops updating nodes as soon as possible,
+					// as another instance may already be leading.
 					cancel()
+
+					// Leadership is also given up when user requests to stop the controller,
+					// which is not an error.
+					select {
+					case <-stop:
+					case <-gaveUp:
+					default:
+						errCh <- ErrLeadershipLost
+					}
 				},
 			},
 		})
 	}()
 
-	<-waitLeading
+	releaseLeadership := func() {
+		cancelLeaderElection()
+		<-leaderElectionDone
+	}
+
+	if err := k.waitLeading(waitLeading, stop); err != nil {
+		close(gaveUp)
+		releaseLeadership()
+		cancel()
+
+		return nil, nil, err
+	}
+
+	return ctx, releaseLeadership, nil
+}
+
+// waitLeading waits until given channel receives a signal that leader election lock has been acquired,
+// periodically logging that the operator is still waiting. If the user requests to stop the controller
+// in the meantime, it returns early without an error.
+//
+// If the lock is not acquired within configured leader election timeout, an error is returned.
+func (k *Kontroller) waitLeading(leading <-chan struct{}, stop <-chan struct{}) error {
+	var timeout <-chan time.Time
+
+	if k.leaderElectionTimeout > 0 {
+		timer := time.NewTimer(k.leaderElectionTimeout)
+		defer timer.Stop()
+
+		timeout = timer.C
+	}
+
+	ticker := time.NewTicker(leaderElectionWaitLogInterval)
+	defer ticker.Stop()
+
+	waitingSince := time.Now()
+
+	for {
+		select {
+		case <-leading:
+			return nil
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			klog.InfoS("Still waiting for acquiring leader election lock", "lock", k.resourceLock.Describe(),
+				"waiting", time.Since(waitingSince).Round(time.Second))
+		case <-timeout:
+			return fmt.Errorf("%w %q within %v, make sure operator has permissions to create and update it",
+				ErrLeaderElectionTimeout, k.resourceLock.Describe(), k.leaderElectionTimeout)
+		}
+	}
+}
+
+// syncNodeCache starts node informer, unless it is already running, and waits until node cache is synced.
+// Informer runs until context given on the first call is done.
+func (k *Kontroller) syncNodeCache(ctx context.Context) error {
+	k.nodeInformerStarted.Do(func() {
+		klog.V(5).Info("Starting node informer")
+
+		go k.nodeInformer.Run(ctx.Done())
+	})
+
+	if !cache.WaitForCacheSync(ctx.Done(), k.nodeInformer.HasSynced) {
+		return fmt.Errorf("waiting for node cache to sync: %w", ctx.Err())
+	}
+
+	return nil
+}
+
+// ReconcileOnce runs a single reconciliation loop and returns an error if any reconciliation phase fails,
+// which allows driving the operator deterministically from tests or tooling, without Run.
+//
+// ReconcileOnce does not acquire leader election lock, so it must not be used while another instance
+// of the operator is running. Node cache is started on the first call and kept until context given on
+// that call is done, so repeated calls should use the same long-lived context.
+func (k *Kontroller) ReconcileOnce(ctx context.Context) error {
+	if err := k.syncNodeCache(ctx); err != nil {
+		return fmt.Errorf("syncing node cache: %w", err)
+	}
+
+	return k.process(ctx)
+}
+
+// ReconcileNode runs reconciliation phases for a single node with given name, moving it through the reboot
+// process the same way as ReconcileOnce does, without updating other nodes. Other nodes are still taken into
+// account, e.g. to not exceed MaxRebootingNodes. It is subject to the same restrictions as ReconcileOnce.
+func (k *Kontroller) ReconcileNode(ctx context.Context, nodeName string) error {
+	if err := k.syncNodeCache(ctx); err != nil {
+		return fmt.Errorf("syncing node cache: %w", err)
+	}
+
+	return k.processNode(ctx, nodeName)
+}
+
+// processRecoveringPanics runs a single reconciliation loop and recovers from any panic which occurs
+// during it, so latent bugs in reconciliation do not take down the whole controller. Reconciliation
+// will be retried in the next period. Reconciliation which panicked is considered failed.
+func (k *Kontroller) processRecoveringPanics(ctx context.Context) (succeeded bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			reconcilePanics.With(clusterLabels("", "")).Inc()
+			klog.ErrorS(nil, "Recovered from panic during reconciliation", "panic", r, "stack", string(debug.Stack()))
+
+			succeeded = false
+		}
+	}()
+
+	return k.ReconcileOnce(ctx) == nil
+}
+
+// process performs the reconcilitation to coordinate reboots.
+//
+// If reconciliation takes longer than configured reconcile timeout, outstanding operations are
+// cancelled and remaining phases are skipped.
+//
+// Returns an error of the first failed phase, if any.
+//
+// Duration of the loop and of each phase is recorded in metrics, as well as the phase which failed.
+func (k *Kontroller) process(ctx context.Context) error {
+	klog.V(4).Info("Going through a loop cycle")
+
+	started := time.Now()
+
+	defer func() {
+		reconcileDuration.With(clusterLabels("", "")).Observe(time.Since(started).Seconds())
+	}()
+
+	loopCtx, cancel := context.WithTimeout(ctx, k.reconcileTimeout)
+	defer cancel()
+
+	paused := k.paused(loopCtx)
+
+	k.refreshRebootWindow(loopCtx)
+
+	defer k.updateStatusSnapshot(paused)
+	defer k.publishRebootingNodes(ctx)
+
+	k.reportStuckNodes()
+
+	for _, phase := range k.phases {
+		// Do not start next phase once the controller is stopped or lost leadership, as another
+		// instance may already be leading.
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return fmt.Errorf("reconciliation cancelled before reconcile phase %q: %w", phase.name, ctx.Err())
+		}
+
+		if paused && phase.name == ReconcilePhaseMarkBeforeReboot {
+			klog.InfoS("Operator is paused using ConfigMap, not labeling rebootable nodes",
+				"reconcilePhase", phase.name, "configMap", k.pauseConfigMap)
+
+			continue
+		}
+
+		klog.V(4).InfoS(phase.description, "reconcilePhase", phase.name)
+
+		phaseStarted := time.Now()
+
+		err := phase.run(loopCtx)
+
+		reconcilePhaseDuration.With(clusterLabels(phase.name, "")).Observe(time.Since(phaseStarted).Seconds())
+
+		// Check the deadline even on success, as not all operations respect the context.
+		if errors.Is(loopCtx.Err(), context.DeadlineExceeded) {
+			reconcileTimeouts.With(clusterLabels(phase.name, "")).Inc()
+			reconcileErrors.With(clusterLabels(phase.name, "")).Inc()
+			klog.ErrorS(loopCtx.Err(), "Reconciliation exceeded timeout, aborting",
+				"reconcilePhase", phase.name, "timeout", k.reconcileTimeout)
+
+			return fmt.Errorf("reconcile phase %q exceeded timeout of %v: %w",
+				phase.name, k.reconcileTimeout, loopCtx.Err())
+		}
+
+		if err != nil {
+			reconcileErrors.With(clusterLabels(phase.name, "")).Inc()
+			klog.ErrorS(err, "Failed to "+phase.failure, "reconcilePhase", phase.name)
+
+			return fmt.Errorf("reconcile phase %q failed to %s: %w", phase.name, phase.failure, err)
+		}
+	}
+
+	return nil
+}
+
+// processNode performs the reconciliation for a single node with given name. Node is read from the cache
+// before each phase, so every phase sees changes made by the previous ones.
+//
+// Returns an error of the first failed phase, if any.
+func (k *Kontroller) processNode(ctx context.Context, nodeName string) error {
+	klog.V(4).InfoS("Reconciling node", "node", nodeName)
+
+	ctx, cancel := context.WithTimeout(ctx, k.reconcileTimeout)
+	defer cancel()
+
+	paused := k.paused(ctx)
+
+	k.refreshRebootWindow(ctx)
+
+	for _, phase := range k.phases {
+		if paused && phase.name == ReconcilePhaseMarkBeforeReboot {
+			klog.InfoS("Operator is paused using ConfigMap, not labeling rebootable node",
+				"reconcilePhase", phase.name, "node", nodeName, "configMap", k.pauseConfigMap)
+
+			continue
+		}
+
+		node, err := k.cachedNode(nodeName)
+		if err != nil {
+			return err
+		}
+
+		klog.V(4).InfoS(phase.description, "reconcilePhase", phase.name, "node", nodeName)
+
+		if err := phase.runNode(ctx, node); err != nil {
+			reconcileErrors.With(clusterLabels(phase.name, "")).Inc()
+			klog.ErrorS(err, "Failed to "+phase.failure, "reconcilePhase", phase.name, "node", nodeName)
+
+			return fmt.Errorf("reconcile phase %q failed to %s for node %q: %w", phase.name, phase.failure, nodeName, err)
+		}
+	}
+
+	return nil
+}
+
+// paused checks if the operator is paused using configured pause ConfigMap.
+//
+// If the ConfigMap cannot be read, the operator is considered paused, so the pause
+// cannot be bypassed by API server errors.
+func (k *Kontroller) paused(ctx context.Context) bool {
+	if k.pauseConfigMap == "" {
+		return false
+	}
+
+	configMap, err := k.kc.CoreV1().ConfigMaps(k.namespace).Get(ctx, k.pauseConfigMap, metav1.GetOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		return false
+	case err != nil:
+		klog.ErrorS(err, "Failed getting pause ConfigMap, assuming operator is paused", "configMap", k.pauseConfigMap)
+
+		return true
+	}
+
+	return configMap.Data[pauseConfigMapKey] == constants.True
+}
+
+// cleanupState attempts to make sure nodes are in a well-defined state before
+// performing state changes on them.
+//
+// If there is an error getting the list of nodes, an error is immediately returned.
+// Failing to update a node does not prevent cleaning up remaining nodes. Errors for
+// all such nodes are aggregated into the returned error.
+func (k *Kontroller) cleanupState(ctx context.Context) error {
+	nodelist, err := k.listNodes(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	var errs []error
+
+	for _, node := range nodelist.Items {
+		node := node
+
+		if err := k.cleanupNode(ctx, &node); err != nil {
+			klog.ErrorS(err, "Failed cleaning up node, continuing with remaining nodes", "node", node.Name)
+
+			errs = append(errs, fmt.Errorf("cleaning up node %q: %w", node.Name, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// cleanupNode makes sure given node is in a well-defined state, by removing the before-reboot
+// label from it if it no longer wants to reboot, for at least the configured grace period.
+func (k *Kontroller) cleanupNode(ctx context.Context, node *corev1.Node) error {
+	// Excluded nodes are managed manually, so their state is left as is.
+	if k.excludedByLabel(node) {
+		return nil
+	}
+
+	// Updating nodes being deleted may fail, while their state does not matter anymore.
+	if node.DeletionTimestamp != nil {
+		return nil
+	}
+
+	uncordoned := false
+
+	err := k8sutil.UpdateNodeRetry(ctx, k.nc, node.Name, func(node *corev1.Node) {
+		uncordoned = false
+
+		// Make sure that nodes with the before-reboot label actually
+		// still wants to reboot.
+		if _, exists := node.Labels[k.keys.LabelBeforeReboot]; !exists {
+			delete(node.Annotations, k.keys.AnnotationRebootUnwantedTime)
+
+			uncordoned = k.uncordonLeftover(node)
+
+			return
+		}
+
+		now := time.Now()
+
+		if k.selectors.Rebootable.Matches(fields.Set(node.Annotations)) && !k.pausedByLabel(node) &&
+			!k.pausedUntil(node, now) {
+			delete(node.Annotations, k.keys.AnnotationRebootUnwantedTime)
+
+			return
+		}
+
+		if k.withinRevertGracePeriod(node, now) {
+			return
+		}
+
+		klog.InfoS("Node no longer wants to reboot, removing label",
+			"node", node.Name, "label", k.keys.LabelBeforeReboot, "annotations", node.Annotations)
+		delete(node.Labels, k.keys.LabelBeforeReboot)
+		delete(node.Annotations, k.keys.AnnotationRebootUnwantedTime)
+		delete(node.Annotations, k.keys.AnnotationRebootCycleStartTime)
+		delete(node.Annotations, k.keys.AnnotationRebootReason)
+		for _, annotation := range k.beforeRebootAnnotations {
+			delete(node.Annotations, annotation)
+		}
+
+		k.allowKarpenterDisruption(node)
+		uncordoned = k.uncordon(node)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !uncordoned {
+		return nil
+	}
+
+	return k.ensureUncordoned(ctx, node.Name)
+}
+
+type checkRebootOptions struct {
+	req         *labels.Requirement
+	annotations []string
+	// Values which annotations are expected to have, by annotation.
+	values map[string]string
+	// labelSelector, when not nil, must match node labels in addition to annotations.
+	labelSelector labels.Selector
+	// Labels removed once checks pass, so they are not reused for the next reboot.
+	approvalLabels []string
+	// If true, configured after reboot checks must pass in addition to annotations.
+	runChecks bool
+	// If true, reason of the finished reboot process is recorded in the last reboot reason annotation.
+	recordRebootReason bool
+	label              string
+	okToReboot         string
+	phase              string
+}
+
+// checkReboot gets all nodes with a given requirement and checks if all of the given annotations are set
+// to their expected values.
+//
+// If they are, it deletes given annotations and label, then sets ok-to-reboot annotation to either true or false,
+// depending on the given parameter.
+//
+// If ok-to-reboot is set to true, it gives node agent a signal that it is OK to proceed with rebooting.
+//
+// If ok-to-reboot is set to false, it means node has finished rebooting successfully.
+//
+// If there is an error getting the list of nodes or updating any of them, an
+// error is immediately returned.
+func (k *Kontroller) checkReboot(ctx context.Context, opt checkRebootOptions) error {
+	nodelist, err := k.listNodes(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	for _, node := range nodelist.Items {
+		node := node
+
+		if err := k.checkNode(ctx, &node, opt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkNode checks if given node, if it matches requirement from given options, passes the checks from given
+// options, and if so, completes them as described in checkReboot.
+func (k *Kontroller) checkNode(ctx context.Context, node *corev1.Node, opt checkRebootOptions) error {
+	if !opt.req.Matches(labels.Set(node.Labels)) {
+		return nil
+	}
+
+	// Nodes which no longer want to reboot must not be approved while waiting for the revert grace period.
+	if _, ok := node.Annotations[k.keys.AnnotationRebootUnwantedTime]; ok && opt.okToReboot == constants.True {
+		return nil
+	}
+
+	if !k.checksPassed(ctx, node, opt) {
+		return nil
+	}
+
+	// Taint is removed first, so if completing the reboot process fails, it is retried in the next loop.
+	if opt.okToReboot == constants.False {
+		if err := k.removeRebootTaint(ctx, node.Name); err != nil {
+			return fmt.Errorf("removing reboot taint: %w", err)
+		}
+	}
+
+	klog.V(4).InfoS("Deleting label", "node", node.Name, "phase", opt.phase, "label", opt.label)
+	klog.V(4).InfoS("Setting annotation", "node", node.Name, "phase", opt.phase,
+		"annotation", k.keys.AnnotationOkToReboot, "value", opt.okToReboot)
+
+	if err := k8sutil.UpdateNodeRetry(ctx, k.nc, node.Name, func(node *corev1.Node) {
+		k.completeCheck(node, opt)
+	}); err != nil {
+		return fmt.Errorf("updating node %q: %w", node.Name, err)
+	}
+
+	if opt.okToReboot == constants.False && k.madeUnschedulableByOperator(node) {
+		if err := k.ensureUncordoned(ctx, node.Name); err != nil {
+			return fmt.Errorf("ensuring node %q is schedulable: %w", node.Name, err)
+		}
+	}
+
+	if opt.okToReboot == constants.True && k.drainCompleteAnnotation != "" {
+		klog.InfoS("Drain of node completed", "node", node.Name, "phase", opt.phase,
+			"annotation", k.drainCompleteAnnotation)
+	}
+
+	if opt.okToReboot == constants.False {
+		now := time.Now()
+
+		k.recordRebootCycleFinished(node, now)
+		k.recordRebootDuration(node, now)
+		k.lastRebootFinished = now
+		k.recordRebootSucceeded()
+	}
+
+	k.recordPhaseTransition(node, opt.phase, "checks-passed")
+
+	if opt.phase == phaseCompleted {
+		k.notify(node.Name, NotificationEventRebootFinished)
+
+		if err := k.deleteSmokeTestJob(ctx, node.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checksPassed returns true when given node has all annotations set to their expected values and
+// passes remaining checks from given options.
+func (k *Kontroller) checksPassed(ctx context.Context, node *corev1.Node, opt checkRebootOptions) bool {
+	if missing := missingAnnotations(node, opt.annotations, opt.values); len(missing) > 0 {
+		klog.V(4).InfoS("Node is missing annotations",
+			"node", node.Name, "phase", opt.phase, "annotations", k.describeAnnotations(missing))
+
+		return false
+	}
+
+	if opt.labelSelector != nil && !opt.labelSelector.Matches(labels.Set(node.Labels)) {
+		klog.V(4).InfoS("Node does not match label selector yet",
+			"node", node.Name, "phase", opt.phase, "labelSelector", opt.labelSelector.String())
+
+		return false
+	}
+
+	if opt.runChecks && !k.afterRebootChecksPassed(ctx, node) {
+		klog.V(4).InfoS("After reboot checks did not pass yet", "node", node.Name, "phase", opt.phase)
+
+		return false
+	}
+
+	return true
+}
+
+// completeCheck updates given node, which passed checks from given options, by removing the label and
+// annotations used for the checks and setting ok-to-reboot annotation.
+func (k *Kontroller) completeCheck(node *corev1.Node, opt checkRebootOptions) {
+	delete(node.Labels, opt.label)
+
+	// Cleanup the annotations.
+	for _, annotation := range opt.annotations {
+		klog.V(4).InfoS("Deleting annotation", "node", node.Name, "phase", opt.phase, "annotation", annotation)
+		delete(node.Annotations, annotation)
+	}
+
+	// Cleanup the labels used as a gate, so they are not reused for the next reboot.
+	for _, label := range opt.approvalLabels {
+		klog.V(4).InfoS("Deleting label", "node", node.Name, "phase", opt.phase, "label", label)
+		delete(node.Labels, label)
+	}
+
+	node.Annotations[k.keys.AnnotationOkToReboot] = opt.okToReboot
+
+	if opt.recordRebootReason {
+		k.recordRebootReason(node)
+	}
+
+	// Track that the reboot has been approved by us, so it is not classified as involuntary
+	// once the node reboots.
+	if opt.okToReboot == constants.True {
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+
+		node.Annotations[k.keys.AnnotationRebootApproved] = constants.True
+		node.Annotations[k.keys.AnnotationRebootApprovedTime] = now
+		node.Annotations[k.keys.AnnotationRebootStartedTime] = now
+		delete(node.Annotations, k.keys.AnnotationRebootCompletedTime)
+		delete(node.Annotations, k.keys.AnnotationBeforeRebootTimedOutTime)
+	} else {
+		node.Annotations[k.keys.AnnotationRebootCompletedTime] = strconv.FormatInt(time.Now().Unix(), 10)
+		delete(node.Annotations, k.keys.AnnotationRebootApproved)
+		delete(node.Annotations, k.keys.AnnotationRebootApprovedTime)
+		delete(node.Annotations, k.keys.AnnotationRebootCycleStartTime)
+		delete(node.Annotations, k.keys.AnnotationAfterRebootAttempts)
+		delete(node.Annotations, k.keys.AnnotationAfterRebootAttemptTime)
+		delete(node.Annotations, k.keys.AnnotationRequestReboot)
+
+		k.allowKarpenterDisruption(node)
+		k.uncordon(node)
+	}
+}
+
+// checkBeforeReboot gets all nodes with the before-reboot=true label and checks
+// if all of the configured before-reboot annotations are set to true. If they
+// are, it deletes the before-reboot=true label and sets reboot-ok=true to tell
+// the agent that it is ready to start the actual reboot process.
+// If there is an error getting the list of nodes or updating any of them, an
+// error is immediately returned.
+func (k *Kontroller) checkBeforeReboot(ctx context.Context) error {
+	if err := k.checkReboot(ctx, k.beforeRebootOptions()); err != nil {
+		return err
+	}
+
+	return k.handleTimedOutBeforeRebootChecks(ctx)
+}
+
+// checkNodeBeforeReboot runs checkBeforeReboot for given node.
+func (k *Kontroller) checkNodeBeforeReboot(ctx context.Context, node *corev1.Node) error {
+	if err := k.checkNode(ctx, node, k.beforeRebootOptions()); err != nil {
+		return err
+	}
+
+	// Node might have passed the checks, so timeout must be checked against its current state.
+	node, err := k.cachedNode(node.Name)
+	if err != nil {
+		return err
+	}
+
+	return k.handleTimedOutBeforeRebootCheck(ctx, node, time.Now())
+}
+
+// beforeRebootOptions returns options for checking if nodes passed before reboot checks.
+func (k *Kontroller) beforeRebootOptions() checkRebootOptions {
+	return checkRebootOptions{
+		req:            k.selectors.BeforeReboot,
+		annotations:    k.beforeRebootAnnotations,
+		values:         k.beforeRebootValues,
+		labelSelector:  k.beforeRebootLabelSelector,
+		approvalLabels: k.beforeRebootApprovalLabels,
+		label:          k.keys.LabelBeforeReboot,
+		okToReboot:     constants.True,
+		phase:          phaseRebooting,
+	}
+}
+
+// checkAfterReboot gets all nodes with the after-reboot=true label and checks
+// if all of the configured after-reboot annotations are set to true and all
+// configured after reboot checks pass. If they are, it deletes the after-reboot=true
+// label and sets reboot-ok=false to tell the agent that it has completed it's reboot
+// successfully. Reason of the finished reboot process is recorded on the node.
+// If there is an error getting the list of nodes or updating any of them, an
+// error is immediately returned.
+func (k *Kontroller) checkAfterReboot(ctx context.Context) error {
+	if err := k.checkReboot(ctx, k.afterRebootOptions()); err != nil {
+		return err
+	}
+
+	return k.failTimedOutAfterRebootChecks(ctx)
+}
+
+// checkNodeAfterReboot runs checkAfterReboot for given node.
+func (k *Kontroller) checkNodeAfterReboot(ctx context.Context, node *corev1.Node) error {
+	if err := k.checkNode(ctx, node, k.afterRebootOptions()); err != nil {
+		return err
+	}
+
+	// Node might have passed the checks, so timeout must be checked against its current state.
+	node, err := k.cachedNode(node.Name)
+	if err != nil {
+		return err
+	}
+
+	return k.failTimedOutAfterRebootCheck(ctx, node, time.Now())
+}
+
+// afterRebootOptions returns options for checking if nodes passed after reboot checks.
+func (k *Kontroller) afterRebootOptions() checkRebootOptions {
+	return checkRebootOptions{
+		req:                k.selectors.AfterReboot,
+		annotations:        k.afterRebootAnnotations,
+		values:             k.afterRebootValues,
+		runChecks:          true,
+		recordRebootReason: true,
+		label:              k.keys.LabelAfterReboot,
+		okToReboot:         constants.False,
+		phase:              phaseCompleted,
+	}
+}
+
+// insideRebootWindow checks if given time falls into any of the reboot windows currently in effect.
+//
+// If no reboot window is configured, true is always returned.
+func (k *Kontroller) insideRebootWindow(now time.Time) bool {
+	rebootWindows := k.currentRebootWindows()
+	if len(rebootWindows) == 0 {
+		return true
+	}
+
+	return insideAnyWindow(rebootWindows, now)
+}
+
+// insideExclusionWindow checks if given time falls into any of configured exclusion windows.
+func (k *Kontroller) insideExclusionWindow(now time.Time) bool {
+	return insideAnyWindow(k.exclusionWindows, now)
+}
+
+// remainingInterRebootDelay returns how long the operator must wait at given time before scheduling
+// the next node for rebooting, based on configured inter reboot delay and the time when the last node
+// finished the reboot process.
+func (k *Kontroller) remainingInterRebootDelay(now time.Time) time.Duration {
+	if k.interRebootDelay == 0 || k.lastRebootFinished.IsZero() {
+		return 0
+	}
+
+	return k.lastRebootFinished.Add(k.interRebootDelay).Sub(now)
+}
+
+// insideAnyWindow checks if given time falls into any of given windows.
+func insideAnyWindow(windows []*Periodic, now time.Time) bool {
+	for _, window := range windows {
+		// Most recent window might still be open.
+		if now.Before(window.Previous(now).End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remainingRebootingCapacity calculates how many more nodes can be rebooted at a time in each
+// reboot group based on a given list of rebooting nodes. Groups without rebooting nodes are omitted,
+// as they have full capacity.
+//
+// If maximum capacity of a group is reached, it is logged and list of rebooting nodes is logged as well.
+func (k *Kontroller) remainingRebootingCapacity(rebootingNodes []corev1.Node) map[string]int {
+	rebootingNodesByGroup := map[string][]corev1.Node{}
+
+	for _, n := range rebootingNodes {
+		n := n
+
+		group := k.rebootGroup(&n)
+		rebootingNodesByGroup[group] = append(rebootingNodesByGroup[group], n)
+	}
+
+	remainingCapacity := map[string]int{}
+	maxRebootingNodes := k.effectiveMaxRebootingNodes()
+
+	for group, nodes := range rebootingNodesByGroup {
+		remainingCapacity[group] = maxRebootingNodes - len(nodes)
+
+		if remainingCapacity[group] > 0 {
+			continue
+		}
+
+		for _, n := range nodes {
+			klog.InfoS("Found node still rebooting, waiting", "node", n.Name, "rebootGroup", group)
+		}
+
+		if k.rebootGroupLabelKey == "" {
+			klog.InfoS("Maximum number of rebooting nodes reached; waiting for completion",
+				"rebootingNodes", len(nodes), "maxRebootingNodes", maxRebootingNodes)
+
+			continue
+		}
+
+		klog.InfoS("Maximum number of rebooting nodes in reboot group reached; waiting for completion",
+			"rebootGroup", group, "rebootingNodes", len(nodes), "maxRebootingNodes", maxRebootingNodes)
+	}
+
+	return remainingCapacity
+}
+
+// recordRebootSlots records in metrics given number of nodes requiring reboot and number of nodes which
+// may still be scheduled for rebooting, given nodes going through the reboot process, without exceeding
+// the maximum number of rebooting nodes. With reboot groups configured, available slots are summed over
+// reboot groups of nodes requiring reboot.
+func (k *Kontroller) recordRebootSlots(rebooting, nodesRequiringReboot []corev1.Node) {
+	rebootingByGroup := map[string]int{}
+
+	for i := range rebooting {
+		rebootingByGroup[k.rebootGroup(&rebooting[i])]++
+	}
+
+	groups := map[string]struct{}{}
+
+	if k.rebootGroupLabelKey == "" {
+		groups[""] = struct{}{}
+	}
+
+	for i := range nodesRequiringReboot {
+		groups[k.rebootGroup(&nodesRequiringReboot[i])] = struct{}{}
+	}
+
+	available := 0
+
+	for group := range groups {
+		if slots := k.effectiveMaxRebootingNodes() - rebootingByGroup[group]; slots > 0 {
+			available += slots
+		}
+	}
+
+	rebootSlotsAvailable.With(clusterLabels("", "")).Set(float64(available))
+	nodesRebootable.With(clusterLabels("", "")).Set(float64(len(nodesRequiringReboot)))
+}
+
+// rebootGroup returns reboot group of given node. Without configured reboot group label, all nodes
+// are in the same group.
+func (k *Kontroller) rebootGroup(node *corev1.Node) string {
+	if k.rebootGroupLabelKey == "" {
+		return ""
+	}
+
+	return node.Labels[k.rebootGroupLabelKey]
+}
+
+// rebootingNodes returns nodes from given list which are going through the reboot process.
+// If configured, nodes stuck rebooting are omitted.
+func (k *Kontroller) rebootingNodes(nodelist *corev1.NodeList) []corev1.Node {
+	rebootingNodes := k8sutil.FilterNodesByAnnotation(nodelist.Items, k.selectors.StillRebooting)
+
+	if k.skipStuckNodes {
+		rebootingNodes = k.withoutStuckNodes(rebootingNodes, time.Now())
+	}
+
+	// Nodes running before and after reboot checks are still considered to be "rebooting" to us.
+	beforeRebootNodes := k8sutil.FilterNodesByRequirements(nodelist.Items, k.selectors.BeforeReboot)
+	afterRebootNodes := k8sutil.FilterNodesByRequirements(nodelist.Items, k.selectors.AfterReboot)
+
+	rebootingNodes = append(append(rebootingNodes, beforeRebootNodes...), afterRebootNodes...)
+
+	return append(rebootingNodes, k.notReadyRebootedNodes(nodelist)...)
+}
+
+// notReadyRebootedNodes returns nodes from given list which finished rebooting, but did not become Ready yet,
+// so they are not labeled for after reboot checks yet. Nodes backing off after failed after reboot checks or
+// which failed to reboot are not included.
+func (k *Kontroller) notReadyRebootedNodes(nodelist *corev1.NodeList) []corev1.Node {
+	justRebootedNodes := k8sutil.FilterNodesByAnnotation(nodelist.Items, k.selectors.JustRebooted)
+	justRebootedNodes = k8sutil.FilterNodesByRequirements(justRebootedNodes, k.notAfterRebootReq)
+
+	result := []corev1.Node{}
+
+	for _, node := range justRebootedNodes {
+		node := node
+
+		if k8sutil.NodeReady(&node) || k.afterRebootAttempts(&node) > 0 ||
+			node.Labels[k.keys.LabelRebootFailed] == constants.True {
+			continue
+		}
+
+		result = append(result, node)
+	}
+
+	return result
+}
+
+// stuck returns true when given node was approved for rebooting longer than configured reboot timeout ago.
+// Nodes without known approval time are never considered stuck.
+func (k *Kontroller) stuck(node *corev1.Node, now time.Time) bool {
+	if k.rebootTimeout == 0 {
+		return false
+	}
+
+	value, ok := node.Annotations[k.keys.AnnotationRebootApprovedTime]
+	if !ok {
+		return false
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationRebootApprovedTime, value)
+
+		return false
+	}
+
+	return now.After(time.Unix(timestamp, 0).Add(k.rebootTimeout))
+}
+
+// withoutStuckNodes returns given rebooting nodes without nodes stuck rebooting.
+func (k *Kontroller) withoutStuckNodes(nodes []corev1.Node, now time.Time) []corev1.Node {
+	result := make([]corev1.Node, 0, len(nodes))
+
+	for _, node := range nodes {
+		node := node
+
+		if k.stuck(&node, now) {
+			klog.InfoS("Node is stuck rebooting, not waiting for it to finish", "node", node.Name)
+
+			continue
+		}
+
+		result = append(result, node)
+	}
+
+	return result
+}
+
+// reportStuckNodes finds nodes which did not finish rebooting within configured reboot timeout,
+// updates stuck reboots metric and emits a warning event for each newly stuck node.
+func (k *Kontroller) reportStuckNodes() {
+	if k.rebootTimeout == 0 {
+		return
+	}
+
+	nodelist, err := k.listNodes(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed listing nodes to find nodes stuck rebooting")
+
+		return
+	}
+
+	now := time.Now()
+	stuckNodes := map[string]string{}
+
+	for _, node := range k8sutil.FilterNodesByAnnotation(nodelist.Items, k.selectors.StillRebooting) {
+		node := node
+
+		if !k.stuck(&node, now) {
+			continue
+		}
+
+		approvedTime := node.Annotations[k.keys.AnnotationRebootApprovedTime]
+		stuckNodes[node.Name] = approvedTime
+
+		if k.reportedStuckNodes[node.Name] == approvedTime {
+			continue
+		}
+
+		klog.InfoS("Node did not finish rebooting within reboot timeout", "node", node.Name, "timeout", k.rebootTimeout)
+
+		k.eventRecorder.Eventf(&node, corev1.EventTypeWarning, eventReasonRebootTimeoutExceeded,
+			"Node %q did not finish rebooting within %v", node.Name, k.rebootTimeout)
+	}
+
+	k.reportedStuckNodes = stuckNodes
+
+	stuckReboots.With(clusterLabels("", "")).Set(float64(len(stuckNodes)))
+}
+
+// rebootingZones returns set of zones of given rebooting nodes, in which no other node may start rebooting.
+// Empty set is returned when rebooting nodes is not serialized per zone.
+func (k *Kontroller) rebootingZones(rebootingNodes []corev1.Node) map[string]struct{} {
+	zones := map[string]struct{}{}
+
+	if k.zoneLabelKey == "" {
+		return zones
+	}
+
+	for _, node := range rebootingNodes {
+		if zone, ok := node.Labels[k.zoneLabelKey]; ok {
+			zones[zone] = struct{}{}
+		}
+	}
+
+	return zones
+}
+
+// nodeZone returns zone of given node. False is returned when rebooting the node is not limited per zone,
+// either because rebooting nodes is not serialized per zone or because the node has no zone label.
+func (k *Kontroller) nodeZone(node *corev1.Node) (string, bool) {
+	if k.zoneLabelKey == "" {
+		return "", false
+	}
+
+	zone, ok := node.Labels[k.zoneLabelKey]
+
+	return zone, ok
+}
+
+// nodesRequiringReboot filters given list of nodes and returns ones which requires a reboot.
+func (k *Kontroller) nodesRequiringReboot(nodelist *corev1.NodeList) []corev1.Node {
+	rebootableNodes := k8sutil.FilterNodesByAnnotation(nodelist.Items, k.selectors.Rebootable)
+
+	rebootableNodes = k8sutil.FilterNodesByRequirements(rebootableNodes, k.notBeforeRebootReq)
+
+	now := time.Now()
+
+	eligibleNodes := []corev1.Node{}
+
+	for _, node := range rebootableNodes {
+		node := node
+
+		if reason := k.ineligibleReason(&node); reason != "" {
+			klog.V(4).InfoS("Node is not eligible for rebooting, skipping", "node", node.Name, "reason", reason)
+			rebootableNodesSkipped.With(clusterLabels("", reason)).Inc()
+
+			continue
+		}
+
+		if !k.outdated(&node) {
+			continue
+		}
+
+		if !k.updateMatured(&node, now) {
+			continue
+		}
+
+		eligibleNodes = append(eligibleNodes, node)
+	}
+
+	return eligibleNodes
+}
+
+// outdated returns true when given node runs different version than the version of the pending update.
+// If only rebooting outdated nodes is not enabled or any of the versions is unknown, node is considered outdated.
+func (k *Kontroller) outdated(node *corev1.Node) bool {
+	if !k.onlyRebootOutdated {
+		return true
+	}
+
+	currentVersion, ok := node.Labels[k.keys.LabelVersion]
+	if !ok || currentVersion == "" {
+		return true
+	}
+
+	newVersion, ok := node.Annotations[k.newVersionAnnotation]
+	if !ok || newVersion == "" {
+		return true
+	}
+
+	if currentVersion == newVersion {
+		klog.V(4).InfoS("Node already runs the pending version, skipping",
+			"node", node.Name, "version", currentVersion, "annotation", k.newVersionAnnotation)
+
+		return false
+	}
+
+	return true
+}
+
+// updateMatured returns true when update available on given node is older than configured update
+// maturity delay. If the time when update became available is unknown, update is considered matured.
+func (k *Kontroller) updateMatured(node *corev1.Node, now time.Time) bool {
+	if k.updateMaturityDelay == 0 {
+		return true
+	}
+
+	value, ok := node.Annotations[k.keys.AnnotationUpdateAvailableTime]
+	if !ok {
+		return true
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationUpdateAvailableTime, value)
+
+		return true
+	}
+
+	maturedAt := time.Unix(timestamp, 0).Add(k.updateMaturityDelay)
+	if now.Before(maturedAt) {
+		klog.V(4).InfoS("Update on node is not matured yet", "node", node.Name, "maturedAt", maturedAt)
+
+		return false
+	}
+
+	return true
+}
+
+// pausedByLabel returns true when given node matches configured pause label selector.
+func (k *Kontroller) pausedByLabel(node *corev1.Node) bool {
+	return k.pauseLabelSelector != nil && k.pauseLabelSelector.Matches(labels.Set(node.Labels))
+}
+
+// pausedUntil returns true when given node has reboot paused until a time which is after given time.
+// Invalid values are ignored, so rebooting is not paused indefinitely by a mistake.
+func (k *Kontroller) pausedUntil(node *corev1.Node, now time.Time) bool {
+	value, ok := node.Annotations[k.keys.AnnotationRebootPausedUntil]
+	if !ok {
+		return false
+	}
+
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationRebootPausedUntil, value)
+
+		return false
+	}
+
+	return now.Before(until)
+}
+
+// excludedByLabel returns true when given node matches configured exclude node label.
+func (k *Kontroller) excludedByLabel(node *corev1.Node) bool {
+	return k.excludeLabelSelector != nil && k.excludeLabelSelector.Matches(labels.Set(node.Labels))
+}
+
+// podsOnNodes lists pods running on each of given nodes, if any of configured checks needs them, so
+// every check uses the same pods. Nodes for which listing pods fails are not included in the result.
+func (k *Kontroller) podsOnNodes(ctx context.Context, nodes []corev1.Node) map[string][]corev1.Pod {
+	if k.blockingPodAnnotation == "" && !k.checkPodDisruptionBudgets && len(k.protectedNamespaces) == 0 &&
+		!k.deferNodesRunningJobs {
+		return nil
+	}
+
+	result := make(map[string][]corev1.Pod, len(nodes))
+
+	for _, node := range nodes {
+		pods, err := k8sutil.NodePods(ctx, k.kc.CoreV1(), node.Name)
+		if err != nil {
+			klog.ErrorS(err, "Failed listing pods on node", "node", node.Name)
+
+			continue
+		}
+
+		result[node.Name] = pods
+	}
+
+	return result
+}
+
+// withoutBlockedNodes returns given nodes except ones running pods with configured blocking pod annotation
+// and, if configured, ones running pods which cannot be evicted due to their PodDisruptionBudget, pods
+// from protected namespaces or pods of Jobs which did not complete yet. If checking pods on the node fails,
+// the node is considered blocked.
+func (k *Kontroller) withoutBlockedNodes(
+	ctx context.Context, nodes []corev1.Node, nodePods map[string][]corev1.Pod,
+) []corev1.Node {
+	blockOnProtectedNamespaces := k.blockOnProtectedNamespaces && len(k.protectedNamespaces) > 0
+
+	if len(nodes) == 0 || (k.blockingPodAnnotation == "" && !k.checkPodDisruptionBudgets &&
+		!blockOnProtectedNamespaces && !k.deferNodesRunningJobs) {
+		return nodes
+	}
+
+	// Listed once for all nodes, as PodDisruptionBudgets are not bound to nodes.
+	var pdbs []policyv1.PodDisruptionBudget
+
+	if k.checkPodDisruptionBudgets {
+		pdbList, err := k.kc.PolicyV1().PodDisruptionBudgets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			klog.ErrorS(err, "Failed listing PodDisruptionBudgets, skipping all nodes")
+
+			return []corev1.Node{}
+		}
+
+		pdbs = pdbList.Items
+	}
+
+	result := make([]corev1.Node, 0, len(nodes))
+
+	for _, node := range nodes {
+		pods, ok := nodePods[node.Name]
+		if !ok || k.blockedByPods(node.Name, pods, pdbs) {
+			continue
+		}
+
+		result = append(result, node)
+	}
+
+	return result
+}
+
+// blockedByPods returns true when given pods running on node with given name prevent it from being rebooted,
+// considering given PodDisruptionBudgets. Reason is logged and recorded in metrics.
+func (k *Kontroller) blockedByPods(nodeName string, pods []corev1.Pod, pdbs []policyv1.PodDisruptionBudget) bool {
+	if k.blockingPodAnnotation != "" {
+		if pod := k8sutil.BlockingPod(pods, k.blockingPodAnnotation); pod != nil {
+			klog.InfoS("Node runs pod with blocking annotation, skipping",
+				"node", nodeName, "pod", klog.KObj(pod), "annotation", k.blockingPodAnnotation)
+			rebootableNodesSkipped.With(clusterLabels("", skipReasonBlockingPod)).Inc()
+
+			return true
+		}
+	}
+
+	if k.blockOnProtectedNamespaces && k.runsProtectedPods(nodeName, pods) {
+		rebootableNodesSkipped.With(clusterLabels("", skipReasonProtectedNamespace)).Inc()
+
+		return true
+	}
+
+	if k.deferNodesRunningJobs {
+		if pod := k8sutil.RunningJobPod(pods); pod != nil {
+			klog.InfoS("Node runs pod of a Job which did not complete yet, deferring reboot",
+				"node", nodeName, "pod", klog.KObj(pod))
+			rebootableNodesSkipped.With(clusterLabels("", skipReasonRunningJob)).Inc()
+
+			return true
+		}
+	}
+
+	if !k.checkPodDisruptionBudgets {
+		return false
+	}
+
+	pod, pdb, err := k8sutil.PodBlockedByDisruptionBudget(pods, pdbs)
+
+	switch {
+	case err != nil:
+		klog.ErrorS(err, "Failed checking PodDisruptionBudgets of pods on node, skipping", "node", nodeName)
+
+		return true
+	case pod != nil:
+		klog.InfoS("Node runs pod whose PodDisruptionBudget allows no disruptions, skipping",
+			"node", nodeName, "pod", klog.KObj(pod), "podDisruptionBudget", klog.KObj(pdb))
+		rebootableNodesSkipped.With(clusterLabels("", skipReasonPodDisruptionBudget)).Inc()
+
+		return true
+	}
+
+	return false
+}
+
+// runsProtectedPods returns true when given pods running on node with given name include pods from
+// configured protected namespaces.
+func (k *Kontroller) runsProtectedPods(nodeName string, pods []corev1.Pod) bool {
+	if len(k.protectedNamespaces) == 0 {
+		return false
+	}
+
+	pod := k8sutil.PodInNamespaces(pods, k.protectedNamespaces)
+	if pod == nil {
+		return false
+	}
+
+	klog.InfoS("Node runs pod from protected namespace",
+		"node", nodeName, "pod", klog.KObj(pod), "block", k.blockOnProtectedNamespaces)
+
+	return true
+}
+
+// deferProtectedNodes moves nodes running pods from configured protected namespaces to the end of given
+// list of nodes, keeping their order, so other nodes get rebooted first. Nodes for which pods could not be
+// listed are deferred as well. When blocking on protected namespaces is configured, such nodes are already
+// filtered out, so given list is returned as is.
+func (k *Kontroller) deferProtectedNodes(nodes []corev1.Node, nodePods map[string][]corev1.Pod) []corev1.Node {
+	if len(k.protectedNamespaces) == 0 || k.blockOnProtectedNamespaces {
+		return nodes
+	}
+
+	result := make([]corev1.Node, 0, len(nodes))
+	deferred := []corev1.Node{}
+
+	for _, node := range nodes {
+		if pods, ok := nodePods[node.Name]; !ok || k.runsProtectedPods(node.Name, pods) {
+			deferred = append(deferred, node)
+
+			continue
+		}
+
+		result = append(result, node)
+	}
+
+	return append(result, deferred...)
+}
+
+// sortByRebootPriority sorts given list of nodes by their reboot priority, so nodes with lower
+// priority are rebooted first. Nodes with equal priority retain their order.
+func (k *Kontroller) sortByRebootPriority(nodes []corev1.Node) {
+	if k.rebootPriorityAnnotation == "" {
+		return
+	}
+
+	priorities := make(map[string]int, len(nodes))
+
+	for _, node := range nodes {
+		node := node
+
+		priorities[node.Name] = k.rebootPriority(&node)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return priorities[nodes[i].Name] < priorities[nodes[j].Name]
+	})
+}
+
+// rebootPriority returns reboot priority of given node. If node has no priority
+// annotation or its value is invalid, default priority of 0 is returned.
+func (k *Kontroller) rebootPriority(node *corev1.Node) int {
+	value, ok := node.Annotations[k.rebootPriorityAnnotation]
+	if !ok {
+		return 0
+	}
+
+	priority, err := strconv.Atoi(value)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.rebootPriorityAnnotation, value)
+
+		return 0
+	}
+
+	return priority
+}
+
+// deferOwnNode moves the node operator runs on to the end of given list of nodes, so it gets
+// rebooted last. This avoids the leader being rebooted while other nodes still wait for a reboot.
+func (k *Kontroller) deferOwnNode(nodes []corev1.Node) []corev1.Node {
+	if k.nodeName == "" {
+		return nodes
+	}
+
+	result := make([]corev1.Node, 0, len(nodes))
+
+	var ownNode *corev1.Node
+
+	for i, node := range nodes {
+		if node.Name == k.nodeName {
+			ownNode = &nodes[i]
+
+			continue
+		}
+
+		result = append(result, node)
+	}
+
+	if ownNode == nil {
+		return nodes
+	}
+
+	if len(result) > 0 {
+		klog.V(4).InfoS("Deferring reboot of node running the operator until other nodes are rebooted", "node", k.nodeName)
+	}
+
+	return append(result, *ownNode)
+}
+
+// rebootableNodes returns list of nodes which can be marked for rebooting based on remaining capacity,
+// given list of all nodes, nodes going through the reboot process and nodes requiring reboot.
+func (k *Kontroller) rebootableNodes(
+	ctx context.Context, nodelist *corev1.NodeList, rebooting, nodesRequiringReboot []corev1.Node,
+) []*corev1.Node {
+	remainingCapacity := k.remainingRebootingCapacity(rebooting)
+
+	readyBudget := k.readyBudget(nodelist, rebooting)
+
+	// Applied before other filters, so nodes of the current rollout group which are blocked
+	// for other reasons still hold back the following groups.
+	nodesRequiringReboot = k.currentRolloutGroup(nodesRequiringReboot, rebooting)
+
+	nodesRequiringReboot = k.insideNodeRebootWindows(nodesRequiringReboot, time.Now())
+
+	// Pods are listed once per node, as multiple checks may need them.
+	nodePods := k.podsOnNodes(ctx, nodesRequiringReboot)
+
+	nodesRequiringReboot = k.withoutBlockedNodes(ctx, nodesRequiringReboot, nodePods)
+
+	// Nodes are listed in order of their names, so sorting them by priority
+	// keeps nodes with equal priority ordered by name.
+	k.sortForRebooting(nodesRequiringReboot)
+
+	nodesRequiringReboot = k.applyNotReadyNodePolicy(nodesRequiringReboot)
+
+	nodesRequiringReboot = k.deferTimedOutNodes(k.deferProtectedNodes(nodesRequiringReboot, nodePods))
+	nodesRequiringReboot = k.deferOwnNode(nodesRequiringReboot)
+
+	// At most one node from each zone is rebooting at a time.
+	busyZones := k.rebootingZones(rebooting)
+
+	chosenNodes := []*corev1.Node{}
+
+	for i := range nodesRequiringReboot {
+		node := &nodesRequiringReboot[i]
+		group := k.rebootGroup(node)
+
+		capacity, ok := remainingCapacity[group]
+		if !ok {
+			capacity = k.effectiveMaxRebootingNodes()
+		}
+
+		if capacity <= 0 {
+			continue
+		}
+
+		zone, limitedPerZone := k.nodeZone(node)
+
+		if _, busy := busyZones[zone]; limitedPerZone && busy {
+			klog.V(4).InfoS("Node shares zone with other rebooting node, skipping", "node", node.Name, "zone", zone)
+
+			continue
+		}
+
+		// Rebooting nodes which are not Ready does not reduce number of Ready nodes.
+		ready := k8sutil.NodeReady(node)
+
+		if ready && readyBudget <= 0 {
+			klog.InfoS("Not scheduling reboot, as it would drop Ready nodes below minimum ready fraction",
+				"node", node.Name, "minReadyFraction", k.minReadyFraction)
+
+			continue
+		}
+
+		if ready {
+			readyBudget--
+		}
+
+		remainingCapacity[group] = capacity - 1
+
+		if limitedPerZone {
+			busyZones[zone] = struct{}{}
+		}
+
+		chosenNodes = append(chosenNodes, node)
+	}
+
+	klog.InfoS("Found nodes that need a reboot", "count", len(chosenNodes))
+
+	return chosenNodes
+}
+
+// readyBudget returns how many more Ready nodes from given list may be scheduled for rebooting
+// without dropping fraction of Ready nodes below configured minimum ready fraction. Nodes which
+// are not Ready or are going through the reboot process, as given, are not counted as Ready.
+func (k *Kontroller) readyBudget(nodelist *corev1.NodeList, rebooting []corev1.Node) int {
+	if k.minReadyFraction == 0 {
+		return math.MaxInt
+	}
+
+	rebootingNames := map[string]struct{}{}
+
+	for _, node := range rebooting {
+		rebootingNames[node.Name] = struct{}{}
+	}
+
+	ready := 0
+
+	for i := range nodelist.Items {
+		node := &nodelist.Items[i]
+
+		if _, ok := rebootingNames[node.Name]; ok || !k8sutil.NodeReady(node) {
+			continue
+		}
+
+		ready++
+	}
+
+	minReady := int(math.Ceil(k.minReadyFraction * float64(len(nodelist.Items))))
+
+	return ready - minReady
+}
+
+// markBeforeReboot gets nodes which want to reboot and marks them with the
+// before-reboot=true label. This is considered the beginning of the reboot
+// process from the perspective of the update-operator. It will only mark
+// nodes with this label up to the maximum number of concurrently rebootable
+// nodes in each reboot group as configured with MaxRebootingNodes. It also checks if
+// we are inside the reboot window.
+// It cleans up the before-reboot annotations before it applies the label, in
+// case there are any left over from the last reboot.
+// If there is an error getting the list of nodes or updating any of them, an
+// error is immediately returned.
+func (k *Kontroller) markBeforeReboot(ctx context.Context) error {
+	chosenNodes, err := k.nodesToMarkBeforeReboot(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Set before-reboot=true for the chosen nodes.
+	for _, n := range chosenNodes {
+		if err := k.scheduleReboot(ctx, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markNodeBeforeReboot runs markBeforeReboot for given node. The node is labeled only if it would be
+// chosen for rebooting among all rebootable nodes, so the limits of rebooting nodes are respected.
+func (k *Kontroller) markNodeBeforeReboot(ctx context.Context, node *corev1.Node) error {
+	chosenNodes, err := k.nodesToMarkBeforeReboot(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range chosenNodes {
+		if n.Name == node.Name {
+			return k.scheduleReboot(ctx, n)
+		}
+	}
 
-	return ctx
+	return nil
 }
 
-// process performs the reconcilitation to coordinate reboots.
-func (k *Kontroller) process(ctx context.Context) {
-	klog.V(4).Info("Going through a loop cycle")
+// nodesToMarkBeforeReboot marks nodes with requested reboot as needing a reboot and returns nodes chosen
+// for rebooting among all nodes requiring reboot. No nodes are returned when scheduling is blocked.
+func (k *Kontroller) nodesToMarkBeforeReboot(ctx context.Context) ([]*corev1.Node, error) {
+	nodelist, err := k.listNodes(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
 
-	// First make sure that all of our nodes are in a well-defined state with
-	// respect to our annotations and labels, and if they are not, then try to
-	// fix them.
-	klog.V(4).Info("Cleaning up node state")
+	if err := k.requestReboots(ctx, nodelist); err != nil {
+		return nil, fmt.Errorf("requesting reboots: %w", err)
+	}
 
-	if err := k.cleanupState(ctx); err != nil {
-		klog.Errorf("Failed to cleanup node state: %v", err)
+	rebooting := k.rebootingNodes(nodelist)
+	nodesRequiringReboot := k.nodesRequiringReboot(nodelist)
 
-		return
+	k.recordRebootSlots(rebooting, nodesRequiringReboot)
+
+	if !k.schedulingAllowed(nodesRequiringReboot) {
+		return nil, nil
 	}
 
-	// Find nodes with the after-reboot=true label and check if all provided
-	// annotations are set. if all annotations are set to true then remove the
-	// after-reboot=true label and set reboot-ok=false, telling the agent that
-	// the reboot has completed.
-	klog.V(4).Info("Checking if configured after-reboot annotations are set to true")
+	return k.rebootableNodes(ctx, nodelist, rebooting, nodesRequiringReboot), nil
+}
 
-	if err := k.checkAfterReboot(ctx); err != nil {
-		klog.Errorf("Failed to check after reboot: %v", err)
+// schedulingAllowed returns true when any of given rebootable nodes may be scheduled for rebooting now.
+// Otherwise, reason why scheduling is blocked is recorded and logged.
+func (k *Kontroller) schedulingAllowed(nodes []corev1.Node) bool {
+	blocked := k.schedulingBlocked(nodes, time.Now())
 
-		return
-	}
+	k.recordSchedulingBlocked(blocked)
 
-	// Find nodes which just rebooted but haven't run after-reboot checks.
-	// remove after-reboot annotations and add the after-reboot=true label.
-	klog.V(4).Info("Labeling rebooted nodes with after-reboot label")
+	switch {
+	case errors.Is(blocked, ErrOutsideRebootWindow):
+		klog.V(4).InfoS("Outside the reboot window; not labeling rebootable nodes for now")
 
-	if err := k.markAfterReboot(ctx); err != nil {
-		klog.Errorf("Failed to update recently rebooted nodes: %v", err)
+		return false
+	case blocked != nil:
+		klog.InfoS("Not labeling rebootable nodes for now", "reason", blocked.Error())
 
-		return
+		return false
 	}
 
-	// Find nodes with the before-reboot=true label and check if all provided
-	// annotations are set. if all annotations are set to true then remove the
-	// before-reboot=true label and set reboot=ok=true, telling the agent it's
-	// time to reboot.
-	klog.V(4).Info("Checking if configured before-reboot annotations are set to true")
+	return true
+}
 
-	if err := k.checkBeforeReboot(ctx); err != nil {
-		klog.Errorf("Failed to check before reboot: %v", err)
+// scheduleReboot labels given node for before reboot checks, which starts its reboot process.
+func (k *Kontroller) scheduleReboot(ctx context.Context, node *corev1.Node) error {
+	// Taint is added first, so if labeling fails, it is retried together with labeling in the next loop.
+	if err := k.addRebootTaint(ctx, node.Name); err != nil {
+		return fmt.Errorf("tainting node for reboot: %w", err)
+	}
 
-		return
+	reason := map[string]string{
+		k.keys.AnnotationRebootReason: k.pendingRebootReason(node),
 	}
 
-	// Take some number of the rebootable nodes. remove before-reboot
-	// annotations and add the before-reboot=true label.
-	klog.V(4).Info("Labeling rebootable nodes with before-reboot label")
+	err := k.mark(ctx, node.Name, k.keys.LabelBeforeReboot, phaseBeforeReboot, k.beforeRebootAnnotations, reason)
+	if err != nil {
+		return fmt.Errorf("labeling node for before reboot checks: %w", err)
+	}
 
-	if err := k.markBeforeReboot(ctx); err != nil {
-		klog.Errorf("Failed to update rebootable nodes: %v", err)
+	k.recordPhaseTransition(node, phaseBeforeReboot, "reboot-needed")
+	k.notify(node.Name, NotificationEventRebootStarted)
 
-		return
-	}
+	return nil
 }
 
-// cleanupState attempts to make sure nodes are in a well-defined state before
-// performing state changes on them.
+// markAfterReboot gets nodes which have completed rebooting and marks them with
+// the after-reboot=true label once they are Ready. A node with the after-reboot=true
+// label or which is not Ready yet is still considered to be rebooting from the
+// perspective of the update-operator, even though it has completed rebooting from
+// the machines perspective.
+// It cleans up the after-reboot annotations before it applies the label, in
+// case there are any left over from the last reboot.
 // If there is an error getting the list of nodes or updating any of them, an
 // error is immediately returned.
-func (k *Kontroller) cleanupState(ctx context.Context) error {
-	nodelist, err := k.nc.List(ctx, metav1.ListOptions{})
+func (k *Kontroller) markAfterReboot(ctx context.Context) error {
+	// Filter out any nodes that are already labeled with after-reboot=true.
+	nodelist, err := k.listNodes(labels.NewSelector().Add(*k.notAfterRebootReq))
 	if err != nil {
 		return fmt.Errorf("listing nodes: %w", err)
 	}
 
-	for _, node := range nodelist.Items {
-		err = k8sutil.UpdateNodeRetry(ctx, k.nc, node.Name, func(node *corev1.Node) {
-			// Make sure that nodes with the before-reboot label actually
-			// still wants to reboot.
-			if _, exists := node.Labels[constants.LabelBeforeReboot]; !exists {
-				return
-			}
+	// Find nodes which just rebooted.
+	justRebootedNodes := k8sutil.FilterNodesByAnnotation(nodelist.Items, k.selectors.JustRebooted)
 
-			if rebootableSelector.Matches(fields.Set(node.Annotations)) {
-				return
-			}
+	klog.InfoS("Found rebooted nodes", "count", len(justRebootedNodes))
 
-			klog.Warningf("Node %q no longer wanted to reboot while we were trying to label it so: %v",
-				node.Name, node.Annotations)
-			delete(node.Labels, constants.LabelBeforeReboot)
-			for _, annotation := range k.beforeRebootAnnotations {
-				delete(node.Annotations, annotation)
-			}
-		})
-		if err != nil {
-			return fmt.Errorf("cleaning up node %q: %w", node.Name, err)
+	now := time.Now()
+
+	// For all the nodes which just rebooted, remove any old annotations and add the after-reboot=true label.
+	for _, n := range justRebootedNodes {
+		n := n
+
+		if err := k.markRebootedNode(ctx, &n, now); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-type checkRebootOptions struct {
-	req         *labels.Requirement
-	annotations []string
-	label       string
-	okToReboot  string
+// markNodeAfterReboot runs markAfterReboot for given node.
+func (k *Kontroller) markNodeAfterReboot(ctx context.Context, node *corev1.Node) error {
+	if !k.notAfterRebootReq.Matches(labels.Set(node.Labels)) ||
+		!k.selectors.JustRebooted.Matches(fields.Set(node.Annotations)) {
+		return nil
+	}
+
+	return k.markRebootedNode(ctx, node, time.Now())
 }
 
-// checkReboot gets all nodes with a given requirement and checks if all of the given annotations are set to true.
-//
-// If they are, it deletes given annotations and label, then sets ok-to-reboot annotation to either true or false,
-// depending on the given parameter.
-//
-// If ok-to-reboot is set to true, it gives node agent a signal that it is OK to proceed with rebooting.
-//
-// If ok-to-reboot is set to false, it means node has finished rebooting successfully.
-//
-// If there is an error getting the list of nodes or updating any of them, an
-// error is immediately returned.
-func (k *Kontroller) checkReboot(ctx context.Context, opt checkRebootOptions) error {
-	nodelist, err := k.nc.List(ctx, metav1.ListOptions{})
+// markRebootedNode labels given node, which just rebooted, for after reboot checks, if it is ready for them
+// at given time.
+func (k *Kontroller) markRebootedNode(ctx context.Context, node *corev1.Node, now time.Time) error {
+	if !k.afterRebootChecksRetryable(node, now) {
+		return nil
+	}
+
+	stabilized, err := k.stabilized(ctx, node, now)
 	if err != nil {
-		return fmt.Errorf("listing nodes: %w", err)
+		return fmt.Errorf("checking if node %q is stabilized: %w", node.Name, err)
 	}
 
-	nodes := k8sutil.FilterNodesByRequirement(nodelist.Items, opt.req)
+	if !stabilized {
+		return nil
+	}
 
-	for _, node := range nodes {
-		if !hasAllAnnotations(node, opt.annotations) {
-			continue
-		}
+	// Annotations are updated by the agent as soon as it starts, which may happen before
+	// the kubelet rejoins the cluster.
+	if !k8sutil.NodeReady(node) {
+		klog.V(4).InfoS("Node rebooted, but is not Ready yet", "node", node.Name)
+
+		return nil
+	}
 
-		klog.V(4).Infof("Deleting label %q for %q", opt.label, node.Name)
-		klog.V(4).Infof("Setting annotation %q to %q for %q",
-			constants.AnnotationOkToReboot, opt.okToReboot, node.Name)
+	err = k.mark(ctx, node.Name, k.keys.LabelAfterReboot, phaseAfterReboot, k.afterRebootAnnotations, nil)
+	if err != nil {
+		return fmt.Errorf("labeling node for after reboot checks: %w", err)
+	}
 
-		if err := k8sutil.UpdateNodeRetry(ctx, k.nc, node.Name, func(node *corev1.Node) {
-			delete(node.Labels, opt.label)
+	k.recordPhaseTransition(node, phaseAfterReboot, k.rebootReason(node))
 
-			// Cleanup the annotations.
-			for _, annotation := range opt.annotations {
-				klog.V(4).Infof("Deleting annotation %q from node %q", annotation, node.Name)
-				delete(node.Annotations, annotation)
-			}
+	return nil
+}
 
-			node.Annotations[constants.AnnotationOkToReboot] = opt.okToReboot
-		}); err != nil {
-			return fmt.Errorf("updating node %q: %w", node.Name, err)
-		}
+// recordRebootCycleFinished records duration of the reboot process of given node, which finished at given time.
+// If the duration exceeds configured reboot SLO, a warning event is emitted for the node.
+func (k *Kontroller) recordRebootCycleFinished(node *corev1.Node, finishedAt time.Time) {
+	startedAt, ok := k.rebootCycleStartTime(node)
+	if !ok {
+		return
 	}
 
-	return nil
+	duration := finishedAt.Sub(startedAt)
+	pool := node.Labels[k.keys.LabelGroup]
+
+	rebootCycleDuration.With(poolLabels(pool)).Observe(duration.Seconds())
+
+	if k.rebootSLO == 0 || duration <= k.rebootSLO {
+		return
+	}
+
+	rebootSLOBreaches.With(k.nodeLabels(node)).Inc()
+
+	k.eventRecorder.Eventf(node, corev1.EventTypeWarning, eventReasonRebootSLOBreached,
+		"Reboot process of node %q took %v, exceeding SLO of %v", node.Name, duration.Round(time.Second), k.rebootSLO)
 }
 
-// checkBeforeReboot gets all nodes with the before-reboot=true label and checks
-// if all of the configured before-reboot annotations are set to true. If they
-// are, it deletes the before-reboot=true label and sets reboot-ok=true to tell
-// the agent that it is ready to start the actual reboot process.
-// If there is an error getting the list of nodes or updating any of them, an
-// error is immediately returned.
-func (k *Kontroller) checkBeforeReboot(ctx context.Context) error {
-	opt := checkRebootOptions{
-		req:         beforeRebootReq,
-		annotations: k.beforeRebootAnnotations,
-		label:       constants.LabelBeforeReboot,
-		okToReboot:  constants.True,
+// recordRebootDuration records duration of the reboot of given node, from allowing it to reboot
+// until given time, when the reboot process finished.
+func (k *Kontroller) recordRebootDuration(node *corev1.Node, finishedAt time.Time) {
+	startedAt, ok := k.rebootStartedTime(node)
+	if !ok {
+		return
 	}
 
-	return k.checkReboot(ctx, opt)
+	rebootDuration.With(poolLabels(node.Labels[k.keys.LabelGroup])).Observe(finishedAt.Sub(startedAt).Seconds())
 }
 
-// checkAfterReboot gets all nodes with the after-reboot=true label and checks
-// if all of the configured after-reboot annotations are set to true. If they
-// are, it deletes the after-reboot=true label and sets reboot-ok=false to tell
-// the agent that it has completed it's reboot successfully.
-// If there is an error getting the list of nodes or updating any of them, an
-// error is immediately returned.
-func (k *Kontroller) checkAfterReboot(ctx context.Context) error {
-	opt := checkRebootOptions{
-		req:         afterRebootReq,
-		annotations: k.afterRebootAnnotations,
-		label:       constants.LabelAfterReboot,
-		okToReboot:  constants.False,
+// rebootCycleStartTime returns time recorded in reboot cycle start time annotation of given node.
+func (k *Kontroller) rebootCycleStartTime(node *corev1.Node) (time.Time, bool) {
+	value, ok := node.Annotations[k.keys.AnnotationRebootCycleStartTime]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationRebootCycleStartTime, value)
+
+		return time.Time{}, false
 	}
 
-	return k.checkReboot(ctx, opt)
+	return time.Unix(timestamp, 0), true
 }
 
-// insideRebootWindow checks if process is inside reboot window at the time
-// of calling this function.
-//
-// If reboot window is not configured, true is always returned.
-func (k *Kontroller) insideRebootWindow() bool {
-	if k.rebootWindow == nil {
-		return true
+// withinRevertGracePeriod returns true if before reboot checks of given node, which no longer wants
+// to reboot, should not be reverted yet. When the node is seen not wanting to reboot for the first time,
+// the time is recorded on the node, so the grace period is preserved across operator restarts.
+func (k *Kontroller) withinRevertGracePeriod(node *corev1.Node, now time.Time) bool {
+	if k.revertGracePeriod == 0 {
+		return false
 	}
 
-	// Most recent reboot window might still be open.
-	mostRecentRebootWindow := k.rebootWindow.Previous(time.Now())
+	unwantedSince, ok := k.rebootUnwantedTime(node)
+	if !ok {
+		klog.InfoS("Node no longer wants to reboot, waiting for grace period before removing label",
+			"node", node.Name, "gracePeriod", k.revertGracePeriod)
+
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+
+		node.Annotations[k.keys.AnnotationRebootUnwantedTime] = strconv.FormatInt(now.Unix(), 10)
+
+		return true
+	}
 
-	return time.Now().Before(mostRecentRebootWindow.End)
+	return now.Sub(unwantedSince) < k.revertGracePeriod
 }
 
-// remainingRebootingCapacity calculates how many more nodes can be rebooted at a time based
-// on a given list of nodes.
-//
-// If maximum capacity is reached, it is logged and list of rebooting nodes is logged as well.
-func (k *Kontroller) remainingRebootingCapacity(nodelist *corev1.NodeList) int {
-	rebootingNodes := k8sutil.FilterNodesByAnnotation(nodelist.Items, stillRebootingSelector)
+// rebootUnwantedTime returns time recorded in reboot unwanted time annotation of given node.
+func (k *Kontroller) rebootUnwantedTime(node *corev1.Node) (time.Time, bool) {
+	value, ok := node.Annotations[k.keys.AnnotationRebootUnwantedTime]
+	if !ok {
+		return time.Time{}, false
+	}
 
-	// Nodes running before and after reboot checks are still considered to be "rebooting" to us.
-	beforeRebootNodes := k8sutil.FilterNodesByRequirement(nodelist.Items, beforeRebootReq)
-	afterRebootNodes := k8sutil.FilterNodesByRequirement(nodelist.Items, afterRebootReq)
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationRebootUnwantedTime, value)
 
-	rebootingNodes = append(append(rebootingNodes, beforeRebootNodes...), afterRebootNodes...)
+		return time.Time{}, false
+	}
 
-	remainingCapacity := k.maxRebootingNodes - len(rebootingNodes)
+	return time.Unix(timestamp, 0), true
+}
 
-	if remainingCapacity == 0 {
-		for _, n := range rebootingNodes {
-			klog.Infof("Found node %q still rebooting, waiting", n.Name)
-		}
+// rebootStartedTime returns time recorded in reboot started time annotation of given node.
+func (k *Kontroller) rebootStartedTime(node *corev1.Node) (time.Time, bool) {
+	value, ok := node.Annotations[k.keys.AnnotationRebootStartedTime]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationRebootStartedTime, value)
 
-		klog.Infof("Found %d (of max %d) rebooting nodes; waiting for completion", len(rebootingNodes), k.maxRebootingNodes)
+		return time.Time{}, false
 	}
 
-	return remainingCapacity
+	return time.Unix(timestamp, 0), true
 }
 
-// nodesRequiringReboot filters given list of nodes and returns ones which requires a reboot.
-func (k *Kontroller) nodesRequiringReboot(nodelist *corev1.NodeList) []corev1.Node {
-	rebootableNodes := k8sutil.FilterNodesByAnnotation(nodelist.Items, rebootableSelector)
+// cordon marks given node as unschedulable, if operator is configured to do so. Nodes which
+// are already unschedulable are left untouched, so they are not made schedulable after the reboot.
+func (k *Kontroller) cordon(node *corev1.Node) {
+	if !k.cordonBeforeReboot || node.Spec.Unschedulable {
+		return
+	}
 
-	return k8sutil.FilterNodesByRequirement(rebootableNodes, notBeforeRebootReq)
+	klog.V(4).InfoS("Marking node as unschedulable", "node", node.Name)
+
+	node.Spec.Unschedulable = true
+	node.Annotations[k.keys.AnnotationOperatorMadeUnschedulable] = constants.True
 }
 
-// rebootableNodes returns list of nodes which can be marked for rebooting based on remaining capacity.
-func (k *Kontroller) rebootableNodes(nodelist *corev1.NodeList) []*corev1.Node {
-	remainingCapacity := k.remainingRebootingCapacity(nodelist)
+// uncordon marks given node as schedulable, if it has been made unschedulable by the operator.
+// Returns true if the node has been marked as schedulable.
+func (k *Kontroller) uncordon(node *corev1.Node) bool {
+	if !k.madeUnschedulableByOperator(node) {
+		return false
+	}
+
+	klog.V(4).InfoS("Marking node as schedulable", "node", node.Name)
 
-	nodesRequiringReboot := k.nodesRequiringReboot(nodelist)
+	node.Spec.Unschedulable = false
+	delete(node.Annotations, k.keys.AnnotationOperatorMadeUnschedulable)
 
-	chosenNodes := make([]*corev1.Node, 0, remainingCapacity)
-	for i := 0; i < remainingCapacity && i < len(nodesRequiringReboot); i++ {
-		chosenNodes = append(chosenNodes, &nodesRequiringReboot[i])
+	return true
+}
+
+// uncordonLeftover marks given node as schedulable, if it has been made unschedulable by the operator,
+// but it is no longer going through the reboot process, e.g. because its reboot process has been
+// finished manually. Returns true if the node has been marked as schedulable.
+func (k *Kontroller) uncordonLeftover(node *corev1.Node) bool {
+	if !k.madeUnschedulableByOperator(node) || node.Annotations[k.keys.AnnotationOkToReboot] == constants.True ||
+		k.selectors.NodeInPhase(node, k8sutil.NodePhaseBeforeReboot) ||
+		k.selectors.NodeInPhase(node, k8sutil.NodePhaseAfterReboot) {
+		return false
 	}
 
-	klog.Infof("Found %d nodes that need a reboot", len(chosenNodes))
+	klog.InfoS("Node made unschedulable by the operator is no longer rebooting, marking it as schedulable",
+		"node", node.Name)
 
-	return chosenNodes
+	return k.uncordon(node)
 }
 
-// markBeforeReboot gets nodes which want to reboot and marks them with the
-// before-reboot=true label. This is considered the beginning of the reboot
-// process from the perspective of the update-operator. It will only mark
-// nodes with this label up to the maximum number of concurrently rebootable
-// nodes as configured with the maxRebootingNodes constant. It also checks if
-// we are inside the reboot window.
-// It cleans up the before-reboot annotations before it applies the label, in
-// case there are any left over from the last reboot.
-// If there is an error getting the list of nodes or updating any of them, an
-// error is immediately returned.
-func (k *Kontroller) markBeforeReboot(ctx context.Context) error {
-	nodelist, err := k.nc.List(ctx, metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("listing nodes: %w", err)
+// ensureUncordoned verifies that node with given name, which has just been marked as schedulable, is
+// schedulable. If it is not, e.g. because update got overridden, marking it as schedulable is retried.
+// If the node remains unschedulable, a warning event is emitted for it.
+func (k *Kontroller) ensureUncordoned(ctx context.Context, nodeName string) error {
+	for attempt := 1; ; attempt++ {
+		node, err := k.nc.Get(ctx, nodeName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("getting node %q: %w", nodeName, err)
+		}
+
+		if !node.Spec.Unschedulable {
+			return nil
+		}
+
+		if attempt > uncordonRetries {
+			klog.InfoS("Node remains unschedulable after marking it as schedulable, giving up",
+				"node", nodeName, "attempts", uncordonRetries)
+
+			k.eventRecorder.Eventf(node, corev1.EventTypeWarning, eventReasonNodeRemainsUnschedulable,
+				"Node %q remains unschedulable after %d attempts to mark it as schedulable", nodeName, uncordonRetries)
+
+			return nil
+		}
+
+		klog.InfoS("Node remains unschedulable after marking it as schedulable, retrying",
+			"node", nodeName, "attempt", attempt)
+
+		if err := k8sutil.UpdateNodeRetry(ctx, k.nc, nodeName, func(node *corev1.Node) {
+			node.Spec.Unschedulable = false
+		}); err != nil {
+			return fmt.Errorf("marking node %q as schedulable: %w", nodeName, err)
+		}
 	}
+}
+
+// madeUnschedulableByOperator returns true if given node has been made unschedulable by the operator.
+func (k *Kontroller) madeUnschedulableByOperator(node *corev1.Node) bool {
+	return node.Annotations[k.keys.AnnotationOperatorMadeUnschedulable] == constants.True
+}
 
-	if !k.insideRebootWindow() {
-		klog.V(4).Info("We are outside the reboot window; not labeling rebootable nodes for now")
+// allowKarpenterDisruption removes annotation preventing Karpenter from disrupting given node,
+// if operator is configured to manage it.
+func (k *Kontroller) allowKarpenterDisruption(node *corev1.Node) {
+	if k.karpenterDoNotDisrupt {
+		delete(node.Annotations, karpenterDoNotDisruptAnnotation)
+	}
+}
 
+// addRebootTaint adds configured reboot taint to given node, if operator is configured to do so.
+func (k *Kontroller) addRebootTaint(ctx context.Context, nodeName string) error {
+	if k.rebootTaint == nil {
 		return nil
 	}
 
-	// Set before-reboot=true for the chosen nodes.
-	for _, n := range k.rebootableNodes(nodelist) {
-		err = k.mark(ctx, n.Name, constants.LabelBeforeReboot, "before-reboot", k.beforeRebootAnnotations)
-		if err != nil {
-			return fmt.Errorf("labeling node for before reboot checks: %w", err)
-		}
+	klog.V(4).InfoS("Adding taint to node", "node", nodeName, "taint", k.rebootTaint.ToString())
+
+	if err := k8sutil.AddNodeTaint(ctx, k.nc, nodeName, *k.rebootTaint); err != nil {
+		return fmt.Errorf("adding taint %q to node %q: %w", k.rebootTaint.ToString(), nodeName, err)
 	}
 
 	return nil
 }
 
-// markAfterReboot gets nodes which have completed rebooting and marks them with
-// the after-reboot=true label. A node with the after-reboot=true label is still
-// considered to be rebooting from the perspective of the update-operator, even
-// though it has completed rebooting from the machines perspective.
-// It cleans up the after-reboot annotations before it applies the label, in
-// case there are any left over from the last reboot.
-// If there is an error getting the list of nodes or updating any of them, an
-// error is immediately returned.
-func (k *Kontroller) markAfterReboot(ctx context.Context) error {
-	nodelist, err := k.nc.List(ctx, metav1.ListOptions{
-		// Filter out any nodes that are already labeled with after-reboot=true.
-		LabelSelector: fmt.Sprintf("%s!=%s", constants.LabelAfterReboot, constants.True),
-	})
-	if err != nil {
-		return fmt.Errorf("listing nodes: %w", err)
+// removeRebootTaint removes configured reboot taint from given node, if operator is configured to manage it.
+func (k *Kontroller) removeRebootTaint(ctx context.Context, nodeName string) error {
+	if k.rebootTaint == nil {
+		return nil
 	}
 
-	// Find nodes which just rebooted.
-	justRebootedNodes := k8sutil.FilterNodesByAnnotation(nodelist.Items, justRebootedSelector)
-
-	klog.Infof("Found %d rebooted nodes", len(justRebootedNodes))
+	klog.V(4).InfoS("Removing taint from node", "node", nodeName, "taint", k.rebootTaint.ToString())
 
-	// For all the nodes which just rebooted, remove any old annotations and add the after-reboot=true label.
-	for _, n := range justRebootedNodes {
-		err = k.mark(ctx, n.Name, constants.LabelAfterReboot, "after-reboot", k.afterRebootAnnotations)
-		if err != nil {
-			return fmt.Errorf("labeling node for after reboot checks: %w", err)
-		}
+	if err := k8sutil.RemoveNodeTaint(ctx, k.nc, nodeName, *k.rebootTaint); err != nil {
+		return fmt.Errorf("removing taint %q from node %q: %w", k.rebootTaint.ToString(), nodeName, err)
 	}
 
 	return nil
 }
 
-func (k *Kontroller) mark(ctx context.Context, nodeName, label, annotationsType string, annotations []string) error {
-	klog.V(4).Infof("Deleting annotations %v for %q", annotations, nodeName)
-	klog.V(4).Infof("Setting label %q to %q for node %q", label, constants.True, nodeName)
+// rebootReason returns reason for given just rebooted node entering after-reboot phase,
+// distinguishing reboots approved by the operator from reboots which happened on their own.
+func (k *Kontroller) rebootReason(node *corev1.Node) string {
+	if node.Annotations[k.keys.AnnotationRebootApproved] == constants.True {
+		return reasonVoluntaryReboot
+	}
+
+	klog.InfoS("Node rebooted without approval from the operator", "node", node.Name)
+
+	return reasonInvoluntaryReboot
+}
+
+// mark deletes given annotations from given node, sets given annotations and labels the node with given label.
+func (k *Kontroller) mark(
+	ctx context.Context, nodeName, label, annotationsType string, annotations []string, setAnnotations map[string]string,
+) error {
+	klog.V(4).InfoS("Deleting annotations", "node", nodeName, "phase", annotationsType, "annotations", annotations)
+	klog.V(4).InfoS("Setting label", "node", nodeName, "phase", annotationsType, "label", label, "value", constants.True)
 
 	err := k8sutil.UpdateNodeRetry(ctx, k.nc, nodeName, func(node *corev1.Node) {
 		for _, annotation := range annotations {
 			delete(node.Annotations, annotation)
 		}
+
+		for key, value := range setAnnotations {
+			node.Annotations[key] = value
+		}
+
+		// Make sure approval from previous reboot is not reused.
+		if label == k.keys.LabelBeforeReboot {
+			for _, gateLabel := range k.beforeRebootApprovalLabels {
+				delete(node.Labels, gateLabel)
+			}
+
+			if k.karpenterDoNotDisrupt {
+				node.Annotations[karpenterDoNotDisruptAnnotation] = constants.True
+			}
+
+			k.cordon(node)
+
+			node.Annotations[k.keys.AnnotationRebootCycleStartTime] = strconv.FormatInt(time.Now().Unix(), 10)
+		}
+
+		if label == k.keys.LabelAfterReboot {
+			delete(node.Annotations, k.keys.AnnotationReadySinceTime)
+
+			if k.afterRebootCheckTimeout != 0 {
+				node.Annotations[k.keys.AnnotationAfterRebootAttemptTime] = strconv.FormatInt(time.Now().Unix(), 10)
+			}
+		}
+
 		node.Labels[label] = constants.True
 	})
 	if err != nil {
@@ -618,21 +3308,142 @@ func (k *Kontroller) mark(ctx context.Context, nodeName, label, annotationsType
 	}
 
 	if len(annotations) > 0 {
-		klog.Infof("Waiting for %s annotations on node %q: %v", annotationsType, nodeName, annotations)
+		klog.InfoS("Waiting for annotations on node",
+			"node", nodeName, "phase", annotationsType, "annotations", k.describeAnnotations(annotations))
+	}
+
+	if label == k.keys.LabelBeforeReboot && k.drainCompleteAnnotation != "" {
+		klog.InfoS("Waiting for drain of node to complete",
+			"node", nodeName, "phase", annotationsType, "annotation", k.drainCompleteAnnotation)
 	}
 
 	return nil
 }
 
-func hasAllAnnotations(node corev1.Node, annotations []string) bool {
+// pendingRebootReason returns reason of the reboot process of given node, which is about to start,
+// based on configured reboot reason source annotation. When source annotation holds version of
+// the pending update, reason describes the version transition.
+func (k *Kontroller) pendingRebootReason(node *corev1.Node) string {
+	target := node.Annotations[k.rebootReasonSourceAnnotation]
+	if target == "" && node.Annotations[k.keys.AnnotationRequestReboot] == constants.True {
+		return rebootReasonRequested
+	}
+
+	if target == "" {
+		return rebootReasonUnknown
+	}
+
+	current := node.Labels[k.keys.LabelVersion]
+	if k.rebootReasonSourceAnnotation != k.keys.AnnotationNewVersion || current == "" {
+		return target
+	}
+
+	return fmt.Sprintf("update from %s to %s", current, target)
+}
+
+// recordRebootReason moves reason of the reboot process of given node to configured last reboot
+// reason annotation. Nodes which started the reboot process before reasons were recorded or which
+// rebooted without approval from the operator get an unknown reason.
+func (k *Kontroller) recordRebootReason(node *corev1.Node) {
+	reason, ok := node.Annotations[k.keys.AnnotationRebootReason]
+	if !ok {
+		reason = rebootReasonUnknown
+	}
+
+	node.Annotations[k.lastRebootReasonAnnotation] = reason
+
+	delete(node.Annotations, k.keys.AnnotationRebootReason)
+}
+
+// logInvalidAnnotation logs that given invalid value of given annotation on given node is ignored.
+func logInvalidAnnotation(err error, node *corev1.Node, annotation, value string) {
+	klog.ErrorS(err, "Ignoring invalid annotation value", "node", node.Name, "annotation", annotation, "value", value)
+}
+
+// missingAnnotations returns given annotations which given node does not have set to their expected values.
+func missingAnnotations(node *corev1.Node, annotations []string, expectedValues map[string]string) []string {
 	nodeAnnotations := node.GetAnnotations()
 
+	var missing []string
+
 	for _, annotation := range annotations {
 		value, ok := nodeAnnotations[annotation]
-		if !ok || value != constants.True {
-			return false
+		if !ok || value != expectedValues[annotation] {
+			missing = append(missing, annotation)
 		}
 	}
 
-	return true
+	return missing
+}
+
+// parseAnnotationChecks parses annotations in format "<key>[=<value>]" into annotation keys and values
+// they are expected to have. Annotations without value are expected to be set to "true".
+func parseAnnotationChecks(annotations []string) ([]string, map[string]string, error) {
+	keys := make([]string, 0, len(annotations))
+	expectedValues := make(map[string]string, len(annotations))
+
+	for _, annotation := range annotations {
+		key, value := annotation, constants.True
+
+		if index := strings.Index(annotation, "="); index != -1 {
+			key, value = annotation[:index], annotation[index+1:]
+		}
+
+		if key == "" {
+			return nil, nil, fmt.Errorf("annotation %q has empty key", annotation)
+		}
+
+		if _, ok := expectedValues[key]; ok {
+			return nil, nil, fmt.Errorf("annotation %q specified more than once", key)
+		}
+
+		keys = append(keys, key)
+		expectedValues[key] = value
+	}
+
+	return keys, expectedValues, nil
+}
+
+// beforeRebootAnnotationChecks returns before reboot annotations from given configuration, including
+// drain complete annotation, if configured.
+func beforeRebootAnnotationChecks(config Config) []string {
+	annotations := append([]string{}, config.BeforeRebootAnnotations...)
+
+	if config.DrainCompleteAnnotation != "" {
+		annotations = append(annotations, config.DrainCompleteAnnotation)
+	}
+
+	return annotations
+}
+
+// parseAnnotationDescriptions parses annotation descriptions in format "<annotation>=<description>"
+// into descriptions by annotation.
+func parseAnnotationDescriptions(values []string) (map[string]string, error) {
+	descriptions := make(map[string]string, len(values))
+
+	for _, value := range values {
+		index := strings.Index(value, "=")
+		if index <= 0 || index == len(value)-1 {
+			return nil, fmt.Errorf("annotation description %q must be in format <annotation>=<description>", value)
+		}
+
+		descriptions[value[:index]] = value[index+1:]
+	}
+
+	return descriptions, nil
+}
+
+// describeAnnotations returns given annotations followed by their descriptions, if configured.
+func (k *Kontroller) describeAnnotations(annotations []string) []string {
+	described := make([]string, 0, len(annotations))
+
+	for _, annotation := range annotations {
+		if description, ok := k.annotationDescriptions[annotation]; ok {
+			annotation = fmt.Sprintf("%s (%s)", annotation, description)
+		}
+
+		described = append(described, annotation)
+	}
+
+	return described
 }
@@ -3,22 +3,45 @@ package operator_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/fake"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/klog/v2"
 
 	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/metrics"
 	"github.com/flatcar/flatcar-linux-update-operator/pkg/operator"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/webhook"
 )
 
 const (
@@ -100,6 +123,171 @@ func Test_Creating_new_operator(t *testing.T) {
 			}
 		})
 
+		t.Run("invalid_leader_election_resource_name_is_configured", func(t *testing.T) {
+			config := validOperatorConfig()
+			config.LeaderElectionResourceName = "Invalid_Name"
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("invalid_before_reboot_label_selector_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.BeforeRebootLabelSelector = "foo in (bar"
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("before_reboot_annotation_without_key_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.BeforeRebootAnnotations = []string{"=ok"}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("after_reboot_annotation_is_configured_more_than_once", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAfterRebootAnnotation + "=ok"}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("invalid_node_selector_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.NodeSelector = "foo in (bar"
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("invalid_pause_label_selector_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.PauseLabelSelector = "foo in bar"
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("unknown_reconcile_phase_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.ReconcilePhases = []string{"foo"}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("reconcile_phase_is_configured_more_than_once", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.ReconcilePhases = []string{operator.ReconcilePhaseCheckAfterReboot, operator.ReconcilePhaseCheckAfterReboot}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("cleanup_reconcile_phase_is_not_configured_first", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.ReconcilePhases = []string{operator.ReconcilePhaseCheckAfterReboot, operator.ReconcilePhaseCleanup}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("mark_reconcile_phase_is_configured_without_matching_check_phase", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.ReconcilePhases = []string{operator.ReconcilePhaseMarkBeforeReboot}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("reconcile_timeout_exceeds_reconciliation_period", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.ReconciliationPeriod = time.Second
+			config.ReconcileTimeout = 2 * time.Second
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("invalid_notification_template_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.NotificationWebhookURL = "http://localhost"
+			config.NotificationTemplate = "{{ .Node"
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("reboot_taint_with_unsupported_effect_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.RebootTaint = &corev1.Taint{Key: "foo", Effect: "Foo"}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("invalid_additional_reboot_window_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.RebootWindows = []operator.RebootWindow{{Start: "Mon 14:00", Length: "1j"}}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
+		t.Run("invalid_exclusion_window_is_configured", func(t *testing.T) {
+			t.Parallel()
+
+			config := validOperatorConfig()
+			config.ExclusionWindows = []operator.RebootWindow{{Start: "Foo 14:00", Length: "1h"}}
+
+			if _, err := operator.New(config); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+
 		t.Run("invalid_reboot_window_is_configured", func(t *testing.T) {
 			t.Parallel()
 
@@ -114,6 +302,129 @@ func Test_Creating_new_operator(t *testing.T) {
 	})
 }
 
+//nolint:paralleltest // This test use environment variables.
+func Test_Creating_new_operator_reads_namespace_from_POD_NAMESPACE_environment_variable_when_not_set(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "test-namespace")
+
+	config := validOperatorConfig()
+	config.Namespace = ""
+
+	if _, err := operator.New(config); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func Test_Validating_configuration(t *testing.T) {
+	t.Parallel()
+
+	t.Run("succeeds_without_fields_required_for_connecting_to_cluster", func(t *testing.T) {
+		t.Parallel()
+
+		config := operator.Config{
+			BeforeRebootAnnotations: []string{testBeforeRebootAnnotation + "=ok"},
+			RebootWindowStart:       "Mon 14:00",
+			RebootWindowLength:      "1h",
+			NodeSelector:            "flatcar.io/managed=true",
+		}
+
+		if err := config.Validate(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	cases := map[string]func(*operator.Config){
+		"lock_type_is_incorrect": func(c *operator.Config) {
+			c.LockType = "foo"
+		},
+		"invalid_leader_election_resource_name_is_configured": func(c *operator.Config) {
+			c.LeaderElectionResourceName = "foo/bar"
+		},
+		"blank_event_source_component_is_configured": func(c *operator.Config) {
+			c.EventSourceComponent = " "
+		},
+		"blank_leader_election_event_source_component_is_configured": func(c *operator.Config) {
+			c.LeaderElectionEventSourceComponent = " "
+		},
+		"annotation_description_without_description_is_configured": func(c *operator.Config) {
+			c.AnnotationDescriptions = []string{testBeforeRebootAnnotation + "="}
+		},
+		"drain_complete_annotation_duplicating_before_reboot_annotation_is_configured": func(c *operator.Config) {
+			c.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+			c.DrainCompleteAnnotation = testBeforeRebootAnnotation
+		},
+		"unsupported_node_selection_strategy_is_configured": func(c *operator.Config) {
+			c.NodeSelectionStrategy = "foo"
+		},
+		"after_reboot_smoke_test_pod_template_without_containers_is_configured": func(c *operator.Config) {
+			c.AfterRebootSmokeTestPodTemplate = &corev1.PodTemplateSpec{}
+		},
+		"invalid_protected_namespace_is_configured": func(c *operator.Config) {
+			c.ProtectedNamespaces = []string{"Payments"}
+		},
+		"unsupported_not_ready_node_policy_is_configured": func(c *operator.Config) {
+			c.NotReadyNodePolicy = "foo"
+		},
+		"negative_duration_is_configured": func(c *operator.Config) {
+			c.RebootSLO = -time.Minute
+		},
+		"minimum_ready_fraction_above_1_is_configured": func(c *operator.Config) {
+			c.MinReadyFraction = 1.5
+		},
+		"negative_maximum_number_of_rebooting_nodes_is_configured": func(c *operator.Config) {
+			c.MaxRebootingNodes = -1
+		},
+		"negative_ramp_up_successful_reboots_is_configured": func(c *operator.Config) {
+			c.RampUpSuccessfulReboots = -1
+		},
+		"negative_ramp_up_step_is_configured": func(c *operator.Config) {
+			c.RampUpStep = -1
+		},
+		"invalid_notification_template_is_configured": func(c *operator.Config) {
+			c.NotificationTemplate = "{{ .Node"
+		},
+		"invalid_pause_label_selector_is_configured": func(c *operator.Config) {
+			c.PauseLabelSelector = "foo in (bar"
+		},
+		"invalid_exclude_node_label_is_configured": func(c *operator.Config) {
+			c.ExcludeNodeLabel = "foo in (bar"
+		},
+		"after_reboot_ready_workload_of_unsupported_kind_is_configured": func(c *operator.Config) {
+			c.AfterRebootReadyWorkloads = []string{"statefulset/default/foo"}
+		},
+		"after_reboot_ready_workload_without_namespace_is_configured": func(c *operator.Config) {
+			c.AfterRebootReadyWorkloads = []string{"daemonset/foo"}
+		},
+		"invalid_annotation_prefix_is_configured": func(c *operator.Config) {
+			c.AnnotationPrefix = "Example Com/"
+		},
+		"unsupported_before_reboot_timeout_action_is_configured": func(c *operator.Config) {
+			c.BeforeRebootTimeoutAction = "foo"
+		},
+		"invalid_reboot_window_is_configured": func(c *operator.Config) {
+			c.RebootWindowStart = "Mon 14"
+			c.RebootWindowLength = "0s"
+		},
+		"invalid_reboot_window_profile_is_configured": func(c *operator.Config) {
+			c.RebootWindowProfiles = map[string][]operator.RebootWindow{"dev": {{Start: "Foo 14:00", Length: "1h"}}}
+		},
+	}
+
+	for name, mutateF := range cases {
+		mutateF := mutateF
+
+		t.Run("fails_when_"+name, func(t *testing.T) {
+			t.Parallel()
+
+			config := operator.Config{}
+			mutateF(&config)
+
+			if err := config.Validate(); err == nil {
+				t.Fatalf("Expected error")
+			}
+		})
+	}
+}
+
 func Test_Operator_exits_gracefully_when_user_requests_shutdown(t *testing.T) {
 	t.Parallel()
 
@@ -205,890 +516,6482 @@ func Test_Operator_shuts_down_leader_election_process_when_user_requests_shutdow
 	}
 }
 
-func Test_Operator_emits_events_about_leader_election_to_configured_namespace(t *testing.T) {
+func Test_Operator_creates_leader_election_lock_using(t *testing.T) {
 	t.Parallel()
 
-	config, fakeClient := testConfig()
-
-	<-process(contextWithDeadline(t), t, config, fakeClient)
+	t.Run("Lease_by_default", func(t *testing.T) {
+		t.Parallel()
 
-	events, err := config.Client.CoreV1().Events(config.Namespace).List(contextWithDeadline(t), metav1.ListOptions{})
-	if err != nil {
-		t.Fatalf("Failed listing events: %v", err)
-	}
+		config, fakeClient := testConfig(idleNode())
 
-	if len(events.Items) == 0 {
-		t.Fatalf("Expected at least one event to be published")
-	}
-}
+		ctx := contextWithDeadline(t)
 
-func Test_Operator_returns_error_when_leadership_is_lost(t *testing.T) {
-	t.Parallel()
+		<-process(ctx, t, config, fakeClient)
 
-	rebootCancelledNode := rebootCancelledNode()
+		lease, err := config.Client.CoordinationV1().Leases(config.Namespace).Get(ctx,
+			"flatcar-linux-update-operator-lock", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Getting lock Lease: %v", err)
+		}
 
-	config, fakeClient := testConfig(rebootCancelledNode)
-	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
-	config.ReconciliationPeriod = 1 * time.Second
-	config.LeaderElectionLease = 2 * time.Second
-	testKontroller := kontrollerWithObjects(t, config)
-	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		if h := lease.Spec.HolderIdentity; h == nil || *h != config.LockID {
+			t.Fatalf("Expected Lease to be held by %q, got %v", config.LockID, h)
+		}
 
-	stop := make(chan struct{})
+		configMaps, err := config.Client.CoreV1().ConfigMaps(config.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Failed listing ConfigMaps: %v", err)
+		}
 
-	t.Cleanup(func() {
-		close(stop)
+		if c := len(configMaps.Items); c != 0 {
+			t.Fatalf("Expected no ConfigMaps to be created, got %d", c)
+		}
 	})
 
-	errCh := make(chan error, 1)
+	t.Run("both_ConfigMap_and_Lease_when_migrating_from_ConfigMap_lock", func(t *testing.T) {
+		t.Parallel()
 
-	go func() {
-		errCh <- testKontroller.Run(stop)
-	}()
+		config, fakeClient := testConfig(idleNode())
+		config.LockType = "configmapsleases"
 
-	// Wait for one reconciliation cycle to run.
-	<-nodeUpdated
+		ctx := contextWithDeadline(t)
 
-	ctx := contextWithDeadline(t)
+		<-process(ctx, t, config, fakeClient)
 
-	// Ensure operator is functional.
-	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+		if _, err := config.Client.CoreV1().ConfigMaps(config.Namespace).Get(ctx,
+			"flatcar-linux-update-operator-lock", metav1.GetOptions{}); err != nil {
+			t.Fatalf("Getting lock ConfigMap: %v", err)
+		}
 
-	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-		t.Fatalf("Expected label %q to be removed from Node after waiting the reconciliation period",
-			constants.LabelBeforeReboot)
-	}
+		if _, err := config.Client.CoordinationV1().Leases(config.Namespace).Get(ctx,
+			"flatcar-linux-update-operator-lock", metav1.GetOptions{}); err != nil {
+			t.Fatalf("Getting lock Lease: %v", err)
+		}
+	})
 
-	stealLeaderElection(ctx, t, config)
+	t.Run("configured_resource_name", func(t *testing.T) {
+		t.Parallel()
 
-	// Wait lease time to ensure operator lost it.
-	time.Sleep(config.LeaderElectionLease)
+		config, fakeClient := testConfig(idleNode())
+		config.LeaderElectionResourceName = "pool-a-lock"
 
-	// Patch node object again to verify if operator is functional.
-	updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
-	updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
+		ctx := contextWithDeadline(t)
 
-	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
-		t.Fatalf("Updating Node object: %v", err)
-	}
+		<-process(ctx, t, config, fakeClient)
 
-	time.Sleep(config.ReconciliationPeriod)
+		if _, err := config.Client.CoordinationV1().Leases(config.Namespace).Get(ctx,
+			config.LeaderElectionResourceName, metav1.GetOptions{}); err != nil {
+			t.Fatalf("Getting lock Lease: %v", err)
+		}
 
-	updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+		if _, err := config.Client.CoordinationV1().Leases(config.Namespace).Get(ctx,
+			"flatcar-linux-update-operator-lock", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+			t.Fatalf("Expected default lock Lease not to be created, got: %v", err)
+		}
+	})
+}
 
-	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
-		t.Fatalf("Expected label %q to remain on Node", constants.LabelBeforeReboot)
-	}
+func Test_Operator_reports_reboot_status_of_managed_nodes(t *testing.T) {
+	t.Parallel()
 
-	if err := <-errCh; err == nil {
-		t.Fatalf("Expected operator to return error when leader election is lost")
-	}
-}
+	config, _ := testConfig(
+		idleNode(),
+		rebootableNode(),
+		scheduledForRebootNode(),
+		rebootingNode(),
+		justRebootedNode(),
+		finishedRebootingNode(),
+	)
+	// Only run phase which does not modify any of given nodes, so reported status is predictable.
+	config.ReconcilePhases = []string{operator.ReconcilePhaseCheckAfterReboot}
+	config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, "not-set-annotation"}
+	config.BeforeRebootAnnotations = []string{"not-set-before-reboot-annotation"}
 
-func stealLeaderElection(ctx context.Context, t *testing.T, config operator.Config) {
-	t.Helper()
-
-	configMapClient := config.Client.CoreV1().ConfigMaps(config.Namespace)
-
-	configMaps, err := configMapClient.List(ctx, metav1.ListOptions{})
-	if err != nil {
-		t.Fatalf("Failed listing ConfigMaps: %v", err)
-	}
-
-	if c := len(configMaps.Items); c != 1 {
-		t.Fatalf("Expected exactly one ConfigMap to exist, got %d", c)
-	}
+	testKontroller := kontrollerWithObjects(t, config)
 
-	lock := configMaps.Items[0]
+	reconciled := make(chan struct{}, 1)
 
-	leaderAnnotation := "control-plane.alpha.kubernetes.io/leader"
+	operator.SetReconciledHook(testKontroller, func() {
+		select {
+		case reconciled <- struct{}{}:
+		default:
+		}
+	})
 
-	leader, ok := lock.Annotations[leaderAnnotation]
-	if !ok {
-		t.Fatalf("Expected annotation %q not found", leaderAnnotation)
-	}
+	stop := make(chan struct{})
 
-	leaderLease := &struct {
-		HolderIdentity       string
-		LeaseDurationSeconds int
-		AcquireTime          time.Time
-		RenewTime            time.Time
-		LeaderTransitions    int
-	}{}
+	t.Cleanup(func() {
+		close(stop)
+	})
 
-	if err := json.Unmarshal([]byte(leader), leaderLease); err != nil {
-		t.Fatalf("Decoding leader annotation data %q: %v", leader, err)
-	}
+	runOperator(contextWithDeadline(t), t, testKontroller, stop)
 
-	leaderLease.HolderIdentity = "baz"
+	<-reconciled
 
-	leaderBytes, err := json.Marshal(leaderLease)
-	if err != nil {
-		t.Fatalf("Encoding leader annotation data: %q: %v", leader, err)
+	expectedStatus := operator.ClusterRebootStatus{
+		Nodes: []operator.NodeRebootStatus{
+			{
+				Name:               "finished-rebooting",
+				State:              operator.NodeRebootStateAfterReboot,
+				MissingAnnotations: []string{"not-set-annotation"},
+			},
+			{Name: "idle", State: operator.NodeRebootStateIdle},
+			{Name: "just-rebooted", State: operator.NodeRebootStateRebooting},
+			{Name: "rebootable", State: operator.NodeRebootStateRebootNeeded},
+			{Name: "rebooting", State: operator.NodeRebootStateRebooting},
+			{
+				Name:               "scheduled-for-reboot",
+				State:              operator.NodeRebootStateBeforeReboot,
+				MissingAnnotations: []string{"not-set-before-reboot-annotation"},
+			},
+		},
+		Totals: map[operator.NodeRebootState]int{
+			operator.NodeRebootStateAfterReboot:  1,
+			operator.NodeRebootStateIdle:         1,
+			operator.NodeRebootStateRebooting:    2,
+			operator.NodeRebootStateRebootNeeded: 1,
+			operator.NodeRebootStateBeforeReboot: 1,
+		},
 	}
 
-	lock.Annotations[leaderAnnotation] = string(leaderBytes)
-
-	if _, err := configMapClient.Update(ctx, &lock, metav1.UpdateOptions{}); err != nil {
-		t.Fatalf("Updating lock ConfigMap: %v", err)
+	if diff := cmp.Diff(expectedStatus, testKontroller.Status()); diff != "" {
+		t.Fatalf("Unexpected status: %s", diff)
 	}
 }
 
-func Test_Operator_waits_for_leader_election_before_reconciliation(t *testing.T) {
+//nolint:funlen,cyclop // Just many subtests.
+func Test_Operator_serves_health_endpoints_which_report(t *testing.T) {
 	t.Parallel()
 
-	rebootCancelledNode := rebootCancelledNode()
+	t.Run("ready_and_healthy_operator_after_first_reconciliation", func(t *testing.T) {
+		t.Parallel()
 
-	config, _ := testConfig(rebootCancelledNode)
-	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
-	config.ReconciliationPeriod = 1 * time.Second
-	testKontroller := kontrollerWithObjects(t, config)
+		config, fakeClient := testConfig(idleNode())
+		config.HealthAddress = freeAddress(t)
 
-	stop := make(chan struct{})
-	stopped := make(chan struct{})
+		ctx := contextWithDeadline(t)
 
-	go func() {
-		if err := testKontroller.Run(stop); err != nil {
-			fmt.Printf("Error running operator: %v\n", err)
-			t.Fail()
+		<-process(ctx, t, config, fakeClient)
+
+		for _, path := range []string{"/readyz", "/healthz"} {
+			if code := waitForHealthResponse(ctx, t, config.HealthAddress, path); code != http.StatusOK {
+				t.Fatalf("Expected status code %d for %q, got %d", http.StatusOK, path, code)
+			}
 		}
-		stopped <- struct{}{}
-	}()
+	})
 
-	time.Sleep(config.ReconciliationPeriod)
+	t.Run("healthy_but_not_ready_operator_waiting_for_leadership", func(t *testing.T) {
+		t.Parallel()
 
-	close(stop)
+		config, fakeClient := testConfig(idleNode())
 
-	<-stopped
+		ctx := contextWithDeadline(t)
 
-	ctx := contextWithDeadline(t)
-	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+		// Leader holds the lock until the test finishes.
+		<-process(ctx, t, config, fakeClient)
 
-	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-		t.Fatalf("Expected label %q to be removed from Node after waiting the reconciliation period",
-			constants.LabelBeforeReboot)
-	}
+		config.LockID = "bar"
+		config.HealthAddress = freeAddress(t)
 
-	updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
-	updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
+		parallelKontroller := kontrollerWithObjects(t, config)
 
-	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
-		t.Fatalf("Updating Node object: %v", err)
-	}
+		parallelStop := make(chan struct{})
 
-	config.LockID = "bar"
-	parallelKontroller := kontrollerWithObjects(t, config)
+		t.Cleanup(func() {
+			close(parallelStop)
+		})
 
-	stop = make(chan struct{})
+		runOperator(ctx, t, parallelKontroller, parallelStop)
 
-	t.Cleanup(func() {
-		close(stop)
+		if code := waitForHealthResponse(ctx, t, config.HealthAddress, "/readyz"); code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected status code %d for readiness, got %d", http.StatusServiceUnavailable, code)
+		}
+
+		if code := waitForHealthResponse(ctx, t, config.HealthAddress, "/healthz"); code != http.StatusOK {
+			t.Fatalf("Expected status code %d for liveness, got %d", http.StatusOK, code)
+		}
 	})
 
-	runOperator(ctx, t, parallelKontroller, stop)
+	t.Run("unhealthy_operator_when_reconciliation_keeps_failing", func(t *testing.T) {
+		t.Parallel()
 
-	time.Sleep(config.ReconciliationPeriod)
+		config, fakeClient := testConfig(rebootableNode())
+		config.HealthAddress = freeAddress(t)
+		config.ReconciliationPeriod = 100 * time.Millisecond
+		config.UnhealthyAfter = 300 * time.Millisecond
 
-	updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+		fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf(t.Name())
+		})
 
-	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
-		t.Fatalf("Expected label %q to remain on Node", constants.LabelBeforeReboot)
-	}
-}
+		testKontroller := kontrollerWithObjects(t, config)
 
-func Test_Operator_stops_reconciliation_loop_when_control_channel_is_closed(t *testing.T) {
-	t.Parallel()
+		stop := make(chan struct{})
 
-	rebootCancelledNode := rebootCancelledNode()
+		t.Cleanup(func() {
+			close(stop)
+		})
 
-	config, fakeClient := testConfig(rebootCancelledNode)
-	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
-	config.ReconciliationPeriod = 1 * time.Second
-	testKontroller := kontrollerWithObjects(t, config)
+		ctx := contextWithDeadline(t)
 
-	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		runOperator(ctx, t, testKontroller, stop)
 
-	stop := make(chan struct{})
+		err := wait.PollImmediateUntil(config.ReconciliationPeriod, func() (bool, error) {
+			return waitForHealthResponse(ctx, t, config.HealthAddress, "/healthz") == http.StatusServiceUnavailable, nil
+		}, ctx.Done())
+		if err != nil {
+			t.Fatalf("Timed out waiting for operator to be reported as unhealthy: %v", err)
+		}
+	})
+}
 
-	ctx := contextWithDeadline(t)
+func Test_Operator_serves_status_endpoint_with_reboot_state_of_nodes_as_of_last_reconciliation(t *testing.T) {
+	t.Parallel()
 
-	runOperator(ctx, t, testKontroller, stop)
+	config, fakeClient := testConfig(idleNode(), rebootingNode())
+	config.StatusAddress = freeAddress(t)
 
-	<-nodeUpdated
+	ctx := contextWithDeadline(t)
 
-	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+	<-process(ctx, t, config, fakeClient)
 
-	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-		t.Fatalf("Expected label %q to be removed from Node after waiting the reconciliation period",
-			constants.LabelBeforeReboot)
+	if code := waitForHealthResponse(ctx, t, config.StatusAddress, "/status"); code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
 	}
 
-	close(stop)
-
-	time.Sleep(config.ReconciliationPeriod * 2)
-
-	updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
-	updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
-
-	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
-		t.Fatalf("Updating Node object: %v", err)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+config.StatusAddress+"/status", nil)
+	if err != nil {
+		t.Fatalf("Creating request: %v", err)
 	}
 
-	time.Sleep(config.ReconciliationPeriod * 2)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Requesting status: %v", err)
+	}
 
-	updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+	t.Cleanup(func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Logf("Closing response body: %v", err)
+		}
+	})
 
-	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
-		t.Fatalf("Expected label %q to remain on Node", constants.LabelBeforeReboot)
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("Expected JSON content type, got %q", contentType)
 	}
-}
-
-func Test_Operator_reconciles_objects_every_configured_period(t *testing.T) {
-	t.Parallel()
 
-	rebootCancelledNode := rebootCancelledNode()
+	status := struct {
+		operator.ClusterRebootStatus
 
-	config, _ := testConfig(rebootCancelledNode)
-	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
-	config.ReconciliationPeriod = 1 * time.Second
-	testKontroller := kontrollerWithObjects(t, config)
+		InsideRebootWindow    bool `json:"insideRebootWindow"`
+		InsideExclusionWindow bool `json:"insideExclusionWindow"`
+		Paused                bool `json:"paused"`
+		RebootingNodes        int  `json:"rebootingNodes"`
+		MaxRebootingNodes     int  `json:"maxRebootingNodes"`
 
-	stop := make(chan struct{})
+		SchedulingBlockedReason string `json:"schedulingBlockedReason"`
+	}{}
 
-	t.Cleanup(func() {
-		close(stop)
-	})
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Decoding status: %v", err)
+	}
 
-	ctx := contextWithDeadline(t)
+	expectedNodes := []operator.NodeRebootStatus{
+		{Name: idleNode().Name, State: operator.NodeRebootStateIdle},
+		{Name: rebootingNode().Name, State: operator.NodeRebootStateRebooting},
+	}
 
-	runOperator(ctx, t, testKontroller, stop)
+	if diff := cmp.Diff(expectedNodes, status.Nodes); diff != "" {
+		t.Fatalf("Unexpected nodes status: %s", diff)
+	}
 
-	time.Sleep(config.ReconciliationPeriod)
+	if !status.InsideRebootWindow || status.InsideExclusionWindow || status.Paused {
+		t.Fatalf("Expected operator inside reboot window, outside exclusion window and not paused, got %+v", status)
+	}
 
-	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+	if status.RebootingNodes != 1 || status.MaxRebootingNodes != 1 {
+		t.Fatalf("Expected 1 of 1 allowed nodes to be rebooting, got %d of %d",
+			status.RebootingNodes, status.MaxRebootingNodes)
+	}
 
-	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-		t.Fatalf("Expected label %q to be removed from Node after waiting the reconciliation period",
-			constants.LabelBeforeReboot)
+	if status.SchedulingBlockedReason != "" {
+		t.Fatalf("Expected scheduling not to be blocked, got reason %q", status.SchedulingBlockedReason)
 	}
+}
 
-	updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
-	updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
+// freeAddress returns local address with a port which is currently not in use.
+func freeAddress(t *testing.T) string {
+	t.Helper()
 
-	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
-		t.Fatalf("Updating Node object: %v", err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listening on random port: %v", err)
 	}
 
-	time.Sleep(config.ReconciliationPeriod * 2)
-
-	updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+	address := listener.Addr().String()
 
-	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-		t.Fatalf("Expected label %q to be removed from Node after waiting another reconciliation period",
-			constants.LabelBeforeReboot)
+	if err := listener.Close(); err != nil {
+		t.Fatalf("Closing listener: %v", err)
 	}
+
+	return address
 }
 
-// before-reboot label is intended to be used as a selector for pre-reboot hooks, so it should only
-// be set for nodes, which are ready to start rebooting any minute.
-func Test_Operator_cleans_up_nodes_which_cannot_be_rebooted(t *testing.T) {
-	t.Parallel()
+// waitForHealthResponse waits until health endpoint with given path responds and returns response status code.
+func waitForHealthResponse(ctx context.Context, t *testing.T, address, path string) int {
+	t.Helper()
 
-	rebootCancelledNode := rebootCancelledNode()
+	var code int
 
-	toBeRebootedNode := &corev1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "bar",
-			Annotations: map[string]string{
-				testBeforeRebootAnnotation: "",
-			},
-		},
-	}
+	err := wait.PollImmediateUntil(10*time.Millisecond, func() (bool, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+path, nil)
+		if err != nil {
+			return false, fmt.Errorf("creating request: %w", err)
+		}
 
-	config, fakeClient := testConfig(rebootCancelledNode, toBeRebootedNode)
-	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			// Server might not be started yet.
+			return false, nil
+		}
 
-	ctx := contextWithDeadline(t)
+		if err := resp.Body.Close(); err != nil {
+			return false, fmt.Errorf("closing response body: %w", err)
+		}
 
-	<-process(ctx, t, config, fakeClient)
+		code = resp.StatusCode
 
-	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		t.Fatalf("Waiting for response from %q: %v", path, err)
+	}
 
-	t.Run("by", func(t *testing.T) {
+	return code
+}
+
+func Test_Operator_reconciling_once(t *testing.T) {
+	t.Parallel()
+
+	t.Run("runs_all_reconciliation_phases", func(t *testing.T) {
 		t.Parallel()
 
-		t.Run("removing_before_reboot_label", func(t *testing.T) {
-			t.Parallel()
+		rebootableNode := rebootableNode()
 
-			if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-				t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
-			}
-		})
+		config, _ := testConfig(rebootableNode)
+		config.DisableLeaderElection = true
 
-		t.Run("removing_configured_before_reboot_annotations", func(t *testing.T) {
-			t.Parallel()
+		testKontroller := kontrollerWithObjects(t, config)
 
-			if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; ok {
-				t.Fatalf("Unexpected annotation %q found for node %q", testBeforeRebootAnnotation, rebootCancelledNode.Name)
-			}
+		ctx := contextWithDeadline(t)
 
-			updatedToBeRebootedNode := node(ctx, t, config.Client.CoreV1().Nodes(), toBeRebootedNode.Name)
+		if err := testKontroller.ReconcileOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
 
-			if _, ok := updatedToBeRebootedNode.Annotations[testBeforeRebootAnnotation]; !ok {
-				t.Fatalf("Annotation %q has been removed from wrong node %q",
-					testBeforeRebootAnnotation, toBeRebootedNode.Name)
-			}
-		})
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
 	})
 
-	// To avoid rebooting nodes which executed before-reboot hooks, but don't need a reboot anymore.
-	t.Run("before_reboot_is_approved", func(t *testing.T) {
+	t.Run("returns_error_when_reconciliation_phase_fails", func(t *testing.T) {
 		t.Parallel()
 
-		if v, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]; ok && v == "true" {
-			t.Fatalf("Unexpected reboot approval")
+		config, fakeClient := testConfig(rebootableNode())
+		config.DisableLeaderElection = true
+
+		expectedErr := fmt.Errorf(t.Name())
+
+		fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, expectedErr
+		})
+
+		testKontroller := kontrollerWithObjects(t, config)
+
+		if err := testKontroller.ReconcileOnce(contextWithDeadline(t)); !errors.Is(err, expectedErr) {
+			t.Fatalf("Expected error %q, got %v", expectedErr, err)
 		}
 	})
 }
 
-func Test_Operator_does_not_count_nodes_as_rebooting_which(t *testing.T) {
+//nolint:funlen // Just many subtests.
+func Test_Operator_reconciling_single_node(t *testing.T) {
 	t.Parallel()
 
-	ctx := contextWithDeadline(t)
+	t.Run("schedules_reboot_process_only_of_given_node", func(t *testing.T) {
+		t.Parallel()
 
-	cases := map[string]struct {
-		expectedNodeUpdates int
-		extraNode           *corev1.Node
-	}{
-		"has_finished_rebooting": {
-			expectedNodeUpdates: 3,
-			extraNode:           finishedRebootingNode(),
-		},
-		"are_idle": {
-			expectedNodeUpdates: 2,
-			extraNode:           idleNode(),
-		},
-	}
+		rebootableNode := rebootableNode()
+		otherRebootableNode := rebootableNode.DeepCopy()
+		otherRebootableNode.Name = "other-rebootable"
 
-	for name, testCase := range cases {
-		testCase := testCase
+		config, _ := testConfig(rebootableNode, otherRebootableNode)
+		config.DisableLeaderElection = true
+		config.MaxRebootingNodes = 2
 
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
+		testKontroller := kontrollerWithObjects(t, config)
 
-			rebootableNode := rebootableNode()
+		ctx := contextWithDeadline(t)
 
-			config, fakeClient := testConfig(testCase.extraNode, rebootableNode)
+		if err := testKontroller.ReconcileNode(ctx, rebootableNode.Name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
 
-			nodeUpdated := nodeUpdatedNTimes(fakeClient, testCase.expectedNodeUpdates)
-			<-process(ctx, t, config, fakeClient)
-			<-nodeUpdated
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
 
-			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), otherRebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot", otherRebootableNode.Name)
+		}
+	})
+
+	t.Run("does_not_schedule_reboot_process_of_given_node_above_maximum_number_of_rebooting_nodes", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, _ := testConfig(rebootableNode, rebootingNode())
+		config.DisableLeaderElection = true
+
+		testKontroller := kontrollerWithObjects(t, config)
+
+		ctx := contextWithDeadline(t)
+
+		if err := testKontroller.ReconcileNode(ctx, rebootableNode.Name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("confirms_reboot_process_of_given_node_which_finished_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := finishedRebootingNode()
+
+		config, _ := testConfig(finishedRebootingNode)
+		config.DisableLeaderElection = true
+
+		testKontroller := kontrollerWithObjects(t, config)
+
+		ctx := contextWithDeadline(t)
+
+		if err := testKontroller.ReconcileNode(ctx, finishedRebootingNode.Name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Expected label %q to be removed from node %q", constants.LabelAfterReboot, finishedRebootingNode.Name)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.False {
+			t.Fatalf("Expected annotation %q to be %q, got %q", constants.AnnotationOkToReboot, constants.False, v)
+		}
+	})
+
+	t.Run("returns_error_when_given_node_does_not_exist", func(t *testing.T) {
+		t.Parallel()
+
+		config, _ := testConfig()
+		config.DisableLeaderElection = true
+
+		testKontroller := kontrollerWithObjects(t, config)
+
+		if err := testKontroller.ReconcileNode(contextWithDeadline(t), "non-existing"); err == nil {
+			t.Fatalf("Expected error reconciling non-existing node")
+		}
+	})
+}
+
+func Test_Operator_records_in_metrics(t *testing.T) {
+	t.Parallel()
+
+	t.Run("duration_of_reconciliation_and_its_phases", func(t *testing.T) {
+		t.Parallel()
+
+		config, _ := testConfig(rebootableNode())
+		config.DisableLeaderElection = true
+
+		if err := kontrollerWithObjects(t, config).ReconcileOnce(contextWithDeadline(t)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if v := metricValue(t, "fluo_reconcile_duration_seconds"); v < 1 {
+			t.Fatalf("Expected reconciliation duration to be recorded in metrics, got %v observations", v)
+		}
+
+		for _, phase := range []string{operator.ReconcilePhaseCleanup, operator.ReconcilePhaseMarkBeforeReboot} {
+			labels := map[string]string{string(metrics.LabelPhase): phase}
+
+			if v := metricValueWithLabels(t, "fluo_reconcile_phase_duration_seconds", labels); v < 1 {
+				t.Fatalf("Expected duration of phase %q to be recorded in metrics, got %v observations", phase, v)
+			}
+		}
+	})
+
+	t.Run("failed_reconciliation_phase", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(rebootableNode())
+		config.DisableLeaderElection = true
+
+		fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf(t.Name())
+		})
+
+		if err := kontrollerWithObjects(t, config).ReconcileOnce(contextWithDeadline(t)); err == nil {
+			t.Fatalf("Expected reconciliation to fail")
+		}
+
+		labels := map[string]string{string(metrics.LabelPhase): operator.ReconcilePhaseCleanup}
+
+		if v := metricValueWithLabels(t, "fluo_reconcile_errors_total", labels); v < 1 {
+			t.Fatalf("Expected failed phase to be recorded in metrics, got %v", v)
+		}
+	})
+}
+
+//nolint:paralleltest // Gauges are global, so parallel tests would change them.
+func Test_Operator_records_available_reboot_slots_and_number_of_rebootable_nodes_in_metrics(t *testing.T) {
+	rebootableNode := rebootableNode()
+	otherRebootableNode := rebootableNode.DeepCopy()
+	otherRebootableNode.Name = "other-rebootable"
+
+	config, fakeClient := testConfig(rebootingNode(), rebootableNode, otherRebootableNode, idleNode())
+	config.MaxRebootingNodes = 2
+
+	<-process(contextWithDeadline(t), t, config, fakeClient)
+
+	if v := metricValue(t, "fluo_reboot_slots_available"); v != 1 {
+		t.Fatalf("Expected 1 available reboot slot, got %v", v)
+	}
+
+	if v := metricValue(t, "fluo_nodes_rebootable"); v != 2 {
+		t.Fatalf("Expected 2 rebootable nodes, got %v", v)
+	}
+}
+
+func Test_Operator_releases_leader_election_lock_when_user_requests_shutdown(t *testing.T) {
+	t.Parallel()
+
+	config, _ := testConfig(idleNode())
+	// Long enough lease, so the test fails if other instance needs to wait for it to expire.
+	config.LeaderElectionLease = time.Minute
+
+	testKontroller := kontrollerWithObjects(t, config)
+
+	reconciled := make(chan struct{}, 1)
+
+	operator.SetReconciledHook(testKontroller, func() {
+		select {
+		case reconciled <- struct{}{}:
+		default:
+		}
+	})
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		if err := testKontroller.Run(stop); err != nil {
+			fmt.Printf("Error running operator: %v\n", err)
+			t.Fail()
+		}
+
+		close(stopped)
+	}()
+
+	<-reconciled
+
+	close(stop)
+
+	<-stopped
+
+	config.LockID = "bar"
+
+	parallelKontroller := kontrollerWithObjects(t, config)
+
+	parallelReconciled := make(chan struct{}, 1)
+
+	operator.SetReconciledHook(parallelKontroller, func() {
+		select {
+		case parallelReconciled <- struct{}{}:
+		default:
+		}
+	})
+
+	parallelStop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(parallelStop)
+	})
+
+	runOperator(contextWithDeadline(t), t, parallelKontroller, parallelStop)
+
+	select {
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for other instance to acquire leadership")
+	case <-parallelReconciled:
+	}
+}
+
+func Test_Operator_emits_events_about_leader_election_to_configured_namespace(t *testing.T) {
+	t.Parallel()
+
+	config, fakeClient := testConfig()
+
+	<-process(contextWithDeadline(t), t, config, fakeClient)
+
+	events, err := config.Client.CoreV1().Events(config.Namespace).List(contextWithDeadline(t), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed listing events: %v", err)
+	}
+
+	if len(events.Items) == 0 {
+		t.Fatalf("Expected at least one event to be published")
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_emits_events_with_configured_source_component(t *testing.T) {
+	t.Parallel()
+
+	// waitForEventFromComponent waits for an event with given reason, if not empty, in given namespace
+	// and returns source component of it.
+	waitForEventFromComponent := func(
+		ctx context.Context, t *testing.T, config operator.Config, namespace, reason string,
+	) string {
+		t.Helper()
+
+		component := ""
+
+		// Events are sent asynchronously, so wait for them to arrive.
+		err := wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+			events, err := config.Client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return false, fmt.Errorf("listing events: %w", err)
+			}
+
+			for _, event := range events.Items {
+				if reason == "" || event.Reason == reason {
+					component = event.Source.Component
+
+					return true, nil
+				}
+			}
+
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("Waiting for event: %v", err)
+		}
+
+		return component
+	}
+
+	nodeEventComponent := func(t *testing.T, component string) string {
+		t.Helper()
+
+		scheduledForRebootNode := scheduledForRebootNode()
+		scheduledForRebootNode.Annotations[constants.AnnotationRebootCycleStartTime] = strconv.FormatInt(
+			time.Now().Add(-2*time.Hour).Unix(), 10)
+
+		config, _ := testConfig(scheduledForRebootNode)
+		config.DisableLeaderElection = true
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootTimeout = time.Hour
+		config.EventSourceComponent = component
+
+		ctx := contextWithDeadline(t)
+
+		if err := kontrollerWithObjects(t, config).ReconcileOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return waitForEventFromComponent(ctx, t, config, metav1.NamespaceDefault, "BeforeRebootChecksTimedOut")
+	}
+
+	leaderElectionEventComponent := func(t *testing.T, component string) string {
+		t.Helper()
+
+		config, fakeClient := testConfig()
+		config.LeaderElectionEventSourceComponent = component
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		return waitForEventFromComponent(ctx, t, config, config.Namespace, "")
+	}
+
+	t.Run("about_nodes", func(t *testing.T) {
+		t.Parallel()
+
+		if component := nodeEventComponent(t, "pool-a-update-operator"); component != "pool-a-update-operator" {
+			t.Fatalf("Expected event source component %q, got %q", "pool-a-update-operator", component)
+		}
+	})
+
+	t.Run("about_nodes_defaulting_to_update_operator", func(t *testing.T) {
+		t.Parallel()
+
+		if component := nodeEventComponent(t, ""); component != "update-operator" {
+			t.Fatalf("Expected event source component %q, got %q", "update-operator", component)
+		}
+	})
+
+	t.Run("about_leader_election", func(t *testing.T) {
+		t.Parallel()
+
+		expected := "pool-a-update-operator-leader-election"
+
+		if component := leaderElectionEventComponent(t, expected); component != expected {
+			t.Fatalf("Expected event source component %q, got %q", expected, component)
+		}
+	})
+
+	t.Run("about_leader_election_defaulting_to_update_operator_leader_election", func(t *testing.T) {
+		t.Parallel()
+
+		expected := "update-operator-leader-election"
+
+		if component := leaderElectionEventComponent(t, ""); component != expected {
+			t.Fatalf("Expected event source component %q, got %q", expected, component)
+		}
+	})
+}
+
+func Test_Operator_returns_error_when_leadership_is_lost(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	config, fakeClient := testConfig(rebootCancelledNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+	config.ReconciliationPeriod = 1 * time.Second
+	config.LeaderElectionLease = 2 * time.Second
+	testKontroller := kontrollerWithObjects(t, config)
+	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+
+	stop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(stop)
+	})
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- testKontroller.Run(stop)
+	}()
+
+	// Wait for one reconciliation cycle to run.
+	<-nodeUpdated
+
+	ctx := contextWithDeadline(t)
+
+	// Ensure operator is functional.
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Expected label %q to be removed from Node after waiting the reconciliation period",
+			constants.LabelBeforeReboot)
+	}
+
+	stealLeaderElection(ctx, t, config)
+
+	// Wait lease time to ensure operator lost it.
+	time.Sleep(config.LeaderElectionLease)
+
+	// Patch node object again to verify if operator is functional.
+	updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
+	updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
+
+	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Updating Node object: %v", err)
+	}
+
+	time.Sleep(config.ReconciliationPeriod)
+
+	updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+		t.Fatalf("Expected label %q to remain on Node", constants.LabelBeforeReboot)
+	}
+
+	if err := <-errCh; !errors.Is(err, operator.ErrLeadershipLost) {
+		t.Fatalf("Expected operator to return error %q when leader election is lost, got %v",
+			operator.ErrLeadershipLost, err)
+	}
+}
+
+// To make misconfiguration like missing RBAC permissions to the lock debuggable instead of hanging silently.
+func Test_Operator_returns_error_when_leader_election_lock_is_not_acquired_within_configured_timeout(
+	t *testing.T,
+) {
+	t.Parallel()
+
+	config, fakeClient := testConfig()
+	config.LeaderElectionLease = time.Second
+	config.LeaderElectionTimeout = 2 * time.Second
+
+	fakeClient.PrependReactor("create", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(coordinationv1.Resource("leases"), "", fmt.Errorf("forbidden"))
+	})
+
+	testKontroller := kontrollerWithObjects(t, config)
+
+	stop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(stop)
+	})
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- testKontroller.Run(stop)
+	}()
+
+	ctx := contextWithDeadline(t)
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, operator.ErrLeaderElectionTimeout) {
+			t.Fatalf("Expected operator to return error %q, got %v", operator.ErrLeaderElectionTimeout, err)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Timed out waiting for operator to return an error")
+	}
+}
+
+func Test_Operator_recovers_from_panic_during_reconciliation(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	config, fakeClient := testConfig(rebootCancelledNode)
+	config.ReconciliationPeriod = 1 * time.Second
+
+	panicked := false
+
+	fakeClient.PrependReactor("get", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if !panicked {
+			panicked = true
+
+			panic("test panic")
+		}
+
+		return false, nil, nil
+	})
+
+	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+
+	stop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(stop)
+	})
+
+	ctx := contextWithDeadline(t)
+
+	runOperator(ctx, t, kontrollerWithObjects(t, config), stop)
+
+	select {
+	case <-ctx.Done():
+		t.Fatalf("Timed out waiting for reconciliation after panic")
+	case <-nodeUpdated:
+	}
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Expected label %q to be removed from Node after recovering from panic",
+			constants.LabelBeforeReboot)
+	}
+
+	if v := metricValue(t, "fluo_reconcile_panics_total"); v < 1 {
+		t.Fatalf("Expected recovered panic to be recorded in metrics, got %v", v)
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_reads_nodes_from_cache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("listing_nodes_from_API_server_only_once_across_reconciliation_loops", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(idleNode())
+		config.ReconciliationPeriod = 100 * time.Millisecond
+
+		listCalls := int32(0)
+
+		fakeClient.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			atomic.AddInt32(&listCalls, 1)
+
+			return false, nil, nil
+		})
+
+		ctx := contextWithDeadline(t)
+
+		reconcileCycle := process(ctx, t, config, fakeClient)
+
+		for i := 0; i < 3; i++ {
+			<-reconcileCycle
+		}
+
+		if calls := atomic.LoadInt32(&listCalls); calls != 1 {
+			t.Fatalf("Expected nodes to be listed once, got %d list calls", calls)
+		}
+	})
+
+	t.Run("which_is_updated_when_nodes_change_between_reconciliation_loops", func(t *testing.T) {
+		t.Parallel()
+
+		idleNode := idleNode()
+
+		config, fakeClient := testConfig(idleNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.ReconciliationPeriod = 100 * time.Millisecond
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		nodeClient := config.Client.CoreV1().Nodes()
+
+		updatedNode := node(ctx, t, nodeClient, idleNode.Name)
+		updatedNode.Annotations[constants.AnnotationRebootNeeded] = constants.True
+
+		if _, err := nodeClient.Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("Updating node: %v", err)
+		}
+
+		err := wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+			n, err := nodeClient.Get(ctx, idleNode.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, fmt.Errorf("getting node: %w", err)
+			}
+
+			return n.Labels[constants.LabelBeforeReboot] == constants.True, nil
+		})
+		if err != nil {
+			t.Fatalf("Waiting for node to be scheduled for reboot: %v", err)
+		}
+	})
+}
+
+func Test_Operator_cancels_reconciliation_exceeding_configured_timeout(t *testing.T) {
+	t.Parallel()
+
+	readyToRebootNode := readyToRebootNode()
+
+	config, fakeClient := testConfig(readyToRebootNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+	config.ReconciliationPeriod = 500 * time.Millisecond
+	config.ReconcileTimeout = 100 * time.Millisecond
+
+	slowed := false
+
+	fakeClient.PrependReactor("get", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if !slowed {
+			slowed = true
+
+			time.Sleep(2 * config.ReconcileTimeout)
+		}
+
+		return false, nil, nil
+	})
+
+	// Cleanup in both loops and approval in the second loop.
+	nodeApproved := nodeUpdatedNTimes(fakeClient, 2)
+
+	stop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(stop)
+	})
+
+	ctx := contextWithDeadline(t)
+
+	runOperator(ctx, t, kontrollerWithObjects(t, config), stop)
+
+	select {
+	case <-ctx.Done():
+		t.Fatalf("Timed out waiting for reconciliation after timeout")
+	case <-nodeApproved:
+	}
+
+	if v := metricValue(t, "fluo_reconcile_timeouts_total"); v < 1 {
+		t.Fatalf("Expected reconcile timeout to be recorded in metrics, got %v", v)
+	}
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+	if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.True {
+		t.Fatalf("Expected node to be approved for reboot in next loop, got annotations %v", updatedNode.Annotations)
+	}
+}
+
+func stealLeaderElection(ctx context.Context, t *testing.T, config operator.Config) {
+	t.Helper()
+
+	leaseClient := config.Client.CoordinationV1().Leases(config.Namespace)
+
+	leases, err := leaseClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed listing Leases: %v", err)
+	}
+
+	if c := len(leases.Items); c != 1 {
+		t.Fatalf("Expected exactly one Lease to exist, got %d", c)
+	}
+
+	lock := leases.Items[0]
+
+	holderIdentity := "baz"
+	lock.Spec.HolderIdentity = &holderIdentity
+
+	if _, err := leaseClient.Update(ctx, &lock, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Updating lock Lease: %v", err)
+	}
+}
+
+func Test_Operator_waits_for_leader_election_before_reconciliation(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	config, fakeClient := testConfig(rebootCancelledNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+
+	ctx := contextWithDeadline(t)
+
+	// Leader runs a single reconciliation cycle and then holds the lock until the test finishes.
+	<-process(ctx, t, config, fakeClient)
+
+	config.ReconciliationPeriod = 1 * time.Second
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Expected label %q to be removed from Node after waiting the reconciliation period",
+			constants.LabelBeforeReboot)
+	}
+
+	updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
+	updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
+
+	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Updating Node object: %v", err)
+	}
+
+	config.LockID = "bar"
+	parallelKontroller := kontrollerWithObjects(t, config)
+
+	stop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(stop)
+	})
+
+	runOperator(ctx, t, parallelKontroller, stop)
+
+	time.Sleep(config.ReconciliationPeriod)
+
+	updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+		t.Fatalf("Expected label %q to remain on Node", constants.LabelBeforeReboot)
+	}
+}
+
+func Test_Operator_reconciles_without_leader_election_lock_when_leader_election_is_disabled(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	config, fakeClient := testConfig(rebootCancelledNode)
+	config.LockID = ""
+	config.DisableLeaderElection = true
+
+	ctx := contextWithDeadline(t)
+
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Expected label %q to be removed from Node", constants.LabelBeforeReboot)
+	}
+
+	leases, err := config.Client.CoordinationV1().Leases(config.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed listing Leases: %v", err)
+	}
+
+	if c := len(leases.Items); c != 0 {
+		t.Fatalf("Expected no Leases to be created, got %d", c)
+	}
+}
+
+func Test_Operator_stops_reconciliation_loop_when_control_channel_is_closed(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	config, fakeClient := testConfig(rebootCancelledNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+	config.ReconciliationPeriod = 1 * time.Second
+	testKontroller := kontrollerWithObjects(t, config)
+
+	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+
+	stop := make(chan struct{})
+
+	ctx := contextWithDeadline(t)
+
+	runOperator(ctx, t, testKontroller, stop)
+
+	<-nodeUpdated
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Expected label %q to be removed from Node after waiting the reconciliation period",
+			constants.LabelBeforeReboot)
+	}
+
+	close(stop)
+
+	time.Sleep(config.ReconciliationPeriod * 2)
+
+	updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
+	updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
+
+	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Updating Node object: %v", err)
+	}
+
+	time.Sleep(config.ReconciliationPeriod * 2)
+
+	updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+		t.Fatalf("Expected label %q to remain on Node", constants.LabelBeforeReboot)
+	}
+}
+
+func Test_Operator_reconciles_objects_every_configured_period(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	config, _ := testConfig(rebootCancelledNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+	config.ReconciliationPeriod = 1 * time.Second
+	testKontroller := kontrollerWithObjects(t, config)
+
+	stop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(stop)
+	})
+
+	ctx := contextWithDeadline(t)
+
+	runOperator(ctx, t, testKontroller, stop)
+
+	time.Sleep(config.ReconciliationPeriod)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Expected label %q to be removed from Node after waiting the reconciliation period",
+			constants.LabelBeforeReboot)
+	}
+
+	updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
+	updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
+
+	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Updating Node object: %v", err)
+	}
+
+	time.Sleep(config.ReconciliationPeriod * 2)
+
+	updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Expected label %q to be removed from Node after waiting another reconciliation period",
+			constants.LabelBeforeReboot)
+	}
+}
+
+func Test_Operator_retries_failed_reconciliation_after_configured_error_retry_backoff(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	config, fakeClient := testConfig(rebootCancelledNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+	// Long enough, so the test times out if failed reconciliation is only retried after the period.
+	config.ReconciliationPeriod = time.Hour
+	config.ErrorRetryBackoff = 100 * time.Millisecond
+
+	var failedUpdates int32
+
+	fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if atomic.AddInt32(&failedUpdates, 1) > 1 {
+			return false, nil, nil
+		}
+
+		return true, nil, fmt.Errorf(t.Name())
+	})
+
+	testKontroller := kontrollerWithObjects(t, config)
+
+	stop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(stop)
+	})
+
+	ctx := contextWithDeadline(t)
+
+	runOperator(ctx, t, testKontroller, stop)
+
+	retryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	t.Cleanup(cancel)
+
+	err := wait.PollImmediateUntilWithContext(retryCtx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+		_, ok := updatedNode.Labels[constants.LabelBeforeReboot]
+
+		return !ok, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected label %q to be removed after retrying failed reconciliation: %v",
+			constants.LabelBeforeReboot, err)
+	}
+}
+
+func Test_Operator_reconciles_objects_when_node_changes_when_watching_nodes_is_enabled(t *testing.T) {
+	t.Parallel()
+
+	idleNode := idleNode()
+
+	config, fakeClient := testConfig(idleNode)
+	config.ReconciliationPeriod = time.Hour
+	config.WatchNodes = true
+	config.NodeChangeDebounce = 10 * time.Millisecond
+
+	clientset, ok := config.Client.(*fake.Clientset)
+	if !ok {
+		t.Fatalf("Unexpected client type %T", config.Client)
+	}
+
+	watchStarted := make(chan struct{})
+	signalWatchStarted := sync.Once{}
+
+	// Fake client does not replay changes made before the watch is established, so wait for it
+	// before changing the node.
+	fakeClient.PrependWatchReactor("nodes", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watcher, err := clientset.Tracker().Watch(action.GetResource(), action.GetNamespace())
+
+		signalWatchStarted.Do(func() { close(watchStarted) })
+
+		return true, watcher, err
+	})
+
+	testKontroller := kontrollerWithObjects(t, config)
+
+	reconciled := make(chan struct{}, 1)
+
+	operator.SetReconciledHook(testKontroller, func() {
+		select {
+		case reconciled <- struct{}{}:
+		default:
+		}
+	})
+
+	stop := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(stop)
+	})
+
+	ctx := contextWithDeadline(t)
+
+	runOperator(ctx, t, testKontroller, stop)
+
+	<-watchStarted
+	<-reconciled
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), idleNode.Name)
+	updatedNode.Annotations[constants.AnnotationRebootNeeded] = constants.True
+
+	if _, err := config.Client.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Updating Node object: %v", err)
+	}
+
+	err := wait.PollImmediateUntilWithContext(ctx, 10*time.Millisecond, func(ctx context.Context) (bool, error) {
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), idleNode.Name)
+
+		return updatedNode.Labels[constants.LabelBeforeReboot] == constants.True, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected node to be scheduled for reboot before next reconciliation period: %v", err)
+	}
+}
+
+// before-reboot label is intended to be used as a selector for pre-reboot hooks, so it should only
+// be set for nodes, which are ready to start rebooting any minute.
+func Test_Operator_cleans_up_nodes_which_cannot_be_rebooted(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	toBeRebootedNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "bar",
+			Annotations: map[string]string{
+				testBeforeRebootAnnotation: "",
+			},
+		},
+	}
+
+	config, fakeClient := testConfig(rebootCancelledNode, toBeRebootedNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+
+	ctx := contextWithDeadline(t)
+
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	t.Run("by", func(t *testing.T) {
+		t.Parallel()
+
+		t.Run("removing_before_reboot_label", func(t *testing.T) {
+			t.Parallel()
+
+			if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+				t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
+			}
+		})
+
+		t.Run("removing_configured_before_reboot_annotations", func(t *testing.T) {
+			t.Parallel()
+
+			if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; ok {
+				t.Fatalf("Unexpected annotation %q found for node %q", testBeforeRebootAnnotation, rebootCancelledNode.Name)
+			}
+
+			updatedToBeRebootedNode := node(ctx, t, config.Client.CoreV1().Nodes(), toBeRebootedNode.Name)
+
+			if _, ok := updatedToBeRebootedNode.Annotations[testBeforeRebootAnnotation]; !ok {
+				t.Fatalf("Annotation %q has been removed from wrong node %q",
+					testBeforeRebootAnnotation, toBeRebootedNode.Name)
+			}
+		})
+	})
+
+	// To avoid rebooting nodes which executed before-reboot hooks, but don't need a reboot anymore.
+	t.Run("before_reboot_is_approved", func(t *testing.T) {
+		t.Parallel()
+
+		if v, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]; ok && v == "true" {
+			t.Fatalf("Unexpected reboot approval")
+		}
+	})
+}
+
+// Before reboot hooks may temporarily remove the reboot-needed annotation, e.g. while re-checking for updates,
+// which by default cancels the in-progress reboot process, as tested above.
+//
+//nolint:funlen // Just many subtests.
+func Test_Operator_with_before_reboot_revert_grace_period_configured(t *testing.T) {
+	t.Parallel()
+
+	gracePeriod := time.Hour
+
+	t.Run("keeps_before_reboot_checks_of_nodes_no_longer_wanting_to_reboot_within_grace_period_by", func(t *testing.T) {
+		t.Parallel()
+
+		rebootCancelledNode := rebootCancelledNode()
+
+		config, fakeClient := testConfig(rebootCancelledNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootRevertGracePeriod = gracePeriod
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+		t.Run("keeping_before_reboot_label", func(t *testing.T) {
+			t.Parallel()
+
+			if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != constants.True {
+				t.Fatalf("Expected label %q value to be %q, got %q", constants.LabelBeforeReboot, constants.True, v)
+			}
+		})
+
+		t.Run("keeping_before_reboot_annotations", func(t *testing.T) {
+			t.Parallel()
+
+			if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; !ok {
+				t.Fatalf("Expected annotation %q to be kept", testBeforeRebootAnnotation)
+			}
+		})
+
+		t.Run("recording_since_when_node_no_longer_wants_to_reboot", func(t *testing.T) {
+			t.Parallel()
+
+			if _, ok := updatedNode.Annotations[constants.AnnotationRebootUnwantedTime]; !ok {
+				t.Fatalf("Expected annotation %q not found, got %v",
+					constants.AnnotationRebootUnwantedTime, updatedNode.Annotations)
+			}
+		})
+
+		t.Run("not_approving_reboot", func(t *testing.T) {
+			t.Parallel()
+
+			if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v == constants.True {
+				t.Fatalf("Unexpected reboot approval")
+			}
+		})
+	})
+
+	t.Run("reverts_before_reboot_checks_of_nodes_no_longer_wanting_to_reboot_after_grace_period", func(t *testing.T) {
+		t.Parallel()
+
+		rebootCancelledNode := rebootCancelledNode()
+		unwantedSince := time.Now().Add(-2 * gracePeriod).Unix()
+		rebootCancelledNode.Annotations[constants.AnnotationRebootUnwantedTime] = strconv.FormatInt(unwantedSince, 10)
+
+		config, fakeClient := testConfig(rebootCancelledNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootRevertGracePeriod = gracePeriod
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationRebootUnwantedTime]; ok {
+			t.Fatalf("Unexpected annotation %q found", constants.AnnotationRebootUnwantedTime)
+		}
+	})
+
+	t.Run("continues_reboot_process_of_nodes_wanting_to_reboot_again_within_grace_period", func(t *testing.T) {
+		t.Parallel()
+
+		readyToRebootNode := readyToRebootNode()
+		unwantedSince := time.Now().Add(-gracePeriod / 2).Unix()
+		readyToRebootNode.Annotations[constants.AnnotationRebootUnwantedTime] = strconv.FormatInt(unwantedSince, 10)
+
+		config, fakeClient := testConfig(readyToRebootNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootRevertGracePeriod = gracePeriod
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationRebootUnwantedTime]; ok {
+			t.Fatalf("Unexpected annotation %q found", constants.AnnotationRebootUnwantedTime)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.True {
+			t.Fatalf("Expected annotation %q value to be %q, got %q", constants.AnnotationOkToReboot, constants.True, v)
+		}
+	})
+}
+
+func Test_Operator_cleans_up_remaining_nodes_when_cleaning_up_one_of_them_fails(t *testing.T) {
+	t.Parallel()
+
+	rebootCancelledNode := rebootCancelledNode()
+
+	failingNode := rebootCancelledNode.DeepCopy()
+	// Sorted before other node, so it gets cleaned up first.
+	failingNode.Name = "a-failing"
+
+	config, fakeClient := testConfig(rebootCancelledNode, failingNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+
+	fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		updateAction, ok := action.(k8stesting.UpdateAction)
+		if !ok {
+			return false, nil, nil
+		}
+
+		if node, ok := updateAction.GetObject().(*corev1.Node); ok && node.Name == failingNode.Name {
+			return true, nil, fmt.Errorf(t.Name())
+		}
+
+		return false, nil, nil
+	})
+
+	ctx := contextWithDeadline(t)
+
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
+	}
+
+	updatedFailingNode := node(ctx, t, config.Client.CoreV1().Nodes(), failingNode.Name)
+
+	if _, ok := updatedFailingNode.Labels[constants.LabelBeforeReboot]; !ok {
+		t.Fatalf("Expected label %q to remain on node which failed to update", constants.LabelBeforeReboot)
+	}
+}
+
+func Test_Operator_does_not_clean_up_nodes_which_are_being_deleted(t *testing.T) {
+	t.Parallel()
+
+	deletedNode := rebootCancelledNode()
+	deletionTimestamp := metav1.Now()
+	deletedNode.DeletionTimestamp = &deletionTimestamp
+
+	config, fakeClient := testConfig(deletedNode)
+	config.DisableLeaderElection = true
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+	config.ReconcilePhases = []string{operator.ReconcilePhaseCleanup}
+
+	fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("node is being deleted")
+	})
+
+	ctx := contextWithDeadline(t)
+
+	if err := kontrollerWithObjects(t, config).ReconcileOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), deletedNode.Name)
+
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+		t.Fatalf("Expected label %q to remain on node being deleted", constants.LabelBeforeReboot)
+	}
+}
+
+func Test_Operator_does_not_count_nodes_as_rebooting_which(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	cases := map[string]struct {
+		expectedNodeUpdates int
+		extraNode           *corev1.Node
+	}{
+		"has_finished_rebooting": {
+			expectedNodeUpdates: 3,
+			extraNode:           finishedRebootingNode(),
+		},
+		"are_idle": {
+			expectedNodeUpdates: 2,
+			extraNode:           idleNode(),
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rebootableNode := rebootableNode()
+
+			config, fakeClient := testConfig(testCase.extraNode, rebootableNode)
+
+			nodeUpdated := nodeUpdatedNTimes(fakeClient, testCase.expectedNodeUpdates)
+			<-process(ctx, t, config, fakeClient)
+			<-nodeUpdated
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+			beforeReboot, ok := updatedNode.Labels[constants.LabelBeforeReboot]
+			if !ok {
+				t.Fatalf("Expected label %q", constants.LabelBeforeReboot)
+			}
+
+			if beforeReboot != constants.True {
+				t.Fatalf("Expected value %q for label %q, got: %q", constants.True, constants.LabelBeforeReboot, beforeReboot)
+			}
+		})
+	}
+}
+
+// This test attempts to schedule a reboot for a schedulable node and depending on the
+// state of the rebooting node, controller will either proceed with scheduling or will
+// not do anything.
+func Test_Operator_counts_nodes_as_rebooting_which(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	cases := map[string]*corev1.Node{
+		"are_scheduled_for_reboot_already": scheduledForRebootNode(),
+		"are_ready_to_reboot":              readyToRebootNode(),
+		"has_reboot_approved":              rebootNotConfirmedNode(),
+		"are_rebooting":                    rebootingNode(),
+		"just_rebooted":                    justRebootedNode(),
+	}
+
+	for name, extraNode := range cases {
+		extraNode := extraNode
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rebootableNode := rebootableNode()
+
+			config, fakeClient := testConfig(extraNode, rebootableNode)
+
+			// Required to test selecting rebooting nodes only with before-reboot label, otherwise
+			// it gets removed before we schedule nodes for rebooting.
+			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+			if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+				t.Fatalf("Unexpected node %q scheduled for rebooting", rebootableNode.Name)
+			}
+		})
+	}
+}
+
+func Test_Operator_does_not_count_nodes_as_rebootable_which(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	cases := map[string]func(*corev1.Node){
+		"do_not_require_reboot": func(updatedNode *corev1.Node) {
+			updatedNode.Annotations[constants.AnnotationRebootNeeded] = constants.False
+		},
+		"are_already_rebooting": func(updatedNode *corev1.Node) {
+			*updatedNode = *rebootingNode()
+			updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
+			updatedNode.Annotations[testAnotherBeforeRebootAnnotation] = constants.True
+		},
+		"has_reboot_paused": func(updatedNode *corev1.Node) {
+			updatedNode.Annotations[constants.AnnotationRebootPaused] = constants.True
+		},
+		"has_reboot_already_scheduled": func(updatedNode *corev1.Node) {
+			updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
+			updatedNode.Annotations[testAnotherBeforeRebootAnnotation] = constants.False
+		},
+		"are_being_deleted": func(updatedNode *corev1.Node) {
+			deletionTimestamp := metav1.Now()
+			updatedNode.DeletionTimestamp = &deletionTimestamp
+		},
+	}
+
+	for name, mutateF := range cases {
+		mutateF := mutateF
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rebootableNode := rebootableNode()
+			rebootableNode.Annotations[testBeforeRebootAnnotation] = constants.True
+			rebootableNode.Annotations[testAnotherBeforeRebootAnnotation] = constants.True
+
+			mutateF(rebootableNode)
+
+			config, fakeClient := testConfig(rebootableNode)
+			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation, testAnotherBeforeRebootAnnotation}
+			// To test filter on before-reboot label.
+			config.MaxRebootingNodes = 2
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+			if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; !ok {
+				t.Fatalf("Unexpected node %q scheduled for rebooting", rebootableNode.Name)
+			}
+		})
+	}
+}
+
+func Test_Operator_counts_nodes_as_rebootable_which_needs_reboot_and_has_all_other_conditions_met(t *testing.T) {
+	t.Parallel()
+
+	rebootableNode := rebootableNode()
+
+	config, fakeClient := testConfig(rebootableNode)
+
+	ctx := contextWithDeadline(t)
+
+	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+	<-process(ctx, t, config, fakeClient)
+	<-nodeUpdated
+
+	updatedNode := node(contextWithDeadline(t), t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+	v, ok := updatedNode.Labels[constants.LabelBeforeReboot]
+	if !ok || v != constants.True {
+		t.Fatalf("Expected node %q to be scheduled for rebooting", rebootableNode.Name)
+	}
+}
+
+func Test_Operator_does_not_schedules_reboot_process_outside_reboot_window(t *testing.T) {
+	t.Parallel()
+
+	rebootableNode := rebootableNode()
+
+	config, fakeClient := testConfig(rebootableNode)
+	config.RebootWindowStart = "Mon 14:00"
+	config.RebootWindowLength = "0s"
+
+	ctx := contextWithDeadline(t)
+
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+	if v, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok && v == constants.True {
+		t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+	}
+}
+
+func Test_Operator_reports_reason_why_scheduling_reboot_process_is_blocked(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	cases := map[string]struct {
+		mutateF     func(*operator.Config)
+		expectedErr error
+	}{
+		"when_outside_reboot_window": {
+			mutateF: func(c *operator.Config) {
+				c.RebootWindowStart = "Mon 14:00"
+				c.RebootWindowLength = "0s"
+			},
+			expectedErr: operator.ErrOutsideRebootWindow,
+		},
+		"when_inside_exclusion_window": {
+			mutateF: func(c *operator.Config) {
+				c.ExclusionWindows = []operator.RebootWindow{
+					{Start: now.Add(-time.Minute).Format("15:04"), Length: "30m"},
+				}
+			},
+			expectedErr: operator.ErrInsideExclusionWindow,
+		},
+		"when_waiting_for_inter_reboot_delay": {
+			mutateF: func(c *operator.Config) {
+				c.InterRebootDelay = time.Hour
+			},
+			expectedErr: operator.ErrInterRebootDelay,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes := []runtime.Object{rebootableNode()}
+
+			// Finishing reboot process of a node starts inter reboot delay.
+			if errors.Is(testCase.expectedErr, operator.ErrInterRebootDelay) {
+				nodes = append(nodes, finishedRebootingNode())
+			}
+
+			config, _ := testConfig(nodes...)
+			config.DisableLeaderElection = true
+			testCase.mutateF(&config)
+
+			testKontroller := kontrollerWithObjects(t, config)
+
+			if err := testKontroller.ReconcileOnce(contextWithDeadline(t)); err != nil {
+				t.Fatalf("Expected reconciliation to succeed when scheduling is blocked, got: %v", err)
+			}
+
+			if err := testKontroller.RebootSchedulingBlocked(); !errors.Is(err, testCase.expectedErr) {
+				t.Fatalf("Expected error %q, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+
+	t.Run("as_none_when_nodes_are_scheduled", func(t *testing.T) {
+		t.Parallel()
+
+		config, _ := testConfig(rebootableNode())
+		config.DisableLeaderElection = true
+
+		testKontroller := kontrollerWithObjects(t, config)
+
+		if err := testKontroller.ReconcileOnce(contextWithDeadline(t)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if err := testKontroller.RebootSchedulingBlocked(); err != nil {
+			t.Fatalf("Expected scheduling not to be blocked, got: %v", err)
+		}
+	})
+}
+
+// To schedule pre-reboot hooks.
+//
+//nolint:funlen // Just many test cases.
+func Test_Operator_schedules_reboot_process(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	t.Run("only_during_reboot_window", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.RebootWindowStart = "Mon 00:00"
+		config.RebootWindowLength = fmt.Sprintf("%ds", (7*24*60*60)-1)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("during_any_of_configured_reboot_windows", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		now := time.Now()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.RebootWindows = []operator.RebootWindow{
+			{Start: now.Add(2 * time.Hour).Format("15:04"), Length: "1h"},
+			{Start: now.Add(-time.Minute).Format("15:04"), Length: "1h"},
+		}
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("not_outside_of_all_configured_reboot_windows", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		now := time.Now()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.RebootWindowStart = now.Add(2 * time.Hour).Format("15:04")
+		config.RebootWindowLength = "1h"
+		config.RebootWindows = []operator.RebootWindow{
+			{Start: now.Add(4 * time.Hour).Format("15:04"), Length: "1h"},
+		}
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("not_during_exclusion_window_overlapping_with_reboot_window", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		now := time.Now()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.RebootWindowStart = now.Add(-time.Hour).Format("15:04")
+		config.RebootWindowLength = "2h"
+		config.ExclusionWindows = []operator.RebootWindow{
+			{Start: now.Add(-time.Minute).Format("15:04"), Length: "30m"},
+		}
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("during_reboot_window_when_exclusion_window_is_closed", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		now := time.Now()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.RebootWindowStart = now.Add(-time.Hour).Format("15:04")
+		config.RebootWindowLength = "2h"
+		config.ExclusionWindows = []operator.RebootWindow{
+			{Start: now.Add(-time.Hour).Format("15:04"), Length: "30m"},
+		}
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("outside_reboot_window_only_for_nodes_using_reboot_window_profile_allowing_it", func(t *testing.T) {
+		t.Parallel()
+
+		anytimeNode := rebootableNode()
+		anytimeNode.Name = "dev"
+		anytimeNode.Annotations[constants.AnnotationRebootWindowProfile] = "anytime"
+
+		defaultNode := rebootableNode()
+		defaultNode.Name = "prod"
+
+		now := time.Now()
+
+		config, fakeClient := testConfig(anytimeNode, defaultNode)
+		config.MaxRebootingNodes = 2
+		config.RebootWindowStart = now.Add(2 * time.Hour).Format("15:04")
+		config.RebootWindowLength = "1h"
+		config.RebootWindowProfiles = map[string][]operator.RebootWindow{"anytime": nil}
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), anytimeNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", anytimeNode.Name)
+		}
+
+		updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), defaultNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot", defaultNode.Name)
+		}
+	})
+
+	t.Run("not_outside_reboot_window_of_reboot_window_profile_used_by_node", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+		rebootableNode.Annotations[constants.AnnotationRebootWindowProfile] = "nightly"
+
+		now := time.Now()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.RebootWindowStart = now.Add(-time.Hour).Format("15:04")
+		config.RebootWindowLength = "2h"
+		config.RebootWindowProfiles = map[string][]operator.RebootWindow{
+			"nightly": {{Start: now.Add(2 * time.Hour).Format("15:04"), Length: "1h"}},
+		}
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("only_for_maximum_number_of_rebooting_nodes_in_parallel", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode, rebootNotConfirmedNode())
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if v, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok && v == constants.True {
+			t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("for_nodes_which_are_rebootable", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledForRebootNode := scheduledForRebootNode()
+
+		config, fakeClient := testConfig(scheduledForRebootNode)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), scheduledForRebootNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot", updatedNode.Name)
+		}
+	})
+
+	t.Run("by", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+		rebootableNode.Annotations[testBeforeRebootAnnotation] = constants.True
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+		t.Run("removing_all_before_reboot_annotations", func(t *testing.T) {
+			t.Parallel()
+
+			if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; ok {
+				t.Fatalf("Unexpected annotation %q found", testBeforeRebootAnnotation)
+			}
+		})
+
+		t.Run("setting_before_reboot_label_to_true", func(t *testing.T) {
+			t.Parallel()
 
 			beforeReboot, ok := updatedNode.Labels[constants.LabelBeforeReboot]
 			if !ok {
-				t.Fatalf("Expected label %q", constants.LabelBeforeReboot)
+				t.Fatalf("Expected label %q not found, got %v instead", constants.LabelBeforeReboot, updatedNode.Labels)
+			}
+
+			if beforeReboot != constants.True {
+				t.Fatalf("Unexpected label value: %q", beforeReboot)
+			}
+		})
+	})
+}
+
+func Test_Operator_schedules_reboot_process_only_for_nodes_matching_configured_node_selector(t *testing.T) {
+	t.Parallel()
+
+	managedNode := rebootableNode()
+	managedNode.Labels["flatcar.io/managed"] = constants.True
+
+	unmanagedNode := rebootableNode()
+	unmanagedNode.Name = "unmanaged"
+
+	config, fakeClient := testConfig(managedNode, unmanagedNode)
+	config.NodeSelector = "flatcar.io/managed=true"
+	config.MaxRebootingNodes = 2
+
+	ctx := contextWithDeadline(t)
+
+	// Cleanup and scheduling of managed node only.
+	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+	<-process(ctx, t, config, fakeClient)
+	<-nodeUpdated
+
+	updatedManagedNode := node(ctx, t, config.Client.CoreV1().Nodes(), managedNode.Name)
+	if _, ok := updatedManagedNode.Labels[constants.LabelBeforeReboot]; !ok {
+		t.Fatalf("Expected node %q to be scheduled for reboot", managedNode.Name)
+	}
+
+	updatedUnmanagedNode := node(ctx, t, config.Client.CoreV1().Nodes(), unmanagedNode.Name)
+	if _, ok := updatedUnmanagedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Unexpected node %q not matching node selector scheduled for reboot", unmanagedNode.Name)
+	}
+}
+
+func Test_Operator_approves_reboot_process_using_labels_and_annotations_with_configured_prefix(t *testing.T) {
+	t.Parallel()
+
+	keys := constants.NewKeys("example.com/")
+
+	prefixedNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "prefixed",
+			Labels: map[string]string{
+				keys.LabelRebootNeeded: constants.True,
+			},
+			Annotations: map[string]string{
+				keys.AnnotationRebootNeeded:     constants.True,
+				keys.AnnotationOkToReboot:       constants.False,
+				keys.AnnotationRebootInProgress: constants.False,
+			},
+		},
+	}
+
+	defaultPrefixNode := rebootableNode()
+
+	config, _ := testConfig(prefixedNode, defaultPrefixNode)
+	config.DisableLeaderElection = true
+	config.AnnotationPrefix = "example.com/"
+	config.MaxRebootingNodes = 2
+
+	testKontroller := kontrollerWithObjects(t, config)
+
+	ctx := contextWithDeadline(t)
+
+	// First reconciliation schedules the node for rebooting, second one approves the reboot.
+	for i := 0; i < 2; i++ {
+		if err := testKontroller.ReconcileOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), prefixedNode.Name)
+
+	if v := updatedNode.Annotations[keys.AnnotationOkToReboot]; v != constants.True {
+		t.Fatalf("Expected annotation %q to be %q, got %q", keys.AnnotationOkToReboot, constants.True, v)
+	}
+
+	if _, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]; ok {
+		t.Fatalf("Unexpected annotation %q with default prefix set", constants.AnnotationOkToReboot)
+	}
+
+	updatedDefaultPrefixNode := node(ctx, t, config.Client.CoreV1().Nodes(), defaultPrefixNode.Name)
+
+	if v := updatedDefaultPrefixNode.Annotations[constants.AnnotationOkToReboot]; v == constants.True {
+		t.Fatalf("Unexpected node %q using default prefix approved for reboot", defaultPrefixNode.Name)
+	}
+
+	if _, ok := updatedDefaultPrefixNode.Labels[keys.LabelBeforeReboot]; ok {
+		t.Fatalf("Unexpected node %q using default prefix scheduled for reboot", defaultPrefixNode.Name)
+	}
+}
+
+func Test_Operator_removes_optional_annotations_with_configured_prefix_when_node_annotations_grow_too_large(
+	t *testing.T,
+) {
+	t.Parallel()
+
+	keys := constants.NewKeys("example.com/")
+
+	annotations := map[string]string{
+		keys.AnnotationRebootNeeded:     constants.True,
+		keys.AnnotationOkToReboot:       constants.False,
+		keys.AnnotationRebootInProgress: constants.False,
+		keys.AnnotationStatus:           "UPDATE_STATUS_UPDATED_NEED_REBOOT",
+		keys.AnnotationNewVersion:       "1.2.3",
+		constants.AnnotationStatus:      "UPDATE_STATUS_IDLE",
+	}
+
+	fillerKey := "filler"
+	annotations[fillerKey] = strings.Repeat("a",
+		k8sutil.AnnotationsSizeThreshold+1-k8sutil.AnnotationsSize(annotations)-len(fillerKey))
+
+	prefixedNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "prefixed",
+			Labels: map[string]string{
+				keys.LabelRebootNeeded: constants.True,
+			},
+			Annotations: annotations,
+		},
+	}
+
+	config, _ := testConfig(prefixedNode)
+	config.DisableLeaderElection = true
+	config.AnnotationPrefix = "example.com/"
+
+	testKontroller := kontrollerWithObjects(t, config)
+
+	ctx := contextWithDeadline(t)
+
+	if err := testKontroller.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), prefixedNode.Name)
+
+	if _, ok := updatedNode.Labels[keys.LabelBeforeReboot]; !ok {
+		t.Fatalf("Expected node %q to be scheduled for reboot", prefixedNode.Name)
+	}
+
+	if _, ok := updatedNode.Annotations[keys.AnnotationStatus]; ok {
+		t.Fatalf("Expected annotation %q to be removed", keys.AnnotationStatus)
+	}
+
+	for _, k := range []string{constants.AnnotationStatus, keys.AnnotationNewVersion} {
+		if _, ok := updatedNode.Annotations[k]; !ok {
+			t.Fatalf("Expected annotation %q to be kept", k)
+		}
+	}
+}
+
+func Test_Operator_runs_reconcile_phases_in_configured_order(t *testing.T) {
+	t.Parallel()
+
+	rebootableNode := rebootableNode()
+
+	config, fakeClient := testConfig(rebootableNode)
+	config.ReconcilePhases = []string{
+		operator.ReconcilePhaseCleanup,
+		operator.ReconcilePhaseMarkBeforeReboot,
+		operator.ReconcilePhaseCheckBeforeReboot,
+		operator.ReconcilePhaseCheckAfterReboot,
+		operator.ReconcilePhaseMarkAfterReboot,
+	}
+
+	ctx := contextWithDeadline(t)
+
+	// With before-reboot checks running right after scheduling, node should get approved
+	// for reboot within a single reconciliation cycle.
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+	if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.True {
+		t.Fatalf("Expected node %q to be approved for reboot, got annotations %v",
+			rebootableNode.Name, updatedNode.Annotations)
+	}
+}
+
+func Test_Operator_schedules_reboot_process_of_node_running_the_operator_after_other_nodes(t *testing.T) {
+	t.Parallel()
+
+	leaderNode := rebootableNode()
+	leaderNode.Name = "leader"
+
+	otherNode := rebootableNode()
+
+	config, fakeClient := testConfig(leaderNode, otherNode)
+	config.NodeName = leaderNode.Name
+
+	ctx := contextWithDeadline(t)
+
+	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+	<-process(ctx, t, config, fakeClient)
+	<-nodeUpdated
+
+	if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), leaderNode.Name).Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Unexpected node %q running the operator scheduled for reboot before other nodes", leaderNode.Name)
+	}
+
+	if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), otherNode.Name).Labels[constants.LabelBeforeReboot]; !ok {
+		t.Fatalf("Expected node %q to be scheduled for reboot", otherNode.Name)
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_schedules_reboot_process_of_nodes_ordered_by_configured_priority_annotation(t *testing.T) {
+	t.Parallel()
+
+	priorityAnnotation := "flatcar.io/reboot-priority"
+
+	cases := map[string]struct {
+		priorities   map[string]string
+		expectedNode string
+	}{
+		"with_lower_priority_first": {
+			priorities:   map[string]string{"a": "10", "b": "-5", "c": "1"},
+			expectedNode: "b",
+		},
+		"without_annotation_as_priority_zero": {
+			priorities:   map[string]string{"a": "1", "b": "1"},
+			expectedNode: "c",
+		},
+		"with_invalid_priority_as_priority_zero": {
+			priorities:   map[string]string{"a": "1", "b": "foo", "c": "1"},
+			expectedNode: "b",
+		},
+		"with_equal_priority_by_name": {
+			priorities:   map[string]string{"a": "1", "b": "1", "c": "1"},
+			expectedNode: "a",
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes := []runtime.Object{}
+
+			// Create nodes in reverse order to ensure they are not ordered by creation.
+			for _, name := range []string{"c", "b", "a"} {
+				n := rebootableNode()
+				n.Name = name
+
+				if priority, ok := testCase.priorities[name]; ok {
+					n.Annotations[priorityAnnotation] = priority
+				}
+
+				nodes = append(nodes, n)
+			}
+
+			config, fakeClient := testConfig(nodes...)
+			config.RebootPriorityAnnotation = priorityAnnotation
+
+			ctx := contextWithDeadline(t)
+
+			nodeUpdated := nodeUpdatedNTimes(fakeClient, 0)
+			<-process(ctx, t, config, fakeClient)
+			<-nodeUpdated
+
+			for _, name := range []string{"a", "b", "c"} {
+				_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+
+				if expected := name == testCase.expectedNode; scheduled != expected {
+					t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+				}
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_schedules_reboot_process_of_nodes_up_the_longest_first_when_uptime_strategy_is_configured(
+	t *testing.T,
+) {
+	t.Parallel()
+
+	bootTimeAnnotation := "flatcar.io/boot-time"
+
+	now := time.Now()
+
+	cases := map[string]struct {
+		bootTimes    map[string]string
+		readySince   map[string]time.Time
+		expectedNode string
+	}{
+		"using_boot_time_annotation": {
+			bootTimes: map[string]string{
+				"a": strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+				"b": strconv.FormatInt(now.Add(-3*time.Hour).Unix(), 10),
+				"c": strconv.FormatInt(now.Add(-2*time.Hour).Unix(), 10),
+			},
+			expectedNode: "b",
+		},
+		"using_time_node_became_ready_without_boot_time_annotation": {
+			readySince: map[string]time.Time{
+				"a": now.Add(-time.Hour),
+				"b": now.Add(-2 * time.Hour),
+				"c": now.Add(-3 * time.Hour),
+			},
+			expectedNode: "c",
+		},
+		"with_unknown_boot_time_last": {
+			bootTimes:    map[string]string{"c": strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)},
+			expectedNode: "c",
+		},
+		"with_invalid_boot_time_as_unknown": {
+			bootTimes:    map[string]string{"a": "foo", "b": strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)},
+			expectedNode: "b",
+		},
+		"with_unknown_boot_times_by_name": {
+			expectedNode: "a",
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes := []runtime.Object{}
+
+			for _, name := range []string{"c", "b", "a"} {
+				n := rebootableNode()
+				n.Name = name
+
+				if bootTime, ok := testCase.bootTimes[name]; ok {
+					n.Annotations[bootTimeAnnotation] = bootTime
+				}
+
+				if readySince, ok := testCase.readySince[name]; ok {
+					n.Status.Conditions = []corev1.NodeCondition{
+						{
+							Type:               corev1.NodeReady,
+							Status:             corev1.ConditionTrue,
+							LastTransitionTime: metav1.NewTime(readySince),
+						},
+					}
+				}
+
+				nodes = append(nodes, n)
+			}
+
+			config, fakeClient := testConfig(nodes...)
+			config.NodeSelectionStrategy = operator.NodeSelectionStrategyUptime
+			config.BootTimeAnnotation = bootTimeAnnotation
+
+			ctx := contextWithDeadline(t)
+
+			nodeUpdated := nodeUpdatedNTimes(fakeClient, 0)
+			<-process(ctx, t, config, fakeClient)
+			<-nodeUpdated
+
+			for _, name := range []string{"a", "b", "c"} {
+				_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+
+				if expected := name == testCase.expectedNode; scheduled != expected {
+					t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+				}
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_schedules_reboot_process_of_nodes_which_are_not_ready_according_to_configured_policy(
+	t *testing.T,
+) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		policy        operator.NotReadyNodePolicy
+		readyNodes    []string
+		expectedNodes []string
+	}{
+		"like_other_nodes_by_default": {
+			readyNodes:    []string{"b"},
+			expectedNodes: []string{"a"},
+		},
+		"never_when_skip_policy_is_configured": {
+			policy:        operator.NotReadyNodePolicySkip,
+			readyNodes:    []string{"b"},
+			expectedNodes: []string{"b"},
+		},
+		"never_when_skip_policy_is_configured_and_only_not_ready_nodes_require_reboot": {
+			policy: operator.NotReadyNodePolicySkip,
+		},
+		"after_ready_nodes_when_last_policy_is_configured": {
+			policy:        operator.NotReadyNodePolicyLast,
+			readyNodes:    []string{"b"},
+			expectedNodes: []string{"b"},
+		},
+		"when_only_not_ready_nodes_require_reboot_when_last_policy_is_configured": {
+			policy:        operator.NotReadyNodePolicyLast,
+			expectedNodes: []string{"a"},
+		},
+		"before_ready_nodes_when_first_policy_is_configured": {
+			policy:        operator.NotReadyNodePolicyFirst,
+			readyNodes:    []string{"a"},
+			expectedNodes: []string{"b"},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes := []runtime.Object{}
+
+			for _, name := range []string{"a", "b"} {
+				n := rebootableNode()
+				n.Name = name
+				n.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionFalse}}
+
+				for _, readyNode := range testCase.readyNodes {
+					if name == readyNode {
+						n.Status.Conditions[0].Status = corev1.ConditionTrue
+					}
+				}
+
+				nodes = append(nodes, n)
+			}
+
+			config, _ := testConfig(nodes...)
+			config.NotReadyNodePolicy = testCase.policy
+			config.DisableLeaderElection = true
+
+			ctx := contextWithDeadline(t)
+
+			if err := kontrollerWithObjects(t, config).ReconcileOnce(ctx); err != nil {
+				t.Fatalf("Unexpected error reconciling: %v", err)
+			}
+
+			for _, name := range []string{"a", "b"} {
+				_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+
+				expected := false
+
+				for _, expectedNode := range testCase.expectedNodes {
+					expected = expected || name == expectedNode
+				}
+
+				if scheduled != expected {
+					t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+				}
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_schedules_reboot_process_of_at_most_one_node_per_zone_when_zone_label_is_configured(t *testing.T) {
+	t.Parallel()
+
+	zoneLabel := "topology.kubernetes.io/zone"
+
+	zonedNode := func(name, zone string, n *corev1.Node) *corev1.Node {
+		n.Name = name
+		n.Labels[zoneLabel] = zone
+
+		return n
+	}
+
+	t.Run("when_no_node_is_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(
+			zonedNode("a1", "a", rebootableNode()),
+			zonedNode("a2", "a", rebootableNode()),
+			zonedNode("b1", "b", rebootableNode()),
+		)
+		config.MaxRebootingNodes = 3
+		config.ZoneLabelKey = zoneLabel
+
+		ctx := contextWithDeadline(t)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		for name, expected := range map[string]bool{"a1": true, "a2": false, "b1": true} {
+			_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+			if scheduled != expected {
+				t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+			}
+		}
+	})
+
+	t.Run("when_other_node_from_the_same_zone_is_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(
+			zonedNode("a1", "a", rebootingNode()),
+			zonedNode("a2", "a", rebootableNode()),
+			zonedNode("b1", "b", rebootableNode()),
+		)
+		config.MaxRebootingNodes = 3
+		config.ZoneLabelKey = zoneLabel
+
+		ctx := contextWithDeadline(t)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 0)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		for name, expected := range map[string]bool{"a2": false, "b1": true} {
+			_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+			if scheduled != expected {
+				t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+			}
+		}
+	})
+
+	t.Run("when_node_from_the_same_zone_is_not_chosen_due_to_reboot_group_capacity", func(t *testing.T) {
+		t.Parallel()
+
+		groupLabel := "example.com/reboot-group"
+
+		groupedNode := func(group string, n *corev1.Node) *corev1.Node {
+			n.Labels[groupLabel] = group
+
+			return n
+		}
+
+		config, fakeClient := testConfig(
+			groupedNode("full", zonedNode("b1", "b", rebootingNode())),
+			groupedNode("full", zonedNode("a1", "a", rebootableNode())),
+			groupedNode("free", zonedNode("a2", "a", rebootableNode())),
+		)
+		config.MaxRebootingNodes = 1
+		config.RebootGroupLabelKey = groupLabel
+		config.ZoneLabelKey = zoneLabel
+
+		ctx := contextWithDeadline(t)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		for name, expected := range map[string]bool{"a1": false, "a2": true} {
+			_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+			if scheduled != expected {
+				t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+			}
+		}
+	})
+
+	t.Run("regardless_of_zones_when_zone_label_is_not_configured", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(
+			zonedNode("a1", "a", rebootableNode()),
+			zonedNode("a2", "a", rebootableNode()),
+			zonedNode("b1", "b", rebootableNode()),
+		)
+		config.MaxRebootingNodes = 3
+
+		ctx := contextWithDeadline(t)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 2)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		for _, name := range []string{"a1", "a2", "b1"} {
+			if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]; !ok {
+				t.Fatalf("Expected node %q to be scheduled for reboot", name)
+			}
+		}
+	})
+}
+
+func Test_Operator_schedules_reboot_process_of_maximum_number_of_nodes_in_each_configured_reboot_group(t *testing.T) {
+	t.Parallel()
+
+	groupLabel := "node.kubernetes.io/pool"
+
+	groupedNode := func(name, group string, n *corev1.Node) *corev1.Node {
+		n.Name = name
+		n.Labels[groupLabel] = group
+
+		return n
+	}
+
+	t.Run("when_no_node_is_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(
+			groupedNode("gpu1", "gpu", rebootableNode()),
+			groupedNode("worker1", "worker", rebootableNode()),
+			groupedNode("worker2", "worker", rebootableNode()),
+		)
+		config.RebootGroupLabelKey = groupLabel
+
+		ctx := contextWithDeadline(t)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		for name, expected := range map[string]bool{"gpu1": true, "worker1": true, "worker2": false} {
+			_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+			if scheduled != expected {
+				t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+			}
+		}
+	})
+
+	t.Run("when_other_node_from_the_same_group_is_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(
+			groupedNode("gpu1", "gpu", rebootableNode()),
+			groupedNode("worker1", "worker", rebootingNode()),
+			groupedNode("worker2", "worker", rebootableNode()),
+		)
+		config.RebootGroupLabelKey = groupLabel
+
+		ctx := contextWithDeadline(t)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 0)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		for name, expected := range map[string]bool{"gpu1": true, "worker2": false} {
+			_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+			if scheduled != expected {
+				t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+			}
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_schedules_reboot_process_of_nodes_group_by_group_in_configured_rollout_order(t *testing.T) {
+	t.Parallel()
+
+	orderLabel := "flatcar.io/rollout-order"
+
+	orderedNode := func(name, order string, n *corev1.Node) *corev1.Node {
+		n.Name = name
+		n.Labels[orderLabel] = order
+
+		return n
+	}
+
+	cases := map[string]struct {
+		objects         []runtime.Object
+		expectedUpdates int
+		expected        map[string]bool
+	}{
+		"only_from_first_group_when_no_node_is_rebooting": {
+			objects: []runtime.Object{
+				orderedNode("a1", "1", rebootableNode()),
+				orderedNode("a2", "1", rebootableNode()),
+				orderedNode("b1", "2", rebootableNode()),
+			},
+			expectedUpdates: 2,
+			expected:        map[string]bool{"a1": true, "a2": true, "b1": false},
+		},
+		"not_from_next_group_while_node_from_current_group_is_rebooting": {
+			objects: []runtime.Object{
+				orderedNode("a1", "1", rebootingNode()),
+				orderedNode("b1", "2", rebootableNode()),
+			},
+			expected: map[string]bool{"b1": false},
+		},
+		"from_next_group_once_all_nodes_from_current_group_finished_rebooting": {
+			objects: []runtime.Object{
+				orderedNode("a1", "1", idleNode()),
+				orderedNode("b1", "2", rebootableNode()),
+				orderedNode("b2", "2", rebootableNode()),
+			},
+			expectedUpdates: 2,
+			expected:        map[string]bool{"b1": true, "b2": true},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config, fakeClient := testConfig(testCase.objects...)
+			config.RolloutOrderLabelKey = orderLabel
+			config.MaxRebootingNodes = 3
+
+			ctx := contextWithDeadline(t)
+
+			nodeUpdated := nodeUpdatedNTimes(fakeClient, testCase.expectedUpdates)
+			<-process(ctx, t, config, fakeClient)
+			<-nodeUpdated
+
+			for name, expected := range testCase.expected {
+				_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+				if scheduled != expected {
+					t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+				}
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many subtests.
+//nolint:funlen // Just many subtests.
+func Test_Operator_schedules_reboot_process_only_when_ready_nodes_stay_above_configured_minimum_ready_fraction(
+	t *testing.T,
+) {
+	t.Parallel()
+
+	withReadyCondition := func(node *corev1.Node, status corev1.ConditionStatus) *corev1.Node {
+		node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}
+
+		return node
+	}
+
+	idleNodeNamed := func(name string, status corev1.ConditionStatus) *corev1.Node {
+		node := withReadyCondition(idleNode(), status)
+		node.Name = name
+
+		return node
+	}
+
+	cases := map[string]struct {
+		objects        []runtime.Object
+		expectRebooted bool
+	}{
+		"when_all_other_nodes_are_ready": {
+			objects: []runtime.Object{
+				idleNodeNamed("idle-1", corev1.ConditionTrue),
+				idleNodeNamed("idle-2", corev1.ConditionTrue),
+				idleNodeNamed("idle-3", corev1.ConditionTrue),
+			},
+			expectRebooted: true,
+		},
+		"not_when_other_node_is_not_ready": {
+			objects: []runtime.Object{
+				idleNodeNamed("idle-1", corev1.ConditionTrue),
+				idleNodeNamed("idle-2", corev1.ConditionTrue),
+				idleNodeNamed("idle-3", corev1.ConditionFalse),
+			},
+		},
+		"not_when_other_node_is_rebooting": {
+			objects: []runtime.Object{
+				idleNodeNamed("idle-1", corev1.ConditionTrue),
+				idleNodeNamed("idle-2", corev1.ConditionTrue),
+				withReadyCondition(rebootingNode(), corev1.ConditionTrue),
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rebootableNode := withReadyCondition(rebootableNode(), corev1.ConditionTrue)
+
+			config, fakeClient := testConfig(append(testCase.objects, rebootableNode)...)
+			config.MaxRebootingNodes = 2
+			config.MinReadyFraction = 0.75
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+			_, scheduled := updatedNode.Labels[constants.LabelBeforeReboot]
+
+			if testCase.expectRebooted && !scheduled {
+				t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+			}
+
+			if !testCase.expectRebooted && scheduled {
+				t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+			}
+		})
+	}
+
+	t.Run("even_when_node_not_reporting_ready_needs_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := withReadyCondition(rebootableNode(), corev1.ConditionFalse)
+
+		config, fakeClient := testConfig(rebootableNode, idleNodeNamed("idle-1", corev1.ConditionTrue))
+		config.MinReadyFraction = 1
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected not ready node %q to be scheduled for reboot", rebootableNode.Name)
+		}
+	})
+}
+
+func Test_Operator_schedules_reboot_process_when_only_rebooting_outdated_nodes_is_enabled(t *testing.T) {
+	t.Parallel()
+
+	customVersionAnnotation := "example.com/pending-version"
+
+	cases := map[string]struct {
+		currentVersion  string
+		newVersion      string
+		useCustomKey    bool
+		expectScheduled bool
+	}{
+		"for_node_with_pending_version_different_than_current_version": {
+			currentVersion:  "3510.2.0",
+			newVersion:      "3510.2.1",
+			expectScheduled: true,
+		},
+		"not_for_node_with_pending_version_equal_to_current_version": {
+			currentVersion: "3510.2.1",
+			newVersion:     "3510.2.1",
+		},
+		"for_node_without_reported_pending_version": {
+			currentVersion:  "3510.2.1",
+			expectScheduled: true,
+		},
+		"not_for_node_with_configured_version_annotation_equal_to_current_version": {
+			currentVersion: "3510.2.1",
+			newVersion:     "3510.2.1",
+			useCustomKey:   true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rebootableNode := rebootableNode()
+			rebootableNode.Labels[constants.LabelVersion] = testCase.currentVersion
+
+			versionAnnotation := constants.AnnotationNewVersion
+			if testCase.useCustomKey {
+				versionAnnotation = customVersionAnnotation
+			}
+
+			if testCase.newVersion != "" {
+				rebootableNode.Annotations[versionAnnotation] = testCase.newVersion
+			}
+
+			config, fakeClient := testConfig(rebootableNode)
+			config.OnlyRebootOutdated = true
+
+			if testCase.useCustomKey {
+				config.NewVersionAnnotation = customVersionAnnotation
+			}
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+			_, scheduled := updatedNode.Labels[constants.LabelBeforeReboot]
+
+			if testCase.expectScheduled && !scheduled {
+				t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+			}
+
+			if !testCase.expectScheduled && scheduled {
+				t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_records_reason_of_reboot_process(t *testing.T) {
+	t.Parallel()
+
+	t.Run("describing_version_transition_when_scheduling_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+		rebootableNode.Labels[constants.LabelVersion] = "3510.2.0"
+		rebootableNode.Annotations[constants.AnnotationNewVersion] = "3510.2.1"
+
+		config, fakeClient := testConfig(rebootableNode)
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+		expectedReason := "update from 3510.2.0 to 3510.2.1"
+
+		if reason := updatedNode.Annotations[constants.AnnotationRebootReason]; reason != expectedReason {
+			t.Fatalf("Expected reboot reason %q, got %q", expectedReason, reason)
+		}
+	})
+
+	t.Run("from_configured_source_annotation_when_scheduling_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		sourceAnnotation := "example.com/reboot-reason"
+
+		rebootableNode := rebootableNode()
+		rebootableNode.Annotations[sourceAnnotation] = "kernel patch"
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.RebootReasonSourceAnnotation = sourceAnnotation
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+		if reason := updatedNode.Annotations[constants.AnnotationRebootReason]; reason != "kernel patch" {
+			t.Fatalf("Expected reboot reason %q, got %q", "kernel patch", reason)
+		}
+	})
+
+	t.Run("in_configured_annotation_when_reboot_process_finishes", func(t *testing.T) {
+		t.Parallel()
+
+		lastReasonAnnotation := "example.com/last-reboot-reason"
+
+		finishedRebootingNode := finishedRebootingNode()
+		finishedRebootingNode.Annotations[constants.AnnotationRebootReason] = "update from 3510.2.0 to 3510.2.1"
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+		config.LastRebootReasonAnnotation = lastReasonAnnotation
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+		expectedReason := "update from 3510.2.0 to 3510.2.1"
+
+		if reason := updatedNode.Annotations[lastReasonAnnotation]; reason != expectedReason {
+			t.Fatalf("Expected last reboot reason %q, got %q", expectedReason, reason)
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationRebootReason]; ok {
+			t.Fatalf("Unexpected annotation %q found", constants.AnnotationRebootReason)
+		}
+	})
+
+	t.Run("as_unknown_when_finished_reboot_process_has_no_reason", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := finishedRebootingNode()
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+		if reason := updatedNode.Annotations[constants.AnnotationLastRebootReason]; reason != "unknown" {
+			t.Fatalf("Expected last reboot reason %q, got %q", "unknown", reason)
+		}
+	})
+}
+
+func Test_Operator_records_names_of_rebooting_nodes_in_configured_ConfigMap(t *testing.T) {
+	t.Parallel()
+
+	configMapName := "rebooting-nodes"
+
+	cases := map[string]struct {
+		objects       []runtime.Object
+		expectedNodes string
+	}{
+		"creating_ConfigMap_when_it_does_not_exist": {
+			objects:       []runtime.Object{idleNode(), rebootingNode()},
+			expectedNodes: rebootingNode().Name,
+		},
+		"updating_existing_ConfigMap": {
+			objects: []runtime.Object{
+				idleNode(),
+				rebootingNode(),
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      configMapName,
+						Namespace: testNamespace,
+					},
+					Data: map[string]string{
+						"rebootingNodes": "foo",
+					},
+				},
+			},
+			expectedNodes: rebootingNode().Name,
+		},
+		"as_empty_list_when_no_node_is_rebooting": {
+			objects: []runtime.Object{idleNode()},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config, fakeClient := testConfig(testCase.objects...)
+			config.RebootingNodesConfigMap = configMapName
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			configMap, err := config.Client.CoreV1().ConfigMaps(testNamespace).Get(ctx, configMapName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Getting ConfigMap: %v", err)
+			}
+
+			rebootingNodes, ok := configMap.Data["rebootingNodes"]
+			if !ok {
+				t.Fatalf("Expected rebooting nodes to be recorded, got %v", configMap.Data)
+			}
+
+			if rebootingNodes != testCase.expectedNodes {
+				t.Fatalf("Expected rebooting nodes %q, got %q", testCase.expectedNodes, rebootingNodes)
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_reads_reboot_window_from_configured_ConfigMap(t *testing.T) {
+	t.Parallel()
+
+	openWindowStart := time.Now().Add(-time.Minute).Format("15:04")
+
+	rebootWindowConfigMap := func(start, length string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "reboot-window",
+				Namespace: testNamespace,
+			},
+			Data: map[string]string{
+				"rebootWindowStart":  start,
+				"rebootWindowLength": length,
+			},
+		}
+	}
+
+	configWithClosedRebootWindow := func(objects ...runtime.Object) operator.Config {
+		config, _ := testConfig(append(objects, rebootableNode())...)
+		config.DisableLeaderElection = true
+		config.RebootWindowStart = "Mon 14:00"
+		config.RebootWindowLength = "0s"
+		config.RebootWindowConfigMap = "reboot-window"
+
+		return config
+	}
+
+	reconcileOnce := func(ctx context.Context, t *testing.T, testKontroller *operator.Kontroller) error {
+		t.Helper()
+
+		if err := testKontroller.ReconcileOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return testKontroller.RebootSchedulingBlocked()
+	}
+
+	updateConfigMap := func(ctx context.Context, t *testing.T, config operator.Config, configMap *corev1.ConfigMap) {
+		t.Helper()
+
+		configMaps := config.Client.CoreV1().ConfigMaps(testNamespace)
+		if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("Updating ConfigMap: %v", err)
+		}
+	}
+
+	t.Run("replacing_configured_reboot_window", func(t *testing.T) {
+		t.Parallel()
+
+		config := configWithClosedRebootWindow(rebootWindowConfigMap(openWindowStart, "1h"))
+
+		ctx := contextWithDeadline(t)
+
+		if err := reconcileOnce(ctx, t, kontrollerWithObjects(t, config)); err != nil {
+			t.Fatalf("Unexpected scheduling blocked: %v", err)
+		}
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode().Name)
+		if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != constants.True {
+			t.Fatalf("Expected node to be scheduled for reboot inside reboot window from ConfigMap")
+		}
+	})
+
+	t.Run("every_reconciliation_loop", func(t *testing.T) {
+		t.Parallel()
+
+		config := configWithClosedRebootWindow(rebootWindowConfigMap("Mon 14:00", "0s"))
+		testKontroller := kontrollerWithObjects(t, config)
+
+		ctx := contextWithDeadline(t)
+
+		if err := reconcileOnce(ctx, t, testKontroller); !errors.Is(err, operator.ErrOutsideRebootWindow) {
+			t.Fatalf("Expected error %q, got %v", operator.ErrOutsideRebootWindow, err)
+		}
+
+		updateConfigMap(ctx, t, config, rebootWindowConfigMap(openWindowStart, "1h"))
+
+		if err := reconcileOnce(ctx, t, testKontroller); err != nil {
+			t.Fatalf("Unexpected scheduling blocked after opening reboot window: %v", err)
+		}
+	})
+
+	t.Run("keeping_last_valid_reboot_window_when_reboot_window_gets_invalid", func(t *testing.T) {
+		t.Parallel()
+
+		config := configWithClosedRebootWindow(rebootWindowConfigMap(openWindowStart, "1h"))
+		testKontroller := kontrollerWithObjects(t, config)
+
+		ctx := contextWithDeadline(t)
+
+		if err := reconcileOnce(ctx, t, testKontroller); err != nil {
+			t.Fatalf("Unexpected scheduling blocked: %v", err)
+		}
+
+		updateConfigMap(ctx, t, config, rebootWindowConfigMap("foo", "1h"))
+
+		if err := reconcileOnce(ctx, t, testKontroller); err != nil {
+			t.Fatalf("Unexpected scheduling blocked after reboot window got invalid: %v", err)
+		}
+	})
+
+	for name, configMap := range map[string]*corev1.ConfigMap{
+		"using_configured_reboot_window_when_ConfigMap_does_not_exist":       nil,
+		"using_configured_reboot_window_when_ConfigMap_has_no_reboot_window": rebootWindowConfigMap("", ""),
+	} {
+		configMap := configMap
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			objects := []runtime.Object{}
+			if configMap != nil {
+				objects = append(objects, configMap)
+			}
+
+			testKontroller := kontrollerWithObjects(t, configWithClosedRebootWindow(objects...))
+
+			err := reconcileOnce(contextWithDeadline(t), t, testKontroller)
+			if !errors.Is(err, operator.ErrOutsideRebootWindow) {
+				t.Fatalf("Expected error %q, got %v", operator.ErrOutsideRebootWindow, err)
+			}
+		})
+	}
+}
+
+func Test_Operator_when_paused_using_ConfigMap(t *testing.T) {
+	t.Parallel()
+
+	pauseConfigMap := func(paused string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pause",
+				Namespace: testNamespace,
+			},
+			Data: map[string]string{
+				"paused": paused,
+			},
+		}
+	}
+
+	t.Run("does_not_schedule_reboot_process", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode, pauseConfigMap(constants.True))
+		config.PauseConfigMap = "pause"
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot while operator is paused", rebootableNode.Name)
+		}
+	})
+
+	t.Run("continues_reboot_process_in_progress", func(t *testing.T) {
+		t.Parallel()
+
+		justRebootedNode := justRebootedNode()
+
+		config, fakeClient := testConfig(justRebootedNode, pauseConfigMap(constants.True))
+		config.PauseConfigMap = "pause"
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+		if v := updatedNode.Labels[constants.LabelAfterReboot]; v != constants.True {
+			t.Fatalf("Expected node %q to be labeled for after reboot checks while operator is paused, got labels %v",
+				justRebootedNode.Name, updatedNode.Labels)
+		}
+	})
+
+	for name, configMap := range map[string]*corev1.ConfigMap{
+		"schedules_reboot_process_when_pause_key_is_not_true":          pauseConfigMap(constants.False),
+		"schedules_reboot_process_when_pause_ConfigMap_does_not_exist": nil,
+	} {
+		configMap := configMap
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rebootableNode := rebootableNode()
+
+			objects := []runtime.Object{rebootableNode}
+			if configMap != nil {
+				objects = append(objects, configMap)
+			}
+
+			config, fakeClient := testConfig(objects...)
+			config.PauseConfigMap = "pause"
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+			if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+				t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+			}
+		})
+	}
+}
+
+func Test_Operator_approves_reboot_process_for_nodes_which_have(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	cases := map[string]struct {
+		mutateF        func(*corev1.Node)
+		expectRebootOK bool
+	}{
+		"all_conditions_met": {
+			// Node without mutation should get ok-to-reboot.
+			expectRebootOK: true,
+		},
+		"before_reboot_label": {
+			mutateF: func(updatedNode *corev1.Node) {
+				// Node without before-reboot label won't get ok-to-reboot.
+				delete(updatedNode.Labels, constants.LabelBeforeReboot)
+			},
+		},
+		"all_before_reboot_annotations_set_to_true": {
+			mutateF: func(updatedNode *corev1.Node) {
+				// Node without all before reboot annotations won't get ok-to-reboot.
+				updatedNode.Annotations[testBeforeRebootAnnotation] = constants.False
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			readyToRebootNode := readyToRebootNode()
+			if testCase.mutateF != nil {
+				testCase.mutateF(readyToRebootNode)
+			}
+
+			config, fakeClient := testConfig(readyToRebootNode)
+
+			// Use beforeRebootAnnotations to be able to test moment when node has before-reboot
+			// label, but it cannot be removed yet.
+			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+			v, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]
+			if testCase.expectRebootOK && (!ok || v != constants.True) {
+				t.Fatalf("Expected reboot-ok annotation, got %v", updatedNode.Annotations)
+			}
+
+			if !testCase.expectRebootOK && ok && v == constants.True {
+				t.Fatalf("Unexpected reboot-ok annotation")
+			}
+		})
+	}
+}
+
+func Test_Operator_approves_reboot_process_for_nodes_which_have_before_reboot_annotations_set_to(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	cases := map[string]struct {
+		value          string
+		expectRebootOK bool
+	}{
+		"configured_value": {
+			value:          "ok",
+			expectRebootOK: true,
+		},
+		"true_only_when_no_value_is_configured": {
+			value: constants.True,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			readyToRebootNode := readyToRebootNode()
+			readyToRebootNode.Annotations[testBeforeRebootAnnotation] = testCase.value
+
+			config, fakeClient := testConfig(readyToRebootNode)
+			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation + "=ok"}
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+			v, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]
+			if testCase.expectRebootOK && (!ok || v != constants.True) {
+				t.Fatalf("Expected reboot-ok annotation, got %v", updatedNode.Annotations)
+			}
+
+			if !testCase.expectRebootOK && ok && v == constants.True {
+				t.Fatalf("Unexpected reboot-ok annotation")
+			}
+		})
+	}
+}
+
+func Test_Operator_approves_reboot_process_when_drain_complete_annotation_is_configured_only_for_nodes_which_have_it(
+	t *testing.T,
+) {
+	t.Parallel()
+
+	drainCompleteAnnotation := "example.com/drain-complete"
+
+	cases := map[string]struct {
+		value          string
+		expectRebootOK bool
+	}{
+		"set_to_true": {
+			value:          constants.True,
+			expectRebootOK: true,
+		},
+		"set_to_other_value": {
+			value: constants.False,
+		},
+		"not_set": {},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			readyToRebootNode := readyToRebootNode()
+
+			if testCase.value != "" {
+				readyToRebootNode.Annotations[drainCompleteAnnotation] = testCase.value
+			}
+
+			config, fakeClient := testConfig(readyToRebootNode)
+			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+			config.DrainCompleteAnnotation = drainCompleteAnnotation
+			config.AnnotationDescriptions = []string{drainCompleteAnnotation + "=node drained by custom logic"}
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+			rebootOK := updatedNode.Annotations[constants.AnnotationOkToReboot] == constants.True
+			if rebootOK != testCase.expectRebootOK {
+				t.Fatalf("Expected reboot-ok annotation: %v, got annotations %v", testCase.expectRebootOK, updatedNode.Annotations)
+			}
+
+			if _, ok := updatedNode.Annotations[drainCompleteAnnotation]; testCase.expectRebootOK && ok {
+				t.Fatalf("Expected drain complete annotation to be removed once reboot is approved")
+			}
+		})
+	}
+}
+
+// To inform agent it can proceed with node draining and rebooting.
+func Test_Operator_approves_reboot_process_by(t *testing.T) {
+	t.Parallel()
+
+	readyToRebootNode := readyToRebootNode()
+
+	config, fakeClient := testConfig(readyToRebootNode)
+	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+
+	ctx := contextWithDeadline(t)
+
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+	// To de-schedule hook pods.
+	t.Run("removing_before_reboot_label", func(t *testing.T) {
+		t.Parallel()
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
+		}
+	})
+
+	t.Run("removing_all_before_reboot_annotations", func(t *testing.T) {
+		t.Parallel()
+
+		if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; ok {
+			t.Fatalf("Unexpected annotation %q found", testBeforeRebootAnnotation)
+		}
+	})
+
+	// To inform agent that all hooks are executed and it can proceed with the reboot.
+	// Right now by setting ok-to-reboot label to true.
+	t.Run("informing_agent_to_proceed_with_reboot_process", func(t *testing.T) {
+		t.Parallel()
+
+		okToReboot, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]
+
+		if !ok {
+			t.Fatalf("Expected annotation %q not found, got %v", constants.AnnotationOkToReboot, updatedNode.Annotations)
+		}
+
+		if okToReboot != constants.True {
+			t.Fatalf("Expected annotation %q value to be %q, got %q",
+				constants.AnnotationOkToReboot, constants.True, okToReboot)
+		}
+	})
+
+	// To be able to tell apart approved reboots from involuntary ones.
+	t.Run("recording_that_reboot_has_been_approved", func(t *testing.T) {
+		t.Parallel()
+
+		if v := updatedNode.Annotations[constants.AnnotationRebootApproved]; v != constants.True {
+			t.Fatalf("Expected annotation %q value to be %q, got %q", constants.AnnotationRebootApproved, constants.True, v)
+		}
+	})
+}
+
+//nolint:funlen // Just many test cases.
+func Test_Operator_approves_reboot_process_using_configured_label_selector(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	approvalLabel := "fluo-approved"
+
+	t.Run("only_when_node_matches_the_selector", func(t *testing.T) {
+		t.Parallel()
+
+		readyToRebootNode := readyToRebootNode()
+
+		config, fakeClient := testConfig(readyToRebootNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootLabelSelector = approvalLabel + "=true"
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v == constants.True {
+			t.Fatalf("Unexpected reboot-ok annotation for node without approval label")
+		}
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected label %q to remain on Node", constants.LabelBeforeReboot)
+		}
+	})
+
+	t.Run("by_removing_approval_label", func(t *testing.T) {
+		t.Parallel()
+
+		readyToRebootNode := readyToRebootNode()
+		readyToRebootNode.Labels[approvalLabel] = constants.True
+
+		config, fakeClient := testConfig(readyToRebootNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootLabelSelector = approvalLabel + "=true"
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.True {
+			t.Fatalf("Expected reboot-ok annotation, got %v", updatedNode.Annotations)
+		}
+
+		if _, ok := updatedNode.Labels[approvalLabel]; ok {
+			t.Fatalf("Expected label %q to be removed from Node", approvalLabel)
+		}
+	})
+
+	t.Run("keeping_labels_selector_only_filters_on", func(t *testing.T) {
+		t.Parallel()
+
+		workerLabel := "node-role.kubernetes.io/worker"
+
+		readyToRebootNode := readyToRebootNode()
+		readyToRebootNode.Labels[approvalLabel] = constants.True
+		readyToRebootNode.Labels[workerLabel] = ""
+
+		config, fakeClient := testConfig(readyToRebootNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootLabelSelector = approvalLabel + "=true," + workerLabel
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.True {
+			t.Fatalf("Expected reboot-ok annotation, got %v", updatedNode.Annotations)
+		}
+
+		if _, ok := updatedNode.Labels[workerLabel]; !ok {
+			t.Fatalf("Expected label %q not owned by operator to remain on Node", workerLabel)
+		}
+	})
+
+	t.Run("removing_only_configured_approval_labels", func(t *testing.T) {
+		t.Parallel()
+
+		poolLabel := "node.kubernetes.io/pool"
+
+		readyToRebootNode := readyToRebootNode()
+		readyToRebootNode.Labels[approvalLabel] = constants.True
+		readyToRebootNode.Labels[poolLabel] = "prod"
+
+		config, fakeClient := testConfig(readyToRebootNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootLabelSelector = approvalLabel + "=true," + poolLabel + "=prod"
+		config.BeforeRebootApprovalLabels = []string{approvalLabel}
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+
+		if _, ok := updatedNode.Labels[approvalLabel]; ok {
+			t.Fatalf("Expected label %q to be removed from Node", approvalLabel)
+		}
+
+		if v := updatedNode.Labels[poolLabel]; v != "prod" {
+			t.Fatalf("Expected label %q not owned by operator to remain on Node, got %v", poolLabel, updatedNode.Labels)
+		}
+	})
+}
+
+func Test_Operator_does_not_schedule_reboot_process_for_nodes_matching_pause_label_selector(t *testing.T) {
+	t.Parallel()
+
+	rebootableNode := rebootableNode()
+	rebootableNode.Labels["fluo-paused"] = constants.True
+
+	config, fakeClient := testConfig(rebootableNode)
+	config.PauseLabelSelector = "fluo-paused=true"
+
+	ctx := contextWithDeadline(t)
+
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+	if v, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok && v == constants.True {
+		t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+	}
+}
+
+func Test_Operator_when_node_has_reboot_paused_until_given_time(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		pausedUntil     string
+		expectScheduled bool
+	}{
+		"does_not_schedule_reboot_process_while_pause_is_active": {
+			pausedUntil: time.Now().Add(time.Hour).Format(time.RFC3339),
+		},
+		"schedules_reboot_process_once_pause_expired": {
+			pausedUntil:     time.Now().Add(-time.Hour).Format(time.RFC3339),
+			expectScheduled: true,
+		},
+		"schedules_reboot_process_when_pause_time_is_invalid": {
+			pausedUntil:     "tomorrow",
+			expectScheduled: true,
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rebootableNode := rebootableNode()
+			rebootableNode.Annotations[constants.AnnotationRebootPausedUntil] = testCase.pausedUntil
+
+			config, fakeClient := testConfig(rebootableNode)
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+			_, scheduled := updatedNode.Labels[constants.LabelBeforeReboot]
+
+			if testCase.expectScheduled && !scheduled {
+				t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+			}
+
+			if !testCase.expectScheduled && scheduled {
+				t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+			}
+		})
+	}
+}
+
+func Test_Operator_waits_configured_inter_reboot_delay_before_scheduling_reboot_process_of_next_node(t *testing.T) {
+	t.Parallel()
+
+	t.Run("after_other_node_finished_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := finishedRebootingNode()
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(finishedRebootingNode, rebootableNode)
+		config.InterRebootDelay = time.Hour
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Expected node %q to finish rebooting", finishedRebootingNode.Name)
+		}
+
+		updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot before inter reboot delay elapsed", rebootableNode.Name)
+		}
+	})
+
+	t.Run("unless_no_node_finished_rebooting_yet", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.InterRebootDelay = time.Hour
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_ramps_up_number_of_nodes_scheduled_for_reboot_process_when_configured(t *testing.T) {
+	t.Parallel()
+
+	configWithRampUp := func(objects ...runtime.Object) (operator.Config, *k8stesting.Fake) {
+		for i := 0; i < 3; i++ {
+			rebootableNode := rebootableNode()
+			rebootableNode.Name = fmt.Sprintf("rebootable-%d", i)
+
+			objects = append(objects, rebootableNode)
+		}
+
+		config, fakeClient := testConfig(objects...)
+		config.MaxRebootingNodes = 3
+		config.RampUpSuccessfulReboots = 1
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation}
+
+		return config, fakeClient
+	}
+
+	scheduledNodes := func(ctx context.Context, t *testing.T, config operator.Config) int {
+		t.Helper()
+
+		nodes, err := config.Client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Listing nodes: %v", err)
+		}
+
+		scheduled := 0
+
+		for _, node := range nodes.Items {
+			if node.Labels[constants.LabelBeforeReboot] == constants.True {
+				scheduled++
+			}
+		}
+
+		return scheduled
+	}
+
+	t.Run("starting_with_single_node", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := configWithRampUp()
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if scheduled := scheduledNodes(ctx, t, config); scheduled != 1 {
+			t.Fatalf("Expected 1 node to be scheduled for reboot, got %d", scheduled)
+		}
+	})
+
+	t.Run("raising_limit_after_configured_number_of_successful_reboots", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := configWithRampUp(finishedRebootingNode())
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if scheduled := scheduledNodes(ctx, t, config); scheduled != 2 {
+			t.Fatalf("Expected 2 nodes to be scheduled for reboot, got %d", scheduled)
+		}
+	})
+
+	t.Run("resetting_limit_when_after_reboot_checks_of_node_fail", func(t *testing.T) {
+		t.Parallel()
+
+		timedOutNode := finishedRebootingNode()
+		timedOutNode.Name = "timed-out"
+		timedOutNode.Annotations[testAfterRebootAnnotation] = constants.False
+		timedOutNode.Annotations[constants.AnnotationAfterRebootAttemptTime] = strconv.FormatInt(
+			time.Now().Add(-2*time.Hour).Unix(), 10)
+
+		config, fakeClient := configWithRampUp(finishedRebootingNode(), timedOutNode)
+		config.AfterRebootCheckTimeout = time.Hour
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), timedOutNode.Name).Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Expected after reboot checks of node %q to fail", timedOutNode.Name)
+		}
+
+		if scheduled := scheduledNodes(ctx, t, config); scheduled != 1 {
+			t.Fatalf("Expected 1 node to be scheduled for reboot, got %d", scheduled)
+		}
+	})
+
+	t.Run("unless_ramp_up_is_disabled", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := configWithRampUp()
+		config.RampUpSuccessfulReboots = 0
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if scheduled := scheduledNodes(ctx, t, config); scheduled != 3 {
+			t.Fatalf("Expected 3 nodes to be scheduled for reboot, got %d", scheduled)
+		}
+	})
+}
+
+func Test_Operator_leaves_nodes_matching_exclude_node_label(t *testing.T) {
+	t.Parallel()
+
+	excludeLabel := "flatcar.io/reboot-exclude"
+
+	t.Run("not_scheduled_for_reboot_even_when_rebootable", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+		rebootableNode.Labels[excludeLabel] = constants.True
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.ExcludeNodeLabel = excludeLabel + "=true"
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if v, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok && v == constants.True {
+			t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+		}
+	})
+
+	t.Run("with_reboot_process_already_in_progress_untouched", func(t *testing.T) {
+		t.Parallel()
+
+		rebootCancelledNode := rebootCancelledNode()
+		rebootCancelledNode.Labels[excludeLabel] = constants.True
+		rebootCancelledNode.Annotations[testBeforeRebootAnnotation] = constants.False
+		rebootCancelledNode.Annotations[constants.AnnotationOkToReboot] = constants.True
+
+		config, fakeClient := testConfig(rebootCancelledNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.ExcludeNodeLabel = excludeLabel + "=true"
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected label %q to be kept, got %v", constants.LabelBeforeReboot, updatedNode.Labels)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.True {
+			t.Fatalf("Expected annotation %q to be kept as %q, got %q", constants.AnnotationOkToReboot, constants.True, v)
+		}
+	})
+}
+
+func Test_Operator_schedules_reboot_process_after_update_maturity_delay(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	updateMaturityDelay := time.Hour
+
+	t.Run("not_before_delay_elapses", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+		rebootableNode.Annotations[constants.AnnotationUpdateAvailableTime] = strconv.FormatInt(time.Now().Unix(), 10)
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.UpdateMaturityDelay = updateMaturityDelay
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot with update within maturity delay", rebootableNode.Name)
+		}
+	})
+
+	t.Run("once_delay_elapses", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+		updateAvailableTime := time.Now().Add(-2 * updateMaturityDelay).Unix()
+		rebootableNode.Annotations[constants.AnnotationUpdateAvailableTime] = strconv.FormatInt(updateAvailableTime, 10)
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.UpdateMaturityDelay = updateMaturityDelay
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
+	})
+}
+
+// Test opposite conditions starting from base to make sure all cases are covered.
+//
+//nolint:funlen,cyclop // Just many test cases.
+func Test_Operator_counts_rebooted_nodes_which_are_not_ready_yet_as_rebooting(t *testing.T) {
+	t.Parallel()
+
+	notReadyNode := justRebootedNode()
+	notReadyNode.Status.Conditions[0].Status = corev1.ConditionFalse
+
+	rebootableNode := rebootableNode()
+
+	config, fakeClient := testConfig(notReadyNode, rebootableNode)
+
+	ctx := contextWithDeadline(t)
+
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+	if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+		t.Fatalf("Unexpected node %q scheduled for reboot while other node is not Ready after reboot",
+			rebootableNode.Name)
+	}
+}
+
+func Test_Operator_counts_nodes_as_just_rebooted_which(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	cases := map[string]struct {
+		mutateF            func(*corev1.Node)
+		expectJustRebooted bool
+	}{
+		"has_all_conditions_met": {
+			expectJustRebooted: true,
+		},
+		// Nodes which we allowed to reboot.
+		"has_reboot_approved": {
+			mutateF: func(updatedNode *corev1.Node) {
+				updatedNode.Annotations[constants.AnnotationOkToReboot] = constants.False
+			},
+		},
+		// Nodes which already rebooted.
+		"does_not_need_a_reboot": {
+			mutateF: func(updatedNode *corev1.Node) {
+				updatedNode.Annotations[constants.AnnotationRebootNeeded] = constants.True
+			},
+		},
+		// Nodes which already reported that they are back from rebooting.
+		"which_finished_the_reboot": {
+			mutateF: func(updatedNode *corev1.Node) {
+				updatedNode.Annotations[constants.AnnotationRebootInProgress] = constants.True
+			},
+		},
+		// Nodes which do not have hooks scheduled yet.
+		"has_no_after_reboot_label": {
+			mutateF: func(updatedNode *corev1.Node) {
+				updatedNode.Labels[constants.LabelAfterReboot] = constants.True
+			},
+		},
+		// Nodes which kubelet rejoined the cluster.
+		"is_ready": {
+			mutateF: func(updatedNode *corev1.Node) {
+				updatedNode.Status.Conditions[0].Status = corev1.ConditionFalse
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			justRebootedNode := justRebootedNode()
+			if testCase.mutateF != nil {
+				testCase.mutateF(justRebootedNode)
+			}
+
+			config, fakeClient := testConfig(justRebootedNode)
+			config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+			v, ok := updatedNode.Labels[constants.LabelAfterReboot]
+			if testCase.expectJustRebooted {
+				if !ok || v != constants.True {
+					t.Errorf("Expected after reboot label, got %v", updatedNode.Labels)
+				}
+
+				if _, ok := updatedNode.Annotations[testAfterRebootAnnotation]; ok {
+					t.Errorf("Expected annotation %q to be removed", testAfterRebootAnnotation)
+				}
+
+				if _, ok := updatedNode.Annotations[testAnotherAfterRebootAnnotation]; ok {
+					t.Errorf("Expected annotation %q to be removed", testAnotherAfterRebootAnnotation)
+				}
+			}
+
+			if !testCase.expectJustRebooted {
+				v, ok := updatedNode.Annotations[testAfterRebootAnnotation]
+				if !ok || v != constants.False {
+					t.Fatalf("Expected annotation %q to be left untouched", testAfterRebootAnnotation)
+				}
+			}
+		})
+	}
+}
+
+// To schedule post-reboot hooks.
+func Test_Operator_confirms_reboot_process_by(t *testing.T) {
+	t.Parallel()
+
+	justRebootedNode := justRebootedNode()
+	justRebootedNode.Annotations[testAfterRebootAnnotation] = constants.True
+	justRebootedNode.Annotations[testAnotherAfterRebootAnnotation] = constants.True
+
+	config, fakeClient := testConfig(justRebootedNode)
+	config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+
+	ctx := contextWithDeadline(t)
+
+	<-process(ctx, t, config, fakeClient)
+
+	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+	// To ensure all annotations are freshly set.
+	t.Run("removing_all_after_reboot_annotations", func(t *testing.T) {
+		t.Parallel()
+
+		if _, ok := updatedNode.Annotations[testAfterRebootAnnotation]; ok {
+			t.Fatalf("Unexpected annotation %q found", testAfterRebootAnnotation)
+		}
+
+		if _, ok := updatedNode.Annotations[testAnotherAfterRebootAnnotation]; ok {
+			t.Fatalf("Unexpected annotation %q found", testAnotherAfterRebootAnnotation)
+		}
+	})
+
+	// To schedule after-reboot hook pods.
+	t.Run("setting_after_reboot_label_to_true", func(t *testing.T) {
+		t.Parallel()
+
+		afterReboot, ok := updatedNode.Labels[constants.LabelAfterReboot]
+		if !ok {
+			t.Fatalf("Expected label %q not found, not %v", constants.LabelAfterReboot, updatedNode.Labels)
+		}
+
+		if afterReboot != constants.True {
+			t.Fatalf("Expected label value %q, got %q", constants.True, afterReboot)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_waits_for_rebooted_node_to_stabilize_before_confirming_reboot_process(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	stabilizationPeriod := time.Hour
+
+	justRebootedNodeReadySince := func(readySince time.Time) *corev1.Node {
+		justRebootedNode := justRebootedNode()
+		justRebootedNode.Status.Conditions = []corev1.NodeCondition{
+			{
+				Type:               corev1.NodeReady,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(readySince),
+			},
+		}
+
+		return justRebootedNode
+	}
+
+	t.Run("by_recording_when_node_is_first_observed_ready", func(t *testing.T) {
+		t.Parallel()
+
+		justRebootedNode := justRebootedNodeReadySince(time.Now().Add(-2 * stabilizationPeriod))
+
+		config, fakeClient := testConfig(justRebootedNode)
+		config.PostRebootStabilizationPeriod = stabilizationPeriod
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Unexpected label %q found on node observed ready for the first time", constants.LabelAfterReboot)
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationReadySinceTime]; !ok {
+			t.Fatalf("Expected annotation %q, got %v", constants.AnnotationReadySinceTime, updatedNode.Annotations)
+		}
+	})
+
+	t.Run("by_resetting_recorded_time_when_node_is_not_ready", func(t *testing.T) {
+		t.Parallel()
+
+		justRebootedNode := justRebootedNodeReadySince(time.Now().Add(-2 * stabilizationPeriod))
+		justRebootedNode.Status.Conditions[0].Status = corev1.ConditionFalse
+		readySince := strconv.FormatInt(time.Now().Add(-2*stabilizationPeriod).Unix(), 10)
+		justRebootedNode.Annotations[constants.AnnotationReadySinceTime] = readySince
+
+		config, fakeClient := testConfig(justRebootedNode)
+		config.PostRebootStabilizationPeriod = stabilizationPeriod
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Unexpected label %q found on node which is not ready", constants.LabelAfterReboot)
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationReadySinceTime]; ok {
+			t.Fatalf("Unexpected annotation %q found on node which is not ready", constants.AnnotationReadySinceTime)
+		}
+	})
+
+	t.Run("by_resetting_recorded_time_when_node_became_ready_again_since_then", func(t *testing.T) {
+		t.Parallel()
+
+		justRebootedNode := justRebootedNodeReadySince(time.Now().Add(-time.Minute))
+		readySince := time.Now().Add(-2 * stabilizationPeriod).Unix()
+		justRebootedNode.Annotations[constants.AnnotationReadySinceTime] = strconv.FormatInt(readySince, 10)
+
+		config, fakeClient := testConfig(justRebootedNode)
+		config.PostRebootStabilizationPeriod = stabilizationPeriod
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Unexpected label %q found on node which recently became ready", constants.LabelAfterReboot)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationReadySinceTime]; v == strconv.FormatInt(readySince, 10) {
+			t.Fatalf("Expected annotation %q to be updated, got %q", constants.AnnotationReadySinceTime, v)
+		}
+	})
+
+	t.Run("by_setting_after_reboot_label_once_node_is_ready_for_stabilization_period", func(t *testing.T) {
+		t.Parallel()
+
+		justRebootedNode := justRebootedNodeReadySince(time.Now().Add(-3 * stabilizationPeriod))
+		readySince := strconv.FormatInt(time.Now().Add(-2*stabilizationPeriod).Unix(), 10)
+		justRebootedNode.Annotations[constants.AnnotationReadySinceTime] = readySince
+
+		config, fakeClient := testConfig(justRebootedNode)
+		config.PostRebootStabilizationPeriod = stabilizationPeriod
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+		if v := updatedNode.Labels[constants.LabelAfterReboot]; v != constants.True {
+			t.Fatalf("Expected label %q to be %q, got %v", constants.LabelAfterReboot, constants.True, updatedNode.Labels)
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationReadySinceTime]; ok {
+			t.Fatalf("Unexpected annotation %q found on node labeled for after reboot checks",
+				constants.AnnotationReadySinceTime)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_handles_before_reboot_checks_which_do_not_pass_within_configured_timeout(t *testing.T) {
+	t.Parallel()
+
+	beforeRebootTimeout := time.Hour
+
+	timestamp := func(t time.Time) string {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+
+	timedOutNode := func() *corev1.Node {
+		scheduledForRebootNode := scheduledForRebootNode()
+		scheduledForRebootNode.Annotations[testBeforeRebootAnnotation] = constants.False
+		scheduledForRebootNode.Annotations[constants.AnnotationRebootCycleStartTime] = timestamp(
+			time.Now().Add(-2 * beforeRebootTimeout))
+
+		return scheduledForRebootNode
+	}
+
+	configWithTimeout := func(objects ...runtime.Object) operator.Config {
+		config, _ := testConfig(objects...)
+		config.DisableLeaderElection = true
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.BeforeRebootTimeout = beforeRebootTimeout
+
+		return config
+	}
+
+	reconcileOnce := func(ctx context.Context, t *testing.T, config operator.Config) {
+		t.Helper()
+
+		if err := kontrollerWithObjects(t, config).ReconcileOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("by_aborting_reboot_process_by_default", func(t *testing.T) {
+		t.Parallel()
+
+		timedOutNode := timedOutNode()
+
+		config := configWithTimeout(timedOutNode)
+		config.ReconcilePhases = []string{operator.ReconcilePhaseCheckBeforeReboot}
+
+		ctx := contextWithDeadline(t)
+
+		reconcileOnce(ctx, t, config)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), timedOutNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v == constants.True {
+			t.Fatalf("Unexpected reboot approved for node with aborted reboot process")
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationBeforeRebootTimedOutTime]; !ok {
+			t.Fatalf("Expected annotation %q to be set", constants.AnnotationBeforeRebootTimedOutTime)
+		}
+
+		// Events are sent asynchronously, so wait for them to arrive.
+		err := wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+			events, err := config.Client.CoreV1().Events(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return false, fmt.Errorf("listing events: %w", err)
+			}
+
+			for _, event := range events.Items {
+				if event.Reason == "BeforeRebootChecksTimedOut" && event.InvolvedObject.Name == timedOutNode.Name {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("Waiting for event: %v", err)
+		}
+	})
+
+	t.Run("by_approving_reboot_when_configured_to_proceed", func(t *testing.T) {
+		t.Parallel()
+
+		timedOutNode := timedOutNode()
+
+		config := configWithTimeout(timedOutNode)
+		config.BeforeRebootTimeoutAction = operator.BeforeRebootTimeoutActionProceed
+
+		ctx := contextWithDeadline(t)
+
+		reconcileOnce(ctx, t, config)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), timedOutNode.Name)
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.True {
+			t.Fatalf("Expected annotation %q to be %q, got %q", constants.AnnotationOkToReboot, constants.True, v)
+		}
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
+		}
+	})
+
+	t.Run("not_before_timeout_elapses", func(t *testing.T) {
+		t.Parallel()
+
+		scheduledForRebootNode := timedOutNode()
+		scheduledForRebootNode.Annotations[constants.AnnotationRebootCycleStartTime] = timestamp(time.Now())
+
+		config := configWithTimeout(scheduledForRebootNode)
+
+		ctx := contextWithDeadline(t)
+
+		reconcileOnce(ctx, t, config)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), scheduledForRebootNode.Name)
+
+		if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != constants.True {
+			t.Fatalf("Expected label %q to be %q, got %v", constants.LabelBeforeReboot, constants.True, updatedNode.Labels)
+		}
+	})
+
+	t.Run("by_scheduling_other_nodes_for_rebooting_before_nodes_with_aborted_reboot_process", func(t *testing.T) {
+		t.Parallel()
+
+		abortedNode := rebootableNode()
+		abortedNode.Name = "aborted"
+		abortedNode.Annotations[constants.AnnotationBeforeRebootTimedOutTime] = timestamp(time.Now())
+
+		rebootableNode := rebootableNode()
+
+		config := configWithTimeout(abortedNode, rebootableNode)
+
+		ctx := contextWithDeadline(t)
+
+		reconcileOnce(ctx, t, config)
+
+		if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), abortedNode.Name).Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q with aborted reboot process scheduled for rebooting first", abortedNode.Name)
+		}
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+		if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != constants.True {
+			t.Fatalf("Expected label %q to be %q, got %v", constants.LabelBeforeReboot, constants.True, updatedNode.Labels)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_retries_after_reboot_checks_which_do_not_pass_within_configured_timeout(t *testing.T) {
+	t.Parallel()
+
+	afterRebootCheckTimeout := time.Hour
+	afterRebootCheckBackoff := time.Hour
+
+	timestamp := func(t time.Time) string {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+
+	timedOutNode := func(attempts string) *corev1.Node {
+		finishedRebootingNode := finishedRebootingNode()
+		finishedRebootingNode.Annotations[testAfterRebootAnnotation] = constants.False
+		finishedRebootingNode.Annotations[constants.AnnotationAfterRebootAttemptTime] = timestamp(
+			time.Now().Add(-2 * afterRebootCheckTimeout))
+
+		if attempts != "" {
+			finishedRebootingNode.Annotations[constants.AnnotationAfterRebootAttempts] = attempts
+		}
+
+		return finishedRebootingNode
+	}
+
+	configWithRetries := func(objects ...runtime.Object) (operator.Config, *k8stesting.Fake) {
+		config, fakeClient := testConfig(objects...)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation}
+		config.AfterRebootCheckTimeout = afterRebootCheckTimeout
+		config.AfterRebootCheckBackoff = afterRebootCheckBackoff
+
+		return config, fakeClient
+	}
+
+	t.Run("by_recording_failed_attempt_and_removing_after_reboot_label", func(t *testing.T) {
+		t.Parallel()
+
+		timedOutNode := timedOutNode("")
+
+		config, fakeClient := configWithRetries(timedOutNode)
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), timedOutNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Unexpected label %q found", constants.LabelAfterReboot)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationAfterRebootAttempts]; v != "1" {
+			t.Fatalf("Expected annotation %q to be %q, got %q", constants.AnnotationAfterRebootAttempts, "1", v)
+		}
+
+		if _, ok := updatedNode.Labels[constants.LabelRebootFailed]; ok {
+			t.Fatalf("Unexpected label %q found on node with remaining attempts", constants.LabelRebootFailed)
+		}
+	})
+
+	t.Run("by_labeling_node_as_failed_to_reboot_once_maximum_attempts_are_reached", func(t *testing.T) {
+		t.Parallel()
+
+		timedOutNode := timedOutNode("2")
+
+		config, fakeClient := configWithRetries(timedOutNode)
+		config.AfterRebootCheckMaxAttempts = 3
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), timedOutNode.Name)
+
+		if v := updatedNode.Labels[constants.LabelRebootFailed]; v != constants.True {
+			t.Fatalf("Expected label %q to be %q, got %v", constants.LabelRebootFailed, constants.True, updatedNode.Labels)
+		}
+
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Unexpected label %q found", constants.LabelAfterReboot)
+		}
+
+		// Events are sent asynchronously, so wait for them to arrive.
+		err := wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+			events, err := config.Client.CoreV1().Events(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return false, fmt.Errorf("listing events: %w", err)
+			}
+
+			for _, event := range events.Items {
+				if event.Reason == "AfterRebootChecksFailed" && event.InvolvedObject.Name == timedOutNode.Name {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("Waiting for event: %v", err)
+		}
+	})
+
+	t.Run("not_before_backoff_elapses", func(t *testing.T) {
+		t.Parallel()
+
+		justRebootedNode := justRebootedNode()
+		justRebootedNode.Annotations[constants.AnnotationAfterRebootAttempts] = "1"
+		justRebootedNode.Annotations[constants.AnnotationAfterRebootAttemptTime] = timestamp(time.Now())
+
+		config, fakeClient := configWithRetries(justRebootedNode)
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Unexpected label %q found on node backing off", constants.LabelAfterReboot)
+		}
+	})
+
+	t.Run("once_backoff_elapses", func(t *testing.T) {
+		t.Parallel()
+
+		justRebootedNode := justRebootedNode()
+		justRebootedNode.Annotations[constants.AnnotationAfterRebootAttempts] = "1"
+		justRebootedNode.Annotations[constants.AnnotationAfterRebootAttemptTime] = timestamp(
+			time.Now().Add(-2 * afterRebootCheckBackoff))
+
+		config, fakeClient := configWithRetries(justRebootedNode)
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+		if v := updatedNode.Labels[constants.LabelAfterReboot]; v != constants.True {
+			t.Fatalf("Expected label %q to be %q, got %v", constants.LabelAfterReboot, constants.True, updatedNode.Labels)
+		}
+	})
+
+	t.Run("not_for_nodes_labeled_as_failed_to_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		justRebootedNode := justRebootedNode()
+		justRebootedNode.Labels[constants.LabelRebootFailed] = constants.True
+
+		config, fakeClient := configWithRetries(justRebootedNode)
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Unexpected label %q found on node failed to reboot", constants.LabelAfterReboot)
+		}
+	})
+
+	t.Run("while_allowing_other_nodes_to_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		timedOutNode := timedOutNode("")
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := configWithRetries(timedOutNode, rebootableNode)
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+		if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != constants.True {
+			t.Fatalf("Expected label %q to be %q, got %v", constants.LabelBeforeReboot, constants.True, updatedNode.Labels)
+		}
+	})
+}
+
+//nolint:funlen // Just many sub-tests.
+func Test_Operator_confirms_reboot_process_only_when_after_reboot_checks_pass(t *testing.T) {
+	t.Parallel()
+
+	t.Run("calling_configured_checks_with_rebooted_node", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := finishedRebootingNode()
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+
+		checkedNodes := make(chan string, 1)
+
+		config.AfterRebootChecks = []operator.AfterRebootCheck{
+			func(ctx context.Context, node *corev1.Node) (bool, error) {
+				select {
+				case checkedNodes <- node.Name:
+				default:
+				}
+
+				return true, nil
+			},
+		}
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if nodeName := <-checkedNodes; nodeName != finishedRebootingNode.Name {
+			t.Fatalf("Expected node %q to be checked, got %q", finishedRebootingNode.Name, nodeName)
+		}
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; ok {
+			t.Fatalf("Expected label %q to be removed, got %v", constants.LabelAfterReboot, updatedNode.Labels)
+		}
+	})
+
+	failingChecks := map[string]operator.AfterRebootCheck{
+		"not_when_configured_check_does_not_pass": func(context.Context, *corev1.Node) (bool, error) {
+			return false, nil
+		},
+		"not_when_configured_check_returns_error": func(context.Context, *corev1.Node) (bool, error) {
+			return true, fmt.Errorf("check failed")
+		},
+	}
+
+	for name, check := range failingChecks {
+		check := check
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			finishedRebootingNode := finishedRebootingNode()
+
+			config, fakeClient := testConfig(finishedRebootingNode)
+			config.AfterRebootChecks = []operator.AfterRebootCheck{check}
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+			if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; !ok {
+				t.Fatalf("Expected label %q to be kept, got %v", constants.LabelAfterReboot, updatedNode.Labels)
+			}
+		})
+	}
+
+	readyCondition := func(status corev1.ConditionStatus) []corev1.PodCondition {
+		return []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+	}
+
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-daemonset",
+			Namespace: testNamespace,
+			UID:       "test-daemonset-uid",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+		},
+	}
+
+	daemonSetPod := func(nodeName string, conditions []corev1.PodCondition) *corev1.Pod {
+		controller := true
+
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-daemonset-" + nodeName,
+				Namespace: testNamespace,
+				Labels:    map[string]string{"app": "test"},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: "apps/v1",
+						Kind:       "DaemonSet",
+						Name:       daemonSet.Name,
+						UID:        daemonSet.UID,
+						Controller: &controller,
+					},
+				},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+			},
+			Status: corev1.PodStatus{
+				Conditions: conditions,
+			},
+		}
+	}
+
+	deployment := func(replicas, readyReplicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-deployment",
+				Namespace: testNamespace,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+			},
+			Status: appsv1.DeploymentStatus{
+				ReadyReplicas: readyReplicas,
+			},
+		}
+	}
+
+	daemonSetWorkload := "daemonset/" + testNamespace + "/" + daemonSet.Name
+	deploymentWorkload := "deployment/" + testNamespace + "/test-deployment"
+	nodeName := finishedRebootingNode().Name
+
+	cases := map[string]struct {
+		workload       string
+		objects        []runtime.Object
+		expectFinished bool
+	}{
+		"when_configured_DaemonSet_has_ready_pod_on_rebooted_node": {
+			workload:       daemonSetWorkload,
+			objects:        []runtime.Object{daemonSet, daemonSetPod(nodeName, readyCondition(corev1.ConditionTrue))},
+			expectFinished: true,
+		},
+		"not_when_configured_DaemonSet_has_not_ready_pod_on_rebooted_node": {
+			workload: daemonSetWorkload,
+			objects:  []runtime.Object{daemonSet, daemonSetPod(nodeName, readyCondition(corev1.ConditionFalse))},
+		},
+		"not_when_configured_DaemonSet_has_ready_pod_only_on_other_node": {
+			workload: daemonSetWorkload,
+			objects:  []runtime.Object{daemonSet, daemonSetPod("other-node", readyCondition(corev1.ConditionTrue))},
+		},
+		"not_when_configured_DaemonSet_does_not_exist": {
+			workload: daemonSetWorkload,
+		},
+		"when_configured_Deployment_has_all_replicas_ready": {
+			workload:       deploymentWorkload,
+			objects:        []runtime.Object{deployment(2, 2)},
+			expectFinished: true,
+		},
+		"not_when_configured_Deployment_has_not_ready_replicas": {
+			workload: deploymentWorkload,
+			objects:  []runtime.Object{deployment(2, 1)},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			finishedRebootingNode := finishedRebootingNode()
+
+			config, fakeClient := testConfig(append(testCase.objects, finishedRebootingNode)...)
+			config.AfterRebootReadyWorkloads = []string{testCase.workload}
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+			_, hasAfterRebootLabel := updatedNode.Labels[constants.LabelAfterReboot]
+
+			if testCase.expectFinished && hasAfterRebootLabel {
+				t.Fatalf("Expected after reboot label to be removed, got %v", updatedNode.Labels)
+			}
+
+			if !testCase.expectFinished && !hasAfterRebootLabel {
+				t.Fatalf("Expected after reboot label to be kept, got %v", updatedNode.Labels)
+			}
+		})
+	}
+}
+
+// Test opposite conditions starting from base to make sure all cases are covered.
+func Test_Operator_counts_nodes_as_which_finished_rebooting_which_has(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	cases := map[string]struct {
+		mutateF                 func(*corev1.Node)
+		expectFinishedRebooting bool
+	}{
+		"all_conditions_met": {
+			expectFinishedRebooting: true,
+		},
+		// Only consider nodes which runs the after-reboot hooks.
+		"after_reboot_label_set": {
+			mutateF: func(updatedNode *corev1.Node) {
+				delete(updatedNode.Labels, constants.LabelAfterReboot)
+			},
+		},
+		// To verify all hooks executed successfully.
+		"all_after_reboot_annotations_set_to_true": {
+			mutateF: func(updatedNode *corev1.Node) {
+				updatedNode.Annotations[testAfterRebootAnnotation] = constants.False
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			finishedRebootingNode := finishedRebootingNode()
+			if testCase.mutateF != nil {
+				testCase.mutateF(finishedRebootingNode)
+			}
+
+			config, fakeClient := testConfig(finishedRebootingNode)
+			config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+			v, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]
+			if !testCase.expectFinishedRebooting && ok && v != constants.True {
+				t.Fatalf("Expected after reboot label, got %v", updatedNode.Labels)
+			}
+
+			if testCase.expectFinishedRebooting && ok && v == constants.True {
+				t.Fatalf("Unexpected after reboot label")
+			}
+		})
+	}
+}
+
+// To verify before reboot annotations are not mistaken for after reboot ones.
+func Test_Operator_counts_nodes_as_finished_rebooting_based_only_on_after_reboot_annotations(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	cases := map[string]struct {
+		annotations             map[string]string
+		expectFinishedRebooting bool
+	}{
+		"when_after_reboot_annotations_are_set": {
+			annotations: map[string]string{
+				testAfterRebootAnnotation: constants.True,
+			},
+			expectFinishedRebooting: true,
+		},
+		"not_when_only_before_reboot_annotations_are_set": {
+			annotations: map[string]string{
+				testAfterRebootAnnotation:  constants.False,
+				testBeforeRebootAnnotation: constants.True,
+			},
+		},
+	}
+
+	for name, testCase := range cases {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			finishedRebootingNode := finishedRebootingNode()
+			delete(finishedRebootingNode.Annotations, testAfterRebootAnnotation)
+			delete(finishedRebootingNode.Annotations, testAnotherAfterRebootAnnotation)
+
+			for k, v := range testCase.annotations {
+				finishedRebootingNode.Annotations[k] = v
+			}
+
+			config, fakeClient := testConfig(finishedRebootingNode)
+			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+			config.AfterRebootAnnotations = []string{testAfterRebootAnnotation}
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+			_, hasAfterRebootLabel := updatedNode.Labels[constants.LabelAfterReboot]
+
+			if testCase.expectFinishedRebooting && hasAfterRebootLabel {
+				t.Fatalf("Expected after reboot label to be removed, got %v", updatedNode.Labels)
+			}
+
+			if !testCase.expectFinishedRebooting && !hasAfterRebootLabel {
+				t.Fatalf("Expected after reboot label to be kept, got %v", updatedNode.Labels)
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many sub-tests.
+func Test_Operator_stops_current_reconciliation_when(t *testing.T) {
+	t.Parallel()
+
+	for name, testCase := range map[string]struct {
+		node                  *corev1.Node
+		failingUpdateCall     int
+		expectedNodeCondition func(*corev1.Node) bool
+	}{
+		"cleaning_up_node_state_fails_because_updating_node_fails": {
+			node:              rebootCancelledNode(),
+			failingUpdateCall: 0,
+			expectedNodeCondition: func(node *corev1.Node) bool {
+				_, ok := node.Labels[constants.LabelBeforeReboot]
+
+				return ok
+			},
+		},
+		"evaluating_nodes_which_finished_rebooting_fails_because_updating_node_fails": {
+			node:              finishedRebootingNode(),
+			failingUpdateCall: 0,
+			expectedNodeCondition: func(node *corev1.Node) bool {
+				_, ok := node.Labels[constants.LabelAfterReboot]
+
+				return ok
+			},
+		},
+		"evaluating_nodes_which_just_rebooted_fails_because_updating_node_fails": {
+			node:              justRebootedNode(),
+			failingUpdateCall: 1,
+			expectedNodeCondition: func(node *corev1.Node) bool {
+				_, ok := node.Labels[constants.LabelAfterReboot]
+
+				return !ok
+			},
+		},
+		"evaluating_nodes_which_are_ready_to_reboot_fails_because_updating_node_fails": {
+			node:              readyToRebootNode(),
+			failingUpdateCall: 1,
+			expectedNodeCondition: func(node *corev1.Node) bool {
+				v, ok := node.Labels[constants.LabelBeforeReboot]
+
+				return ok || v != constants.True
+			},
+		},
+		"evaluating_nodes_which_needs_to_reboot_fails_because_updating_node_fails": {
+			node:              rebootableNode(),
+			failingUpdateCall: 1,
+			expectedNodeCondition: func(node *corev1.Node) bool {
+				v, ok := node.Labels[constants.LabelBeforeReboot]
+
+				return ok || v != constants.True
+			},
+		},
+	} {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config, fakeClient := testConfig(testCase.node)
+			requestFailed, failRequest := failOnNthCall(testCase.failingUpdateCall, fmt.Errorf(t.Name()))
+			fakeClient.PrependReactor("update", "nodes", failRequest)
+
+			ctx, cancel := context.WithTimeout(contextWithDeadline(t), 5*time.Second)
+			t.Cleanup(cancel)
+
+			process(ctx, t, config, fakeClient)
+
+			select {
+			case <-requestFailed:
+			case <-ctx.Done():
+				t.Fatalf("Timed out waiting for request to fail")
+			}
+
+			if !testCase.expectedNodeCondition(node(ctx, t, config.Client.CoreV1().Nodes(), testCase.node.Name)) {
+				t.Fatalf("Expected condition not met")
+			}
+		})
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_cordons_node_during_reboot_process(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	t.Run("by_marking_node_unschedulable_when_scheduling_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.CordonBeforeReboot = true
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if !updatedNode.Spec.Unschedulable {
+			t.Fatalf("Expected node %q to be marked as unschedulable", rebootableNode.Name)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationOperatorMadeUnschedulable]; v != constants.True {
+			t.Fatalf("Expected annotation %q to be %q, got %q",
+				constants.AnnotationOperatorMadeUnschedulable, constants.True, v)
+		}
+	})
+
+	t.Run("by_marking_node_schedulable_when_reboot_process_finishes", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := finishedRebootingNode()
+		finishedRebootingNode.Spec.Unschedulable = true
+		finishedRebootingNode.Annotations[constants.AnnotationOperatorMadeUnschedulable] = constants.True
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+		config.CordonBeforeReboot = true
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+		if updatedNode.Spec.Unschedulable {
+			t.Fatalf("Expected node %q to be marked as schedulable", finishedRebootingNode.Name)
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationOperatorMadeUnschedulable]; ok {
+			t.Fatalf("Unexpected annotation %q found", constants.AnnotationOperatorMadeUnschedulable)
+		}
+	})
+
+	// Simulates e.g. another controller overriding schedulability of the node given number of times.
+	keepingUnschedulable := func(fakeClient *k8stesting.Fake, times int) {
+		overrides := 0
+
+		fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			updateAction, ok := action.(k8stesting.UpdateAction)
+			if !ok {
+				return false, nil, nil
+			}
+
+			node, ok := updateAction.GetObject().(*corev1.Node)
+			if ok && !node.Spec.Unschedulable && overrides < times {
+				overrides++
+				node.Spec.Unschedulable = true
+			}
+
+			return false, nil, nil
+		})
+	}
+
+	cordonedFinishedRebootingNode := func() *corev1.Node {
+		finishedRebootingNode := finishedRebootingNode()
+		finishedRebootingNode.Spec.Unschedulable = true
+		finishedRebootingNode.Annotations[constants.AnnotationOperatorMadeUnschedulable] = constants.True
+
+		return finishedRebootingNode
+	}
+
+	t.Run("by_retrying_marking_node_schedulable_when_it_remains_unschedulable", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := cordonedFinishedRebootingNode()
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.CordonBeforeReboot = true
+
+		keepingUnschedulable(fakeClient, 1)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+		if updatedNode.Spec.Unschedulable {
+			t.Fatalf("Expected node %q to be marked as schedulable", finishedRebootingNode.Name)
+		}
+	})
+
+	t.Run("by_emitting_event_when_node_remains_unschedulable_after_retries", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := cordonedFinishedRebootingNode()
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.CordonBeforeReboot = true
+
+		keepingUnschedulable(fakeClient, math.MaxInt)
+
+		<-process(ctx, t, config, fakeClient)
+
+		// Events are sent asynchronously, so wait for them to arrive.
+		err := wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+			events, err := config.Client.CoreV1().Events(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return false, fmt.Errorf("listing events: %w", err)
+			}
+
+			for _, event := range events.Items {
+				if event.Reason == "NodeRemainsUnschedulable" && event.InvolvedObject.Name == finishedRebootingNode.Name {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("Waiting for event: %v", err)
+		}
+	})
+
+	t.Run("by_marking_node_schedulable_when_it_is_no_longer_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		idleNode := idleNode()
+		idleNode.Spec.Unschedulable = true
+		idleNode.Annotations[constants.AnnotationOperatorMadeUnschedulable] = constants.True
+
+		config, fakeClient := testConfig(idleNode)
+		config.CordonBeforeReboot = true
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), idleNode.Name)
+		if updatedNode.Spec.Unschedulable {
+			t.Fatalf("Expected node %q to be marked as schedulable", idleNode.Name)
+		}
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationOperatorMadeUnschedulable]; ok {
+			t.Fatalf("Unexpected annotation %q found", constants.AnnotationOperatorMadeUnschedulable)
+		}
+	})
+
+	t.Run("leaving_node_unschedulable_while_it_is_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		rebootingNode := rebootingNode()
+		rebootingNode.Spec.Unschedulable = true
+		rebootingNode.Annotations[constants.AnnotationOperatorMadeUnschedulable] = constants.True
+
+		config, fakeClient := testConfig(rebootingNode)
+		config.CordonBeforeReboot = true
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootingNode.Name)
+		if !updatedNode.Spec.Unschedulable {
+			t.Fatalf("Expected node %q to remain unschedulable", rebootingNode.Name)
+		}
+	})
+
+	t.Run("by_marking_node_schedulable_when_reboot_is_cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		rebootCancelledNode := rebootCancelledNode()
+		rebootCancelledNode.Spec.Unschedulable = true
+		rebootCancelledNode.Annotations[constants.AnnotationOperatorMadeUnschedulable] = constants.True
+
+		config, fakeClient := testConfig(rebootCancelledNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.CordonBeforeReboot = true
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+		if updatedNode.Spec.Unschedulable {
+			t.Fatalf("Expected node %q to be marked as schedulable", rebootCancelledNode.Name)
+		}
+	})
+
+	t.Run("leaving_node_which_was_already_unschedulable_unschedulable_after_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+		rebootableNode.Spec.Unschedulable = true
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.CordonBeforeReboot = true
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Annotations[constants.AnnotationOperatorMadeUnschedulable]; ok {
+			t.Fatalf("Unexpected annotation %q found on node which was already unschedulable",
+				constants.AnnotationOperatorMadeUnschedulable)
+		}
+
+		finishedRebootingNode := finishedRebootingNode()
+		finishedRebootingNode.Spec.Unschedulable = true
+
+		config, fakeClient = testConfig(finishedRebootingNode)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+		config.CordonBeforeReboot = true
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode = node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+		if !updatedNode.Spec.Unschedulable {
+			t.Fatalf("Expected node %q to remain unschedulable", finishedRebootingNode.Name)
+		}
+	})
+
+	t.Run("only_when_enabled", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if updatedNode.Spec.Unschedulable {
+			t.Fatalf("Unexpected node %q marked as unschedulable", rebootableNode.Name)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_prevents_Karpenter_from_disrupting_node_during_reboot_process(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	doNotDisruptAnnotation := "karpenter.sh/do-not-disrupt"
+
+	t.Run("by_annotating_node_when_scheduling_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.KarpenterDoNotDisrupt = true
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if v := updatedNode.Annotations[doNotDisruptAnnotation]; v != constants.True {
+			t.Fatalf("Expected annotation %q to be %q, got %q", doNotDisruptAnnotation, constants.True, v)
+		}
+	})
+
+	t.Run("by_keeping_annotation_when_approving_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		readyToRebootNode := readyToRebootNode()
+		readyToRebootNode.Annotations[doNotDisruptAnnotation] = constants.True
+
+		config, fakeClient := testConfig(readyToRebootNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.KarpenterDoNotDisrupt = true
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.True {
+			t.Fatalf("Expected node to be approved for reboot, got annotations %v", updatedNode.Annotations)
+		}
+
+		if v := updatedNode.Annotations[doNotDisruptAnnotation]; v != constants.True {
+			t.Fatalf("Expected annotation %q to be %q, got %q", doNotDisruptAnnotation, constants.True, v)
+		}
+	})
+
+	t.Run("by_removing_annotation_when_reboot_process_finishes", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := finishedRebootingNode()
+		finishedRebootingNode.Annotations[doNotDisruptAnnotation] = constants.True
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+		config.KarpenterDoNotDisrupt = true
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+		if _, ok := updatedNode.Annotations[doNotDisruptAnnotation]; ok {
+			t.Fatalf("Unexpected annotation %q found", doNotDisruptAnnotation)
+		}
+	})
+
+	t.Run("by_removing_annotation_when_reboot_is_cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		rebootCancelledNode := rebootCancelledNode()
+		rebootCancelledNode.Annotations[doNotDisruptAnnotation] = constants.True
+
+		config, fakeClient := testConfig(rebootCancelledNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.KarpenterDoNotDisrupt = true
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootCancelledNode.Name)
+		if _, ok := updatedNode.Annotations[doNotDisruptAnnotation]; ok {
+			t.Fatalf("Unexpected annotation %q found", doNotDisruptAnnotation)
+		}
+	})
+
+	t.Run("only_when_enabled", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+
+		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
+		<-process(ctx, t, config, fakeClient)
+		<-nodeUpdated
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Annotations[doNotDisruptAnnotation]; ok {
+			t.Fatalf("Unexpected annotation %q found", doNotDisruptAnnotation)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_taints_node_during_reboot_process(t *testing.T) {
+	t.Parallel()
+
+	ctx := contextWithDeadline(t)
+
+	rebootTaint := corev1.Taint{
+		Key:    "flatcar.io/rebooting",
+		Value:  constants.True,
+		Effect: corev1.TaintEffectNoSchedule,
+	}
+
+	otherTaint := corev1.Taint{
+		Key:    "other",
+		Effect: corev1.TaintEffectNoExecute,
+	}
+
+	t.Run("by_adding_configured_taint_when_scheduling_reboot", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+		rebootableNode.Spec.Taints = []corev1.Taint{otherTaint}
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.RebootTaint = &rebootTaint
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
+
+		if diff := cmp.Diff([]corev1.Taint{otherTaint, rebootTaint}, updatedNode.Spec.Taints); diff != "" {
+			t.Fatalf("Unexpected node taints (-expected +got):\n%s", diff)
+		}
+	})
+
+	t.Run("by_removing_configured_taint_when_reboot_process_finishes", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := finishedRebootingNode()
+		finishedRebootingNode.Spec.Taints = []corev1.Taint{rebootTaint, otherTaint}
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+		config.RebootTaint = &rebootTaint
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.False {
+			t.Fatalf("Expected reboot process of node %q to finish, got annotations %v",
+				finishedRebootingNode.Name, updatedNode.Annotations)
+		}
+
+		if diff := cmp.Diff([]corev1.Taint{otherTaint}, updatedNode.Spec.Taints); diff != "" {
+			t.Fatalf("Unexpected node taints (-expected +got):\n%s", diff)
+		}
+	})
+
+	t.Run("only_when_configured", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if taints := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name).Spec.Taints; len(taints) != 0 {
+			t.Fatalf("Unexpected taints on node: %v", taints)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_sends_notification_to_configured_webhook_when(t *testing.T) {
+	t.Parallel()
+
+	// notificationServer returns URL of a webhook server responding with given status code and
+	// a channel receiving notification payloads sent to it.
+	notificationServer := func(t *testing.T, statusCode int) (string, <-chan map[string]string) {
+		t.Helper()
+
+		payloads := make(chan map[string]string, 1)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			payload := map[string]string{}
+
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Errorf("Decoding notification payload: %v", err)
+			}
+
+			// Only first notification is of interest.
+			select {
+			case payloads <- payload:
+			default:
+			}
+
+			w.WriteHeader(statusCode)
+		}))
+		t.Cleanup(server.Close)
+
+		return server.URL, payloads
+	}
+
+	waitForPayload := func(ctx context.Context, t *testing.T, payloads <-chan map[string]string) map[string]string {
+		t.Helper()
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for notification")
+		case payload := <-payloads:
+			return payload
+		}
+
+		return nil
+	}
+
+	t.Run("node_is_scheduled_for_rebooting", func(t *testing.T) {
+		t.Parallel()
+
+		url, payloads := notificationServer(t, http.StatusOK)
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.NotificationWebhookURL = url
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		payload := waitForPayload(ctx, t, payloads)
+
+		if payload["node"] != rebootableNode.Name || payload["event"] != operator.NotificationEventRebootStarted {
+			t.Fatalf("Expected notification about reboot of node %q being started, got %v", rebootableNode.Name, payload)
+		}
+
+		if expected := "Node rebootable started rebooting at "; !strings.HasPrefix(payload["text"], expected) {
+			t.Fatalf("Expected notification text to start with %q, got %q", expected, payload["text"])
+		}
+
+		if _, err := time.Parse(time.RFC3339, payload["timestamp"]); err != nil {
+			t.Fatalf("Expected notification timestamp in RFC 3339 format, got %q: %v", payload["timestamp"], err)
+		}
+	})
+
+	t.Run("node_finishes_rebooting_using_configured_template", func(t *testing.T) {
+		t.Parallel()
+
+		url, payloads := notificationServer(t, http.StatusOK)
+
+		finishedRebootingNode := finishedRebootingNode()
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+		config.NotificationWebhookURL = url
+		config.NotificationTemplate = "{{ .Event }} of {{ .Node }}"
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		payload := waitForPayload(ctx, t, payloads)
+
+		if expected := "reboot-finished of " + finishedRebootingNode.Name; payload["text"] != expected {
+			t.Fatalf("Expected notification text %q, got %q", expected, payload["text"])
+		}
+	})
+
+	t.Run("node_is_scheduled_for_rebooting_signed_with_configured_secret", func(t *testing.T) {
+		t.Parallel()
+
+		secret := []byte("notification-secret")
+		signatureValid := make(chan bool, 1)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Errorf("Reading notification payload: %v", err)
+			}
+
+			select {
+			case signatureValid <- webhook.Verify(body, secret, r.Header.Get(webhook.SignatureHeader)):
+			default:
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		config, fakeClient := testConfig(rebootableNode())
+		config.NotificationWebhookURL = server.URL
+		config.NotificationWebhookSecret = secret
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Timed out waiting for notification")
+		case valid := <-signatureValid:
+			if !valid {
+				t.Fatalf("Expected notification to have valid signature in %q header", webhook.SignatureHeader)
 			}
+		}
+	})
+
+	t.Run("node_is_scheduled_for_rebooting_even_if_webhook_fails", func(t *testing.T) {
+		t.Parallel()
+
+		url, payloads := notificationServer(t, http.StatusInternalServerError)
+
+		rebootableNode := rebootableNode()
+
+		config, fakeClient := testConfig(rebootableNode)
+		config.NotificationWebhookURL = url
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		waitForPayload(ctx, t, payloads)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_forcefully_rebooting_node(t *testing.T) {
+	t.Parallel()
+
+	t.Run("schedules_reboot_process_outside_reboot_window_when_max_rebooting_nodes_is_reached", func(t *testing.T) {
+		t.Parallel()
+
+		rebootableNode := rebootableNode()
+
+		config, _ := testConfig(rebootableNode, rebootingNode())
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config.RebootWindowStart = "Mon 00:00"
+		config.RebootWindowLength = "0s"
+
+		ctx := contextWithDeadline(t)
+
+		if err := kontrollerWithObjects(t, config).ForceReboot(ctx, rebootableNode.Name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+
+		if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != constants.True {
+			t.Fatalf("Expected node %q to be scheduled for reboot, got labels %v", rebootableNode.Name, updatedNode.Labels)
+		}
+
+		if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; ok {
+			t.Fatalf("Expected before reboot annotation %q to be removed", testBeforeRebootAnnotation)
+		}
+	})
 
-			if beforeReboot != constants.True {
-				t.Fatalf("Expected value %q for label %q, got: %q", constants.True, constants.LabelBeforeReboot, beforeReboot)
+	t.Run("fails_when", func(t *testing.T) {
+		t.Parallel()
+
+		pausedNode := rebootableNode()
+		pausedNode.Annotations[constants.AnnotationRebootPaused] = constants.True
+
+		for name, testCase := range map[string]struct {
+			node   *corev1.Node
+			config func(*operator.Config)
+		}{
+			"node_does_not_need_reboot": {
+				node: idleNode(),
+			},
+			"node_has_reboot_paused": {
+				node: pausedNode,
+			},
+			"node_is_already_scheduled_for_reboot": {
+				node: scheduledForRebootNode(),
+			},
+			"node_does_not_match_configured_node_selector": {
+				node: rebootableNode(),
+				config: func(config *operator.Config) {
+					config.NodeSelector = "flatcar.io/managed=true"
+				},
+			},
+			"number_of_rebooting_nodes_reaches_configured_limit": {
+				node: rebootableNode(),
+				config: func(config *operator.Config) {
+					config.ForceRebootLimit = 1
+				},
+			},
+		} {
+			testCase := testCase
+
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				config, _ := testConfig(testCase.node, rebootingNode())
+				if testCase.config != nil {
+					testCase.config(&config)
+				}
+
+				ctx := contextWithDeadline(t)
+
+				if err := kontrollerWithObjects(t, config).ForceReboot(ctx, testCase.node.Name); err == nil {
+					t.Fatalf("Expected error")
+				}
+
+				updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), testCase.node.Name)
+				if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != testCase.node.Labels[constants.LabelBeforeReboot] {
+					t.Fatalf("Unexpected change of before reboot label of node %q to %q", testCase.node.Name, v)
+				}
+			})
+		}
+
+		t.Run("node_does_not_exist", func(t *testing.T) {
+			t.Parallel()
+
+			config, _ := testConfig()
+
+			if err := kontrollerWithObjects(t, config).ForceReboot(contextWithDeadline(t), "foo"); err == nil {
+				t.Fatalf("Expected error")
 			}
 		})
-	}
+	})
 }
 
-// This test attempts to schedule a reboot for a schedulable node and depending on the
-// state of the rebooting node, controller will either proceed with scheduling or will
-// not do anything.
-func Test_Operator_counts_nodes_as_rebooting_which(t *testing.T) {
+func Test_Operator_classifies_reboot_of_node_as(t *testing.T) {
 	t.Parallel()
 
-	ctx := contextWithDeadline(t)
-
-	cases := map[string]*corev1.Node{
-		"are_scheduled_for_reboot_already": scheduledForRebootNode(),
-		"are_ready_to_reboot":              readyToRebootNode(),
-		"has_reboot_approved":              rebootNotConfirmedNode(),
-		"are_rebooting":                    rebootingNode(),
-		"just_rebooted":                    justRebootedNode(),
-	}
-
-	for name, extraNode := range cases {
-		extraNode := extraNode
+	for name, testCase := range map[string]struct {
+		mutateF        func(*corev1.Node)
+		expectedReason string
+	}{
+		"voluntary_when_reboot_has_been_approved_by_operator": {
+			mutateF:        func(*corev1.Node) {},
+			expectedReason: "voluntary-reboot",
+		},
+		"involuntary_when_reboot_has_not_been_approved_by_operator": {
+			mutateF: func(node *corev1.Node) {
+				delete(node.Annotations, constants.AnnotationRebootApproved)
+			},
+			expectedReason: "involuntary-reboot",
+		},
+	} {
+		name, testCase := name, testCase
 
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			rebootableNode := rebootableNode()
+			justRebootedNode := justRebootedNode()
+			// Use unique node name to be able to distinguish metrics from other tests.
+			justRebootedNode.Name = strings.ReplaceAll(name, "_", "-")
+			testCase.mutateF(justRebootedNode)
 
-			config, fakeClient := testConfig(extraNode, rebootableNode)
+			config, fakeClient := testConfig(justRebootedNode)
 
-			// Required to test selecting rebooting nodes only with before-reboot label, otherwise
-			// it gets removed before we schedule nodes for rebooting.
-			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+			ctx := contextWithDeadline(t)
 
 			<-process(ctx, t, config, fakeClient)
 
-			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+			if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; !ok {
+				t.Fatalf("Expected label %q on node", constants.LabelAfterReboot)
+			}
 
-			if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-				t.Fatalf("Unexpected node %q scheduled for rebooting", rebootableNode.Name)
+			labels := map[string]string{
+				string(metrics.LabelNode):   justRebootedNode.Name,
+				string(metrics.LabelReason): testCase.expectedReason,
+			}
+
+			if v := metricValueWithLabels(t, "fluo_node_phase_transitions_total", labels); v < 1 {
+				t.Fatalf("Expected node transition with reason %q to be recorded", testCase.expectedReason)
 			}
 		})
 	}
 }
 
-func Test_Operator_does_not_count_nodes_as_rebootable_which(t *testing.T) {
+func Test_Operator_emits_event_once_when_reboot_process_exceeds_configured_SLO(t *testing.T) {
 	t.Parallel()
 
+	rebootSLO := 20 * time.Minute
+
+	finishedRebootingNode := finishedRebootingNode()
+	// Use unique node name to be able to distinguish metrics from other tests.
+	finishedRebootingNode.Name = "slow-rebooting"
+	rebootCycleStartTime := strconv.FormatInt(time.Now().Add(-2*rebootSLO).Unix(), 10)
+	finishedRebootingNode.Annotations[constants.AnnotationRebootCycleStartTime] = rebootCycleStartTime
+
+	config, fakeClient := testConfig(finishedRebootingNode)
+	config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+	config.RebootSLO = rebootSLO
+	config.ReconciliationPeriod = 100 * time.Millisecond
+
+	labels := map[string]string{string(metrics.LabelNode): finishedRebootingNode.Name}
+	initialBreaches := metricValueWithLabels(t, "fluo_reboot_slo_breaches_total", labels)
+
 	ctx := contextWithDeadline(t)
 
-	cases := map[string]func(*corev1.Node){
-		"do_not_require_reboot": func(updatedNode *corev1.Node) {
-			updatedNode.Annotations[constants.AnnotationRebootNeeded] = constants.False
-		},
-		"are_already_rebooting": func(updatedNode *corev1.Node) {
-			*updatedNode = *rebootingNode()
-			updatedNode.Annotations[testBeforeRebootAnnotation] = constants.True
-			updatedNode.Annotations[testAnotherBeforeRebootAnnotation] = constants.True
+	reconcileCycle := process(ctx, t, config, fakeClient)
+
+	// Run a few cycles to ensure event is not emitted again.
+	for i := 0; i < 3; i++ {
+		<-reconcileCycle
+	}
+
+	var events *corev1.EventList
+
+	// Events are sent asynchronously, so wait for them to arrive.
+	err := wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+		var err error
+
+		events, err = config.Client.CoreV1().Events(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return false, fmt.Errorf("listing events: %w", err)
+		}
+
+		for _, event := range events.Items {
+			if event.Reason == "RebootSLOBreached" {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		t.Fatalf("Waiting for events: %v", err)
+	}
+
+	breachEvents := 0
+
+	for _, event := range events.Items {
+		if event.Reason == "RebootSLOBreached" && event.InvolvedObject.Name == finishedRebootingNode.Name {
+			breachEvents += int(event.Count)
+		}
+	}
+
+	if breachEvents != 1 {
+		t.Fatalf("Expected exactly one SLO breach event, got %d: %v", breachEvents, events.Items)
+	}
+
+	if v := metricValueWithLabels(t, "fluo_reboot_slo_breaches_total", labels) - initialBreaches; v != 1 {
+		t.Fatalf("Expected SLO breach to be recorded exactly once, got %v", v)
+	}
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_reboots_nodes_with_reboot_requested_by(t *testing.T) {
+	t.Parallel()
+
+	t.Run("scheduling_reboot_process_of_idle_node", func(t *testing.T) {
+		t.Parallel()
+
+		requestedNode := idleNode()
+		requestedNode.Annotations[constants.AnnotationRequestReboot] = constants.True
+
+		config, fakeClient := testConfig(requestedNode)
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), requestedNode.Name)
+
+		if v := updatedNode.Annotations[constants.AnnotationRebootNeeded]; v != constants.True {
+			t.Fatalf("Expected annotation %q value %q, got %q", constants.AnnotationRebootNeeded, constants.True, v)
+		}
+
+		if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != constants.True {
+			t.Fatalf("Expected label %q value %q, got %q", constants.LabelBeforeReboot, constants.True, v)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationRebootReason]; v != "requested" {
+			t.Fatalf("Expected annotation %q value %q, got %q", constants.AnnotationRebootReason, "requested", v)
+		}
+	})
+
+	t.Run("scheduling_reboot_process_of_idle_node_when_reconciling_only_the_node", func(t *testing.T) {
+		t.Parallel()
+
+		requestedNode := idleNode()
+		requestedNode.Annotations[constants.AnnotationRequestReboot] = constants.True
+
+		config, _ := testConfig(requestedNode)
+		config.DisableLeaderElection = true
+
+		testKontroller := kontrollerWithObjects(t, config)
+
+		ctx := contextWithDeadline(t)
+
+		if err := testKontroller.ReconcileNode(ctx, requestedNode.Name); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), requestedNode.Name)
+
+		if v := updatedNode.Labels[constants.LabelBeforeReboot]; v != constants.True {
+			t.Fatalf("Expected label %q value %q, got %q", constants.LabelBeforeReboot, constants.True, v)
+		}
+	})
+
+	t.Run("respecting_maximum_number_of_rebooting_nodes", func(t *testing.T) {
+		t.Parallel()
+
+		requestedNode := idleNode()
+		requestedNode.Annotations[constants.AnnotationRequestReboot] = constants.True
+
+		config, fakeClient := testConfig(requestedNode, rebootingNode())
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), requestedNode.Name)
+
+		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
+		}
+	})
+
+	t.Run("removing_request_annotation_when_reboot_process_finishes", func(t *testing.T) {
+		t.Parallel()
+
+		finishedRebootingNode := finishedRebootingNode()
+		finishedRebootingNode.Annotations[constants.AnnotationRequestReboot] = constants.True
+
+		config, fakeClient := testConfig(finishedRebootingNode)
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationRequestReboot]; ok {
+			t.Fatalf("Unexpected annotation %q found", constants.AnnotationRequestReboot)
+		}
+
+		if v := updatedNode.Annotations[constants.AnnotationRebootNeeded]; v == constants.True {
+			t.Fatalf("Unexpected annotation %q value %q", constants.AnnotationRebootNeeded, v)
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Operator_schedules_reboot_process_of_nodes_running_pods_owned_by_jobs(t *testing.T) {
+	t.Parallel()
+
+	jobPod := func(phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "batch",
+				Namespace:       testNamespace,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "batch"}},
+			},
+			Spec: corev1.PodSpec{
+				NodeName: rebootableNode().Name,
+			},
+			Status: corev1.PodStatus{
+				Phase: phase,
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		pod      *corev1.Pod
+		deferJob bool
+		expected bool
+	}{
+		"not_until_pods_complete_when_deferring_nodes_running_jobs_is_configured": {
+			pod:      jobPod(corev1.PodRunning),
+			deferJob: true,
 		},
-		"has_reboot_paused": func(updatedNode *corev1.Node) {
-			updatedNode.Annotations[constants.AnnotationRebootPaused] = constants.True
+		"when_pods_completed_and_deferring_nodes_running_jobs_is_configured": {
+			pod:      jobPod(corev1.PodSucceeded),
+			deferJob: true,
+			expected: true,
 		},
-		"has_reboot_already_scheduled": func(updatedNode *corev1.Node) {
-			updatedNode.Labels[constants.LabelBeforeReboot] = constants.True
-			updatedNode.Annotations[testAnotherBeforeRebootAnnotation] = constants.False
+		"like_other_nodes_when_deferring_nodes_running_jobs_is_not_configured": {
+			pod:      jobPod(corev1.PodRunning),
+			expected: true,
 		},
 	}
 
-	for name, mutateF := range cases {
-		mutateF := mutateF
+	for name, testCase := range cases {
+		testCase := testCase
 
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
 			rebootableNode := rebootableNode()
-			rebootableNode.Annotations[testBeforeRebootAnnotation] = constants.True
-			rebootableNode.Annotations[testAnotherBeforeRebootAnnotation] = constants.True
-
-			mutateF(rebootableNode)
 
 			config, fakeClient := testConfig(rebootableNode)
-			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation, testAnotherBeforeRebootAnnotation}
-			// To test filter on before-reboot label.
-			config.MaxRebootingNodes = 2
+			config.DisableLeaderElection = true
+			config.DeferNodesRunningJobs = testCase.deferJob
 
-			<-process(ctx, t, config, fakeClient)
+			fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(testCase.pod))
+
+			ctx := contextWithDeadline(t)
+
+			if err := kontrollerWithObjects(t, config).ReconcileOnce(ctx); err != nil {
+				t.Fatalf("Unexpected error reconciling: %v", err)
+			}
 
 			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
 
-			if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; !ok {
-				t.Fatalf("Unexpected node %q scheduled for rebooting", rebootableNode.Name)
+			if _, scheduled := updatedNode.Labels[constants.LabelBeforeReboot]; scheduled != testCase.expected {
+				t.Fatalf("Expected node to be scheduled for reboot: %v, got: %v", testCase.expected, scheduled)
 			}
 		})
 	}
 }
 
-func Test_Operator_counts_nodes_as_rebootable_which_needs_reboot_and_has_all_other_conditions_met(t *testing.T) {
+//nolint:funlen // Just many subtests.
+func Test_Operator_does_not_schedule_reboot_process_of_nodes_not_satisfying_configured_eligibility_predicates(
+	t *testing.T,
+) {
 	t.Parallel()
 
-	rebootableNode := rebootableNode()
-
-	config, fakeClient := testConfig(rebootableNode)
-
-	ctx := contextWithDeadline(t)
+	const ineligibleReason = "maintenance-freeze"
 
-	nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
-	<-process(ctx, t, config, fakeClient)
-	<-nodeUpdated
+	frozenNode := rebootableNode()
+	frozenNode.Name = "frozen"
+	frozenNode.Labels["maintenance"] = "frozen"
 
-	updatedNode := node(contextWithDeadline(t), t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+	rebootableNode := rebootableNode()
 
-	v, ok := updatedNode.Labels[constants.LabelBeforeReboot]
-	if !ok || v != constants.True {
-		t.Fatalf("Expected node %q to be scheduled for rebooting", rebootableNode.Name)
+	config, _ := testConfig(frozenNode, rebootableNode)
+	config.DisableLeaderElection = true
+	config.MaxRebootingNodes = 2
+	config.NodeEligibilityPredicates = []operator.NodeEligibilityPredicate{
+		func(node *corev1.Node) (bool, string) {
+			return node.Labels["maintenance"] != "frozen", ineligibleReason
+		},
 	}
-}
-
-func Test_Operator_does_not_schedules_reboot_process_outside_reboot_window(t *testing.T) {
-	t.Parallel()
-
-	rebootableNode := rebootableNode()
 
-	config, fakeClient := testConfig(rebootableNode)
-	config.RebootWindowStart = "Mon 14:00"
-	config.RebootWindowLength = "0s"
+	labels := map[string]string{string(metrics.LabelReason): ineligibleReason}
+	initialSkips := metricValueWithLabels(t, "fluo_rebootable_nodes_skipped_total", labels)
 
 	ctx := contextWithDeadline(t)
 
-	<-process(ctx, t, config, fakeClient)
+	testKontroller := kontrollerWithObjects(t, config)
 
-	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
-	if v, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok && v == constants.True {
-		t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+	if err := testKontroller.ReconcileOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error reconciling: %v", err)
 	}
-}
 
-// To schedule pre-reboot hooks.
-//
-//nolint:funlen // Just many test cases.
-func Test_Operator_schedules_reboot_process(t *testing.T) {
-	t.Parallel()
+	nodeClient := config.Client.CoreV1().Nodes()
 
-	ctx := contextWithDeadline(t)
+	t.Run("skipping_nodes_not_satisfying_predicates", func(t *testing.T) {
+		t.Parallel()
 
-	t.Run("only_during_reboot_window", func(t *testing.T) {
+		if _, ok := node(ctx, t, nodeClient, frozenNode.Name).Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected label %q found on ineligible node", constants.LabelBeforeReboot)
+		}
+	})
+
+	t.Run("scheduling_nodes_satisfying_predicates", func(t *testing.T) {
 		t.Parallel()
 
-		rebootableNode := rebootableNode()
+		if _, ok := node(ctx, t, nodeClient, rebootableNode.Name).Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected label %q not found on eligible node", constants.LabelBeforeReboot)
+		}
+	})
 
-		config, fakeClient := testConfig(rebootableNode)
-		config.RebootWindowStart = "Mon 00:00"
-		config.RebootWindowLength = fmt.Sprintf("%ds", (7*24*60*60)-1)
+	t.Run("recording_reason_in_metrics", func(t *testing.T) {
+		t.Parallel()
 
-		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
-		<-process(ctx, t, config, fakeClient)
-		<-nodeUpdated
+		if v := metricValueWithLabels(t, "fluo_rebootable_nodes_skipped_total", labels) - initialSkips; v < 1 {
+			t.Fatalf("Expected skipped node to be recorded in metrics, got %v", v)
+		}
+	})
 
-		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
-		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; !ok {
-			t.Fatalf("Expected node %q to be scheduled for reboot", rebootableNode.Name)
+	t.Run("reporting_reason_in_status", func(t *testing.T) {
+		t.Parallel()
+
+		for _, nodeStatus := range testKontroller.Status().Nodes {
+			if nodeStatus.Name != frozenNode.Name {
+				continue
+			}
+
+			if nodeStatus.IneligibleReason != ineligibleReason {
+				t.Fatalf("Expected ineligible reason %q, got %q", ineligibleReason, nodeStatus.IneligibleReason)
+			}
+
+			return
 		}
+
+		t.Fatalf("Status of node %q not found", frozenNode.Name)
 	})
+}
 
-	t.Run("only_for_maximum_number_of_rebooting_nodes_in_parallel", func(t *testing.T) {
-		t.Parallel()
+//nolint:funlen // Just many subtests.
+func Test_Operator_runs_configured_smoke_test_job_on_rebooted_nodes_and(t *testing.T) {
+	t.Parallel()
 
-		rebootableNode := rebootableNode()
+	// setup reconciles node which finished rebooting once, so smoke test Job gets created,
+	// then sets given condition on the Job, if any, and reconciles again.
+	setup := func(t *testing.T, condition batchv1.JobConditionType) (operator.Config, *batchv1.Job) {
+		t.Helper()
+
+		config, _ := testConfig(finishedRebootingNode())
+		config.DisableLeaderElection = true
+		config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+		config.AfterRebootSmokeTestPodTemplate = &corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "smoke-test", Image: "busybox"}},
+			},
+		}
 
-		config, fakeClient := testConfig(rebootableNode, rebootNotConfirmedNode())
+		ctx := contextWithDeadline(t)
 
-		<-process(ctx, t, config, fakeClient)
+		testKontroller := kontrollerWithObjects(t, config)
 
-		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
-		if v, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok && v == constants.True {
-			t.Fatalf("Unexpected node %q scheduled for reboot", rebootableNode.Name)
+		if err := testKontroller.ReconcileOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error reconciling: %v", err)
 		}
-	})
 
-	t.Run("for_nodes_which_are_rebootable", func(t *testing.T) {
-		t.Parallel()
+		jobs, err := config.Client.BatchV1().Jobs(testNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Listing Jobs: %v", err)
+		}
 
-		scheduledForRebootNode := scheduledForRebootNode()
+		if len(jobs.Items) != 1 {
+			t.Fatalf("Expected exactly one smoke test Job, got %d", len(jobs.Items))
+		}
 
-		config, fakeClient := testConfig(scheduledForRebootNode)
+		job := &jobs.Items[0]
 
-		<-process(ctx, t, config, fakeClient)
+		if condition == "" {
+			return config, job
+		}
 
-		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), scheduledForRebootNode.Name)
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
 
-		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-			t.Fatalf("Unexpected node %q scheduled for reboot", updatedNode.Name)
+		if _, err := config.Client.BatchV1().Jobs(testNamespace).UpdateStatus(ctx, job, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("Updating Job status: %v", err)
 		}
-	})
 
-	t.Run("by", func(t *testing.T) {
-		t.Parallel()
-
-		rebootableNode := rebootableNode()
-		rebootableNode.Annotations[testBeforeRebootAnnotation] = constants.True
+		if err := testKontroller.ReconcileOnce(ctx); err != nil {
+			t.Fatalf("Unexpected error reconciling: %v", err)
+		}
 
-		config, fakeClient := testConfig(rebootableNode)
-		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		return config, job
+	}
 
-		nodeUpdated := nodeUpdatedNTimes(fakeClient, 1)
-		<-process(ctx, t, config, fakeClient)
-		<-nodeUpdated
+	t.Run("pins_job_to_rebooted_node", func(t *testing.T) {
+		t.Parallel()
 
-		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+		_, job := setup(t, "")
 
-		t.Run("removing_all_before_reboot_annotations", func(t *testing.T) {
-			t.Parallel()
+		if nodeName := job.Spec.Template.Spec.NodeName; nodeName != finishedRebootingNode().Name {
+			t.Fatalf("Expected Job pods to run on node %q, got %q", finishedRebootingNode().Name, nodeName)
+		}
+	})
 
-			if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; ok {
-				t.Fatalf("Unexpected annotation %q found", testBeforeRebootAnnotation)
-			}
-		})
+	t.Run("waits_for_job_to_complete_before_finishing_reboot_process", func(t *testing.T) {
+		t.Parallel()
 
-		t.Run("setting_before_reboot_label_to_true", func(t *testing.T) {
-			t.Parallel()
+		config, _ := setup(t, "")
 
-			beforeReboot, ok := updatedNode.Labels[constants.LabelBeforeReboot]
-			if !ok {
-				t.Fatalf("Expected label %q not found, got %v instead", constants.LabelBeforeReboot, updatedNode.Labels)
-			}
+		updatedNode := node(contextWithDeadline(t), t, config.Client.CoreV1().Nodes(), finishedRebootingNode().Name)
 
-			if beforeReboot != constants.True {
-				t.Fatalf("Unexpected label value: %q", beforeReboot)
-			}
-		})
+		if _, ok := updatedNode.Labels[constants.LabelAfterReboot]; !ok {
+			t.Fatalf("Expected label %q not found", constants.LabelAfterReboot)
+		}
 	})
-}
 
-func Test_Operator_approves_reboot_process_for_nodes_which_have(t *testing.T) {
-	t.Parallel()
+	t.Run("finishes_reboot_process_and_removes_job_when_job_completes", func(t *testing.T) {
+		t.Parallel()
 
-	ctx := contextWithDeadline(t)
+		config, job := setup(t, batchv1.JobComplete)
 
-	cases := map[string]struct {
-		mutateF        func(*corev1.Node)
-		expectRebootOK bool
-	}{
-		"all_conditions_met": {
-			// Node without mutation should get ok-to-reboot.
-			expectRebootOK: true,
-		},
-		"before_reboot_label": {
-			mutateF: func(updatedNode *corev1.Node) {
-				// Node without before-reboot label won't get ok-to-reboot.
-				delete(updatedNode.Labels, constants.LabelBeforeReboot)
-			},
-		},
-		"all_before_reboot_annotations_set_to_true": {
-			mutateF: func(updatedNode *corev1.Node) {
-				// Node without all before reboot annotations won't get ok-to-reboot.
-				updatedNode.Annotations[testBeforeRebootAnnotation] = constants.False
-			},
-		},
-	}
+		ctx := contextWithDeadline(t)
 
-	for name, testCase := range cases {
-		testCase := testCase
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode().Name)
 
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
+		if v := updatedNode.Annotations[constants.AnnotationOkToReboot]; v != constants.False {
+			t.Fatalf("Expected annotation %q value %q, got %q", constants.AnnotationOkToReboot, constants.False, v)
+		}
 
-			readyToRebootNode := readyToRebootNode()
-			if testCase.mutateF != nil {
-				testCase.mutateF(readyToRebootNode)
-			}
+		_, err := config.Client.BatchV1().Jobs(testNamespace).Get(ctx, job.Name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			t.Fatalf("Expected smoke test Job to be removed, got: %v", err)
+		}
+	})
 
-			config, fakeClient := testConfig(readyToRebootNode)
+	t.Run("labels_node_as_failed_to_reboot_and_removes_job_when_job_fails", func(t *testing.T) {
+		t.Parallel()
 
-			// Use beforeRebootAnnotations to be able to test moment when node has before-reboot
-			// label, but it cannot be removed yet.
-			config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+		config, job := setup(t, batchv1.JobFailed)
 
-			<-process(ctx, t, config, fakeClient)
+		ctx := contextWithDeadline(t)
 
-			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode().Name)
 
-			v, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]
-			if testCase.expectRebootOK && (!ok || v != constants.True) {
-				t.Fatalf("Expected reboot-ok annotation, got %v", updatedNode.Annotations)
-			}
+		if v := updatedNode.Labels[constants.LabelRebootFailed]; v != constants.True {
+			t.Fatalf("Expected label %q value %q, got %q", constants.LabelRebootFailed, constants.True, v)
+		}
 
-			if !testCase.expectRebootOK && ok && v == constants.True {
-				t.Fatalf("Unexpected reboot-ok annotation")
-			}
-		})
-	}
+		_, err := config.Client.BatchV1().Jobs(testNamespace).Get(ctx, job.Name, metav1.GetOptions{})
+		if !apierrors.IsNotFound(err) {
+			t.Fatalf("Expected smoke test Job to be removed, got: %v", err)
+		}
+	})
 }
 
-// To inform agent it can proceed with node draining and rebooting.
-func Test_Operator_approves_reboot_process_by(t *testing.T) {
+//nolint:funlen // Just many subtests.
+func Test_Operator_records_time_of_reboot_of_nodes_by(t *testing.T) {
 	t.Parallel()
 
+	previousRebootTime := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
 	readyToRebootNode := readyToRebootNode()
+	readyToRebootNode.Annotations[constants.AnnotationRebootStartedTime] = previousRebootTime
+	readyToRebootNode.Annotations[constants.AnnotationRebootCompletedTime] = previousRebootTime
 
-	config, fakeClient := testConfig(readyToRebootNode)
+	finishedRebootingNode := finishedRebootingNode()
+	// Use unique pool to be able to distinguish metrics from other tests.
+	finishedRebootingNode.Labels[constants.LabelGroup] = "reboot-duration"
+	finishedRebootingNode.Annotations[constants.AnnotationRebootStartedTime] = previousRebootTime
+
+	config, fakeClient := testConfig(readyToRebootNode, finishedRebootingNode)
 	config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
+	config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+
+	labels := map[string]string{string(metrics.LabelPool): finishedRebootingNode.Labels[constants.LabelGroup]}
+	initialObservations := metricValueWithLabels(t, "fluo_reboot_duration_seconds", labels)
 
 	ctx := contextWithDeadline(t)
 
 	<-process(ctx, t, config, fakeClient)
 
-	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
+	nodeClient := config.Client.CoreV1().Nodes()
 
-	// To de-schedule hook pods.
-	t.Run("removing_before_reboot_label", func(t *testing.T) {
+	t.Run("overwriting_reboot_started_time_when_node_is_allowed_to_reboot", func(t *testing.T) {
 		t.Parallel()
 
-		if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok {
-			t.Fatalf("Unexpected label %q found", constants.LabelBeforeReboot)
+		updatedNode := node(ctx, t, nodeClient, readyToRebootNode.Name)
+
+		startedTime, ok := updatedNode.Annotations[constants.AnnotationRebootStartedTime]
+		if !ok {
+			t.Fatalf("Expected annotation %q not found", constants.AnnotationRebootStartedTime)
+		}
+
+		if startedTime == previousRebootTime {
+			t.Fatalf("Expected annotation %q to be updated, got previous value %q",
+				constants.AnnotationRebootStartedTime, startedTime)
 		}
 	})
 
-	t.Run("removing_all_before_reboot_annotations", func(t *testing.T) {
+	t.Run("removing_reboot_completed_time_of_previous_reboot_when_node_is_allowed_to_reboot", func(t *testing.T) {
 		t.Parallel()
 
-		if _, ok := updatedNode.Annotations[testBeforeRebootAnnotation]; ok {
-			t.Fatalf("Unexpected annotation %q found", testBeforeRebootAnnotation)
+		updatedNode := node(ctx, t, nodeClient, readyToRebootNode.Name)
+
+		if v, ok := updatedNode.Annotations[constants.AnnotationRebootCompletedTime]; ok {
+			t.Fatalf("Unexpected annotation %q found with value %q", constants.AnnotationRebootCompletedTime, v)
 		}
 	})
 
-	// To inform agent that all hooks are executed and it can proceed with the reboot.
-	// Right now by setting ok-to-reboot label to true.
-	t.Run("informing_agent_to_proceed_with_reboot_process", func(t *testing.T) {
+	t.Run("keeping_reboot_started_time_and_setting_reboot_completed_time_when_reboot_finishes", func(t *testing.T) {
 		t.Parallel()
 
-		okToReboot, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]
+		updatedNode := node(ctx, t, nodeClient, finishedRebootingNode.Name)
+
+		if v := updatedNode.Annotations[constants.AnnotationRebootStartedTime]; v != previousRebootTime {
+			t.Fatalf("Expected annotation %q value %q, got %q", constants.AnnotationRebootStartedTime, previousRebootTime, v)
+		}
 
+		completedTime, ok := updatedNode.Annotations[constants.AnnotationRebootCompletedTime]
 		if !ok {
-			t.Fatalf("Expected annotation %q not found, got %v", constants.AnnotationOkToReboot, updatedNode.Annotations)
+			t.Fatalf("Expected annotation %q not found", constants.AnnotationRebootCompletedTime)
 		}
 
-		if okToReboot != constants.True {
-			t.Fatalf("Expected annotation %q value to be %q, got %q",
-				constants.AnnotationOkToReboot, constants.True, okToReboot)
+		if _, err := strconv.ParseInt(completedTime, 10, 64); err != nil {
+			t.Fatalf("Expected annotation %q to be a UNIX timestamp, got %q",
+				constants.AnnotationRebootCompletedTime, completedTime)
+		}
+	})
+
+	t.Run("recording_reboot_duration_metric_when_reboot_finishes", func(t *testing.T) {
+		t.Parallel()
+
+		if v := metricValueWithLabels(t, "fluo_reboot_duration_seconds", labels) - initialObservations; v != 1 {
+			t.Fatalf("Expected reboot duration to be observed exactly once, got %v", v)
 		}
 	})
 }
 
-// Test opposite conditions starting from base to make sure all cases are covered.
-//
-//nolint:funlen,cyclop // Just many test cases.
-func Test_Operator_counts_nodes_as_just_rebooted_which(t *testing.T) {
+//nolint:funlen // Just many subtests.
+func Test_Operator_handles_nodes_stuck_rebooting_by(t *testing.T) {
 	t.Parallel()
 
-	ctx := contextWithDeadline(t)
+	rebootTimeout := time.Hour
 
-	cases := map[string]struct {
-		mutateF            func(*corev1.Node)
-		expectJustRebooted bool
-	}{
-		"has_all_conditions_met": {
-			expectJustRebooted: true,
-		},
-		// Nodes which we allowed to reboot.
-		"has_reboot_approved": {
-			mutateF: func(updatedNode *corev1.Node) {
-				updatedNode.Annotations[constants.AnnotationOkToReboot] = constants.False
-			},
-		},
-		// Nodes which already rebooted.
-		"does_not_need_a_reboot": {
-			mutateF: func(updatedNode *corev1.Node) {
-				updatedNode.Annotations[constants.AnnotationRebootNeeded] = constants.True
-			},
-		},
-		// Nodes which already reported that they are back from rebooting.
-		"which_finished_the_reboot": {
-			mutateF: func(updatedNode *corev1.Node) {
-				updatedNode.Annotations[constants.AnnotationRebootInProgress] = constants.True
-			},
-		},
-		// Nodes which do not have hooks scheduled yet.
-		"has_no_after_reboot_label": {
-			mutateF: func(updatedNode *corev1.Node) {
-				updatedNode.Labels[constants.LabelAfterReboot] = constants.True
-			},
-		},
+	stuckNode := func() *corev1.Node {
+		n := rebootingNode()
+		n.Name = "stuck-rebooting"
+		n.Annotations[constants.AnnotationRebootApprovedTime] = strconv.FormatInt(time.Now().Add(-2*rebootTimeout).Unix(), 10)
+
+		return n
 	}
 
-	for name, testCase := range cases {
-		testCase := testCase
+	t.Run("recording_reboot_approval_time", func(t *testing.T) {
+		t.Parallel()
 
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
+		readyToRebootNode := readyToRebootNode()
 
-			justRebootedNode := justRebootedNode()
-			if testCase.mutateF != nil {
-				testCase.mutateF(justRebootedNode)
-			}
+		config, fakeClient := testConfig(readyToRebootNode)
+		config.BeforeRebootAnnotations = []string{testBeforeRebootAnnotation}
 
-			config, fakeClient := testConfig(justRebootedNode)
-			config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+		ctx := contextWithDeadline(t)
 
-			<-process(ctx, t, config, fakeClient)
+		<-process(ctx, t, config, fakeClient)
 
-			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+		updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), readyToRebootNode.Name)
 
-			v, ok := updatedNode.Labels[constants.LabelAfterReboot]
-			if testCase.expectJustRebooted {
-				if !ok || v != constants.True {
-					t.Errorf("Expected after reboot label, got %v", updatedNode.Labels)
-				}
+		value, ok := updatedNode.Annotations[constants.AnnotationRebootApprovedTime]
+		if !ok {
+			t.Fatalf("Expected annotation %q to be set, got %v", constants.AnnotationRebootApprovedTime, updatedNode.Annotations)
+		}
 
-				if _, ok := updatedNode.Annotations[testAfterRebootAnnotation]; ok {
-					t.Errorf("Expected annotation %q to be removed", testAfterRebootAnnotation)
-				}
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			t.Fatalf("Expected annotation %q to be a UNIX timestamp, got %q", constants.AnnotationRebootApprovedTime, value)
+		}
+	})
 
-				if _, ok := updatedNode.Annotations[testAnotherAfterRebootAnnotation]; ok {
-					t.Errorf("Expected annotation %q to be removed", testAnotherAfterRebootAnnotation)
-				}
+	t.Run("emitting_event_and_recording_metric", func(t *testing.T) {
+		t.Parallel()
+
+		stuckNode := stuckNode()
+
+		config, fakeClient := testConfig(stuckNode)
+		config.RebootTimeout = rebootTimeout
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if v := metricValue(t, "fluo_stuck_reboots"); v < 1 {
+			t.Fatalf("Expected stuck reboot to be recorded in metrics, got %v", v)
+		}
+
+		// Events are sent asynchronously, so wait for them to arrive.
+		err := wait.PollImmediateUntilWithContext(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) {
+			events, err := config.Client.CoreV1().Events(metav1.NamespaceDefault).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return false, fmt.Errorf("listing events: %w", err)
 			}
 
-			if !testCase.expectJustRebooted {
-				v, ok := updatedNode.Annotations[testAfterRebootAnnotation]
-				if !ok || v != constants.False {
-					t.Fatalf("Expected annotation %q to be left untouched", testAfterRebootAnnotation)
+			for _, event := range events.Items {
+				if event.Reason == "RebootTimeoutExceeded" && event.InvolvedObject.Name == stuckNode.Name {
+					return true, nil
 				}
 			}
+
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("Waiting for reboot timeout event: %v", err)
+		}
+	})
+
+	for name, testCase := range map[string]struct {
+		skipStuckNodes bool
+		expectedLabel  bool
+	}{
+		"waiting_for_them_to_finish_rebooting_by_default": {},
+		"scheduling_reboot_of_other_nodes_when_configured": {
+			skipStuckNodes: true,
+			expectedLabel:  true,
+		},
+	} {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rebootableNode := rebootableNode()
+
+			config, fakeClient := testConfig(stuckNode(), rebootableNode)
+			config.RebootTimeout = rebootTimeout
+			config.SkipStuckNodes = testCase.skipStuckNodes
+
+			ctx := contextWithDeadline(t)
+
+			<-process(ctx, t, config, fakeClient)
+
+			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), rebootableNode.Name)
+			if _, ok := updatedNode.Labels[constants.LabelBeforeReboot]; ok != testCase.expectedLabel {
+				t.Fatalf("Expected node %q to be scheduled for reboot: %v, got labels %v",
+					rebootableNode.Name, testCase.expectedLabel, updatedNode.Labels)
+			}
 		})
 	}
 }
 
-// To schedule post-reboot hooks.
-func Test_Operator_confirms_reboot_process_by(t *testing.T) {
+//nolint:funlen // Just many subtests.
+func Test_Operator_skips_scheduling_reboot_process_of_nodes_running_pods_with_blocking_pod_annotation(t *testing.T) {
 	t.Parallel()
 
-	justRebootedNode := justRebootedNode()
-	justRebootedNode.Annotations[testAfterRebootAnnotation] = constants.True
-	justRebootedNode.Annotations[testAnotherAfterRebootAnnotation] = constants.True
+	const blockingPodAnnotation = "flatcar.io/do-not-evict"
 
-	config, fakeClient := testConfig(justRebootedNode)
-	config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+	blockedNode := rebootableNode()
+	blockedNode.Name = "blocked"
 
-	ctx := contextWithDeadline(t)
+	podOnNode := func(nodeName string, annotations map[string]string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        nodeName + "-pod",
+				Namespace:   testNamespace,
+				Annotations: annotations,
+			},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+			},
+			Status: corev1.PodStatus{
+				Phase: phase,
+			},
+		}
+	}
 
-	<-process(ctx, t, config, fakeClient)
+	t.Run("and_schedules_other_nodes_instead", func(t *testing.T) {
+		t.Parallel()
 
-	updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), justRebootedNode.Name)
+		otherNode := rebootableNode()
 
-	// To ensure all annotations are freshly set.
-	t.Run("removing_all_after_reboot_annotations", func(t *testing.T) {
-		t.Parallel()
+		blockingPod := podOnNode(blockedNode.Name, map[string]string{blockingPodAnnotation: constants.True},
+			corev1.PodRunning)
+		otherPod := podOnNode(otherNode.Name, map[string]string{blockingPodAnnotation: constants.False},
+			corev1.PodRunning)
 
-		if _, ok := updatedNode.Annotations[testAfterRebootAnnotation]; ok {
-			t.Fatalf("Unexpected annotation %q found", testAfterRebootAnnotation)
+		config, fakeClient := testConfig(blockedNode, otherNode)
+		config.BlockingPodAnnotation = blockingPodAnnotation
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(blockingPod, otherPod))
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		nodeClient := config.Client.CoreV1().Nodes()
+
+		if _, ok := node(ctx, t, nodeClient, blockedNode.Name).Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot while running blocking pod", blockedNode.Name)
 		}
 
-		if _, ok := updatedNode.Annotations[testAnotherAfterRebootAnnotation]; ok {
-			t.Fatalf("Unexpected annotation %q found", testAnotherAfterRebootAnnotation)
+		if _, ok := node(ctx, t, nodeClient, otherNode.Name).Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", otherNode.Name)
 		}
 	})
 
-	// To schedule after-reboot hook pods.
-	t.Run("setting_after_reboot_label_to_true", func(t *testing.T) {
+	t.Run("unless_blocking_pod_has_completed", func(t *testing.T) {
 		t.Parallel()
 
-		afterReboot, ok := updatedNode.Labels[constants.LabelAfterReboot]
-		if !ok {
-			t.Fatalf("Expected label %q not found, not %v", constants.LabelAfterReboot, updatedNode.Labels)
+		completedPod := podOnNode(blockedNode.Name, map[string]string{blockingPodAnnotation: constants.True},
+			corev1.PodSucceeded)
+
+		config, fakeClient := testConfig(blockedNode)
+		config.BlockingPodAnnotation = blockingPodAnnotation
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(completedPod))
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), blockedNode.Name).Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", blockedNode.Name)
 		}
+	})
 
-		if afterReboot != constants.True {
-			t.Fatalf("Expected label value %q, got %q", constants.True, afterReboot)
+	t.Run("when_listing_pods_fails", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(blockedNode)
+		config.BlockingPodAnnotation = blockingPodAnnotation
+
+		fakeClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("test error")
+		})
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), blockedNode.Name).Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot when checking for blocking pods failed", blockedNode.Name)
 		}
 	})
 }
 
-// Test opposite conditions starting from base to make sure all cases are covered.
-func Test_Operator_counts_nodes_as_which_finished_rebooting_which_has(t *testing.T) {
+//nolint:funlen // Just many subtests.
+func Test_Operator_schedules_reboot_process_of_nodes_running_pods_from_protected_namespaces(t *testing.T) {
 	t.Parallel()
 
-	ctx := contextWithDeadline(t)
+	const protectedNamespace = "payments"
+
+	podOnNode := func(nodeName, namespace string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nodeName + "-pod",
+				Namespace: namespace,
+			},
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+			},
+		}
+	}
+
+	daemonSetPod := podOnNode("a", protectedNamespace)
+	daemonSetPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "logs"}}
+
+	completedPod := podOnNode("a", protectedNamespace)
+	completedPod.Status.Phase = corev1.PodSucceeded
 
 	cases := map[string]struct {
-		mutateF                 func(*corev1.Node)
-		expectFinishedRebooting bool
+		nodes         []string
+		pods          []*corev1.Pod
+		block         bool
+		expectedNodes []string
 	}{
-		"all_conditions_met": {
-			expectFinishedRebooting: true,
+		"after_other_nodes": {
+			nodes:         []string{"a", "b"},
+			pods:          []*corev1.Pod{podOnNode("a", protectedNamespace), podOnNode("b", testNamespace)},
+			expectedNodes: []string{"b"},
 		},
-		// Only consider nodes which runs the after-reboot hooks.
-		"after_reboot_label_set": {
-			mutateF: func(updatedNode *corev1.Node) {
-				delete(updatedNode.Labels, constants.LabelAfterReboot)
-			},
+		"when_only_nodes_running_them_require_reboot": {
+			nodes:         []string{"a"},
+			pods:          []*corev1.Pod{podOnNode("a", protectedNamespace)},
+			expectedNodes: []string{"a"},
 		},
-		// To verify all hooks executed successfully.
-		"all_after_reboot_annotations_set_to_true": {
-			mutateF: func(updatedNode *corev1.Node) {
-				updatedNode.Annotations[testAfterRebootAnnotation] = constants.False
-			},
+		"never_when_blocking_on_protected_namespaces_is_configured": {
+			nodes: []string{"a"},
+			pods:  []*corev1.Pod{podOnNode("a", protectedNamespace)},
+			block: true,
+		},
+		"like_other_nodes_when_pod_from_protected_namespace_is_owned_by_DaemonSet": {
+			nodes:         []string{"a", "b"},
+			pods:          []*corev1.Pod{daemonSetPod},
+			expectedNodes: []string{"a"},
+		},
+		"like_other_nodes_when_pod_from_protected_namespace_has_completed": {
+			nodes:         []string{"a", "b"},
+			pods:          []*corev1.Pod{completedPod},
+			block:         true,
+			expectedNodes: []string{"a"},
 		},
 	}
 
@@ -1098,135 +7001,206 @@ func Test_Operator_counts_nodes_as_which_finished_rebooting_which_has(t *testing
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			finishedRebootingNode := finishedRebootingNode()
-			if testCase.mutateF != nil {
-				testCase.mutateF(finishedRebootingNode)
+			nodes := []runtime.Object{}
+
+			for _, name := range testCase.nodes {
+				n := rebootableNode()
+				n.Name = name
+
+				nodes = append(nodes, n)
 			}
 
-			config, fakeClient := testConfig(finishedRebootingNode)
-			config.AfterRebootAnnotations = []string{testAfterRebootAnnotation, testAnotherAfterRebootAnnotation}
+			config, fakeClient := testConfig(nodes...)
+			config.DisableLeaderElection = true
+			config.ProtectedNamespaces = []string{protectedNamespace}
+			config.BlockOnProtectedNamespaces = testCase.block
 
-			<-process(ctx, t, config, fakeClient)
+			fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(testCase.pods...))
 
-			updatedNode := node(ctx, t, config.Client.CoreV1().Nodes(), finishedRebootingNode.Name)
+			ctx := contextWithDeadline(t)
 
-			v, ok := updatedNode.Annotations[constants.AnnotationOkToReboot]
-			if !testCase.expectFinishedRebooting && ok && v != constants.True {
-				t.Fatalf("Expected after reboot label, got %v", updatedNode.Labels)
+			if err := kontrollerWithObjects(t, config).ReconcileOnce(ctx); err != nil {
+				t.Fatalf("Unexpected error reconciling: %v", err)
 			}
 
-			if testCase.expectFinishedRebooting && ok && v == constants.True {
-				t.Fatalf("Unexpected after reboot label")
+			for _, name := range testCase.nodes {
+				_, scheduled := node(ctx, t, config.Client.CoreV1().Nodes(), name).Labels[constants.LabelBeforeReboot]
+
+				expected := false
+
+				for _, expectedNode := range testCase.expectedNodes {
+					expected = expected || name == expectedNode
+				}
+
+				if scheduled != expected {
+					t.Fatalf("Expected node %q to be scheduled for reboot: %v, got: %v", name, expected, scheduled)
+				}
 			}
 		})
 	}
 }
 
-//nolint:funlen // Just many sub-tests.
-func Test_Operator_stops_current_reconciliation_when(t *testing.T) {
+//nolint:funlen // Just many subtests.
+func Test_Operator_skips_scheduling_reboot_process_of_nodes_running_pods_which_cannot_be_disrupted_when_configured(
+	t *testing.T,
+) {
 	t.Parallel()
 
-	for name, testCase := range map[string]struct {
-		node                  *corev1.Node
-		failingListCall       int
-		failingUpdateCall     int
-		expectedNodeCondition func(*corev1.Node) bool
-	}{
-		"cleaning_up_node_state_fails_because": {
-			node:              rebootCancelledNode(),
-			failingListCall:   0,
-			failingUpdateCall: 0,
-			expectedNodeCondition: func(node *corev1.Node) bool {
-				_, ok := node.Labels[constants.LabelBeforeReboot]
+	blockedNode := rebootableNode()
+	blockedNode.Name = "blocked"
 
-				return ok
+	podOnNode := func(nodeName, ownerKind string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nodeName + "-pod",
+				Namespace: testNamespace,
+				Labels:    map[string]string{"app": nodeName},
 			},
-		},
-		"evaluating_nodes_which_finished_rebooting_fails_because": {
-			node:              finishedRebootingNode(),
-			failingListCall:   1,
-			failingUpdateCall: 0,
-			expectedNodeCondition: func(node *corev1.Node) bool {
-				_, ok := node.Labels[constants.LabelAfterReboot]
-
-				return ok
+			Spec: corev1.PodSpec{
+				NodeName: nodeName,
 			},
-		},
-		"evaluating_nodes_which_just_rebooted_fails_because": {
-			node:              justRebootedNode(),
-			failingListCall:   2,
-			failingUpdateCall: 1,
-			expectedNodeCondition: func(node *corev1.Node) bool {
-				_, ok := node.Labels[constants.LabelAfterReboot]
-
-				return !ok
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
 			},
-		},
-		"evaluating_nodes_which_are_ready_to_reboot_fails_because": {
-			node:              readyToRebootNode(),
-			failingListCall:   3,
-			failingUpdateCall: 1,
-			expectedNodeCondition: func(node *corev1.Node) bool {
-				v, ok := node.Labels[constants.LabelBeforeReboot]
+		}
 
-				return ok || v != constants.True
-			},
-		},
-		"evaluating_nodes_which_needs_to_reboot_fails_because": {
-			node:              rebootableNode(),
-			failingListCall:   4,
-			failingUpdateCall: 1,
-			expectedNodeCondition: func(node *corev1.Node) bool {
-				v, ok := node.Labels[constants.LabelBeforeReboot]
+		if ownerKind != "" {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: nodeName}}
+		}
 
-				return ok || v != constants.True
+		return pod
+	}
+
+	pdb := func(app string, disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      app,
+				Namespace: testNamespace,
 			},
-		},
-	} {
-		testCase := testCase
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": app},
+				},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{
+				DisruptionsAllowed: disruptionsAllowed,
+			},
+		}
+	}
 
-		t.Run(name, func(t *testing.T) {
-			t.Parallel()
+	t.Run("and_schedules_other_nodes_instead", func(t *testing.T) {
+		t.Parallel()
 
-			for subName, subTestCase := range map[string]struct {
-				failingCall int
-				verb        string
-			}{
-				"listing_node_objects_fails": {
-					failingCall: testCase.failingListCall,
-					verb:        "list",
-				},
-				"updating_node_fails": {
-					failingCall: testCase.failingUpdateCall,
-					verb:        "update",
-				},
-			} {
-				subTestCase := subTestCase
+		otherNode := rebootableNode()
 
-				t.Run(subName, func(t *testing.T) {
-					t.Parallel()
+		config, fakeClient := testConfig(blockedNode, otherNode, pdb(blockedNode.Name, 0), pdb(otherNode.Name, 1))
+		config.CheckPodDisruptionBudgets = true
 
-					config, fakeClient := testConfig(testCase.node)
-					requestFailed, failRequest := failOnNthCall(subTestCase.failingCall, fmt.Errorf(t.Name()))
-					fakeClient.PrependReactor(subTestCase.verb, "nodes", failRequest)
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(
+			podOnNode(blockedNode.Name, ""), podOnNode(otherNode.Name, "")))
 
-					ctx, cancel := context.WithTimeout(contextWithDeadline(t), 5*time.Second)
-					t.Cleanup(cancel)
+		ctx := contextWithDeadline(t)
 
-					process(ctx, t, config, fakeClient)
+		<-process(ctx, t, config, fakeClient)
 
-					select {
-					case <-requestFailed:
-					case <-ctx.Done():
-						t.Fatalf("Timed out waiting for request to fail")
-					}
+		nodeClient := config.Client.CoreV1().Nodes()
 
-					if !testCase.expectedNodeCondition(node(ctx, t, config.Client.CoreV1().Nodes(), testCase.node.Name)) {
-						t.Fatalf("Expected condition not met")
-					}
-				})
-			}
-		})
+		if _, ok := node(ctx, t, nodeClient, blockedNode.Name).Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot while running pod which cannot be disrupted",
+				blockedNode.Name)
+		}
+
+		if _, ok := node(ctx, t, nodeClient, otherNode.Name).Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", otherNode.Name)
+		}
+	})
+
+	t.Run("unless_pod_is_owned_by_DaemonSet", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(blockedNode, pdb(blockedNode.Name, 0))
+		config.CheckPodDisruptionBudgets = true
+
+		fakeClient.PrependReactor("list", "pods", listPodsWithFieldSelector(podOnNode(blockedNode.Name, "DaemonSet")))
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), blockedNode.Name).Labels[constants.LabelBeforeReboot]; !ok {
+			t.Fatalf("Expected node %q to be scheduled for reboot", blockedNode.Name)
+		}
+	})
+
+	t.Run("when_listing_PodDisruptionBudgets_fails", func(t *testing.T) {
+		t.Parallel()
+
+		config, fakeClient := testConfig(blockedNode)
+		config.CheckPodDisruptionBudgets = true
+
+		fakeClient.PrependReactor("list", "poddisruptionbudgets",
+			func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("test error")
+			})
+
+		ctx := contextWithDeadline(t)
+
+		<-process(ctx, t, config, fakeClient)
+
+		if _, ok := node(ctx, t, config.Client.CoreV1().Nodes(), blockedNode.Name).Labels[constants.LabelBeforeReboot]; ok {
+			t.Fatalf("Unexpected node %q scheduled for reboot when checking PodDisruptionBudgets failed",
+				blockedNode.Name)
+		}
+	})
+}
+
+func Test_Operator_lists_pods_of_each_node_and_PodDisruptionBudgets_once_per_reconciliation(t *testing.T) {
+	t.Parallel()
+
+	nodes := []runtime.Object{}
+
+	for _, name := range []string{"a", "b", "c"} {
+		n := rebootableNode()
+		n.Name = name
+
+		nodes = append(nodes, n)
+	}
+
+	config, fakeClient := testConfig(nodes...)
+	config.DisableLeaderElection = true
+	config.BlockingPodAnnotation = "example.com/blocking"
+	config.CheckPodDisruptionBudgets = true
+	config.DeferNodesRunningJobs = true
+	config.ProtectedNamespaces = []string{"payments"}
+
+	ctx := contextWithDeadline(t)
+
+	if err := kontrollerWithObjects(t, config).ReconcileOnce(ctx); err != nil {
+		t.Fatalf("Unexpected error reconciling: %v", err)
+	}
+
+	podLists := 0
+	pdbLists := 0
+
+	for _, action := range fakeClient.Actions() {
+		if !action.Matches("list", action.GetResource().Resource) {
+			continue
+		}
+
+		switch action.GetResource().Resource {
+		case "pods":
+			podLists++
+		case "poddisruptionbudgets":
+			pdbLists++
+		}
+	}
+
+	if podLists != len(nodes) {
+		t.Fatalf("Expected pods to be listed once for each of %d nodes, got %d lists", len(nodes), podLists)
+	}
+
+	if pdbLists != 1 {
+		t.Fatalf("Expected PodDisruptionBudgets to be listed once, got %d lists", pdbLists)
 	}
 }
 
@@ -1279,6 +7253,15 @@ func Test_Operator_finishes_reboot_process_by(t *testing.T) {
 			t.Fatalf("Expected annotation %q value %q, got %q", constants.AnnotationOkToReboot, constants.False, okToReboot)
 		}
 	})
+
+	// So the next reboot does not get classified as approved.
+	t.Run("removing_reboot_approval_annotation", func(t *testing.T) {
+		t.Parallel()
+
+		if _, ok := updatedNode.Annotations[constants.AnnotationRebootApproved]; ok {
+			t.Fatalf("Unexpected annotation %q found", constants.AnnotationRebootApproved)
+		}
+	})
 }
 
 // Expose klog flags to be able to increase verbosity for operator logs.
@@ -1322,6 +7305,60 @@ func runOperator(_ context.Context, t *testing.T, k *operator.Kontroller, stopCh
 	}()
 }
 
+// metricValue returns sum of values of all series of given counter or gauge metric. For histograms,
+// number of observations is summed instead.
+func metricValue(t *testing.T, name string) float64 {
+	t.Helper()
+
+	return metricValueWithLabels(t, name, nil)
+}
+
+// metricValueWithLabels returns sum of values of series of given counter or gauge metric
+// having all given label values. For histograms, number of observations is summed instead.
+//
+//nolint:cyclop // Just nested iteration over gathered metrics.
+func metricValueWithLabels(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+
+	if err := metrics.Register(registry); err != nil {
+		t.Fatalf("Registering metrics: %v", err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gathering metrics: %v", err)
+	}
+
+	value := 0.0
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			matchedLabels := 0
+
+			for _, label := range metric.GetLabel() {
+				if v, ok := labels[label.GetName()]; ok && v == label.GetValue() {
+					matchedLabels++
+				}
+			}
+
+			if matchedLabels != len(labels) {
+				continue
+			}
+
+			value += metric.GetCounter().GetValue() + metric.GetGauge().GetValue() +
+				float64(metric.GetHistogram().GetSampleCount())
+		}
+	}
+
+	return value
+}
+
 func validOperatorConfig() operator.Config {
 	return operator.Config{
 		Client:    fake.NewSimpleClientset(),
@@ -1330,6 +7367,27 @@ func validOperatorConfig() operator.Config {
 	}
 }
 
+// listPodsWithFieldSelector returns a reactor listing given pods matching node name field selector,
+// as fake client ignores field selectors.
+func listPodsWithFieldSelector(allPods ...*corev1.Pod) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		actionList, ok := action.(k8stesting.ListActionImpl)
+		if !ok {
+			return true, nil, fmt.Errorf("unexpected action type, expected %T, got %T", k8stesting.ListActionImpl{}, action)
+		}
+
+		pods := []corev1.Pod{}
+
+		for _, pod := range allPods {
+			if actionList.GetListRestrictions().Fields.Matches(fields.Set{"spec.nodeName": pod.Spec.NodeName}) {
+				pods = append(pods, *pod)
+			}
+		}
+
+		return true, &corev1.PodList{Items: pods}, nil
+	}
+}
+
 func testConfig(objects ...runtime.Object) (operator.Config, *k8stesting.Fake) {
 	client := fake.NewSimpleClientset(objects...)
 
@@ -1472,6 +7530,7 @@ func justRebootedNode() *corev1.Node {
 			Labels: map[string]string{},
 			Annotations: map[string]string{
 				constants.AnnotationOkToReboot:       constants.True,
+				constants.AnnotationRebootApproved:   constants.True,
 				constants.AnnotationRebootNeeded:     constants.False,
 				constants.AnnotationRebootInProgress: constants.False,
 
@@ -1480,6 +7539,14 @@ func justRebootedNode() *corev1.Node {
 				testAnotherAfterRebootAnnotation: constants.False,
 			},
 		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{
+					Type:   corev1.NodeReady,
+					Status: corev1.ConditionTrue,
+				},
+			},
+		},
 	}
 }
 
@@ -1493,6 +7560,7 @@ func finishedRebootingNode() *corev1.Node {
 			},
 			Annotations: map[string]string{
 				constants.AnnotationOkToReboot:       constants.True,
+				constants.AnnotationRebootApproved:   constants.True,
 				testAfterRebootAnnotation:            constants.True,
 				testAnotherAfterRebootAnnotation:     constants.True,
 				constants.AnnotationRebootInProgress: constants.False,
@@ -1517,21 +7585,13 @@ func process(ctx context.Context, t *testing.T, config operator.Config, fakeClie
 
 	reconcileCycleCh := make(chan struct{}, 1)
 
-	listCallsCount := 0
+	kontroller := kontrollerWithObjects(t, config)
 
-	fakeClient.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
-		operatorListOperations := 4
-
-		if listCallsCount == operatorListOperations {
-			reconcileCycleCh <- struct{}{}
-			listCallsCount = 0
-
-			return false, nil, nil
+	operator.SetReconciledHook(kontroller, func() {
+		select {
+		case reconcileCycleCh <- struct{}{}:
+		case <-ctx.Done():
 		}
-
-		listCallsCount++
-
-		return false, nil, nil
 	})
 
 	stop := make(chan struct{})
@@ -1540,7 +7600,7 @@ func process(ctx context.Context, t *testing.T, config operator.Config, fakeClie
 		close(stop)
 	})
 
-	runOperator(ctx, t, kontrollerWithObjects(t, config), stop)
+	runOperator(ctx, t, kontroller, stop)
 
 	return reconcileCycleCh
 }
@@ -1551,7 +7611,11 @@ func nodeUpdatedNTimes(fakeClient *k8stesting.Fake, expectedUpdateCalls int) cha
 
 	fakeClient.PrependReactor("update", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
 		if updateCallsCount == expectedUpdateCalls {
-			nodeUpdatedCh <- struct{}{}
+			// Do not block the client if nobody waits for more updates.
+			select {
+			case nodeUpdatedCh <- struct{}{}:
+			default:
+			}
 
 			updateCallsCount = 0
 
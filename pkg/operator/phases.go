@@ -0,0 +1,147 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Names of reconciliation phases, which can be used to configure which phases
+// are run during reconciliation and in what order.
+const (
+	// ReconcilePhaseCleanup makes sure that all of our nodes are in a well-defined state
+	// with respect to our annotations and labels, and if they are not, then tries to fix them.
+	ReconcilePhaseCleanup = "cleanup"
+
+	// ReconcilePhaseCheckAfterReboot finds nodes with the after-reboot=true label and checks if
+	// all provided annotations are set. If all annotations are set to true then removes the
+	// after-reboot=true label and set reboot-ok=false, telling the agent that the reboot has completed.
+	ReconcilePhaseCheckAfterReboot = "check-after-reboot"
+
+	// ReconcilePhaseMarkAfterReboot finds nodes which just rebooted but haven't run after-reboot
+	// checks, removes after-reboot annotations and adds the after-reboot=true label.
+	ReconcilePhaseMarkAfterReboot = "mark-after-reboot"
+
+	// ReconcilePhaseCheckBeforeReboot finds nodes with the before-reboot=true label and checks if
+	// all provided annotations are set. If all annotations are set to true then removes the
+	// before-reboot=true label and set reboot=ok=true, telling the agent it's time to reboot.
+	ReconcilePhaseCheckBeforeReboot = "check-before-reboot"
+
+	// ReconcilePhaseMarkBeforeReboot takes some number of the rebootable nodes, removes before-reboot
+	// annotations and adds the before-reboot=true label.
+	ReconcilePhaseMarkBeforeReboot = "mark-before-reboot"
+)
+
+// DefaultReconcilePhases returns reconciliation phases run in default order.
+func DefaultReconcilePhases() []string {
+	return []string{
+		ReconcilePhaseCleanup,
+		ReconcilePhaseCheckAfterReboot,
+		ReconcilePhaseMarkAfterReboot,
+		ReconcilePhaseCheckBeforeReboot,
+		ReconcilePhaseMarkBeforeReboot,
+	}
+}
+
+type reconcilePhase struct {
+	name        string
+	description string
+	failure     string
+	run         func(context.Context) error
+	// runNode runs the phase for a single given node.
+	runNode func(context.Context, *corev1.Node) error
+}
+
+// reconcilePhases returns reconciliation phases with given names in given order.
+func (k *Kontroller) reconcilePhases(names []string) []reconcilePhase {
+	available := map[string]reconcilePhase{
+		ReconcilePhaseCleanup: {
+			description: "Cleaning up node state",
+			failure:     "cleanup node state",
+			run:         k.cleanupState,
+			runNode:     k.cleanupNode,
+		},
+		ReconcilePhaseCheckAfterReboot: {
+			description: "Checking if configured after-reboot annotations are set to true",
+			failure:     "check after reboot",
+			run:         k.checkAfterReboot,
+			runNode:     k.checkNodeAfterReboot,
+		},
+		ReconcilePhaseMarkAfterReboot: {
+			description: "Labeling rebooted nodes with after-reboot label",
+			failure:     "update recently rebooted nodes",
+			run:         k.markAfterReboot,
+			runNode:     k.markNodeAfterReboot,
+		},
+		ReconcilePhaseCheckBeforeReboot: {
+			description: "Checking if configured before-reboot annotations are set to true",
+			failure:     "check before reboot",
+			run:         k.checkBeforeReboot,
+			runNode:     k.checkNodeBeforeReboot,
+		},
+		ReconcilePhaseMarkBeforeReboot: {
+			description: "Labeling rebootable nodes with before-reboot label",
+			failure:     "update rebootable nodes",
+			run:         k.markBeforeReboot,
+			runNode:     k.markNodeBeforeReboot,
+		},
+	}
+
+	phases := make([]reconcilePhase, 0, len(names))
+
+	for _, name := range names {
+		phase := available[name]
+		phase.name = name
+
+		phases = append(phases, phase)
+	}
+
+	return phases
+}
+
+// checkReconcilePhases checks if given list of reconciliation phases is valid.
+//
+// Each phase may only be used once. Cleanup phase, if enabled, must run first to ensure
+// other phases operate on nodes in a well-defined state. Marking nodes for before or after
+// reboot checks requires the matching check phase, otherwise marked nodes would never
+// leave given phase.
+func checkReconcilePhases(names []string) error {
+	known := map[string]struct{}{}
+
+	for _, name := range DefaultReconcilePhases() {
+		known[name] = struct{}{}
+	}
+
+	enabled := map[string]struct{}{}
+
+	for i, name := range names {
+		if _, ok := known[name]; !ok {
+			return fmt.Errorf("unknown reconcile phase %q", name)
+		}
+
+		if _, ok := enabled[name]; ok {
+			return fmt.Errorf("reconcile phase %q specified more than once", name)
+		}
+
+		if name == ReconcilePhaseCleanup && i != 0 {
+			return fmt.Errorf("reconcile phase %q must run first", name)
+		}
+
+		enabled[name] = struct{}{}
+	}
+
+	for mark, check := range map[string]string{
+		ReconcilePhaseMarkBeforeReboot: ReconcilePhaseCheckBeforeReboot,
+		ReconcilePhaseMarkAfterReboot:  ReconcilePhaseCheckAfterReboot,
+	} {
+		_, markEnabled := enabled[mark]
+		_, checkEnabled := enabled[check]
+
+		if markEnabled && !checkEnabled {
+			return fmt.Errorf("reconcile phase %q requires phase %q to be enabled", mark, check)
+		}
+	}
+
+	return nil
+}
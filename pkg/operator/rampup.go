@@ -0,0 +1,53 @@
+package operator
+
+import (
+	"k8s.io/klog/v2"
+)
+
+// effectiveMaxRebootingNodes returns number of nodes which may go through the reboot process at once
+// in each reboot group. With ramp-up configured, it starts at 1 and is raised by configured step
+// every configured number of consecutive successful reboots, up to MaxRebootingNodes.
+func (k *Kontroller) effectiveMaxRebootingNodes() int {
+	if k.rampUpSuccessfulReboots == 0 {
+		return k.maxRebootingNodes
+	}
+
+	limit := 1 + k.consecutiveSuccessfulReboots/k.rampUpSuccessfulReboots*k.rampUpStep
+	if limit > k.maxRebootingNodes {
+		return k.maxRebootingNodes
+	}
+
+	return limit
+}
+
+// recordRebootSucceeded records that a node successfully finished the reboot process, raising
+// the number of nodes which may go through the reboot process at once when ramp-up is configured.
+func (k *Kontroller) recordRebootSucceeded() {
+	if k.rampUpSuccessfulReboots == 0 {
+		return
+	}
+
+	previousLimit := k.effectiveMaxRebootingNodes()
+
+	k.consecutiveSuccessfulReboots++
+
+	if limit := k.effectiveMaxRebootingNodes(); limit != previousLimit {
+		klog.InfoS("Raising maximum number of rebooting nodes after consecutive successful reboots",
+			"successfulReboots", k.consecutiveSuccessfulReboots, "maxRebootingNodes", limit)
+	}
+}
+
+// resetRampUp resets the number of nodes which may go through the reboot process at once to 1
+// when ramp-up is configured, as given node failed after reboot checks.
+func (k *Kontroller) resetRampUp(nodeName string) {
+	if k.rampUpSuccessfulReboots == 0 {
+		return
+	}
+
+	if k.effectiveMaxRebootingNodes() > 1 {
+		klog.InfoS("Resetting maximum number of rebooting nodes, as node failed after reboot checks",
+			"node", nodeName, "maxRebootingNodes", 1)
+	}
+
+	k.consecutiveSuccessfulReboots = 0
+}
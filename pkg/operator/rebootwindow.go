@@ -0,0 +1,167 @@
+package operator
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Key of the reboot window ConfigMap holding start of the reboot window, e.g. "Mon 14:00".
+	rebootWindowConfigMapStartKey = "rebootWindowStart"
+
+	// Key of the reboot window ConfigMap holding length of the reboot window, e.g. "1h".
+	rebootWindowConfigMapLengthKey = "rebootWindowLength"
+)
+
+// liveRebootWindow is a reboot window read from the reboot window ConfigMap.
+type liveRebootWindow struct {
+	// Window as read from the ConfigMap, so it is parsed again only when it changes.
+	window   RebootWindow
+	periodic *Periodic
+}
+
+// refreshRebootWindow reads the reboot window from configured reboot window ConfigMap.
+//
+// When the ConfigMap does not exist or holds no reboot window, statically configured reboot windows are used.
+// When the ConfigMap cannot be read or holds an invalid reboot window, the error is logged and the last valid
+// reboot window is kept.
+func (k *Kontroller) refreshRebootWindow(ctx context.Context) {
+	if k.rebootWindowConfigMap == "" {
+		return
+	}
+
+	configMap, err := k.kc.CoreV1().ConfigMaps(k.namespace).Get(ctx, k.rebootWindowConfigMap, metav1.GetOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		k.setLiveRebootWindow(nil)
+
+		return
+	case err != nil:
+		klog.ErrorS(err, "Failed getting reboot window ConfigMap, keeping current reboot window",
+			"configMap", k.rebootWindowConfigMap)
+
+		return
+	}
+
+	window := RebootWindow{
+		Start:  configMap.Data[rebootWindowConfigMapStartKey],
+		Length: configMap.Data[rebootWindowConfigMapLengthKey],
+	}
+
+	if window.Start == "" && window.Length == "" {
+		k.setLiveRebootWindow(nil)
+
+		return
+	}
+
+	if k.liveRebootWindow != nil && k.liveRebootWindow.window == window {
+		return
+	}
+
+	periodic, err := ParsePeriodic(window.Start, window.Length)
+	if err != nil {
+		klog.ErrorS(err, "Failed parsing reboot window from ConfigMap, keeping current reboot window",
+			"configMap", k.rebootWindowConfigMap, "start", window.Start, "length", window.Length)
+
+		return
+	}
+
+	k.setLiveRebootWindow(&liveRebootWindow{window: window, periodic: periodic})
+}
+
+// setLiveRebootWindow sets given reboot window read from the reboot window ConfigMap, logging when it changes.
+// If nil, statically configured reboot windows are used.
+func (k *Kontroller) setLiveRebootWindow(window *liveRebootWindow) {
+	switch {
+	case window == nil && k.liveRebootWindow == nil:
+		return
+	case window == nil:
+		klog.InfoS("Reboot window ConfigMap holds no reboot window, using configured reboot windows",
+			"configMap", k.rebootWindowConfigMap)
+	default:
+		klog.InfoS("Using reboot window from ConfigMap", "configMap", k.rebootWindowConfigMap,
+			"start", window.window.Start, "length", window.window.Length)
+	}
+
+	k.liveRebootWindow = window
+}
+
+// currentRebootWindows returns reboot windows currently in effect. Reboot window read from the reboot window
+// ConfigMap replaces the window configured using RebootWindowStart and RebootWindowLength.
+func (k *Kontroller) currentRebootWindows() []*Periodic {
+	if k.liveRebootWindow == nil {
+		return k.rebootWindows
+	}
+
+	return append([]*Periodic{k.liveRebootWindow.periodic}, k.additionalRebootWindows...)
+}
+
+// insideAnyNodeRebootWindow checks if reboot window of any of given nodes is open at given time.
+// Without reboot window profiles configured or without any nodes, default reboot windows are checked.
+func (k *Kontroller) insideAnyNodeRebootWindow(nodes []corev1.Node, now time.Time) bool {
+	if len(k.rebootWindowProfiles) == 0 || len(nodes) == 0 {
+		return k.insideRebootWindow(now)
+	}
+
+	for i := range nodes {
+		if k.insideNodeRebootWindow(&nodes[i], now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// insideNodeRebootWindows returns nodes from given list, which reboot window is open at given time.
+func (k *Kontroller) insideNodeRebootWindows(nodes []corev1.Node, now time.Time) []corev1.Node {
+	if len(k.rebootWindowProfiles) == 0 {
+		return nodes
+	}
+
+	result := make([]corev1.Node, 0, len(nodes))
+
+	for i := range nodes {
+		node := &nodes[i]
+
+		if !k.insideNodeRebootWindow(node, now) {
+			klog.V(4).InfoS("Outside the reboot window of node, skipping", "node", node.Name,
+				"profile", node.Annotations[k.keys.AnnotationRebootWindowProfile])
+
+			continue
+		}
+
+		result = append(result, *node)
+	}
+
+	return result
+}
+
+// insideNodeRebootWindow checks if reboot window of given node is open at given time. Nodes using
+// reboot window profile are checked against windows of the profile, while other nodes are checked
+// against default reboot windows. Nodes using unknown profile use default reboot windows as well.
+func (k *Kontroller) insideNodeRebootWindow(node *corev1.Node, now time.Time) bool {
+	profile, ok := node.Annotations[k.keys.AnnotationRebootWindowProfile]
+	if !ok {
+		return k.insideRebootWindow(now)
+	}
+
+	windows, ok := k.rebootWindowProfiles[profile]
+	if !ok {
+		klog.InfoS("Ignoring unknown reboot window profile, using default reboot windows",
+			"node", node.Name, "profile", profile)
+
+		return k.insideRebootWindow(now)
+	}
+
+	if len(windows) == 0 {
+		return true
+	}
+
+	return insideAnyWindow(windows, now)
+}
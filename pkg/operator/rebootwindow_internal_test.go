@@ -0,0 +1,127 @@
+package operator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+)
+
+func Test_Checking_node_reboot_window(t *testing.T) {
+	t.Parallel()
+
+	rebootWindow, err := ParsePeriodic("Mon 14:00", "1h")
+	if err != nil {
+		t.Fatalf("Parsing reboot window: %v", err)
+	}
+
+	profileWindow, err := ParsePeriodic("Tue 14:00", "1h")
+	if err != nil {
+		t.Fatalf("Parsing profile reboot window: %v", err)
+	}
+
+	k := &Kontroller{
+		keys:                 constants.NewKeys(""),
+		rebootWindows:        []*Periodic{rebootWindow},
+		rebootWindowProfiles: map[string][]*Periodic{"tuesday": {profileWindow}},
+	}
+
+	// Some Monday and Tuesday at 14:30 in local time, in which reboot windows are evaluated.
+	monday := time.Date(2024, time.January, 1, 14, 30, 0, 0, time.Local)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	nodeWithProfile := func(profile string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+		if profile != "" {
+			node.Annotations = map[string]string{constants.AnnotationRebootWindowProfile: profile}
+		}
+
+		return node
+	}
+
+	for name, testCase := range map[string]struct {
+		profile  string
+		now      time.Time
+		expected bool
+	}{
+		"node_without_profile_inside_default_window_at_given_time": {
+			now:      monday,
+			expected: true,
+		},
+		"node_without_profile_outside_default_window_at_given_time": {
+			now: tuesday,
+		},
+		"node_with_unknown_profile_inside_default_window_at_given_time": {
+			profile:  "unknown",
+			now:      monday,
+			expected: true,
+		},
+		"node_with_unknown_profile_outside_default_window_at_given_time": {
+			profile: "unknown",
+			now:     tuesday,
+		},
+		"node_with_profile_inside_profile_window_at_given_time": {
+			profile:  "tuesday",
+			now:      tuesday,
+			expected: true,
+		},
+		"node_with_profile_outside_profile_window_at_given_time": {
+			profile: "tuesday",
+			now:     monday,
+		},
+	} {
+		testCase := testCase
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if inside := k.insideNodeRebootWindow(nodeWithProfile(testCase.profile), testCase.now); inside != testCase.expected {
+				t.Fatalf("Expected inside reboot window to be %t, got %t", testCase.expected, inside)
+			}
+		})
+	}
+
+	t.Run("node_without_profile_is_always_inside_reboot_window_when_no_default_windows_are_configured",
+		func(t *testing.T) {
+			t.Parallel()
+
+			k := &Kontroller{
+				keys:                 constants.NewKeys(""),
+				rebootWindowProfiles: map[string][]*Periodic{"tuesday": {profileWindow}},
+			}
+
+			if !k.insideNodeRebootWindow(nodeWithProfile(""), monday) {
+				t.Fatalf("Expected node to be inside reboot window")
+			}
+		})
+}
+
+func Test_Scheduling_is_blocked_by_exclusion_window_open_at_given_time(t *testing.T) {
+	t.Parallel()
+
+	exclusionWindow, err := ParsePeriodic("Mon 14:00", "1h")
+	if err != nil {
+		t.Fatalf("Parsing exclusion window: %v", err)
+	}
+
+	k := &Kontroller{
+		keys:             constants.NewKeys(""),
+		exclusionWindows: []*Periodic{exclusionWindow},
+	}
+
+	// Some Monday at 14:30 in local time, in which exclusion windows are evaluated.
+	monday := time.Date(2024, time.January, 1, 14, 30, 0, 0, time.Local)
+
+	if err := k.schedulingBlocked(nil, monday); !errors.Is(err, ErrInsideExclusionWindow) {
+		t.Fatalf("Expected scheduling to be blocked by exclusion window, got: %v", err)
+	}
+
+	if err := k.schedulingBlocked(nil, monday.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("Expected scheduling not to be blocked outside exclusion window, got: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+// Reason of the reboot process recorded for nodes, which reboot was requested using
+// the request reboot annotation while no update was pending.
+const rebootReasonRequested = "requested"
+
+// requestReboots marks idle nodes from given list, which have reboot requested using the request
+// reboot annotation, as needing a reboot, the same way the agent does when an update is installed.
+// Given list is updated in place, so requested nodes are considered rebootable right away.
+//
+// Unlike ForceReboot, requested nodes go through the regular reboot process, so all limits
+// and windows are respected. Request annotation is removed once the reboot process finishes.
+func (k *Kontroller) requestReboots(ctx context.Context, nodelist *corev1.NodeList) error {
+	for i := range nodelist.Items {
+		node := &nodelist.Items[i]
+
+		if !k.rebootRequested(node) {
+			continue
+		}
+
+		klog.InfoS("Reboot of node requested, marking it as needing a reboot", "node", node.Name,
+			"annotation", k.keys.AnnotationRequestReboot)
+
+		updatedNode, err := k8sutil.MutateNode(ctx, k.nc, node.Name, k.markRebootNeeded)
+		if err != nil {
+			return fmt.Errorf("marking node %q as needing a reboot: %w", node.Name, err)
+		}
+
+		nodelist.Items[i] = *updatedNode
+	}
+
+	return nil
+}
+
+// rebootRequested returns true if reboot of given node has been requested and the node
+// is idle, so it can be marked as needing a reboot.
+func (k *Kontroller) rebootRequested(node *corev1.Node) bool {
+	if node.Annotations[k.keys.AnnotationRequestReboot] != constants.True {
+		return false
+	}
+
+	// Excluded nodes are managed manually and nodes being deleted will go away anyway.
+	if k.excludedByLabel(node) || node.DeletionTimestamp != nil {
+		return false
+	}
+
+	for _, annotation := range []string{
+		k.keys.AnnotationRebootNeeded,
+		k.keys.AnnotationOkToReboot,
+		k.keys.AnnotationRebootInProgress,
+	} {
+		if node.Annotations[annotation] == constants.True {
+			return false
+		}
+	}
+
+	return node.Labels[k.keys.LabelBeforeReboot] != constants.True &&
+		node.Labels[k.keys.LabelAfterReboot] != constants.True
+}
+
+// markRebootNeeded sets reboot needed annotation and label of given node.
+func (k *Kontroller) markRebootNeeded(node *corev1.Node) {
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+
+	node.Annotations[k.keys.AnnotationRebootNeeded] = constants.True
+	node.Labels[k.keys.LabelRebootNeeded] = constants.True
+}
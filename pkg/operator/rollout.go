@@ -0,0 +1,68 @@
+package operator
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// currentRolloutGroup returns nodes from given list of nodes requiring reboot which belong to the
+// current rollout group, which is the group with the lowest order among given nodes requiring reboot
+// and given nodes going through the reboot process. This way, nodes of the next group are only
+// scheduled for rebooting once all nodes of the current group finished rebooting.
+func (k *Kontroller) currentRolloutGroup(nodes, rebootingNodes []corev1.Node) []corev1.Node {
+	if k.rolloutOrderLabelKey == "" || len(nodes) == 0 {
+		return nodes
+	}
+
+	current := k.rolloutOrder(&nodes[0])
+
+	for i := range nodes {
+		if order := k.rolloutOrder(&nodes[i]); order < current {
+			current = order
+		}
+	}
+
+	for i := range rebootingNodes {
+		if order := k.rolloutOrder(&rebootingNodes[i]); order < current {
+			current = order
+		}
+	}
+
+	result := make([]corev1.Node, 0, len(nodes))
+
+	for i := range nodes {
+		node := &nodes[i]
+
+		if order := k.rolloutOrder(node); order != current {
+			klog.V(4).InfoS("Node belongs to later rollout group, skipping",
+				"node", node.Name, "rolloutOrder", order, "currentRolloutOrder", current)
+
+			continue
+		}
+
+		result = append(result, *node)
+	}
+
+	return result
+}
+
+// rolloutOrder returns rollout order of given node from configured rollout order label. Nodes without
+// the label or with invalid label value have order 0.
+func (k *Kontroller) rolloutOrder(node *corev1.Node) int {
+	value, ok := node.Labels[k.rolloutOrderLabelKey]
+	if !ok {
+		return 0
+	}
+
+	order, err := strconv.Atoi(value)
+	if err != nil {
+		klog.ErrorS(err, "Ignoring invalid label value", "node", node.Name, "label", k.rolloutOrderLabelKey,
+			"value", value)
+
+		return 0
+	}
+
+	return order
+}
@@ -0,0 +1,163 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+// NodeSelectionStrategy determines in which order nodes requiring a reboot are scheduled for rebooting.
+type NodeSelectionStrategy string
+
+const (
+	// NodeSelectionStrategyPriority schedules nodes in order of their reboot priority, if reboot priority
+	// annotation is configured, then in order of their names. This is the default strategy.
+	NodeSelectionStrategyPriority NodeSelectionStrategy = "priority"
+
+	// NodeSelectionStrategyUptime schedules nodes which are up the longest first, as they are most likely
+	// to have accumulated pending updates or leaked resources. Nodes with unknown boot time are scheduled
+	// last, in order of their names.
+	NodeSelectionStrategyUptime NodeSelectionStrategy = "uptime"
+)
+
+// checkNodeSelectionStrategy checks if given node selection strategy, if configured, is supported.
+func checkNodeSelectionStrategy(strategy NodeSelectionStrategy) error {
+	switch strategy {
+	case "", NodeSelectionStrategyPriority, NodeSelectionStrategyUptime:
+		return nil
+	default:
+		return fmt.Errorf("unsupported strategy %q, expected %q or %q",
+			strategy, NodeSelectionStrategyPriority, NodeSelectionStrategyUptime)
+	}
+}
+
+// NotReadyNodePolicy determines how nodes requiring a reboot, which are not Ready, are scheduled for rebooting.
+type NotReadyNodePolicy string
+
+const (
+	// NotReadyNodePolicySkip never schedules nodes which are not Ready for rebooting, as the reboot
+	// might make the cause of the node not being Ready harder to investigate.
+	NotReadyNodePolicySkip NotReadyNodePolicy = "skip"
+
+	// NotReadyNodePolicyLast schedules nodes which are not Ready for rebooting after Ready nodes.
+	NotReadyNodePolicyLast NotReadyNodePolicy = "last"
+
+	// NotReadyNodePolicyFirst schedules nodes which are not Ready for rebooting before Ready nodes,
+	// as rebooting them does not reduce capacity of the cluster and might bring them back.
+	NotReadyNodePolicyFirst NotReadyNodePolicy = "first"
+)
+
+// checkNotReadyNodePolicy checks if given policy for nodes which are not Ready, if configured, is supported.
+func checkNotReadyNodePolicy(policy NotReadyNodePolicy) error {
+	switch policy {
+	case "", NotReadyNodePolicySkip, NotReadyNodePolicyLast, NotReadyNodePolicyFirst:
+		return nil
+	default:
+		return fmt.Errorf("unsupported policy %q, expected %q, %q or %q",
+			policy, NotReadyNodePolicySkip, NotReadyNodePolicyLast, NotReadyNodePolicyFirst)
+	}
+}
+
+// applyNotReadyNodePolicy partitions given list of nodes into Ready nodes and nodes which are not Ready,
+// keeping their order, and returns them ordered or filtered according to configured policy for nodes
+// which are not Ready. If no policy is configured, given list is returned as is.
+func (k *Kontroller) applyNotReadyNodePolicy(nodes []corev1.Node) []corev1.Node {
+	if k.notReadyNodePolicy == "" {
+		return nodes
+	}
+
+	ready := make([]corev1.Node, 0, len(nodes))
+	notReady := []corev1.Node{}
+
+	for _, node := range nodes {
+		node := node
+
+		if k8sutil.NodeReady(&node) {
+			ready = append(ready, node)
+
+			continue
+		}
+
+		notReady = append(notReady, node)
+	}
+
+	switch k.notReadyNodePolicy {
+	case NotReadyNodePolicySkip:
+		for _, node := range notReady {
+			klog.V(4).InfoS("Node is not Ready, skipping", "node", node.Name, "policy", k.notReadyNodePolicy)
+		}
+
+		return ready
+	case NotReadyNodePolicyFirst:
+		return append(notReady, ready...)
+	default:
+		return append(ready, notReady...)
+	}
+}
+
+// sortForRebooting sorts given list of nodes in order in which they should be scheduled for rebooting,
+// according to configured node selection strategy.
+func (k *Kontroller) sortForRebooting(nodes []corev1.Node) {
+	if k.nodeSelectionStrategy == NodeSelectionStrategyUptime {
+		k.sortByBootTime(nodes)
+
+		return
+	}
+
+	k.sortByRebootPriority(nodes)
+}
+
+// sortByBootTime sorts given list of nodes by their boot time, so nodes which booted earlier come first.
+// Nodes with unknown boot time come last.
+func (k *Kontroller) sortByBootTime(nodes []corev1.Node) {
+	bootTimes := make(map[string]time.Time, len(nodes))
+
+	for _, node := range nodes {
+		node := node
+
+		if bootTime, ok := k.bootTime(&node); ok {
+			bootTimes[node.Name] = bootTime
+		}
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		bootTimeI, knownI := bootTimes[nodes[i].Name]
+		bootTimeJ, knownJ := bootTimes[nodes[j].Name]
+
+		if knownI != knownJ {
+			return knownI
+		}
+
+		return bootTimeI.Before(bootTimeJ)
+	})
+}
+
+// bootTime returns time when given node booted. Time is read from configured boot time annotation
+// holding a UNIX timestamp. If the annotation is not set, time when the node last became Ready is
+// used as an approximation, as kubelet reports Ready shortly after boot.
+func (k *Kontroller) bootTime(node *corev1.Node) (time.Time, bool) {
+	value, ok := node.Annotations[k.bootTimeAnnotation]
+	if !ok {
+		readyCondition := nodeReadyCondition(node)
+		if readyCondition == nil || readyCondition.LastTransitionTime.IsZero() {
+			return time.Time{}, false
+		}
+
+		return readyCondition.LastTransitionTime.Time, true
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.bootTimeAnnotation, value)
+
+		return time.Time{}, false
+	}
+
+	return time.Unix(timestamp, 0), true
+}
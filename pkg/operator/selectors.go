@@ -0,0 +1,129 @@
+package operator
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/fields"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+// nodeSelectors is a set of selectors used to classify nodes based on their annotations.
+type nodeSelectors struct {
+	// Keys of annotations the selectors are built for.
+	keys constants.Keys
+
+	rebootable     fields.Selector
+	stillRebooting fields.Selector
+	justRebooted   fields.Selector
+}
+
+// newNodeSelectors returns selectors used by the operator configured with given keys.
+func newNodeSelectors(keys constants.Keys) nodeSelectors {
+	phaseSelectors := k8sutil.NewPhaseSelectors(keys)
+
+	return nodeSelectors{
+		keys:           keys,
+		rebootable:     phaseSelectors.Rebootable,
+		stillRebooting: phaseSelectors.StillRebooting,
+		justRebooted:   phaseSelectors.JustRebooted,
+	}
+}
+
+// defaultNodeSelectors returns selectors used by the operator configured with default keys.
+func defaultNodeSelectors() nodeSelectors {
+	return newNodeSelectors(constants.NewKeys(""))
+}
+
+// nodeStateClassification describes a representative set of node annotations and
+// which selectors are expected to match it.
+type nodeStateClassification struct {
+	name           string
+	annotations    map[string]string
+	rebootable     bool
+	stillRebooting bool
+	justRebooted   bool
+}
+
+func nodeStateClassifications(keys constants.Keys) []nodeStateClassification {
+	return []nodeStateClassification{
+		{
+			name: "idle",
+			annotations: map[string]string{
+				keys.AnnotationOkToReboot:       constants.False,
+				keys.AnnotationRebootNeeded:     constants.False,
+				keys.AnnotationRebootInProgress: constants.False,
+			},
+		},
+		{
+			name: "reboot needed",
+			annotations: map[string]string{
+				keys.AnnotationOkToReboot:       constants.False,
+				keys.AnnotationRebootNeeded:     constants.True,
+				keys.AnnotationRebootInProgress: constants.False,
+			},
+			rebootable: true,
+		},
+		{
+			name: "reboot needed but paused",
+			annotations: map[string]string{
+				keys.AnnotationOkToReboot:       constants.False,
+				keys.AnnotationRebootNeeded:     constants.True,
+				keys.AnnotationRebootInProgress: constants.False,
+				keys.AnnotationRebootPaused:     constants.True,
+			},
+		},
+		{
+			name: "reboot approved",
+			annotations: map[string]string{
+				keys.AnnotationOkToReboot:       constants.True,
+				keys.AnnotationRebootNeeded:     constants.True,
+				keys.AnnotationRebootInProgress: constants.False,
+			},
+			stillRebooting: true,
+		},
+		{
+			name: "reboot in progress",
+			annotations: map[string]string{
+				keys.AnnotationOkToReboot:       constants.True,
+				keys.AnnotationRebootNeeded:     constants.True,
+				keys.AnnotationRebootInProgress: constants.True,
+			},
+			stillRebooting: true,
+		},
+		{
+			name: "just rebooted",
+			annotations: map[string]string{
+				keys.AnnotationOkToReboot:       constants.True,
+				keys.AnnotationRebootNeeded:     constants.False,
+				keys.AnnotationRebootInProgress: constants.False,
+			},
+			justRebooted: true,
+		},
+	}
+}
+
+// check verifies that selectors classify representative node states as intended, so inconsistent
+// selectors, which could silently prevent any node from being rebooted, are detected early.
+func (s nodeSelectors) check() error {
+	for _, state := range nodeStateClassifications(s.keys) {
+		annotations := fields.Set(state.annotations)
+
+		for name, match := range map[string]struct {
+			selector fields.Selector
+			expected bool
+		}{
+			"rebootable":      {selector: s.rebootable, expected: state.rebootable},
+			"still rebooting": {selector: s.stillRebooting, expected: state.stillRebooting},
+			"just rebooted":   {selector: s.justRebooted, expected: state.justRebooted},
+		} {
+			if matches := match.selector.Matches(annotations); matches != match.expected {
+				return fmt.Errorf("node in state %q is expected to match %q selector %q: %t, got %t",
+					state.name, name, match.selector, match.expected, matches)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+func Test_Checking_node_selectors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("succeeds_for_default_selectors", func(t *testing.T) {
+		t.Parallel()
+
+		if err := defaultNodeSelectors().check(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("succeeds_for_selectors_built_with_custom_prefix", func(t *testing.T) {
+		t.Parallel()
+
+		if err := newNodeSelectors(constants.NewKeys("example.com/")).check(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("fails_when", func(t *testing.T) {
+		t.Parallel()
+
+		for name, mutateF := range map[string]func(*nodeSelectors){
+			"rebootable_selector_matches_nodes_approved_for_reboot": func(s *nodeSelectors) {
+				s.rebootable = fields.ParseSelectorOrDie(constants.AnnotationRebootNeeded + "==" + constants.True +
+					"," + constants.AnnotationRebootPaused + "!=" + constants.True)
+			},
+			"just_rebooted_selector_never_matches": func(s *nodeSelectors) {
+				s.justRebooted = fields.Set(map[string]string{
+					constants.AnnotationOkToReboot:   constants.True,
+					constants.AnnotationRebootNeeded: constants.True,
+					constants.Prefix + "unknown":     constants.True,
+				}).AsSelector()
+			},
+			"still_rebooting_selector_matches_just_rebooted_nodes": func(s *nodeSelectors) {
+				s.stillRebooting = fields.Set(map[string]string{
+					constants.AnnotationOkToReboot: constants.True,
+				}).AsSelector()
+			},
+		} {
+			mutateF := mutateF
+
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				selectors := defaultNodeSelectors()
+				mutateF(&selectors)
+
+				if err := selectors.check(); err == nil {
+					t.Fatalf("Expected error")
+				}
+			})
+		}
+	})
+}
+
+//nolint:funlen // Just many selectors to compare.
+func Test_Operator_selectors_built_for_default_configuration_match_package_level_selectors(t *testing.T) {
+	t.Parallel()
+
+	k, err := New(Config{
+		Client:                fake.NewSimpleClientset(),
+		Namespace:             "default",
+		DisableLeaderElection: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating operator: %v", err)
+	}
+
+	// Selectors and requirements as they were defined at package level before being built per operator.
+	for name, selectors := range map[string]struct {
+		expected fmt.Stringer
+		got      fmt.Stringer
+	}{
+		"just_rebooted": {
+			expected: fields.Set(map[string]string{
+				constants.AnnotationOkToReboot:       constants.True,
+				constants.AnnotationRebootNeeded:     constants.False,
+				constants.AnnotationRebootInProgress: constants.False,
+			}).AsSelector(),
+			got: k.selectors.JustRebooted,
+		},
+		"rebootable": {
+			expected: fields.ParseSelectorOrDie(constants.AnnotationRebootNeeded + "==" + constants.True +
+				"," + constants.AnnotationRebootPaused + "!=" + constants.True +
+				"," + constants.AnnotationOkToReboot + "!=" + constants.True +
+				"," + constants.AnnotationRebootInProgress + "!=" + constants.True),
+			got: k.selectors.Rebootable,
+		},
+		"still_rebooting": {
+			expected: fields.Set(map[string]string{
+				constants.AnnotationOkToReboot:   constants.True,
+				constants.AnnotationRebootNeeded: constants.True,
+			}).AsSelector(),
+			got: k.selectors.StillRebooting,
+		},
+		"before_reboot": {
+			expected: k8sutil.NewRequirementOrDie(constants.LabelBeforeReboot, selection.In, []string{constants.True}),
+			got:      k.selectors.BeforeReboot,
+		},
+		"after_reboot": {
+			expected: k8sutil.NewRequirementOrDie(constants.LabelAfterReboot, selection.In, []string{constants.True}),
+			got:      k.selectors.AfterReboot,
+		},
+		"not_before_reboot": {
+			expected: k8sutil.NewRequirementOrDie(constants.LabelBeforeReboot, selection.NotIn, []string{constants.True}),
+			got:      k.notBeforeRebootReq,
+		},
+		"not_after_reboot": {
+			expected: k8sutil.NewRequirementOrDie(constants.LabelAfterReboot, selection.NotIn, []string{constants.True}),
+			got:      k.notAfterRebootReq,
+		},
+	} {
+		selectors := selectors
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			// Order of terms of selectors built from sets is not stable.
+			if expected, got := selectorTerms(selectors.expected), selectorTerms(selectors.got); expected != got {
+				t.Fatalf("Expected selector %q, got %q", expected, got)
+			}
+		})
+	}
+}
+
+func selectorTerms(selector fmt.Stringer) string {
+	terms := strings.Split(selector.String(), ",")
+	sort.Strings(terms)
+
+	return strings.Join(terms, ",")
+}
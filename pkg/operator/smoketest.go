@@ -0,0 +1,156 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Time within which smoke test Job must succeed, if not configured otherwise.
+	defaultAfterRebootSmokeTestTimeout = 10 * time.Minute
+
+	// Prefix of names of smoke test Jobs, followed by a hash of the node name, as node names
+	// may be longer than names of Jobs allow.
+	smokeTestJobNamePrefix = "fluo-smoke-test-"
+
+	eventReasonAfterRebootSmokeTestFailed = "AfterRebootSmokeTestFailed"
+)
+
+// smokeTestCheck is an after reboot check which passes once a Job created from configured smoke test
+// pod template and pinned to the checked node succeeds. The Job is created on the first call and
+// deleted once the reboot process of the node finishes.
+//
+// If the Job fails or does not succeed within configured smoke test timeout, the node is labeled
+// as failed to reboot right away, as retrying is unlikely to help with e.g. a broken kernel module.
+func (k *Kontroller) smokeTestCheck(ctx context.Context, node *corev1.Node) (bool, error) {
+	jobs := k.kc.BatchV1().Jobs(k.namespace)
+
+	job, err := jobs.Get(ctx, smokeTestJobName(node.Name), metav1.GetOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		if _, err := jobs.Create(ctx, k.smokeTestJob(node.Name), metav1.CreateOptions{}); err != nil {
+			return false, fmt.Errorf("creating smoke test Job: %w", err)
+		}
+
+		klog.InfoS("Created smoke test Job", "node", node.Name, "phase", phaseAfterReboot,
+			"job", klog.KRef(k.namespace, smokeTestJobName(node.Name)))
+
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("getting smoke test Job: %w", err)
+	}
+
+	// Job left over from previous reboot, e.g. when deleting it failed, must not be reused.
+	if startedAt, ok := k.rebootStartedTime(node); ok && job.CreationTimestamp.Time.Before(startedAt) {
+		klog.InfoS("Removing smoke test Job left over from previous reboot", "node", node.Name, "job", klog.KObj(job))
+
+		return false, k.deleteSmokeTestJob(ctx, node.Name)
+	}
+
+	if jobConditionTrue(job, batchv1.JobComplete) {
+		return true, nil
+	}
+
+	if !jobConditionTrue(job, batchv1.JobFailed) {
+		klog.V(4).InfoS("Smoke test Job did not complete yet", "node", node.Name, "job", klog.KObj(job))
+
+		return false, nil
+	}
+
+	klog.InfoS("Smoke test Job failed, giving up", "node", node.Name, "phase", phaseAfterReboot,
+		"job", klog.KObj(job), "label", k.keys.LabelRebootFailed)
+
+	k.eventRecorder.Eventf(node, corev1.EventTypeWarning, eventReasonAfterRebootSmokeTestFailed,
+		"Smoke test Job %s/%s of node %q failed", job.Namespace, job.Name, node.Name)
+
+	if err := k.failAfterRebootChecks(ctx, node, k.afterRebootMaxAttempts, time.Now()); err != nil {
+		return false, fmt.Errorf("recording failed smoke test: %w", err)
+	}
+
+	// Failed Job is removed, so the test runs again when the node gets retried.
+	if err := k.deleteSmokeTestJob(ctx, node.Name); err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// smokeTestJob returns smoke test Job for node with given name. Pods of the Job are never retried and
+// the Job fails once configured smoke test timeout passes.
+func (k *Kontroller) smokeTestJob(nodeName string) *batchv1.Job {
+	template := k.smokeTestPodTemplate.DeepCopy()
+
+	// Bypass the scheduler, so the pod runs on the tested node even if it is cordoned or tainted.
+	template.Spec.NodeName = nodeName
+
+	if template.Spec.RestartPolicy == "" {
+		template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+
+	backoffLimit := int32(0)
+	activeDeadlineSeconds := int64(k.smokeTestTimeout.Seconds())
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      smokeTestJobName(nodeName),
+			Namespace: k.namespace,
+			Annotations: map[string]string{
+				k.keys.AnnotationSmokeTestNode: nodeName,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &activeDeadlineSeconds,
+			Template:              *template,
+		},
+	}
+}
+
+// deleteSmokeTestJob deletes smoke test Job of node with given name together with its pods, if smoke
+// test is configured. Missing Job is not an error.
+func (k *Kontroller) deleteSmokeTestJob(ctx context.Context, nodeName string) error {
+	if k.smokeTestPodTemplate == nil {
+		return nil
+	}
+
+	propagationPolicy := metav1.DeletePropagationBackground
+
+	err := k.kc.BatchV1().Jobs(k.namespace).Delete(ctx, smokeTestJobName(nodeName), metav1.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("deleting smoke test Job of node %q: %w", nodeName, err)
+	}
+
+	return nil
+}
+
+// smokeTestJobName returns name of smoke test Job for node with given name.
+func smokeTestJobName(nodeName string) string {
+	hash := fnv.New32a()
+
+	// Writing to hash never fails.
+	_, _ = hash.Write([]byte(nodeName))
+
+	return fmt.Sprintf("%s%08x", smokeTestJobNamePrefix, hash.Sum32())
+}
+
+// jobConditionTrue returns true if given Job has condition of given type with status true.
+func jobConditionTrue(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+// stabilized returns true when given rebooted node has been continuously Ready for at least configured
+// post-reboot stabilization period.
+//
+// Time when the node was first observed Ready is recorded in an annotation on the node. Recorded time
+// is reset when the node is observed not Ready or when its Ready condition changed since then.
+func (k *Kontroller) stabilized(ctx context.Context, node *corev1.Node, now time.Time) (bool, error) {
+	if k.stabilizationPeriod == 0 {
+		return true, nil
+	}
+
+	readyConditionSince, ready := k8sutil.NodeReadySince(node)
+	if !ready {
+		klog.V(4).InfoS("Node is not ready yet, waiting for it to stabilize", "node", node.Name)
+
+		return false, k.updateReadySinceTime(ctx, node, nil)
+	}
+
+	readySince, ok := k.readySinceTime(node)
+	if !ok || readySince.Before(readyConditionSince) {
+		klog.V(4).InfoS("Node observed ready, waiting for it to stabilize",
+			"node", node.Name, "stabilizationPeriod", k.stabilizationPeriod)
+
+		return false, k.updateReadySinceTime(ctx, node, &now)
+	}
+
+	stabilizedAt := readySince.Add(k.stabilizationPeriod)
+	if now.Before(stabilizedAt) {
+		klog.V(4).InfoS("Node is not stabilized yet", "node", node.Name, "stabilizedAt", stabilizedAt)
+
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// updateReadySinceTime sets annotation holding time when given node was first observed Ready
+// to given time. If given time is nil, annotation is removed.
+func (k *Kontroller) updateReadySinceTime(ctx context.Context, node *corev1.Node, readySince *time.Time) error {
+	if _, ok := node.Annotations[k.keys.AnnotationReadySinceTime]; !ok && readySince == nil {
+		return nil
+	}
+
+	err := k8sutil.UpdateNodeRetry(ctx, k.nc, node.Name, func(node *corev1.Node) {
+		if readySince == nil {
+			delete(node.Annotations, k.keys.AnnotationReadySinceTime)
+
+			return
+		}
+
+		node.Annotations[k.keys.AnnotationReadySinceTime] = strconv.FormatInt(readySince.Unix(), 10)
+	})
+	if err != nil {
+		return fmt.Errorf("updating annotation %q: %w", k.keys.AnnotationReadySinceTime, err)
+	}
+
+	return nil
+}
+
+// readySinceTime returns time recorded in the ready since annotation of given node. If annotation
+// is missing or has invalid value, false is returned.
+func (k *Kontroller) readySinceTime(node *corev1.Node) (time.Time, bool) {
+	value, ok := node.Annotations[k.keys.AnnotationReadySinceTime]
+	if !ok {
+		return time.Time{}, false
+	}
+
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logInvalidAnnotation(err, node, k.keys.AnnotationReadySinceTime, value)
+
+		return time.Time{}, false
+	}
+
+	return time.Unix(timestamp, 0), true
+}
+
+// nodeReadyCondition returns Ready condition of given node or nil, if node does not report it.
+func nodeReadyCondition(node *corev1.Node) *corev1.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == corev1.NodeReady {
+			return &node.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/constants"
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/k8sutil"
+)
+
+// NodeRebootState describes in which state of the reboot process the node is.
+type NodeRebootState string
+
+// States of the reboot process reported in node status.
+const (
+	// NodeRebootStateIdle is used for nodes which do not need a reboot.
+	NodeRebootStateIdle NodeRebootState = "idle"
+	// NodeRebootStateRebootNeeded is used for nodes which want to reboot, but are not scheduled for rebooting yet.
+	NodeRebootStateRebootNeeded NodeRebootState = "reboot-needed"
+	// NodeRebootStateBeforeReboot is used for nodes running before reboot checks.
+	NodeRebootStateBeforeReboot NodeRebootState = phaseBeforeReboot
+	// NodeRebootStateRebooting is used for nodes approved for rebooting, which did not finish rebooting yet.
+	NodeRebootStateRebooting NodeRebootState = phaseRebooting
+	// NodeRebootStateAfterReboot is used for nodes running after reboot checks.
+	NodeRebootStateAfterReboot NodeRebootState = phaseAfterReboot
+	// NodeRebootStateRebootFailed is used for nodes which did not pass after reboot checks within configured
+	// number of attempts.
+	NodeRebootStateRebootFailed NodeRebootState = "reboot-failed"
+)
+
+// NodeRebootStatus describes the state of the reboot process of a single node.
+type NodeRebootStatus struct {
+	Name  string          `json:"name"`
+	State NodeRebootState `json:"state"`
+	// Configured before or after reboot annotations which are not set to their expected values yet,
+	// for nodes running before or after reboot checks respectively. Empty for nodes in other states.
+	MissingAnnotations []string `json:"missingAnnotations,omitempty"`
+	// Reason why node requiring a reboot is not eligible to be scheduled for rebooting, as reported by
+	// node eligibility predicates. Empty for eligible nodes and nodes in other states.
+	IneligibleReason string `json:"ineligibleReason,omitempty"`
+}
+
+// ClusterRebootStatus summarizes the state of the reboot process of nodes managed by the operator.
+type ClusterRebootStatus struct {
+	// Status of each managed node, sorted by node name.
+	Nodes []NodeRebootStatus `json:"nodes"`
+	// Number of nodes in each state. States without any nodes are omitted.
+	Totals map[NodeRebootState]int `json:"totals"`
+}
+
+// Key of the rebooting nodes ConfigMap holding comma-separated names of nodes going through the reboot process.
+const rebootingNodesConfigMapKey = "rebootingNodes"
+
+// statusSnapshot is the state of the reboot process served by the status endpoint, captured
+// at the end of a reconciliation loop.
+type statusSnapshot struct {
+	ClusterRebootStatus
+
+	InsideRebootWindow    bool `json:"insideRebootWindow"`
+	InsideExclusionWindow bool `json:"insideExclusionWindow"`
+	Paused                bool `json:"paused"`
+	// Reason why rebootable nodes were not scheduled for rebooting, empty if they were not blocked.
+	SchedulingBlockedReason string    `json:"schedulingBlockedReason,omitempty"`
+	RebootingNodes          int       `json:"rebootingNodes"`
+	MaxRebootingNodes       int       `json:"maxRebootingNodes"`
+	UpdatedAt               time.Time `json:"updatedAt"`
+}
+
+// statusCache holds the latest status snapshot, so serving status does not require
+// listing nodes on every request.
+type statusCache struct {
+	mu sync.RWMutex
+
+	// Nil until the first reconciliation loop completes.
+	snapshot *statusSnapshot
+
+	// Reason why scheduling rebootable nodes was blocked in the last reconciliation loop.
+	schedulingBlockedBy error
+}
+
+// Status returns the state of the reboot process of all nodes managed by the operator.
+//
+// Status is computed from the node cache, so it is only available while operator is running.
+func (k *Kontroller) Status() ClusterRebootStatus {
+	nodelist, err := k.listNodes(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed listing nodes for status: %v", err)
+
+		return k.clusterRebootStatus(&corev1.NodeList{})
+	}
+
+	return k.clusterRebootStatus(nodelist)
+}
+
+// clusterRebootStatus returns the state of the reboot process of given nodes.
+func (k *Kontroller) clusterRebootStatus(nodelist *corev1.NodeList) ClusterRebootStatus {
+	status := ClusterRebootStatus{
+		Nodes:  []NodeRebootStatus{},
+		Totals: map[NodeRebootState]int{},
+	}
+
+	for _, node := range nodelist.Items {
+		node := node
+
+		state := k.nodeRebootState(&node)
+
+		nodeStatus := NodeRebootStatus{
+			Name:               node.Name,
+			State:              state,
+			MissingAnnotations: k.awaitedAnnotations(&node, state),
+		}
+
+		if state == NodeRebootStateRebootNeeded {
+			nodeStatus.IneligibleReason = k.ineligibleReason(&node)
+		}
+
+		status.Nodes = append(status.Nodes, nodeStatus)
+		status.Totals[state]++
+	}
+
+	return status
+}
+
+// nodeRebootState returns state of the reboot process of given node, using the same
+// selectors as reconciliation phases.
+func (k *Kontroller) nodeRebootState(node *corev1.Node) NodeRebootState {
+	switch {
+	case node.Labels[k.keys.LabelRebootFailed] == constants.True:
+		return NodeRebootStateRebootFailed
+	case k.selectors.NodeInPhase(node, k8sutil.NodePhaseAfterReboot):
+		return NodeRebootStateAfterReboot
+	case k.selectors.NodeInPhase(node, k8sutil.NodePhaseBeforeReboot):
+		return NodeRebootStateBeforeReboot
+	case k.selectors.NodeInPhase(node, k8sutil.NodePhaseRebooting),
+		k.selectors.NodeInPhase(node, k8sutil.NodePhaseJustRebooted):
+		return NodeRebootStateRebooting
+	case k.selectors.NodeInPhase(node, k8sutil.NodePhaseRebootable):
+		return NodeRebootStateRebootNeeded
+	default:
+		return NodeRebootStateIdle
+	}
+}
+
+// awaitedAnnotations returns before or after reboot annotations, which given node in given state
+// of the reboot process still waits for to be set to their expected values.
+func (k *Kontroller) awaitedAnnotations(node *corev1.Node, state NodeRebootState) []string {
+	switch state {
+	case NodeRebootStateBeforeReboot:
+		return missingAnnotations(node, k.beforeRebootAnnotations, k.beforeRebootValues)
+	case NodeRebootStateAfterReboot:
+		return missingAnnotations(node, k.afterRebootAnnotations, k.afterRebootValues)
+	default:
+		return nil
+	}
+}
+
+// updateStatusSnapshot captures the current state of the reboot process for the status endpoint,
+// if it is configured.
+func (k *Kontroller) updateStatusSnapshot(paused bool) {
+	if k.statusAddress == "" {
+		return
+	}
+
+	nodelist, err := k.listNodes(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed listing nodes for status: %v", err)
+
+		return
+	}
+
+	var schedulingBlockedReason string
+	if blocked := k.RebootSchedulingBlocked(); blocked != nil {
+		schedulingBlockedReason = blocked.Error()
+	}
+
+	now := time.Now()
+
+	snapshot := &statusSnapshot{
+		ClusterRebootStatus:     k.clusterRebootStatus(nodelist),
+		SchedulingBlockedReason: schedulingBlockedReason,
+		InsideRebootWindow:      k.insideRebootWindow(now),
+		InsideExclusionWindow:   k.insideExclusionWindow(now),
+		Paused:                  paused,
+		RebootingNodes:          len(k.rebootingNodes(nodelist)),
+		MaxRebootingNodes:       k.maxRebootingNodes,
+		UpdatedAt:               now.UTC(),
+	}
+
+	k.status.mu.Lock()
+	defer k.status.mu.Unlock()
+
+	k.status.snapshot = snapshot
+}
+
+// Maximum time publishing rebooting nodes may take, so an unresponsive API server does not block
+// the reconciliation loop after it finishes.
+const publishRebootingNodesTimeout = 10 * time.Second
+
+// publishRebootingNodes records names of nodes going through the reboot process in configured rebooting
+// nodes ConfigMap, if it is configured, creating the ConfigMap if it does not exist. ConfigMap is only
+// updated when the list of nodes changes.
+//
+// It is called at the end of every reconciliation loop, which only runs while the operator is leading,
+// so only the leader writes to the ConfigMap.
+func (k *Kontroller) publishRebootingNodes(ctx context.Context) {
+	if k.rebootingNodesConfigMap == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishRebootingNodesTimeout)
+	defer cancel()
+
+	if err := k.updateRebootingNodesConfigMap(ctx); err != nil {
+		klog.ErrorS(err, "Failed publishing rebooting nodes", "configMap", k.rebootingNodesConfigMap)
+	}
+}
+
+func (k *Kontroller) updateRebootingNodesConfigMap(ctx context.Context) error {
+	nodelist, err := k.listNodes(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("listing nodes: %w", err)
+	}
+
+	names := []string{}
+
+	for _, node := range k.rebootingNodes(nodelist) {
+		names = append(names, node.Name)
+	}
+
+	sort.Strings(names)
+
+	rebootingNodes := strings.Join(names, ",")
+
+	configMaps := k.kc.CoreV1().ConfigMaps(k.namespace)
+
+	configMap, err := configMaps.Get(ctx, k.rebootingNodesConfigMap, metav1.GetOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      k.rebootingNodesConfigMap,
+				Namespace: k.namespace,
+			},
+			Data: map[string]string{
+				rebootingNodesConfigMapKey: rebootingNodes,
+			},
+		}
+
+		if _, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("creating ConfigMap: %w", err)
+		}
+
+		return nil
+	case err != nil:
+		return fmt.Errorf("getting ConfigMap: %w", err)
+	}
+
+	if value, ok := configMap.Data[rebootingNodesConfigMapKey]; ok && value == rebootingNodes {
+		return nil
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+
+	configMap.Data[rebootingNodesConfigMapKey] = rebootingNodes
+
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating ConfigMap: %w", err)
+	}
+
+	return nil
+}
+
+// statusHandler returns a handler serving the latest status snapshot as JSON.
+func (k *Kontroller) statusHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		k.status.mu.RLock()
+		snapshot := k.status.snapshot
+		k.status.mu.RUnlock()
+
+		if snapshot == nil {
+			http.Error(w, "waiting for first reconciliation to complete", http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+			klog.Errorf("Failed writing status response: %v", err)
+		}
+	})
+
+	return mux
+}
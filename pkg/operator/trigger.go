@@ -0,0 +1,137 @@
+package operator
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Time to wait after observing a node change before reconciling, if not configured otherwise,
+	// so changes made to multiple nodes at once are handled by a single reconciliation loop.
+	defaultNodeChangeDebounce = time.Second
+
+	// Time to wait before retrying failed reconciliation for the first time, if not configured otherwise.
+	defaultErrorRetryBackoff = time.Second
+
+	// Key under which failures of reconciliation are tracked by the error retry rate limiter.
+	reconcileRetryKey = "reconcile"
+)
+
+// nodeChangeHandler returns event handler for node informer, which triggers reconciliation
+// when reboot related state of a node changes.
+func (k *Kontroller) nodeChangeHandler() cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode, ok := oldObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+
+			newNode, ok := newObj.(*corev1.Node)
+			if !ok {
+				return
+			}
+
+			if !k.rebootStateChanged(oldNode, newNode) {
+				return
+			}
+
+			klog.V(4).InfoS("Reboot state of node changed, triggering reconciliation", "node", newNode.Name)
+
+			k.triggerReconciliation()
+		},
+	}
+}
+
+// rebootStateChanged returns true when labels or any annotation relevant for the reboot process
+// differ between given versions of a node.
+func (k *Kontroller) rebootStateChanged(oldNode, newNode *corev1.Node) bool {
+	if !labels.Equals(oldNode.Labels, newNode.Labels) {
+		return true
+	}
+
+	annotations := []string{
+		k.keys.AnnotationRebootNeeded,
+		k.keys.AnnotationRebootInProgress,
+		k.keys.AnnotationRebootPaused,
+		k.keys.AnnotationRebootPausedUntil,
+		k.keys.AnnotationRequestReboot,
+		k.keys.AnnotationOkToReboot,
+	}
+
+	annotations = append(annotations, k.beforeRebootAnnotations...)
+	annotations = append(annotations, k.afterRebootAnnotations...)
+
+	for _, annotation := range annotations {
+		oldValue, oldOK := oldNode.Annotations[annotation]
+		newValue, newOK := newNode.Annotations[annotation]
+
+		if oldOK != newOK || oldValue != newValue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// triggerReconciliation requests reconciliation loop to run without waiting for the next period.
+// Requests made while one is already pending are merged.
+func (k *Kontroller) triggerReconciliation() {
+	select {
+	case k.reconcileTrigger <- struct{}{}:
+	default:
+	}
+}
+
+// reconcileUntil calls given function every reconciliation period, measured since the previous call
+// finished, until given context is done.
+//
+// If given function reports a failure, it is called again sooner, after a backoff growing with each
+// consecutive failure up to the reconciliation period.
+//
+// If watching nodes is enabled, given function is also called when reconciliation gets triggered by
+// a node change, once node change debounce passes. Changes observed in the meantime are handled by
+// the same call.
+func (k *Kontroller) reconcileUntil(ctx context.Context, reconcile func() bool) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		delay := k.reconciliationPeriod
+
+		if reconcile() {
+			k.errorRetryLimiter.Forget(reconcileRetryKey)
+		} else {
+			delay = k.errorRetryLimiter.When(reconcileRetryKey)
+
+			klog.V(4).InfoS("Reconciliation failed, retrying sooner", "retryAfter", delay)
+		}
+
+		// When watching nodes is disabled, trigger channel is nil, so it blocks forever.
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		case <-k.reconcileTrigger:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(k.nodeChangeDebounce):
+			}
+
+			// Changes observed during debounce will be handled by the upcoming reconciliation.
+			select {
+			case <-k.reconcileTrigger:
+			default:
+			}
+		}
+	}
+}
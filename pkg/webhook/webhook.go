@@ -0,0 +1,89 @@
+// Package webhook provides a client for sending JSON payloads to HTTP webhooks,
+// optionally signed with a shared secret, so receivers can verify their origin.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// SignatureHeader is a HTTP header carrying the signature of the payload.
+	SignatureHeader = "X-FLUO-Signature-256"
+
+	// SignaturePrefix is a prefix of the signature value, identifying used algorithm.
+	SignaturePrefix = "sha256="
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Sign returns HMAC-SHA256 signature of given payload using given secret in the format
+// used in SignatureHeader.
+func Sign(payload, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+
+	// Writing to hash never returns an error.
+	_, _ = mac.Write(payload)
+
+	return SignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks if given signature matches given payload and secret.
+func Verify(payload, secret []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(payload, secret)), []byte(signature))
+}
+
+// Sender sends JSON payloads to configured webhook URL.
+type Sender struct {
+	// URL of the webhook.
+	URL string
+	// Secret used for signing payloads. If empty, payloads are not signed.
+	Secret []byte
+	// HTTP client used for sending requests. If nil, client with default timeout is used.
+	Client *http.Client
+}
+
+// Send encodes given payload as JSON and POSTs it to the configured URL. If secret is configured,
+// signature of the encoded payload is sent in SignatureHeader.
+func (s *Sender) Send(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(s.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(body, s.Secret))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck // We do not read the body, so closing errors are irrelevant.
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response status %q", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flatcar/flatcar-linux-update-operator/pkg/webhook"
+)
+
+func Test_Signing_payload(t *testing.T) {
+	t.Parallel()
+
+	t.Run("produces_HMAC_SHA256_of_payload", func(t *testing.T) {
+		t.Parallel()
+
+		// Test case 2 from RFC 4231.
+		expected := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+
+		if got := webhook.Sign([]byte("what do ya want for nothing?"), []byte("Jefe")); got != expected {
+			t.Fatalf("Expected signature %q, got %q", expected, got)
+		}
+	})
+
+	t.Run("produces_signature_which_does_not_verify_with_different_secret", func(t *testing.T) {
+		t.Parallel()
+
+		payload := []byte("foo")
+		signature := webhook.Sign(payload, []byte("secret"))
+
+		if webhook.Verify(payload, []byte("other-secret"), signature) {
+			t.Fatalf("Expected signature to not verify with different secret")
+		}
+	})
+}
+
+//nolint:funlen // Just many subtests.
+func Test_Sending_payload(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("test-secret")
+	payload := map[string]string{"node": "foo"}
+
+	t.Run("signs_canonical_JSON_payload_when_secret_is_configured", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Errorf("Reading body: %v", err)
+			}
+
+			expectedBody, err := json.Marshal(payload)
+			if err != nil {
+				t.Errorf("Encoding payload: %v", err)
+			}
+
+			if string(body) != string(expectedBody) {
+				t.Errorf("Expected body %q, got %q", expectedBody, body)
+			}
+
+			if !webhook.Verify(body, secret, r.Header.Get(webhook.SignatureHeader)) {
+				t.Errorf("Signature %q does not match payload", r.Header.Get(webhook.SignatureHeader))
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		sender := &webhook.Sender{URL: server.URL, Secret: secret}
+
+		if err := sender.Send(context.Background(), payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("does_not_sign_payload_when_secret_is_not_configured", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if v := r.Header.Get(webhook.SignatureHeader); v != "" {
+				t.Errorf("Unexpected signature header with value %q", v)
+			}
+		}))
+		t.Cleanup(server.Close)
+
+		sender := &webhook.Sender{URL: server.URL}
+
+		if err := sender.Send(context.Background(), payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+
+	t.Run("returns_error_when_webhook_responds_with_error", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(server.Close)
+
+		sender := &webhook.Sender{URL: server.URL, Secret: secret}
+
+		if err := sender.Send(context.Background(), payload); err == nil {
+			t.Fatalf("Expected error")
+		}
+	})
+}